@@ -22,16 +22,40 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/codeexecutor"
+	"github.com/sjzsdu/adk-go/compaction"
 	agentinternal "github.com/sjzsdu/adk-go/internal/agent"
 	icontext "github.com/sjzsdu/adk-go/internal/context"
 	"github.com/sjzsdu/adk-go/internal/llminternal"
 	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/planner"
 	"github.com/sjzsdu/adk-go/session"
 	"github.com/sjzsdu/adk-go/tool"
 )
 
 // New is a constructor for LLMAgent.
 func New(cfg Config) (agent.Agent, error) {
+	switch cfg.IncludeContents {
+	case "", IncludeContentsDefault, IncludeContentsNone:
+	case IncludeContentsLastTurns:
+		if cfg.IncludeContentsTurns <= 0 && cfg.IncludeContentsProvider == nil {
+			return nil, fmt.Errorf("agent %q: IncludeContentsTurns must be positive with IncludeContentsLastTurns", cfg.Name)
+		}
+	case IncludeContentsTokenBudget:
+		if cfg.IncludeContentsMaxTokens <= 0 && cfg.IncludeContentsProvider == nil {
+			return nil, fmt.Errorf("agent %q: IncludeContentsMaxTokens must be positive with IncludeContentsTokenBudget", cfg.Name)
+		}
+	default:
+		if cfg.IncludeContentsProvider == nil {
+			return nil, fmt.Errorf("agent %q: unknown IncludeContents %q", cfg.Name, cfg.IncludeContents)
+		}
+	}
+	if cfg.Compaction != nil {
+		if err := cfg.Compaction.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid compaction config for agent %q: %w", cfg.Name, err)
+		}
+	}
+
 	beforeModelCallbacks := make([]llminternal.BeforeModelCallback, 0, len(cfg.BeforeModelCallbacks))
 	for _, c := range cfg.BeforeModelCallbacks {
 		beforeModelCallbacks = append(beforeModelCallbacks, llminternal.BeforeModelCallback(c))
@@ -85,11 +109,17 @@ func New(cfg Config) (agent.Agent, error) {
 			OutputSchema:             cfg.OutputSchema,
 			// TODO: internal type for includeContents
 			IncludeContents:           string(cfg.IncludeContents),
+			IncludeContentsTurns:      cfg.IncludeContentsTurns,
+			IncludeContentsMaxTokens:  cfg.IncludeContentsMaxTokens,
+			IncludeContentsProvider:   llminternal.IncludeContentsProvider(cfg.IncludeContentsProvider),
 			Instruction:               cfg.Instruction,
 			InstructionProvider:       llminternal.InstructionProvider(cfg.InstructionProvider),
 			GlobalInstruction:         cfg.GlobalInstruction,
 			GlobalInstructionProvider: llminternal.InstructionProvider(cfg.GlobalInstructionProvider),
 			OutputKey:                 cfg.OutputKey,
+			Planner:                   cfg.Planner,
+			CodeExecutor:              cfg.CodeExecutor,
+			Compaction:                cfg.Compaction,
 		},
 	}
 
@@ -234,14 +264,26 @@ type Config struct {
 
 	// Whether to include contents (conversation history) in the model request.
 	IncludeContents IncludeContents
+	// IncludeContentsTurns is the number of turns included with
+	// IncludeContentsLastTurns.
+	IncludeContentsTurns int
+	// IncludeContentsMaxTokens is the token budget of IncludeContentsTokenBudget.
+	IncludeContentsMaxTokens int
+	// IncludeContentsProvider builds the conversation history sent to the
+	// model. It takes over the IncludeContents field if both are set.
+	IncludeContentsProvider IncludeContentsProvider
 
 	// TODO(ngeorgy): consider to switch to jsonschema for input and output schema.
 	// The input schema when agent is used as a tool.
 	InputSchema *genai.Schema
 	// The output schema when agent replies.
 	//
-	// NOTE: when this is set, agent can only reply and cannot use any tools,
-	// such as function tools, RAGs, agent transfer, etc.
+	// If the agent has tools or can transfer to other agents and the model
+	// cannot combine function calling with a response schema (Gemini 2.5 and
+	// lower models of the Gemini API), the agent gets a set_model_response
+	// tool whose parameters are the output schema. A successful call of that
+	// tool is the final response of the agent, with the arguments of the
+	// call as its JSON text.
 	OutputSchema *genai.Schema
 
 	// Callbacks are executed in the order they are provided.
@@ -260,12 +302,38 @@ type Config struct {
 
 	OnToolErrorCallbacks []OnToolErrorCallback
 
+	// OutputRetries is the number of times an agent created with NewTyped
+	// asks the model to fix a final response that does not match the output
+	// schema. Zero means DefaultOutputRetries, a negative value disables the
+	// retries.
+	OutputRetries int
+
 	// OutputKey is an optional parameter to specify the key in session state for the agent output.
 	//
 	// Typical uses cases are:
 	// - Extracts agent reply for later use, such as in tools, callbacks, etc.
 	// - Connects agents to coordinate with each other.
 	OutputKey string
+
+	// Planner instructs the agent to make a plan and execute it step by step.
+	//
+	// See planner.NewPlanReAct and planner.NewBuiltIn.
+	Planner planner.Planner
+
+	// CodeExecutor executes code blocks found in model responses and sends
+	// the results back to the model. Output files are saved as artifacts.
+	//
+	// See codeexecutor.CodeExecutor and localexecutor.New.
+	CodeExecutor codeexecutor.CodeExecutor
+
+	// Compaction enables the automatic compaction of the conversation history.
+	// When a model request exceeds the token budget, older events are
+	// summarized and the summary is sent instead. If nil, the full history is
+	// sent.
+	//
+	// Compaction applies to IncludeContentsDefault only and is not used with
+	// IncludeContentsProvider.
+	Compaction *compaction.Config
 }
 
 // BeforeModelCallback that is called before sending a request to the model.
@@ -321,8 +389,22 @@ const (
 	IncludeContentsNone IncludeContents = "none"
 	// IncludeContentsDefault is enabled by default. The llmagent receives the relevant conversation history.
 	IncludeContentsDefault IncludeContents = "default"
+	// IncludeContentsLastTurns makes the llmagent receive the last Config.IncludeContentsTurns turns of the conversation history.
+	// A turn starts with a user message or with the reply of another agent.
+	IncludeContentsLastTurns IncludeContents = "last_turns"
+	// IncludeContentsTokenBudget makes the llmagent receive the most recent conversation history that fits
+	// Config.IncludeContentsMaxTokens tokens, as estimated by compaction.EstimateTokens.
+	IncludeContentsTokenBudget IncludeContents = "token_budget"
 )
 
+// IncludeContentsProvider returns the contents sent to the model for the
+// conversation history.
+//
+// The events are the history visible to the agent: events of other branches
+// and events without content are removed, replies of other agents are
+// converted to user messages, and function responses follow their calls.
+type IncludeContentsProvider func(ctx agent.ReadonlyContext, events []*session.Event) ([]*genai.Content, error)
+
 type llmAgent struct {
 	agent.Agent
 	llminternal.State
@@ -340,6 +422,11 @@ type llmAgent struct {
 
 	inputSchema  *genai.Schema
 	outputSchema *genai.Schema
+
+	// outputParser decodes and validates final responses of agents created
+	// with NewTyped.
+	outputParser  func(text string) (any, error)
+	outputRetries int
 }
 
 type agentState = agentinternal.State
@@ -370,15 +457,112 @@ func (a *llmAgent) run(ctx agent.InvocationContext) iter.Seq2[*session.Event, er
 	}
 
 	return func(yield func(*session.Event, error) bool) {
-		for ev, err := range f.Run(ctx) {
-			a.maybeSaveOutputToState(ev)
-			if !yield(ev, err) {
+		for attempt := 0; ; attempt++ {
+			var rejected *session.Event
+			var outputErr error
+			// partials holds the partial events of a streamed response of an
+			// agent created with NewTyped until the response is validated.
+			var partials []*session.Event
+			flushPartials := func() bool {
+				for _, partial := range partials {
+					if !yield(partial, nil) {
+						return false
+					}
+				}
+				partials = nil
+				return true
+			}
+			for ev, err := range f.Run(ctx) {
+				if err == nil && a.outputParser != nil && ev != nil && ev.Partial && ev.Author == a.Name() {
+					partials = append(partials, ev)
+					continue
+				}
+				if err == nil {
+					if parsed, err := a.maybeParseOutput(ev); parsed && err != nil {
+						// The invalid response is not yielded, nor are its
+						// partial events, so that it is neither persisted
+						// nor seen as the final response of the agent.
+						rejected, outputErr = ev, err
+						partials = nil
+						continue
+					}
+				}
+				if !flushPartials() {
+					return
+				}
+				a.maybeSaveOutputToState(ev)
+				if !yield(ev, err) {
+					return
+				}
+			}
+			if !flushPartials() {
+				return
+			}
+			if rejected == nil || ctx.Ended() {
+				return
+			}
+			if attempt >= a.outputRetries {
+				yield(nil, fmt.Errorf("agent %q: final response does not match the output schema: %w", a.Name(), outputErr))
+				return
+			}
+			// Feed the invalid response and the error back to the model as
+			// a user message and let it try again.
+			if !yield(outputFeedbackEvent(ctx, rejected, outputErr), nil) {
 				return
 			}
 		}
 	}
 }
 
+// outputFeedbackEvent returns the user event asking the model to fix the
+// rejected final response. It carries the actions of the rejected response,
+// e.g. state changes made by callbacks.
+func outputFeedbackEvent(ctx agent.InvocationContext, rejected *session.Event, err error) *session.Event {
+	var sb strings.Builder
+	for _, part := range rejected.Content.Parts {
+		if part.Text != "" && !part.Thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	feedback := session.NewEvent(ctx.InvocationID())
+	feedback.Author = "user"
+	feedback.Branch = ctx.Branch()
+	feedback.Actions = rejected.Actions
+	feedback.Content = genai.NewContentFromText(fmt.Sprintf(
+		"Your previous response is invalid: %v\nPrevious response:\n%s\nReply again with only a JSON value matching the output schema.", err, sb.String()), genai.RoleUser)
+	return feedback
+}
+
+// maybeParseOutput decodes and validates the final response of an agent
+// created with NewTyped. It reports whether the event is such a response,
+// and the validation error if any. On success, the decoded value replaces the
+// raw text saved to the state.
+func (a *llmAgent) maybeParseOutput(event *session.Event) (bool, error) {
+	if a.outputParser == nil || event == nil || event.Author != a.Name() || event.Partial || !event.IsFinalResponse() {
+		return false, nil
+	}
+	if event.Content == nil || event.Content.Role == genai.RoleUser {
+		return false, nil
+	}
+	var sb strings.Builder
+	for _, part := range event.Content.Parts {
+		if part.Text != "" && !part.Thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	value, err := a.outputParser(sb.String())
+	if err != nil {
+		return true, err
+	}
+	if a.OutputKey != "" {
+		if event.Actions.StateDelta == nil {
+			event.Actions.StateDelta = make(map[string]any)
+		}
+		event.Actions.StateDelta[a.OutputKey] = value
+	}
+	return true, nil
+}
+
 // maybeSaveOutputToState saves the model output to state if needed. skip if the event
 // was authored by some other agent (e.g. current agent transferred to another agent)
 func (a *llmAgent) maybeSaveOutputToState(event *session.Event) {
@@ -389,6 +573,10 @@ func (a *llmAgent) maybeSaveOutputToState(event *session.Event) {
 		// TODO: log "Skipping output save for agent %s: event authored by %s"
 		return
 	}
+	if a.outputParser != nil {
+		// Only valid responses are saved, by maybeParseOutput.
+		return
+	}
 	if a.OutputKey != "" && !event.Partial && event.Content != nil && len(event.Content.Parts) > 0 {
 		var sb strings.Builder
 		for _, part := range event.Content.Parts {
@@ -15,22 +15,30 @@
 package llmagent_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"iter"
+	"maps"
 	"net/http"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
 	"github.com/sjzsdu/adk-go/agent/llmagent"
+	"github.com/sjzsdu/adk-go/codeexecutor"
 	"github.com/sjzsdu/adk-go/internal/testutil"
+	"github.com/sjzsdu/adk-go/internal/utils"
 	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/model/gemini"
+	"github.com/sjzsdu/adk-go/planner"
+	"github.com/sjzsdu/adk-go/runner"
 	"github.com/sjzsdu/adk-go/session"
 	"github.com/sjzsdu/adk-go/tool"
 	"github.com/sjzsdu/adk-go/tool/functiontool"
@@ -876,6 +884,134 @@ func TestFunctionTool(t *testing.T) {
 	}
 }
 
+func TestOutputSchemaWithTools(t *testing.T) {
+	schema := &genai.Schema{
+		Type:       genai.TypeObject,
+		Properties: map[string]*genai.Schema{"city": {Type: genai.TypeString}},
+		Required:   []string{"city"},
+	}
+	lookupTool, err := functiontool.New(functiontool.Config{
+		Name:        "lookup",
+		Description: "looks up the capital",
+	}, func(tool.Context, struct{}) (map[string]any, error) {
+		return map[string]any{"capital": "Paris"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockModel := &testutil.MockModel{Responses: []*genai.Content{
+		genai.NewContentFromFunctionCall("lookup", map[string]any{}, genai.RoleModel),
+		genai.NewContentFromFunctionCall("set_model_response", map[string]any{"town": "Paris"}, genai.RoleModel),
+		genai.NewContentFromFunctionCall("set_model_response", map[string]any{"city": "Lyon"}, genai.RoleModel),
+		genai.NewContentFromFunctionCall("set_model_response", map[string]any{"city": "Paris"}, genai.RoleModel),
+	}}
+	a, err := llmagent.New(llmagent.Config{
+		Name: "research_agent",
+		// Gemini 2.x models of the Gemini API can't combine tools and a
+		// response schema.
+		Model:        geminiAPIModel{mockModel},
+		Tools:        []tool.Tool{lookupTool},
+		OutputSchema: schema,
+		OutputKey:    "answer",
+		AfterToolCallbacks: []llmagent.AfterToolCallback{
+			func(ctx tool.Context, tool tool.Tool, args, result map[string]any, err error) (map[string]any, error) {
+				// A callback turning the response invalid.
+				if tool.Name() == "set_model_response" && args["city"] == "Lyon" {
+					return map[string]any{"town": "Lyon"}, nil
+				}
+				return nil, nil
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := testutil.CollectEvents(testutil.NewTestAgentRunner(t, a).Run(t, "session", "what is the capital of France?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(mockModel.Requests), 4; got != want {
+		t.Fatalf("got %d model requests, want %d", got, want)
+	}
+	for _, req := range mockModel.Requests {
+		if req.Config.ResponseSchema != nil {
+			t.Errorf("request has response schema %v, want none since the agent has tools", req.Config.ResponseSchema)
+		}
+		if _, ok := req.Tools["set_model_response"]; !ok {
+			t.Errorf("request tools = %v, want set_model_response", req.Tools)
+		}
+	}
+	// The invalid responses are reported back to the model.
+	for _, req := range mockModel.Requests[2:] {
+		last := req.Contents[len(req.Contents)-1].Parts[0]
+		if resp := last.FunctionResponse; resp == nil || !strings.Contains(fmt.Sprint(resp.Response["error"]), "invalid output schema") {
+			t.Errorf("last content of the retry request = %v, want the validation error", last)
+		}
+	}
+
+	last := events[len(events)-1]
+	if !last.IsFinalResponse() || last.Author != "research_agent" || last.Content.Role != genai.RoleModel {
+		t.Fatalf("last event = %+v, want the final response of the agent", last)
+	}
+	if got, want := last.Content.Parts[0].Text, `{"city":"Paris"}`; got != want {
+		t.Errorf("final response = %q, want %q", got, want)
+	}
+	if got, want := last.Actions.StateDelta["answer"], `{"city":"Paris"}`; got != want {
+		t.Errorf("saved output = %v, want %q", got, want)
+	}
+	for _, ev := range events[:len(events)-1] {
+		if ev.IsFinalResponse() {
+			t.Errorf("got final response %v before the structured response", ev.Content.Parts[0])
+		}
+	}
+}
+
+func TestOutputSchemaWithTools_NativeSupport(t *testing.T) {
+	lookupTool, err := functiontool.New(functiontool.Config{
+		Name:        "lookup",
+		Description: "looks up the capital",
+	}, func(tool.Context, struct{}) (map[string]any, error) {
+		return map[string]any{"capital": "Paris"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{"city": {Type: genai.TypeString}}}
+	mockModel := &testutil.MockModel{Responses: []*genai.Content{genai.NewContentFromText(`{"city": "Paris"}`, genai.RoleModel)}}
+	a, err := llmagent.New(llmagent.Config{
+		Name:         "research_agent",
+		Model:        mockModel,
+		Tools:        []tool.Tool{lookupTool},
+		OutputSchema: schema,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testutil.CollectEvents(testutil.NewTestAgentRunner(t, a).Run(t, "session", "what is the capital of France?")); err != nil {
+		t.Fatal(err)
+	}
+	// Models other than Gemini 2.x of the Gemini API get the response
+	// schema.
+	req := mockModel.Requests[0]
+	if diff := cmp.Diff(schema, req.Config.ResponseSchema); diff != "" {
+		t.Errorf("request response schema mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := req.Tools["set_model_response"]; ok {
+		t.Errorf("request tools = %v, want no set_model_response", req.Tools)
+	}
+}
+
+// geminiAPIModel is a mock Gemini 2.0 model of the Gemini API.
+type geminiAPIModel struct {
+	*testutil.MockModel
+}
+
+func (geminiAPIModel) Name() string { return "gemini-2.0-flash" }
+
+func (geminiAPIModel) GetGoogleLLMVariant() genai.Backend { return genai.BackendGeminiAPI }
+
 func TestAgentTransfer(t *testing.T) {
 	// Helpers to create genai.Content conveniently.
 	transferCall := func(agentName string) *genai.Content {
@@ -1096,3 +1232,332 @@ type roundTripperFunc func(*http.Request) (*http.Response, error)
 func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 	return fn(req)
 }
+
+func TestPlanReActPlanner(t *testing.T) {
+	mockModel := &testutil.MockModel{
+		Responses: []*genai.Content{
+			genai.NewContentFromText(planner.PlanningTag+" 1. answer "+planner.FinalAnswerTag+"42", genai.RoleModel),
+			genai.NewContentFromText(planner.FinalAnswerTag+"43", genai.RoleModel),
+		},
+	}
+	a, err := llmagent.New(llmagent.Config{
+		Name:    "planning_agent",
+		Model:   mockModel,
+		Planner: planner.NewPlanReAct(),
+	})
+	if err != nil {
+		t.Fatalf("failed to create LLM Agent: %v", err)
+	}
+
+	runner := testutil.NewTestAgentRunner(t, a)
+	events, err := testutil.CollectEvents(runner.Run(t, "session1", "question"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantParts := []*genai.Part{
+		{Text: planner.PlanningTag + " 1. answer " + planner.FinalAnswerTag, Thought: true},
+		{Text: "42"},
+	}
+	if diff := cmp.Diff(wantParts, events[len(events)-1].Content.Parts); diff != "" {
+		t.Errorf("unexpected response parts (-want +got):\n%s", diff)
+	}
+
+	if _, err := testutil.CollectEvents(runner.Run(t, "session1", "next question")); err != nil {
+		t.Fatal(err)
+	}
+	if len(mockModel.Requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(mockModel.Requests))
+	}
+	req := mockModel.Requests[1]
+	if si := req.Config.SystemInstruction; si == nil || !strings.Contains(si.Parts[0].Text, planner.PlanningTag) {
+		t.Errorf("planning instruction missing from system instruction: %v", si)
+	}
+	for _, content := range req.Contents {
+		for _, part := range content.Parts {
+			if part.Thought {
+				t.Errorf("thought part %q was sent to the model", part.Text)
+			}
+		}
+	}
+}
+
+func TestBuiltInPlanner(t *testing.T) {
+	mockModel := &testutil.MockModel{
+		Responses: []*genai.Content{genai.NewContentFromText("answer", genai.RoleModel)},
+	}
+	budget := int32(1024)
+	thinkingConfig := &genai.ThinkingConfig{IncludeThoughts: true, ThinkingBudget: &budget}
+	a, err := llmagent.New(llmagent.Config{
+		Name:    "thinking_agent",
+		Model:   mockModel,
+		Planner: planner.NewBuiltIn(thinkingConfig),
+	})
+	if err != nil {
+		t.Fatalf("failed to create LLM Agent: %v", err)
+	}
+
+	runner := testutil.NewTestAgentRunner(t, a)
+	if _, err := testutil.CollectEvents(runner.Run(t, "session1", "question")); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(thinkingConfig, mockModel.Requests[0].Config.ThinkingConfig); diff != "" {
+		t.Errorf("unexpected thinking config (-want +got):\n%s", diff)
+	}
+}
+
+type fakeCodeExecutor struct {
+	inputs  []*codeexecutor.Input
+	results []*codeexecutor.Result
+}
+
+func (e *fakeCodeExecutor) ExecuteCode(ctx agent.InvocationContext, input *codeexecutor.Input) (*codeexecutor.Result, error) {
+	e.inputs = append(e.inputs, input)
+	result := e.results[0]
+	e.results = e.results[1:]
+	return result, nil
+}
+
+func (e *fakeCodeExecutor) Options() codeexecutor.Options {
+	return codeexecutor.DefaultOptions()
+}
+
+func TestCodeExecutor(t *testing.T) {
+	mockModel := &testutil.MockModel{
+		Responses: []*genai.Content{
+			genai.NewContentFromText("Let me compute.\n```python\nprint(6 * 7)\n```\nignored", genai.RoleModel),
+			genai.NewContentFromText("```sh\nfalse\n```", genai.RoleModel),
+			genai.NewContentFromText("The answer is 42.", genai.RoleModel),
+		},
+	}
+	executor := &fakeCodeExecutor{
+		results: []*codeexecutor.Result{
+			{Stdout: "42"},
+			{Stderr: "failed"},
+		},
+	}
+	a, err := llmagent.New(llmagent.Config{
+		Name:         "code_agent",
+		Model:        mockModel,
+		CodeExecutor: executor,
+	})
+	if err != nil {
+		t.Fatalf("failed to create LLM Agent: %v", err)
+	}
+
+	runner := testutil.NewTestAgentRunner(t, a)
+	events, err := testutil.CollectEvents(runner.Run(t, "session1", "what is 6 * 7?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantInputs := []*codeexecutor.Input{
+		{Code: "print(6 * 7)", Language: codeexecutor.LanguagePython},
+		{Code: "false", Language: codeexecutor.LanguageShell},
+	}
+	if diff := cmp.Diff(wantInputs, executor.inputs); diff != "" {
+		t.Errorf("unexpected executed code (-want +got):\n%s", diff)
+	}
+
+	var gotParts [][]*genai.Part
+	for _, event := range events {
+		gotParts = append(gotParts, event.Content.Parts)
+	}
+	wantParts := [][]*genai.Part{
+		{
+			{Text: "Let me compute.\n"},
+			{ExecutableCode: &genai.ExecutableCode{Code: "print(6 * 7)", Language: genai.LanguagePython}},
+		},
+		{{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "Code execution result:\n42\n"}}},
+		{{Text: "```sh\nfalse\n```"}},
+		{{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeFailed, Output: "failed"}}},
+		{{Text: "The answer is 42."}},
+	}
+	if diff := cmp.Diff(wantParts, gotParts); diff != "" {
+		t.Errorf("unexpected event parts (-want +got):\n%s", diff)
+	}
+
+	if len(mockModel.Requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(mockModel.Requests))
+	}
+	// Code and results are sent back to the model as text.
+	contents := mockModel.Requests[2].Contents
+	wantContents := []*genai.Content{
+		genai.NewContentFromText("what is 6 * 7?", genai.RoleUser),
+		{Role: genai.RoleModel, Parts: []*genai.Part{{Text: "Let me compute.\n"}, {Text: "```tool_code\nprint(6 * 7)\n```"}}},
+		genai.NewContentFromText("```tool_output\nCode execution result:\n42\n\n```", genai.RoleUser),
+		genai.NewContentFromText("```sh\nfalse\n```", genai.RoleModel),
+		genai.NewContentFromText("```tool_output\nfailed\n```", genai.RoleUser),
+	}
+	if diff := cmp.Diff(wantContents, contents); diff != "" {
+		t.Errorf("unexpected request contents (-want +got):\n%s", diff)
+	}
+}
+
+// blockingModel blocks until the request context is done.
+type blockingModel struct{}
+
+func (blockingModel) Name() string { return "blocking-model" }
+
+func (blockingModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		<-ctx.Done()
+		yield(nil, ctx.Err())
+	}
+}
+
+func TestRunConfigLimits(t *testing.T) {
+	pingCall := genai.NewContentFromFunctionCall("ping", map[string]any{}, genai.RoleModel)
+	transferCall := func(agentName string) *genai.Content {
+		return genai.NewContentFromFunctionCall("transfer_to_agent", map[string]any{"agent_name": agentName}, genai.RoleModel)
+	}
+	pingTool, err := functiontool.New(functiontool.Config{
+		Name:        "ping",
+		Description: "returns pong",
+	}, func(tool.Context, struct{}) (map[string]any, error) {
+		return map[string]any{"result": "pong"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAgent := func(t *testing.T, name string, m model.LLM, subAgents ...agent.Agent) agent.Agent {
+		t.Helper()
+		a, err := llmagent.New(llmagent.Config{
+			Name:      name,
+			Model:     m,
+			Tools:     []tool.Tool{pingTool},
+			SubAgents: subAgents,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	tests := []struct {
+		name      string
+		agent     func(t *testing.T) agent.Agent
+		cfg       agent.RunConfig
+		wantLimit agent.Limit
+		// authors of the events before the limit event.
+		wantAuthors     []string
+		wantLimitAuthor string
+	}{
+		{
+			name: "max llm calls",
+			agent: func(t *testing.T) agent.Agent {
+				return newAgent(t, "root", &testutil.MockModel{Responses: []*genai.Content{pingCall, pingCall, pingCall}})
+			},
+			cfg:             agent.RunConfig{MaxLLMCalls: 2},
+			wantLimit:       agent.LimitLLMCalls,
+			wantAuthors:     []string{"root", "root", "root", "root"},
+			wantLimitAuthor: "root",
+		},
+		{
+			name: "max tool calls",
+			agent: func(t *testing.T) agent.Agent {
+				return newAgent(t, "root", &testutil.MockModel{Responses: []*genai.Content{pingCall, pingCall, pingCall}})
+			},
+			cfg:       agent.RunConfig{MaxToolCalls: 1},
+			wantLimit: agent.LimitToolCalls,
+			// The second function call is not yielded, as it exceeds the
+			// limit.
+			wantAuthors:     []string{"root", "root"},
+			wantLimitAuthor: "root",
+		},
+		{
+			name: "max agent transfers",
+			agent: func(t *testing.T) agent.Agent {
+				child := newAgent(t, "child", &testutil.MockModel{Responses: []*genai.Content{transferCall("root")}})
+				return newAgent(t, "root", &testutil.MockModel{Responses: []*genai.Content{transferCall("child")}}, child)
+			},
+			cfg:             agent.RunConfig{MaxAgentTransfers: 1},
+			wantLimit:       agent.LimitAgentTransfers,
+			wantAuthors:     []string{"root", "root", "child", "child"},
+			wantLimitAuthor: "child",
+		},
+		{
+			name: "timeout",
+			agent: func(t *testing.T) agent.Agent {
+				return newAgent(t, "root", blockingModel{})
+			},
+			cfg:             agent.RunConfig{Timeout: 50 * time.Millisecond},
+			wantLimit:       agent.LimitTimeout,
+			wantLimitAuthor: "root",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionService := session.InMemoryService()
+			r, err := runner.New(runner.Config{
+				AppName:        "test_app",
+				Agent:          tt.agent(t),
+				SessionService: sessionService,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "test_app", UserID: "user"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var events []*session.Event
+			var errs []error
+			for event, err := range r.Run(t.Context(), "user", created.Session.ID(), genai.NewContentFromText("hi", genai.RoleUser), tt.cfg) {
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				events = append(events, event)
+			}
+
+			if len(errs) != 1 {
+				t.Fatalf("got errors %v, want exactly one limit error", errs)
+			}
+			var limitErr *agent.LimitExceededError
+			if !errors.As(errs[0], &limitErr) {
+				t.Fatalf("got error %v, want *agent.LimitExceededError", errs[0])
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("exceeded limit = %q, want %q", limitErr.Limit, tt.wantLimit)
+			}
+			if len(events) == 0 {
+				t.Fatal("got no events")
+			}
+			last := events[len(events)-1]
+			if last.ErrorCode != agent.LimitExceededErrorCode || last.Author != tt.wantLimitAuthor || !last.IsFinalResponse() {
+				t.Errorf("last event = {Author: %q, ErrorCode: %q}, want a final limit event from %q", last.Author, last.ErrorCode, tt.wantLimitAuthor)
+			}
+			var gotAuthors []string
+			for _, event := range events[:len(events)-1] {
+				gotAuthors = append(gotAuthors, event.Author)
+			}
+			if diff := cmp.Diff(tt.wantAuthors, gotAuthors); diff != "" {
+				t.Errorf("event authors mismatch (-want +got):\n%s", diff)
+			}
+
+			// The limit event is stored in the session.
+			resp, err := sessionService.Get(t.Context(), &session.GetRequest{AppName: "test_app", UserID: "user", SessionID: created.Session.ID()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored := resp.Session.Events().At(resp.Session.Events().Len() - 1)
+			if stored.ErrorCode != agent.LimitExceededErrorCode {
+				t.Errorf("last stored event error code = %q, want %q", stored.ErrorCode, agent.LimitExceededErrorCode)
+			}
+			// Every function call stored in the session has a response.
+			pending := make(map[string]bool)
+			for event := range resp.Session.Events().All() {
+				for _, call := range utils.FunctionCalls(event.Content) {
+					pending[call.ID] = true
+				}
+				for _, fr := range utils.FunctionResponses(event.Content) {
+					delete(pending, fr.ID)
+				}
+			}
+			if len(pending) > 0 {
+				t.Errorf("function calls %v have no response in the session", slices.Collect(maps.Keys(pending)))
+			}
+		})
+	}
+}
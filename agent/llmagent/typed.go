@@ -0,0 +1,188 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llmagent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/internal/typeutil"
+	"github.com/sjzsdu/adk-go/internal/utils"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// DefaultOutputRetries is the number of times an agent created with NewTyped
+// asks the model to fix a final response that does not match the output
+// schema, unless Config.OutputRetries is set.
+const DefaultOutputRetries = 2
+
+// NewTyped creates an LLM agent whose final response is a JSON value of type
+// T.
+//
+// The output schema is derived from T the same way functiontool derives the
+// schemas of tool arguments, so cfg.OutputSchema must not be set. Final
+// responses are validated against the schema. A response that does not
+// match is not yielded: it is sent back to the model with the validation
+// error in a user event, and the model is asked to reply again, up to
+// cfg.OutputRetries times. If the model does not produce a valid response,
+// the run ends with an error. So the only final response of the agent is a
+// valid one. When streaming, the partial events of a response are yielded
+// only once the complete response is validated.
+//
+// When cfg.OutputKey is set, the value of type T is stored in the state under
+// that key. Use OutputFromState to read it back, since session services that
+// persist the state as JSON return a decoded JSON value instead.
+func NewTyped[T any](cfg Config) (agent.Agent, error) {
+	if cfg.OutputSchema != nil {
+		return nil, fmt.Errorf("agent %q: OutputSchema must not be set, it is derived from %T", cfg.Name, *new(T))
+	}
+	schema, err := jsonschema.For[T](nil)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q: failed to infer the output schema: %w", cfg.Name, err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q: failed to resolve the output schema: %w", cfg.Name, err)
+	}
+	cfg.OutputSchema, err = utils.GenaiSchemaFromJSONSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q: unsupported output type %T: %w", cfg.Name, *new(T), err)
+	}
+	cfg.OutputSchema.PropertyOrdering = propertyOrdering(reflect.TypeFor[T]())
+
+	a, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	llmAgent := a.(*llmAgent)
+	llmAgent.outputParser = func(text string) (any, error) {
+		return parseOutput[T](text, resolved)
+	}
+	switch {
+	case cfg.OutputRetries == 0:
+		llmAgent.outputRetries = DefaultOutputRetries
+	case cfg.OutputRetries > 0:
+		llmAgent.outputRetries = cfg.OutputRetries
+	}
+	return a, nil
+}
+
+// OutputAs decodes the text of the event, typically the final response of an
+// agent created with NewTyped, as a JSON value of type T.
+func OutputAs[T any](event *session.Event) (T, error) {
+	var zero T
+	if event == nil || event.Content == nil {
+		return zero, errors.New("event has no content")
+	}
+	var sb strings.Builder
+	for _, part := range event.Content.Parts {
+		if part.Text != "" && !part.Thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	return parseOutput[T](sb.String(), nil)
+}
+
+// OutputFromState returns the value of type T that an agent created with
+// NewTyped stored in the state under key.
+func OutputFromState[T any](state session.ReadonlyState, key string) (T, error) {
+	var zero T
+	v, err := state.Get(key)
+	if err != nil {
+		return zero, err
+	}
+	switch v := v.(type) {
+	case T:
+		return v, nil
+	case string:
+		// Stored before the response was validated, e.g. by a plain agent.
+		return parseOutput[T](v, nil)
+	}
+	typed, err := typeutil.ConvertToWithJSONSchema[any, T](v, nil)
+	if err != nil {
+		return zero, fmt.Errorf("state key %q holds %T, not convertible to %T: %w", key, v, zero, err)
+	}
+	return typed, nil
+}
+
+// parseOutput decodes the model output as a JSON value of type T. If resolved
+// is not nil, the value is validated against it first.
+func parseOutput[T any](text string, resolved *jsonschema.Resolved) (T, error) {
+	var zero T
+	text = stripCodeFence(strings.TrimSpace(text))
+	if text == "" {
+		return zero, errors.New("the response is empty")
+	}
+	if resolved != nil {
+		var raw any
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return zero, fmt.Errorf("the response is not valid JSON: %w", err)
+		}
+		if err := resolved.Validate(raw); err != nil {
+			return zero, fmt.Errorf("the response does not match the schema: %w", err)
+		}
+	}
+	var v T
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return zero, fmt.Errorf("the response is not a valid %T: %w", zero, err)
+	}
+	return v, nil
+}
+
+// stripCodeFence removes a markdown code fence around the text, which models
+// sometimes add even when asked for JSON.
+func stripCodeFence(text string) string {
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	text = strings.TrimSuffix(text[3:], "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 && !strings.ContainsAny(text[:i], "{[\"") {
+		text = text[i+1:] // language tag, e.g. json
+	}
+	return strings.TrimSpace(text)
+}
+
+// propertyOrdering returns the JSON names of the fields of a struct type in
+// declaration order, so that the model generates them in that order.
+func propertyOrdering(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,307 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llmagent_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/agent/llmagent"
+	"github.com/sjzsdu/adk-go/agent/workflowagents/mapagent"
+	"github.com/sjzsdu/adk-go/internal/testutil"
+	"github.com/sjzsdu/adk-go/runner"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+type weather struct {
+	City        string `json:"city"`
+	Temperature int    `json:"temperature"`
+}
+
+func TestNewTyped(t *testing.T) {
+	const validJSON = `{"city": "Paris", "temperature": 21}`
+	tests := []struct {
+		name       string
+		responses  []string
+		retries    int
+		wantTexts  []string
+		wantErr    bool
+		wantOutput *weather
+	}{
+		{
+			name:       "valid response",
+			responses:  []string{validJSON},
+			wantTexts:  []string{validJSON},
+			wantOutput: &weather{City: "Paris", Temperature: 21},
+		},
+		{
+			name:       "code fence",
+			responses:  []string{"```json\n" + validJSON + "\n```"},
+			wantTexts:  []string{"```json\n" + validJSON + "\n```"},
+			wantOutput: &weather{City: "Paris", Temperature: 21},
+		},
+		{
+			name:       "retry after invalid response",
+			responses:  []string{"It is sunny in Paris.", `{"city": "Paris"}`, validJSON},
+			wantTexts:  []string{"Your previous response is invalid", "Your previous response is invalid", validJSON},
+			wantOutput: &weather{City: "Paris", Temperature: 21},
+		},
+		{
+			name:      "retries exhausted",
+			responses: []string{"It is sunny in Paris.", "Sunny."},
+			retries:   1,
+			wantTexts: []string{"Your previous response is invalid"},
+			wantErr:   true,
+		},
+		{
+			name:      "retries disabled",
+			responses: []string{"It is sunny in Paris."},
+			retries:   -1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockModel := &testutil.MockModel{}
+			for _, text := range tt.responses {
+				mockModel.Responses = append(mockModel.Responses, genai.NewContentFromText(text, genai.RoleModel))
+			}
+			a, err := llmagent.NewTyped[weather](llmagent.Config{
+				Name:          "weather_agent",
+				Model:         mockModel,
+				OutputKey:     "weather",
+				OutputRetries: tt.retries,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sessionService := session.InMemoryService()
+			r, err := runner.New(runner.Config{AppName: "test_app", Agent: a, SessionService: sessionService})
+			if err != nil {
+				t.Fatal(err)
+			}
+			created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "test_app", UserID: "user"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var gotTexts []string
+			var gotErr error
+			var last *session.Event
+			for ev, err := range r.Run(t.Context(), "user", created.Session.ID(), genai.NewContentFromText("weather in Paris?", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					gotErr = err
+					continue
+				}
+				text := ev.Content.Parts[0].Text
+				if prefix, _, ok := strings.Cut(text, ":"); ok && strings.HasPrefix(text, "Your previous response is invalid") {
+					text = prefix
+					if ev.Author != "user" {
+						t.Errorf("feedback event author = %q, want %q", ev.Author, "user")
+					}
+				}
+				gotTexts = append(gotTexts, text)
+				last = ev
+			}
+			if diff := cmp.Diff(tt.wantTexts, gotTexts); diff != "" {
+				t.Errorf("event texts mismatch (-want +got):\n%s", diff)
+			}
+			if (gotErr != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", gotErr, tt.wantErr)
+			}
+			if len(mockModel.Requests) != len(tt.responses) {
+				t.Errorf("got %d model requests, want %d", len(mockModel.Requests), len(tt.responses))
+			}
+			if len(mockModel.Requests) > 1 {
+				// The validation error is sent back to the model.
+				contents := mockModel.Requests[1].Contents
+				feedback := contents[len(contents)-1]
+				if feedback.Role != genai.RoleUser || !strings.Contains(feedback.Parts[0].Text, "the response is not valid JSON") {
+					t.Errorf("retry request ends with %v, want the validation error", feedback.Parts[0].Text)
+				}
+				if !strings.Contains(feedback.Parts[0].Text, tt.responses[0]) {
+					t.Errorf("retry request ends with %v, want the invalid response", feedback.Parts[0].Text)
+				}
+			}
+
+			resp, err := sessionService.Get(t.Context(), &session.GetRequest{AppName: "test_app", UserID: "user", SessionID: created.Session.ID()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantOutput == nil {
+				if v, err := resp.Session.State().Get("weather"); err == nil {
+					t.Errorf("state holds %v, want no output for an invalid response", v)
+				}
+				return
+			}
+			got, err := llmagent.OutputFromState[weather](resp.Session.State(), "weather")
+			if err != nil {
+				t.Fatalf("OutputFromState() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantOutput, &got); diff != "" {
+				t.Errorf("OutputFromState() mismatch (-want +got):\n%s", diff)
+			}
+			got, err = llmagent.OutputAs[weather](last)
+			if err != nil {
+				t.Fatalf("OutputAs() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantOutput, &got); diff != "" {
+				t.Errorf("OutputAs() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewTyped_MapAgent(t *testing.T) {
+	const validJSON = `{"city": "Paris", "temperature": 21}`
+	mockModel := &testutil.MockModel{Responses: []*genai.Content{
+		genai.NewContentFromText("It is sunny in Paris.", genai.RoleModel),
+		genai.NewContentFromText(validJSON, genai.RoleModel),
+	}}
+	mapper, err := llmagent.NewTyped[weather](llmagent.Config{Name: "weather_agent", Model: mockModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := mapagent.New(mapagent.Config{
+		AgentConfig: agent.Config{Name: "map"},
+		Mapper:      mapper,
+		InputKey:    "cities",
+		OutputKey:   "forecasts",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: "test_app", Agent: a, SessionService: sessionService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "test_app", UserID: "user", State: map[string]any{"cities": []any{"Paris"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, err := range r.Run(t.Context(), "user", created.Session.ID(), genai.NewContentFromText("weather?", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := sessionService.Get(t.Context(), &session.GetRequest{AppName: "test_app", UserID: "user", SessionID: created.Session.ID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := resp.Session.State().Get("forecasts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]any{validJSON}, got); diff != "" {
+		t.Errorf("map agent results mismatch (-want +got):\n%s", diff)
+	}
+	// The only final response of the typed agent in the session is the
+	// valid one.
+	var finals []string
+	for ev := range resp.Session.Events().All() {
+		if ev.Author == "weather_agent" && ev.IsFinalResponse() {
+			finals = append(finals, ev.Content.Parts[0].Text)
+		}
+	}
+	if diff := cmp.Diff([]string{validJSON}, finals); diff != "" {
+		t.Errorf("final responses of the typed agent mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewTyped_Streaming(t *testing.T) {
+	mockModel := &testutil.MockModel{
+		Responses: []*genai.Content{
+			genai.NewContentFromText("It is ", genai.RoleModel),
+			genai.NewContentFromText("sunny.", genai.RoleModel),
+			genai.NewContentFromText(`{"city": "Paris", `, genai.RoleModel),
+			genai.NewContentFromText(`"temperature": 21}`, genai.RoleModel),
+		},
+		StreamResponsesCount: 2,
+	}
+	a, err := llmagent.NewTyped[weather](llmagent.Config{Name: "weather_agent", Model: mockModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: "test_app", Agent: a, SessionService: sessionService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "test_app", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type event struct {
+		Author  string
+		Partial bool
+		Text    string
+	}
+	var got []event
+	for ev, err := range r.Run(t.Context(), "user", created.Session.ID(), genai.NewContentFromText("weather in Paris?", genai.RoleUser), agent.RunConfig{StreamingMode: agent.StreamingModeSSE}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, _, _ := strings.Cut(ev.Content.Parts[0].Text, ":")
+		got = append(got, event{Author: ev.Author, Partial: ev.Partial, Text: text})
+	}
+	// The partial events of the invalid response are not yielded.
+	want := []event{
+		{Author: "user", Text: "Your previous response is invalid"},
+		{Author: "weather_agent", Partial: true, Text: `{"city"`},
+		{Author: "weather_agent", Partial: true, Text: `"temperature"`},
+		{Author: "weather_agent", Text: `{"city"`},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewTyped_OutputSchema(t *testing.T) {
+	mockModel := &testutil.MockModel{Responses: []*genai.Content{genai.NewContentFromText(`{"city": "Rome", "temperature": 25}`, genai.RoleModel)}}
+	a, err := llmagent.NewTyped[weather](llmagent.Config{Name: "weather_agent", Model: mockModel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testutil.CollectEvents(testutil.NewTestAgentRunner(t, a).Run(t, "session", "weather in Rome?")); err != nil {
+		t.Fatal(err)
+	}
+	want := &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"city":        {Type: genai.TypeString},
+			"temperature": {Type: genai.TypeInteger},
+		},
+		Required:         []string{"city", "temperature"},
+		PropertyOrdering: []string{"city", "temperature"},
+	}
+	var got *genai.Schema
+	if req := mockModel.Requests[0]; req.Config != nil {
+		got = req.Config.ResponseSchema
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("response schema mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := llmagent.NewTyped[weather](llmagent.Config{Name: "weather_agent", Model: mockModel, OutputSchema: want}); err == nil {
+		t.Error("NewTyped() with OutputSchema succeeded, want error")
+	}
+}
@@ -14,6 +14,11 @@
 
 package agent
 
+import (
+	"fmt"
+	"time"
+)
+
 // StreamingMode defines the streaming mode for agent execution.
 type StreamingMode string
 
@@ -32,4 +37,54 @@ type RunConfig struct {
 	// If true, ADK runner will save each part of the user input that is a blob
 	// (e.g., images, files) as an artifact.
 	SaveInputBlobsAsArtifacts bool
+
+	// MaxLLMCalls limits the number of LLM calls within an invocation.
+	// Zero means no limit.
+	MaxLLMCalls int
+	// MaxToolCalls limits the number of tool calls within an invocation.
+	// A model response whose function calls would exceed the limit ends the
+	// invocation before any of them is recorded. Zero means no limit.
+	MaxToolCalls int
+	// MaxAgentTransfers limits the number of transfers between agents within
+	// an invocation. Zero means no limit.
+	MaxAgentTransfers int
+	// Timeout limits the wall time of an invocation, measured from when
+	// Runner.Run or Runner.Resume acquires the session: the time spent
+	// waiting for prior runs of the session is not counted. Zero means no
+	// limit.
+	Timeout time.Duration
+}
+
+// Limit identifies a limit of RunConfig.
+type Limit string
+
+const (
+	LimitLLMCalls       Limit = "max_llm_calls"
+	LimitToolCalls      Limit = "max_tool_calls"
+	LimitAgentTransfers Limit = "max_agent_transfers"
+	LimitTimeout        Limit = "timeout"
+)
+
+// LimitExceededErrorCode is the ErrorCode of the event ending an invocation
+// that exceeded a limit of RunConfig.
+const LimitExceededErrorCode = "LIMIT_EXCEEDED"
+
+// LimitExceededError is returned when an invocation exceeds a limit of
+// RunConfig. Before it is returned, an event with LimitExceededErrorCode is
+// emitted and the invocation ends.
+type LimitExceededError struct {
+	// Limit is the exceeded limit.
+	Limit Limit
+	// Max is the configured maximum of LimitLLMCalls, LimitToolCalls and
+	// LimitAgentTransfers.
+	Max int
+	// Timeout is the configured timeout for LimitTimeout.
+	Timeout time.Duration
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Limit == LimitTimeout {
+		return fmt.Sprintf("invocation exceeded the timeout of %v", e.Timeout)
+	}
+	return fmt.Sprintf("invocation exceeded the %s limit of %d", e.Limit, e.Max)
 }
@@ -19,9 +19,12 @@ package loopagent
 import (
 	"fmt"
 	"iter"
+	"slices"
 
 	"github.com/sjzsdu/adk-go/agent"
 	agentinternal "github.com/sjzsdu/adk-go/internal/agent"
+	"github.com/sjzsdu/adk-go/internal/agent/checkpoint"
+	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
 	"github.com/sjzsdu/adk-go/session"
 )
 
@@ -72,33 +75,85 @@ type loopAgent struct {
 	maxIterations uint
 }
 
-func (a *loopAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
-	count := a.maxIterations
+// loopState is the checkpoint of a LoopAgent in a resumable invocation.
+type loopState struct {
+	// SubAgent is the name of the sub-agent being run.
+	SubAgent string `json:"sub_agent"`
+	// Iteration is the zero-based iteration of the loop.
+	Iteration uint `json:"iteration"`
+}
 
+func (a *loopAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
 	return func(yield func(*session.Event, error) bool) {
-		for {
+		subAgents := ctx.Agent().SubAgents()
+		checkpoints := checkpoint.FromContext(ctx)
+
+		// A resumed invocation continues with the sub-agent that paused it.
+		var state loopState
+		first := 0
+		resumed, err := checkpoints.Resume(ctx.Agent().Name(), &state)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if resumed {
+			first = slices.IndexFunc(subAgents, func(a agent.Agent) bool { return a.Name() == state.SubAgent })
+			if first < 0 {
+				yield(nil, fmt.Errorf("agent %q: cannot resume unknown sub-agent %q", ctx.Agent().Name(), state.SubAgent))
+				return
+			}
+		}
+
+		for iteration := state.Iteration; ; iteration++ {
 			shouldExit := false
-			for _, subAgent := range ctx.Agent().SubAgents() {
+			for _, subAgent := range subAgents[first:] {
+				if limitEvent, err := runconfig.LimitExceeded(ctx, nil); err != nil {
+					if limitEvent == nil || yield(limitEvent, nil) {
+						yield(nil, err)
+					}
+					return
+				}
+				// The checkpoint of a resumed sub-agent is stored already.
+				if checkpoints != nil && !resumed {
+					ev, err := checkpoint.Event(ctx, loopState{SubAgent: subAgent.Name(), Iteration: iteration})
+					if !yield(ev, err) || err != nil {
+						return
+					}
+				}
+				resumed = false
+				paused := false
 				for event, err := range subAgent.Run(ctx) {
 					// TODO: ensure consistency -- if there's an error, return and close iterator, verify everywhere in ADK.
 					if !yield(event, err) {
 						return
 					}
+					// An exceeded limit ends the whole invocation.
+					if runconfig.IsLimitExceeded(err) {
+						return
+					}
 
 					if event != nil && event.Actions.Escalate {
 						shouldExit = true
 					}
+					if checkpoint.ShouldPause(ctx, event) {
+						paused = true
+					}
 				}
-				if shouldExit {
+				if paused {
+					// The invocation continues from the checkpoint when resumed.
 					return
 				}
+				if shouldExit {
+					break
+				}
 			}
+			first = 0
 
-			if count > 0 {
-				count--
-				if count == 0 {
-					return
+			if shouldExit || (a.maxIterations > 0 && iteration+1 >= a.maxIterations) {
+				if checkpoints != nil {
+					yield(checkpoint.EndEvent(ctx), nil)
 				}
+				return
 			}
 		}
 	}
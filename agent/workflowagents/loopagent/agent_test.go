@@ -16,6 +16,7 @@ package loopagent_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"iter"
 	"testing"
@@ -357,3 +358,64 @@ func (f *FakeLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, st
 		}
 	}
 }
+
+func TestLoopAgent_RunConfigLimits(t *testing.T) {
+	fakeLLM := &FakeLLM{}
+	llmAgent, err := llmagent.New(llmagent.Config{
+		Name:  "llm_agent",
+		Model: fakeLLM,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loopAgent, err := loopagent.New(loopagent.Config{
+		AgentConfig: agent.Config{
+			Name:      "loop_agent",
+			SubAgents: []agent.Agent{llmAgent},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionService := session.InMemoryService()
+	agentRunner, err := runner.New(runner.Config{
+		AppName:        "test_app",
+		Agent:          loopAgent,
+		SessionService: sessionService,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessionService.Create(t.Context(), &session.CreateRequest{
+		AppName:   "test_app",
+		UserID:    "user_id",
+		SessionID: "session_id",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotEvents []*session.Event
+	var gotErr error
+	for event, err := range agentRunner.Run(t.Context(), "user_id", "session_id", genai.NewContentFromText("user input", genai.RoleUser), agent.RunConfig{MaxLLMCalls: 3}) {
+		if err != nil {
+			if gotErr != nil {
+				t.Fatalf("got a second error %v after %v", err, gotErr)
+			}
+			gotErr = err
+			continue
+		}
+		gotEvents = append(gotEvents, event)
+	}
+
+	var limitErr *agent.LimitExceededError
+	if !errors.As(gotErr, &limitErr) || limitErr.Limit != agent.LimitLLMCalls {
+		t.Fatalf("got error %v, want %q limit error", gotErr, agent.LimitLLMCalls)
+	}
+	if fakeLLM.callCounter != 3 {
+		t.Errorf("got %d model calls, want 3", fakeLLM.callCounter)
+	}
+	if last := gotEvents[len(gotEvents)-1]; last.ErrorCode != agent.LimitExceededErrorCode || last.Author != "llm_agent" {
+		t.Errorf("last event = {Author: %q, ErrorCode: %q}, want limit event from llm_agent", last.Author, last.ErrorCode)
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mapagent provides an agent that runs a sub-agent over every item of
+// a list stored in session state and collects the results.
+package mapagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/sjzsdu/adk-go/agent"
+	agentinternal "github.com/sjzsdu/adk-go/internal/agent"
+	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
+	icontext "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// DefaultItemKey is the state key the current item is injected into when
+// Config.ItemKey is empty.
+const DefaultItemKey = session.KeyPrefixTemp + "item"
+
+// Config defines the configuration for a MapAgent.
+type Config struct {
+	// Basic agent setup. SubAgents must be empty, they are populated from
+	// Mapper and Reducer.
+	AgentConfig agent.Config
+
+	// Mapper is run once per item of the input list.
+	Mapper agent.Agent
+	// Reducer is an optional agent that runs once after all items are
+	// processed. It can read the collected results from OutputKey.
+	Reducer agent.Agent
+
+	// InputKey is the session state key holding the list of items.
+	InputKey string
+	// ItemKey is the state key under which the current item is visible to the
+	// Mapper. It must have the "temp:" prefix. Defaults to DefaultItemKey.
+	ItemKey string
+	// OutputKey is the session state key the list of results is written to.
+	// Results keep the order of the input items.
+	OutputKey string
+
+	// MaxConcurrency limits the number of items processed at the same time.
+	// If MaxConcurrency == 0, all items are processed concurrently.
+	MaxConcurrency int
+}
+
+// New creates a MapAgent.
+//
+// MapAgent reads a list from the session state under InputKey and runs the
+// Mapper over each item with bounded concurrency. Every item runs in its own
+// branch, so mapper runs don't see each other's conversation history, and
+// the item itself is available to the mapper under the ItemKey state key.
+//
+// The final text response of each mapper run is collected into a list that
+// is stored under OutputKey. If a Reducer is configured, it runs afterwards
+// to combine the results.
+//
+// Use the MapAgent when the same processing needs to be applied to a
+// dynamic number of inputs, such as documents, tickets or URLs.
+func New(cfg Config) (agent.Agent, error) {
+	if cfg.AgentConfig.Run != nil {
+		return nil, fmt.Errorf("MapAgent doesn't allow custom Run implementations")
+	}
+	if len(cfg.AgentConfig.SubAgents) > 0 {
+		return nil, fmt.Errorf("MapAgent sub-agents must be set via Mapper and Reducer")
+	}
+	if cfg.Mapper == nil {
+		return nil, fmt.Errorf("MapAgent requires a Mapper")
+	}
+	if cfg.InputKey == "" || cfg.OutputKey == "" {
+		return nil, fmt.Errorf("MapAgent requires InputKey and OutputKey, got InputKey: %q, OutputKey: %q", cfg.InputKey, cfg.OutputKey)
+	}
+	if cfg.ItemKey == "" {
+		cfg.ItemKey = DefaultItemKey
+	}
+	if !strings.HasPrefix(cfg.ItemKey, session.KeyPrefixTemp) {
+		return nil, fmt.Errorf("MapAgent ItemKey must have the %q prefix, got %q", session.KeyPrefixTemp, cfg.ItemKey)
+	}
+	if cfg.MaxConcurrency < 0 {
+		return nil, fmt.Errorf("MapAgent MaxConcurrency must not be negative, got %d", cfg.MaxConcurrency)
+	}
+
+	mapAgentImpl := &mapAgent{
+		mapper:         cfg.Mapper,
+		reducer:        cfg.Reducer,
+		inputKey:       cfg.InputKey,
+		itemKey:        cfg.ItemKey,
+		outputKey:      cfg.OutputKey,
+		maxConcurrency: cfg.MaxConcurrency,
+	}
+
+	agentCfg := cfg.AgentConfig
+	agentCfg.SubAgents = []agent.Agent{cfg.Mapper}
+	if cfg.Reducer != nil {
+		agentCfg.SubAgents = append(agentCfg.SubAgents, cfg.Reducer)
+	}
+	agentCfg.Run = mapAgentImpl.Run
+
+	mapAgent, err := agent.New(agentCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base agent: %w", err)
+	}
+
+	internalAgent, ok := mapAgent.(agentinternal.Agent)
+	if !ok {
+		return nil, fmt.Errorf("internal error: failed to convert to internal agent")
+	}
+	state := agentinternal.Reveal(internalAgent)
+	state.AgentType = agentinternal.TypeMapAgent
+	state.Config = cfg
+
+	return mapAgent, nil
+}
+
+type mapAgent struct {
+	mapper, reducer agent.Agent
+
+	inputKey, itemKey, outputKey string
+	maxConcurrency               int
+}
+
+func (a *mapAgent) Run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		if limitEvent, err := runconfig.LimitExceeded(ctx, nil); err != nil {
+			if limitEvent == nil || yield(limitEvent, nil) {
+				yield(nil, err)
+			}
+			return
+		}
+
+		items, err := a.readItems(ctx)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		results, ok := a.runMapper(ctx, items, yield)
+		if !ok {
+			return
+		}
+
+		event := session.NewEvent(ctx.InvocationID())
+		event.Author = ctx.Agent().Name()
+		event.Branch = ctx.Branch()
+		event.Actions.StateDelta[a.outputKey] = results
+		if !yield(event, nil) {
+			return
+		}
+
+		if a.reducer == nil || ctx.Ended() {
+			return
+		}
+		if limitEvent, err := runconfig.LimitExceeded(ctx, nil); err != nil {
+			if limitEvent == nil || yield(limitEvent, nil) {
+				yield(nil, err)
+			}
+			return
+		}
+		for event, err := range a.reducer.Run(ctx) {
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readItems returns the list stored under the input key. Any slice type is
+// accepted, since the list may have been set in code or decoded from JSON.
+func (a *mapAgent) readItems(ctx agent.InvocationContext) ([]any, error) {
+	val, err := ctx.Session().State().Get(a.inputKey)
+	if err != nil {
+		if errors.Is(err, session.ErrStateKeyNotExist) {
+			return nil, fmt.Errorf("MapAgent %q: input key %q not found in state", ctx.Agent().Name(), a.inputKey)
+		}
+		return nil, fmt.Errorf("MapAgent %q: failed to read input key %q: %w", ctx.Agent().Name(), a.inputKey, err)
+	}
+	if val == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("MapAgent %q: input key %q holds %T, want a list", ctx.Agent().Name(), a.inputKey, val)
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// runMapper runs the mapper over all items and forwards their events. It
+// returns the collected results and false if the iteration was stopped.
+func (a *mapAgent) runMapper(ctx agent.InvocationContext, items []any, yield func(*session.Event, error) bool) ([]any, bool) {
+	results := make([]any, len(items))
+	if len(items) == 0 {
+		return results, true
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	var (
+		errGroup, errGroupCtx = errgroup.WithContext(runCtx)
+		doneChan              = make(chan bool)
+		resultsChan           = make(chan result)
+		mu                    sync.Mutex
+	)
+	if a.maxConcurrency > 0 {
+		errGroup.SetLimit(a.maxConcurrency)
+	}
+
+	go func() {
+		for i, item := range items {
+			if errGroupCtx.Err() != nil {
+				break
+			}
+			// Go blocks while MaxConcurrency items are in flight.
+			errGroup.Go(func() error {
+				out, err := a.runItem(errGroupCtx, ctx, i, item, resultsChan, doneChan)
+				if err != nil {
+					return fmt.Errorf("failed to run mapper %q on item %d: %w", a.mapper.Name(), i, err)
+				}
+				mu.Lock()
+				results[i] = out
+				mu.Unlock()
+				return nil
+			})
+		}
+		_ = errGroup.Wait() // this error is already sent to the user via iterator
+		close(resultsChan)
+	}()
+
+	defer cancel()
+	defer close(doneChan)
+	failed := false
+	for res := range resultsChan {
+		if res.err != nil {
+			// The first failure cancels the other items, their errors are
+			// not reported.
+			if failed && errors.Is(res.err, context.Canceled) {
+				continue
+			}
+			failed = true
+		}
+		// An exceeded limit ends the whole invocation.
+		if !yield(res.event, res.err) || runconfig.IsLimitExceeded(res.err) {
+			return nil, false
+		}
+	}
+	if failed {
+		return nil, false
+	}
+	if err := ctx.Err(); err != nil {
+		limitEvent, err := runconfig.LimitExceeded(ctx, err)
+		if limitEvent == nil || yield(limitEvent, nil) {
+			yield(nil, err)
+		}
+		return nil, false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return results, true
+}
+
+// runItem runs the mapper on a single item in an isolated branch and returns
+// the text of its last final response.
+func (a *mapAgent) runItem(groupCtx context.Context, parentCtx agent.InvocationContext, index int, item any, results chan<- result, done <-chan bool) (any, error) {
+	branch := fmt.Sprintf("%s.%s_%d", parentCtx.Agent().Name(), a.mapper.Name(), index)
+	if parentCtx.Branch() != "" {
+		branch = fmt.Sprintf("%s.%s", parentCtx.Branch(), branch)
+	}
+
+	itemCtx := icontext.NewInvocationContext(groupCtx, icontext.InvocationContextParams{
+		Artifacts:    parentCtx.Artifacts(),
+		Memory:       parentCtx.Memory(),
+		Session:      newItemSession(parentCtx.Session(), a.itemKey, item),
+		Branch:       branch,
+		Agent:        a.mapper,
+		UserContent:  parentCtx.UserContent(),
+		RunConfig:    parentCtx.RunConfig(),
+		InvocationID: parentCtx.InvocationID(),
+	})
+
+	var output any
+	for event, err := range a.mapper.Run(itemCtx) {
+		// Custom agents may not set the branch themselves, keep the events
+		// of each item isolated.
+		if event != nil && event.Branch == "" {
+			event.Branch = branch
+		}
+		select {
+		case <-done:
+			return nil, nil
+		case <-itemCtx.Done():
+			select {
+			case <-done:
+			case results <- result{
+				err: itemCtx.Err(),
+			}:
+			}
+			return nil, itemCtx.Err()
+		case results <- result{
+			event: event,
+			err:   err,
+		}:
+			if err != nil {
+				return nil, err
+			}
+		}
+		if text, ok := finalResponseText(event); ok {
+			output = text
+		}
+	}
+	return output, nil
+}
+
+// finalResponseText returns the concatenated non-thought text of a final
+// response event.
+func finalResponseText(event *session.Event) (string, bool) {
+	if event == nil || event.Content == nil || !event.IsFinalResponse() || event.Partial {
+		return "", false
+	}
+	var sb strings.Builder
+	hasText := false
+	for _, part := range event.Content.Parts {
+		if part.Text != "" && !part.Thought {
+			sb.WriteString(part.Text)
+			hasText = true
+		}
+	}
+	return sb.String(), hasText
+}
+
+type result struct {
+	event *session.Event
+	err   error
+}
@@ -0,0 +1,319 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapagent_test
+
+import (
+	"fmt"
+	"iter"
+	rand "math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/agent/workflowagents/mapagent"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/runner"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+func TestMapAgent(t *testing.T) {
+	tests := []struct {
+		name           string
+		items          any
+		maxConcurrency int
+		withReducer    bool
+		mapperErr      error
+		wantResults    []any
+		wantReduced    string
+		wantErr        bool
+	}{
+		{
+			name:        "maps items in order",
+			items:       []string{"a", "b", "c", "d"},
+			wantResults: []any{"mapped a", "mapped b", "mapped c", "mapped d"},
+		},
+		{
+			name:           "bounded concurrency",
+			items:          []any{"x", "y", "z"},
+			maxConcurrency: 1,
+			wantResults:    []any{"mapped x", "mapped y", "mapped z"},
+		},
+		{
+			name:        "with reducer",
+			items:       []any{"1", "2"},
+			withReducer: true,
+			wantResults: []any{"mapped 1", "mapped 2"},
+			wantReduced: "mapped 1|mapped 2",
+		},
+		{
+			name:        "empty list",
+			items:       []any{},
+			wantResults: []any{},
+		},
+		{
+			name:      "mapper error",
+			items:     []any{"a", "b"},
+			mapperErr: fmt.Errorf("mapper failed"),
+			wantErr:   true,
+		},
+		{
+			name:    "input is not a list",
+			items:   "not a list",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+
+			var inFlight, maxInFlight atomic.Int32
+			mapper := must(agent.New(agent.Config{
+				Name: "mapper",
+				Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+					return func(yield func(*session.Event, error) bool) {
+						cur := inFlight.Add(1)
+						defer inFlight.Add(-1)
+						for {
+							prev := maxInFlight.Load()
+							if cur <= prev || maxInFlight.CompareAndSwap(prev, cur) {
+								break
+							}
+						}
+						time.Sleep(time.Duration(rand.IntN(3)+1) * time.Millisecond)
+						if tt.mapperErr != nil {
+							yield(nil, tt.mapperErr)
+							return
+						}
+						item, err := ctx.Session().State().Get(mapagent.DefaultItemKey)
+						if err != nil {
+							yield(nil, err)
+							return
+						}
+						yield(&session.Event{
+							LLMResponse: model.LLMResponse{
+								Content: genai.NewContentFromText(fmt.Sprintf("mapped %v", item), genai.RoleModel),
+							},
+						}, nil)
+					}
+				},
+			}))
+
+			var reducer agent.Agent
+			if tt.withReducer {
+				reducer = must(agent.New(agent.Config{
+					Name: "reducer",
+					Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+						return func(yield func(*session.Event, error) bool) {
+							results, err := ctx.Session().State().Get("results")
+							if err != nil {
+								yield(nil, err)
+								return
+							}
+							var parts []string
+							for _, r := range results.([]any) {
+								parts = append(parts, r.(string))
+							}
+							yield(&session.Event{
+								LLMResponse: model.LLMResponse{
+									Content: genai.NewContentFromText(strings.Join(parts, "|"), genai.RoleModel),
+								},
+							}, nil)
+						}
+					},
+				}))
+			}
+
+			mapAgent, err := mapagent.New(mapagent.Config{
+				AgentConfig:    agent.Config{Name: "map"},
+				Mapper:         mapper,
+				Reducer:        reducer,
+				InputKey:       "items",
+				OutputKey:      "results",
+				MaxConcurrency: tt.maxConcurrency,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sessionService := session.InMemoryService()
+			if _, err := sessionService.Create(ctx, &session.CreateRequest{
+				AppName:   "test_app",
+				UserID:    "user_id",
+				SessionID: "session_id",
+				State:     map[string]any{"items": tt.items},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			agentRunner, err := runner.New(runner.Config{
+				AppName:        "test_app",
+				Agent:          mapAgent,
+				SessionService: sessionService,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var gotErr error
+			var reduced string
+			branches := make(map[string]bool)
+			for event, err := range agentRunner.Run(ctx, "user_id", "session_id", genai.NewContentFromText("go", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					gotErr = err
+					continue
+				}
+				if event.Author == "mapper" {
+					branches[event.Branch] = true
+				}
+				if event.Author == "reducer" {
+					reduced = event.Content.Parts[0].Text
+				}
+			}
+			if tt.wantErr {
+				if gotErr == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if gotErr != nil {
+				t.Fatalf("unexpected error: %v", gotErr)
+			}
+
+			resp, err := sessionService.Get(ctx, &session.GetRequest{AppName: "test_app", UserID: "user_id", SessionID: "session_id"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := resp.Session.State().Get("results")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.wantResults, got); diff != "" {
+				t.Errorf("results mismatch (-want +got):\n%s", diff)
+			}
+			if _, err := resp.Session.State().Get(mapagent.DefaultItemKey); err == nil {
+				t.Errorf("item key %q leaked into session state", mapagent.DefaultItemKey)
+			}
+			if len(branches) != len(tt.wantResults) {
+				t.Errorf("got %d mapper branches, want one per item (%d)", len(branches), len(tt.wantResults))
+			}
+			if tt.maxConcurrency > 0 && int(maxInFlight.Load()) > tt.maxConcurrency {
+				t.Errorf("max in-flight mapper runs = %d, want <= %d", maxInFlight.Load(), tt.maxConcurrency)
+			}
+			if reduced != tt.wantReduced {
+				t.Errorf("reduced = %q, want %q", reduced, tt.wantReduced)
+			}
+		})
+	}
+}
+
+func TestMapAgent_Failure(t *testing.T) {
+	ctx := t.Context()
+	// The first item fails, the others run until they are canceled.
+	mapper := must(agent.New(agent.Config{
+		Name: "mapper",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				item, err := ctx.Session().State().Get(mapagent.DefaultItemKey)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if item == "bad" {
+					yield(nil, fmt.Errorf("mapper failed"))
+					return
+				}
+				<-ctx.Done()
+				yield(nil, ctx.Err())
+			}
+		},
+	}))
+	mapAgent, err := mapagent.New(mapagent.Config{
+		AgentConfig: agent.Config{Name: "map"},
+		Mapper:      mapper,
+		InputKey:    "items",
+		OutputKey:   "results",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	if _, err := sessionService.Create(ctx, &session.CreateRequest{
+		AppName:   "test_app",
+		UserID:    "user_id",
+		SessionID: "session_id",
+		State:     map[string]any{"items": []any{"a", "bad", "b"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	agentRunner, err := runner.New(runner.Config{AppName: "test_app", Agent: mapAgent, SessionService: sessionService})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotErrs []string
+	for _, err := range agentRunner.Run(ctx, "user_id", "session_id", genai.NewContentFromText("go", genai.RoleUser), agent.RunConfig{}) {
+		if err != nil {
+			gotErrs = append(gotErrs, err.Error())
+		}
+	}
+	want := []string{"mapper failed"}
+	if diff := cmp.Diff(want, gotErrs); diff != "" {
+		t.Errorf("errors mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	mapper := must(agent.New(agent.Config{Name: "mapper"}))
+	tests := []struct {
+		name string
+		cfg  mapagent.Config
+	}{
+		{
+			name: "missing mapper",
+			cfg:  mapagent.Config{AgentConfig: agent.Config{Name: "map"}, InputKey: "in", OutputKey: "out"},
+		},
+		{
+			name: "missing keys",
+			cfg:  mapagent.Config{AgentConfig: agent.Config{Name: "map"}, Mapper: mapper},
+		},
+		{
+			name: "non temp item key",
+			cfg:  mapagent.Config{AgentConfig: agent.Config{Name: "map"}, Mapper: mapper, InputKey: "in", OutputKey: "out", ItemKey: "item"},
+		},
+		{
+			name: "sub-agents set",
+			cfg:  mapagent.Config{AgentConfig: agent.Config{Name: "map", SubAgents: []agent.Agent{mapper}}, Mapper: mapper, InputKey: "in", OutputKey: "out"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := mapagent.New(tt.cfg); err == nil {
+				t.Error("mapagent.New() succeeded, want error")
+			}
+		})
+	}
+}
+
+func must[T agent.Agent](a T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapagent
+
+import (
+	"iter"
+	"sync"
+
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// itemSession is a view of the shared session in which the item key resolves
+// to the item processed by a single mapper run. Concurrent mapper runs share
+// the underlying session, so the item is kept in the view instead of being
+// written to the session state.
+type itemSession struct {
+	session.Session
+
+	state *itemState
+}
+
+func newItemSession(s session.Session, key string, item any) *itemSession {
+	return &itemSession{
+		Session: s,
+		state: &itemState{
+			State: s.State(),
+			key:   key,
+			item:  item,
+		},
+	}
+}
+
+func (s *itemSession) State() session.State {
+	return s.state
+}
+
+type itemState struct {
+	session.State
+
+	mu   sync.RWMutex
+	key  string
+	item any
+}
+
+func (s *itemState) Get(key string) (any, error) {
+	if key == s.key {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return s.item, nil
+	}
+	return s.State.Get(key)
+}
+
+func (s *itemState) Set(key string, val any) error {
+	if key == s.key {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.item = val
+		return nil
+	}
+	return s.State.Set(key, val)
+}
+
+func (s *itemState) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for k, v := range s.State.All() {
+			if k == s.key {
+				continue
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+		s.mu.RLock()
+		item := s.item
+		s.mu.RUnlock()
+		yield(s.key, item)
+	}
+}
+
+var _ session.Session = (*itemSession)(nil)
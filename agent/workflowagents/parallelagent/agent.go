@@ -23,6 +23,7 @@ import (
 
 	"github.com/sjzsdu/adk-go/agent"
 	agentinternal "github.com/sjzsdu/adk-go/internal/agent"
+	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
 	icontext "github.com/sjzsdu/adk-go/internal/context"
 	"github.com/sjzsdu/adk-go/session"
 )
@@ -108,7 +109,8 @@ func run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
 		defer close(doneChan)
 
 		for res := range resultsChan {
-			if !yield(res.event, res.err) {
+			// An exceeded limit ends the whole invocation.
+			if !yield(res.event, res.err) || runconfig.IsLimitExceeded(res.err) {
 				break
 			}
 		}
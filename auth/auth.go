@@ -0,0 +1,116 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth provides types for tools that need credentials, such as API
+// keys or OAuth2 tokens, to call external services.
+//
+// A tool describes the credential it needs with an [AuthConfig] and calls
+// tool.Context.RequestCredential if tool.Context.GetCredential returns no
+// credential. ADK then emits an "adk_request_credential" function call (see
+// [FunctionCallName]) to the client and the invocation ends. Once the client
+// responds, ADK exchanges the response for a credential if needed, stores it
+// and runs the tool again.
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// FunctionCallName is the name of the FunctionCall event emitted by ADK when
+// a tool requests a credential.
+//
+// The 'args' of this FunctionCall include:
+//   - "functionCallId": The ID of the function call of the tool that requested
+//     the credential.
+//   - "authConfig": The AuthConfig of the requested credential. For OAuth2
+//     and OpenID Connect, ExchangedAuthCredential.OAuth2.AuthURI contains the
+//     URI to redirect the user to.
+//
+// Client applications must:
+//  1. Listen for events containing a FunctionCall with this name.
+//  2. Obtain the credential from the user, e.g. by redirecting the user to the
+//     AuthURI and capturing the redirect back to the RedirectURI.
+//  3. Send a FunctionResponse message back to ADK. This FunctionResponse MUST:
+//     - Have the same 'id' as the received "adk_request_credential" FunctionCall.
+//     - Have the name set to "adk_request_credential".
+//     - Have the received AuthConfig as the response, with
+//     ExchangedAuthCredential updated. For OAuth2, set AuthResponseURI to the
+//     full redirect URI, or AuthCode to the authorization code, or
+//     AccessToken if the client exchanged the code itself.
+const FunctionCallName = "adk_request_credential"
+
+// AuthConfig describes the credential required by a tool and carries it
+// between the tool, the client and the credential store.
+type AuthConfig struct {
+	// AuthScheme describes how the credential is used to call the service.
+	AuthScheme *AuthScheme `json:"authScheme"`
+	// RawAuthCredential is the credential known in advance, e.g. the OAuth2
+	// client ID and secret.
+	RawAuthCredential *AuthCredential `json:"rawAuthCredential,omitempty"`
+	// ExchangedAuthCredential is the credential obtained from the client, or
+	// exchanged from the client response, that is used to call the service.
+	ExchangedAuthCredential *AuthCredential `json:"exchangedAuthCredential,omitempty"`
+	// CredentialKey identifies the credential in the credential stores. If
+	// empty, a key derived from AuthScheme and RawAuthCredential is used.
+	CredentialKey string `json:"credentialKey,omitempty"`
+}
+
+// Key returns the key identifying the credential in the credential stores.
+func (c *AuthConfig) Key() string {
+	if c.CredentialKey != "" {
+		return c.CredentialKey
+	}
+	key := "adk"
+	if c.AuthScheme != nil {
+		key += fmt.Sprintf("_%s_%s", c.AuthScheme.Type, hashJSON(c.AuthScheme))
+	}
+	if c.RawAuthCredential != nil {
+		// Only the stable parts of the credential identify it.
+		stable := c.RawAuthCredential.Copy()
+		if stable.OAuth2 != nil {
+			stable.OAuth2 = &OAuth2Auth{
+				ClientID:     stable.OAuth2.ClientID,
+				ClientSecret: stable.OAuth2.ClientSecret,
+				RedirectURI:  stable.OAuth2.RedirectURI,
+			}
+		}
+		key += fmt.Sprintf("_%s_%s", stable.AuthType, hashJSON(stable))
+	}
+	return key
+}
+
+// Copy returns a deep copy of the config.
+func (c *AuthConfig) Copy() *AuthConfig {
+	if c == nil {
+		return nil
+	}
+	cfg := *c
+	cfg.AuthScheme = c.AuthScheme.Copy()
+	cfg.RawAuthCredential = c.RawAuthCredential.Copy()
+	cfg.ExchangedAuthCredential = c.ExchangedAuthCredential.Copy()
+	return &cfg
+}
+
+func hashJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// Types of this package are always serializable.
+		panic(fmt.Sprintf("failed to marshal %T: %v", v, err))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
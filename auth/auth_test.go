@@ -0,0 +1,231 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/internal/testutil"
+)
+
+func newOAuth2Config(server *testutil.FakeOAuthServer) *auth.AuthConfig {
+	return &auth.AuthConfig{
+		AuthScheme: &auth.AuthScheme{
+			Type: auth.SchemeOAuth2,
+			Flows: &auth.OAuthFlows{
+				AuthorizationCode: &auth.OAuthFlow{
+					AuthorizationURL: server.AuthorizationURL(),
+					TokenURL:         server.TokenURL(),
+					Scopes:           map[string]string{"read": "read access", "write": "write access"},
+				},
+			},
+		},
+		RawAuthCredential: &auth.AuthCredential{
+			AuthType: auth.CredentialOAuth2,
+			OAuth2: &auth.OAuth2Auth{
+				ClientID:     server.ClientID,
+				ClientSecret: server.ClientSecret,
+				RedirectURI:  "http://localhost/callback",
+			},
+		},
+	}
+}
+
+func TestAuthConfig_Key(t *testing.T) {
+	server := testutil.NewFakeOAuthServer(t)
+	cfg := newOAuth2Config(server)
+
+	key := cfg.Key()
+	if !strings.HasPrefix(key, "adk_oauth2_") {
+		t.Errorf("Key() = %q, want prefix adk_oauth2_", key)
+	}
+
+	withToken := cfg.Copy()
+	withToken.RawAuthCredential.OAuth2.AccessToken = "token"
+	withToken.ExchangedAuthCredential = &auth.AuthCredential{AuthType: auth.CredentialOAuth2}
+	if got := withToken.Key(); got != key {
+		t.Errorf("Key() changed with dynamic fields: got %q, want %q", got, key)
+	}
+
+	otherClient := cfg.Copy()
+	otherClient.RawAuthCredential.OAuth2.ClientID = "other"
+	if got := otherClient.Key(); got == key {
+		t.Errorf("Key() = %q for a different client, want a different key", got)
+	}
+
+	custom := cfg.Copy()
+	custom.CredentialKey = "my_key"
+	if got := custom.Key(); got != "my_key" {
+		t.Errorf("Key() = %q, want %q", got, "my_key")
+	}
+}
+
+func TestGenerateAuthRequest(t *testing.T) {
+	server := testutil.NewFakeOAuthServer(t)
+	cfg := newOAuth2Config(server)
+
+	req, err := auth.GenerateAuthRequest(cfg)
+	if err != nil {
+		t.Fatalf("GenerateAuthRequest() error = %v", err)
+	}
+	if cfg.ExchangedAuthCredential != nil {
+		t.Errorf("GenerateAuthRequest() modified the config")
+	}
+	exchanged := req.ExchangedAuthCredential.OAuth2
+	if exchanged.State == "" {
+		t.Errorf("state is empty")
+	}
+	authURI, err := url.Parse(exchanged.AuthURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantQuery := url.Values{
+		"access_type":   {"offline"},
+		"client_id":     {server.ClientID},
+		"prompt":        {"consent"},
+		"redirect_uri":  {"http://localhost/callback"},
+		"response_type": {"code"},
+		"scope":         {"read write"},
+		"state":         {exchanged.State},
+	}
+	if diff := cmp.Diff(wantQuery, authURI.Query()); diff != "" {
+		t.Errorf("auth uri query mismatch (-want +got):\n%s", diff)
+	}
+
+	// An existing auth request is kept.
+	again, err := auth.GenerateAuthRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(req, again); diff != "" {
+		t.Errorf("GenerateAuthRequest() changed an existing request (-want +got):\n%s", diff)
+	}
+
+	// Other schemes need no auth request.
+	apiKey := &auth.AuthConfig{AuthScheme: &auth.AuthScheme{Type: auth.SchemeAPIKey, In: "header", Name: "X-API-Key"}}
+	got, err := auth.GenerateAuthRequest(apiKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(apiKey, got); diff != "" {
+		t.Errorf("GenerateAuthRequest() mismatch (-want +got):\n%s", diff)
+	}
+
+	noSecret := newOAuth2Config(server)
+	noSecret.RawAuthCredential.OAuth2.ClientSecret = ""
+	if _, err := auth.GenerateAuthRequest(noSecret); err == nil {
+		t.Errorf("GenerateAuthRequest() without client secret succeeded, want error")
+	}
+}
+
+func TestExchangeCredential(t *testing.T) {
+	server := testutil.NewFakeOAuthServer(t)
+	req, err := auth.GenerateAuthRequest(newOAuth2Config(server))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := req.Copy()
+	resp.ExchangedAuthCredential.OAuth2.AuthResponseURI = server.Authorize(t, req.ExchangedAuthCredential.OAuth2.AuthURI)
+	cred, err := auth.ExchangeCredential(t.Context(), resp)
+	if err != nil {
+		t.Fatalf("ExchangeCredential() error = %v", err)
+	}
+	if got, want := cred.OAuth2.AccessToken, "access-code-1"; got != want {
+		t.Errorf("access token = %q, want %q", got, want)
+	}
+	if got, want := cred.OAuth2.RefreshToken, "refresh-code-1"; got != want {
+		t.Errorf("refresh token = %q, want %q", got, want)
+	}
+	if cred.OAuth2.ExpiresAt == 0 {
+		t.Errorf("expiry is not set")
+	}
+	if diff := cmp.Diff([]string{"code-1"}, server.ExchangedCodes()); diff != "" {
+		t.Errorf("exchanged codes mismatch (-want +got):\n%s", diff)
+	}
+
+	// The code can only be used once.
+	if _, err := auth.ExchangeCredential(t.Context(), resp); err == nil {
+		t.Errorf("ExchangeCredential() with a used code succeeded, want error")
+	}
+}
+
+func TestExchangeCredential_Errors(t *testing.T) {
+	server := testutil.NewFakeOAuthServer(t)
+	req, err := auth.GenerateAuthRequest(newOAuth2Config(server))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name            string
+		authResponseURI string
+		wantErr         string
+	}{
+		{
+			name:            "state mismatch",
+			authResponseURI: "http://localhost/callback?code=code-1&state=forged",
+			wantErr:         "state does not match",
+		},
+		{
+			name:            "authorization denied",
+			authResponseURI: "http://localhost/callback?error=access_denied",
+			wantErr:         "access_denied",
+		},
+		{
+			name:    "no code",
+			wantErr: "neither access token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := req.Copy()
+			resp.ExchangedAuthCredential.OAuth2.AuthResponseURI = tt.authResponseURI
+			_, err := auth.ExchangeCredential(t.Context(), resp)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExchangeCredential() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExchangeCredential_NoExchangeNeeded(t *testing.T) {
+	server := testutil.NewFakeOAuthServer(t)
+	withToken := newOAuth2Config(server)
+	withToken.ExchangedAuthCredential = &auth.AuthCredential{
+		AuthType: auth.CredentialOAuth2,
+		OAuth2:   &auth.OAuth2Auth{AccessToken: "token"},
+	}
+	apiKey := &auth.AuthConfig{
+		AuthScheme:              &auth.AuthScheme{Type: auth.SchemeAPIKey, In: "header", Name: "X-API-Key"},
+		ExchangedAuthCredential: &auth.AuthCredential{AuthType: auth.CredentialAPIKey, APIKey: "key"},
+	}
+	for _, cfg := range []*auth.AuthConfig{withToken, apiKey} {
+		got, err := auth.ExchangeCredential(t.Context(), cfg)
+		if err != nil {
+			t.Fatalf("ExchangeCredential() error = %v", err)
+		}
+		if diff := cmp.Diff(cfg.ExchangedAuthCredential, got); diff != "" {
+			t.Errorf("ExchangeCredential() mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if codes := server.ExchangedCodes(); len(codes) != 0 {
+		t.Errorf("unexpected code exchanges: %v", codes)
+	}
+}
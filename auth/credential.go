@@ -0,0 +1,94 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+// AuthCredentialType is the type of an AuthCredential.
+type AuthCredentialType string
+
+const (
+	CredentialAPIKey        AuthCredentialType = "apiKey"
+	CredentialHTTP          AuthCredentialType = "http"
+	CredentialOAuth2        AuthCredentialType = "oauth2"
+	CredentialOpenIDConnect AuthCredentialType = "openIdConnect"
+)
+
+// AuthCredential is a credential used to call a service. Only the field
+// matching AuthType is set.
+type AuthCredential struct {
+	AuthType AuthCredentialType `json:"authType"`
+	// ResourceRef optionally identifies the resource the credential is for.
+	ResourceRef string `json:"resourceRef,omitempty"`
+
+	APIKey string      `json:"apiKey,omitempty"`
+	HTTP   *HTTPAuth   `json:"http,omitempty"`
+	OAuth2 *OAuth2Auth `json:"oauth2,omitempty"`
+}
+
+// HTTPAuth is a credential for HTTP authentication schemes.
+type HTTPAuth struct {
+	// Scheme is the HTTP authorization scheme, e.g. "basic" or "bearer".
+	Scheme      string          `json:"scheme"`
+	Credentials HTTPCredentials `json:"credentials"`
+}
+
+// HTTPCredentials are the values of an HTTPAuth credential.
+type HTTPCredentials struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// OAuth2Auth is a credential for OAuth2 and OpenID Connect. It holds both the
+// client configuration and the state of the authorization code flow.
+type OAuth2Auth struct {
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	// RedirectURI is the URI the user is redirected to after authorization.
+	RedirectURI string `json:"redirectUri,omitempty"`
+
+	// AuthURI is the URI the user is redirected to for authorization.
+	AuthURI string `json:"authUri,omitempty"`
+	// State protects the authorization code flow from CSRF.
+	State string `json:"state,omitempty"`
+	// AuthResponseURI is the full redirect URI received after authorization.
+	AuthResponseURI string `json:"authResponseUri,omitempty"`
+	// AuthCode is the authorization code. It is parsed from AuthResponseURI if
+	// empty.
+	AuthCode string `json:"authCode,omitempty"`
+
+	AccessToken  string `json:"accessToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// ExpiresAt is the expiry of AccessToken in seconds since the Unix epoch.
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+	// ExpiresIn is the lifetime of AccessToken in seconds.
+	ExpiresIn int64 `json:"expiresIn,omitempty"`
+}
+
+// Copy returns a deep copy of the credential.
+func (c *AuthCredential) Copy() *AuthCredential {
+	if c == nil {
+		return nil
+	}
+	cred := *c
+	if c.HTTP != nil {
+		http := *c.HTTP
+		cred.HTTP = &http
+	}
+	if c.OAuth2 != nil {
+		oauth2 := *c.OAuth2
+		cred.OAuth2 = &oauth2
+	}
+	return &cred
+}
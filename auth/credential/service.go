@@ -0,0 +1,128 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package credential defines the service storing credentials obtained for
+// tools, so that they can be reused across invocations.
+//
+// A Service is configured via runner.Config.CredentialService. Without it,
+// credentials obtained from the client are only available within the
+// invocation that received them.
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/internal/converters"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// Service stores credentials keyed by auth.AuthConfig.Key.
+type Service interface {
+	// LoadCredential returns the stored credential for the config, or nil if
+	// there is none.
+	LoadCredential(ctx agent.CallbackContext, cfg *auth.AuthConfig) (*auth.AuthCredential, error)
+	// SaveCredential stores cfg.ExchangedAuthCredential.
+	SaveCredential(ctx agent.CallbackContext, cfg *auth.AuthConfig) error
+}
+
+// InMemoryService returns a new in-memory implementation of the credential
+// service. Credentials are scoped to the app and user. Thread-safe.
+func InMemoryService() Service {
+	return &inMemoryService{
+		store: make(map[key]*auth.AuthCredential),
+	}
+}
+
+type key struct {
+	appName, userID, credentialKey string
+}
+
+type inMemoryService struct {
+	mu    sync.RWMutex
+	store map[key]*auth.AuthCredential
+}
+
+func (s *inMemoryService) LoadCredential(ctx agent.CallbackContext, cfg *auth.AuthConfig) (*auth.AuthCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.store[key{ctx.AppName(), ctx.UserID(), cfg.Key()}].Copy(), nil
+}
+
+func (s *inMemoryService) SaveCredential(ctx agent.CallbackContext, cfg *auth.AuthConfig) error {
+	if cfg.ExchangedAuthCredential == nil {
+		return fmt.Errorf("exchanged auth credential is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store[key{ctx.AppName(), ctx.UserID(), cfg.Key()}] = cfg.ExchangedAuthCredential.Copy()
+	return nil
+}
+
+// SessionStateService returns a credential service storing credentials in the
+// session state under auth.AuthConfig.Key. Credentials are scoped to the
+// session.
+//
+// NOTE: credentials are stored in plain text by the session service and are
+// visible in the state deltas of session events. Use it for development only.
+func SessionStateService() Service {
+	return sessionStateService{}
+}
+
+type sessionStateService struct{}
+
+func (sessionStateService) LoadCredential(ctx agent.CallbackContext, cfg *auth.AuthConfig) (*auth.AuthCredential, error) {
+	val, err := ctx.State().Get(cfg.Key())
+	if errors.Is(err, session.ErrStateKeyNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromStateValue(val)
+}
+
+func (sessionStateService) SaveCredential(ctx agent.CallbackContext, cfg *auth.AuthConfig) error {
+	if cfg.ExchangedAuthCredential == nil {
+		return fmt.Errorf("exchanged auth credential is required")
+	}
+	m, err := converters.ToMapStructure(cfg.ExchangedAuthCredential)
+	if err != nil {
+		return fmt.Errorf("failed to convert credential: %w", err)
+	}
+	return ctx.State().Set(cfg.Key(), m)
+}
+
+// fromStateValue converts a credential stored in the session state back to an
+// auth.AuthCredential. Session services may return the stored value as is or
+// decoded from JSON.
+func fromStateValue(val any) (*auth.AuthCredential, error) {
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case *auth.AuthCredential:
+		return v.Copy(), nil
+	case map[string]any:
+		cred, err := converters.FromMapStructure[auth.AuthCredential](v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode credential: %w", err)
+		}
+		return cred, nil
+	default:
+		return nil, fmt.Errorf("unexpected credential type %T in state", val)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credential_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/auth/credential"
+	icontext "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+func newCallbackContext(t *testing.T, userID, sessionID string) agent.CallbackContext {
+	t.Helper()
+	resp, err := session.InMemoryService().Create(t.Context(), &session.CreateRequest{
+		AppName:   "app",
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := icontext.NewInvocationContext(t.Context(), icontext.InvocationContextParams{Session: resp.Session})
+	return icontext.NewCallbackContext(ctx)
+}
+
+func newAuthConfig() *auth.AuthConfig {
+	return &auth.AuthConfig{
+		AuthScheme: &auth.AuthScheme{Type: auth.SchemeHTTP, Scheme: "bearer"},
+		ExchangedAuthCredential: &auth.AuthCredential{
+			AuthType: auth.CredentialHTTP,
+			HTTP: &auth.HTTPAuth{
+				Scheme:      "bearer",
+				Credentials: auth.HTTPCredentials{Token: "token"},
+			},
+		},
+	}
+}
+
+func TestServices(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  credential.Service
+		// whether a credential saved in one session is loaded in another
+		// session of the same user.
+		sharedAcrossSessions bool
+	}{
+		{
+			name:                 "in memory",
+			svc:                  credential.InMemoryService(),
+			sharedAcrossSessions: true,
+		},
+		{
+			name:                 "session state",
+			svc:                  credential.SessionStateService(),
+			sharedAcrossSessions: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newCallbackContext(t, "user", "s1")
+			cfg := newAuthConfig()
+
+			got, err := tt.svc.LoadCredential(ctx, cfg)
+			if err != nil || got != nil {
+				t.Fatalf("LoadCredential() before save = %v, %v, want nil, nil", got, err)
+			}
+
+			if err := tt.svc.SaveCredential(ctx, cfg); err != nil {
+				t.Fatalf("SaveCredential() error = %v", err)
+			}
+			got, err = tt.svc.LoadCredential(ctx, newAuthConfig())
+			if err != nil {
+				t.Fatalf("LoadCredential() error = %v", err)
+			}
+			if diff := cmp.Diff(cfg.ExchangedAuthCredential, got); diff != "" {
+				t.Errorf("LoadCredential() mismatch (-want +got):\n%s", diff)
+			}
+
+			got, err = tt.svc.LoadCredential(newCallbackContext(t, "user", "s2"), cfg)
+			if err != nil {
+				t.Fatalf("LoadCredential() error = %v", err)
+			}
+			if (got != nil) != tt.sharedAcrossSessions {
+				t.Errorf("LoadCredential() in another session = %v, want shared %v", got, tt.sharedAcrossSessions)
+			}
+
+			got, err = tt.svc.LoadCredential(newCallbackContext(t, "other_user", "s3"), cfg)
+			if err != nil || got != nil {
+				t.Errorf("LoadCredential() for another user = %v, %v, want nil, nil", got, err)
+			}
+
+			if err := tt.svc.SaveCredential(ctx, &auth.AuthConfig{AuthScheme: cfg.AuthScheme}); err == nil {
+				t.Errorf("SaveCredential() without credential succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"golang.org/x/oauth2"
+)
+
+// GenerateAuthRequest returns a copy of the config prepared to be sent to the
+// client.
+//
+// For OAuth2 and OpenID Connect schemes using the authorization code flow, it
+// sets ExchangedAuthCredential to a copy of RawAuthCredential with the
+// AuthURI the user should be redirected to and a random State, unless
+// ExchangedAuthCredential already has an AuthURI. Other configs are returned
+// unchanged.
+func GenerateAuthRequest(cfg *AuthConfig) (*AuthConfig, error) {
+	if cfg == nil || cfg.AuthScheme == nil {
+		return nil, fmt.Errorf("auth scheme is required")
+	}
+	req := cfg.Copy()
+	if !isOAuth2(cfg.AuthScheme) {
+		return req, nil
+	}
+	if req.ExchangedAuthCredential != nil && req.ExchangedAuthCredential.OAuth2 != nil && req.ExchangedAuthCredential.OAuth2.AuthURI != "" {
+		return req, nil
+	}
+	if req.RawAuthCredential == nil || req.RawAuthCredential.OAuth2 == nil {
+		return nil, fmt.Errorf("raw OAuth2 credential is required for auth scheme %q", cfg.AuthScheme.Type)
+	}
+	raw := req.RawAuthCredential.OAuth2
+	if raw.ClientID == "" || raw.ClientSecret == "" {
+		return nil, fmt.Errorf("client id and client secret are required for auth scheme %q", cfg.AuthScheme.Type)
+	}
+
+	oauth2Config, err := newOAuth2Config(cfg.AuthScheme, raw)
+	if err != nil {
+		return nil, err
+	}
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	exchanged := req.RawAuthCredential.Copy()
+	exchanged.OAuth2.State = state
+	exchanged.OAuth2.AuthURI = oauth2Config.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("prompt", "consent"))
+	req.ExchangedAuthCredential = exchanged
+	return req, nil
+}
+
+// ExchangeCredential returns the credential to call the service with, based
+// on the ExchangedAuthCredential received from the client.
+//
+// For OAuth2 and OpenID Connect schemes, the authorization code is exchanged
+// for an access token at the token endpoint, unless the client already
+// provided an access token. The authorization code is taken from AuthCode or
+// parsed from AuthResponseURI. Other credentials are returned unchanged.
+func ExchangeCredential(ctx context.Context, cfg *AuthConfig) (*AuthCredential, error) {
+	if cfg == nil || cfg.AuthScheme == nil {
+		return nil, fmt.Errorf("auth scheme is required")
+	}
+	if cfg.ExchangedAuthCredential == nil {
+		return nil, fmt.Errorf("exchanged auth credential is required")
+	}
+	cred := cfg.ExchangedAuthCredential.Copy()
+	if !isOAuth2(cfg.AuthScheme) {
+		return cred, nil
+	}
+	if cred.OAuth2 == nil {
+		return nil, fmt.Errorf("OAuth2 credential is required for auth scheme %q", cfg.AuthScheme.Type)
+	}
+	if cred.OAuth2.AccessToken != "" {
+		return cred, nil
+	}
+
+	code := cred.OAuth2.AuthCode
+	if code == "" && cred.OAuth2.AuthResponseURI != "" {
+		var err error
+		code, err = parseAuthResponseURI(cred.OAuth2.AuthResponseURI, cred.OAuth2.State)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if code == "" {
+		return nil, fmt.Errorf("neither access token, auth code nor auth response uri is set")
+	}
+
+	oauth2Config, err := newOAuth2Config(cfg.AuthScheme, cred.OAuth2)
+	if err != nil {
+		return nil, err
+	}
+	token, err := oauth2Config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange auth code: %w", err)
+	}
+	cred.OAuth2.AuthCode = ""
+	cred.OAuth2.AccessToken = token.AccessToken
+	cred.OAuth2.RefreshToken = token.RefreshToken
+	cred.OAuth2.ExpiresIn = token.ExpiresIn
+	if !token.Expiry.IsZero() {
+		cred.OAuth2.ExpiresAt = token.Expiry.Unix()
+	}
+	return cred, nil
+}
+
+func isOAuth2(scheme *AuthScheme) bool {
+	return scheme.Type == SchemeOAuth2 || scheme.Type == SchemeOpenIDConnect
+}
+
+func newOAuth2Config(scheme *AuthScheme, cred *OAuth2Auth) (*oauth2.Config, error) {
+	cfg := &oauth2.Config{
+		ClientID:     cred.ClientID,
+		ClientSecret: cred.ClientSecret,
+		RedirectURL:  cred.RedirectURI,
+	}
+	switch scheme.Type {
+	case SchemeOAuth2:
+		if scheme.Flows == nil || scheme.Flows.AuthorizationCode == nil {
+			return nil, fmt.Errorf("only the authorization code flow is supported for auth scheme %q", scheme.Type)
+		}
+		flow := scheme.Flows.AuthorizationCode
+		cfg.Endpoint = oauth2.Endpoint{AuthURL: flow.AuthorizationURL, TokenURL: flow.TokenURL}
+		for scope := range flow.Scopes {
+			cfg.Scopes = append(cfg.Scopes, scope)
+		}
+		slices.Sort(cfg.Scopes)
+	case SchemeOpenIDConnect:
+		cfg.Endpoint = oauth2.Endpoint{AuthURL: scheme.AuthorizationEndpoint, TokenURL: scheme.TokenEndpoint}
+		cfg.Scopes = scheme.Scopes
+	}
+	if cfg.Endpoint.AuthURL == "" || cfg.Endpoint.TokenURL == "" {
+		return nil, fmt.Errorf("authorization and token endpoints are required for auth scheme %q", scheme.Type)
+	}
+	return cfg, nil
+}
+
+func parseAuthResponseURI(uri, state string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse auth response uri: %w", err)
+	}
+	query := u.Query()
+	if e := query.Get("error"); e != "" {
+		return "", fmt.Errorf("authorization failed: %s %s", e, query.Get("error_description"))
+	}
+	if state != "" && query.Get("state") != state {
+		return "", fmt.Errorf("auth response state does not match the auth request")
+	}
+	return query.Get("code"), nil
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import "maps"
+
+// AuthSchemeType is the type of an AuthScheme.
+type AuthSchemeType string
+
+const (
+	SchemeAPIKey        AuthSchemeType = "apiKey"
+	SchemeHTTP          AuthSchemeType = "http"
+	SchemeOAuth2        AuthSchemeType = "oauth2"
+	SchemeOpenIDConnect AuthSchemeType = "openIdConnect"
+)
+
+// AuthScheme describes how a credential is used to call a service. It
+// follows the OpenAPI Security Scheme Object.
+type AuthScheme struct {
+	Type        AuthSchemeType `json:"type"`
+	Description string         `json:"description,omitempty"`
+
+	// Name of the header, query or cookie parameter for SchemeAPIKey.
+	Name string `json:"name,omitempty"`
+	// In is the location of the API key for SchemeAPIKey: "query", "header"
+	// or "cookie".
+	In string `json:"in,omitempty"`
+
+	// Scheme is the HTTP authorization scheme for SchemeHTTP, e.g. "basic" or
+	// "bearer".
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+
+	// Flows are the supported OAuth2 flows for SchemeOAuth2.
+	Flows *OAuthFlows `json:"flows,omitempty"`
+
+	// OpenIDConnectURL is the OpenID Connect discovery URL for
+	// SchemeOpenIDConnect.
+	OpenIDConnectURL string `json:"openIdConnectUrl,omitempty"`
+	// AuthorizationEndpoint, TokenEndpoint and Scopes configure
+	// SchemeOpenIDConnect without discovery.
+	AuthorizationEndpoint string   `json:"authorizationEndpoint,omitempty"`
+	TokenEndpoint         string   `json:"tokenEndpoint,omitempty"`
+	Scopes                []string `json:"scopes,omitempty"`
+}
+
+// OAuthFlows are the OAuth2 flows supported by a service.
+type OAuthFlows struct {
+	Implicit          *OAuthFlow `json:"implicit,omitempty"`
+	Password          *OAuthFlow `json:"password,omitempty"`
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+// OAuthFlow configures an OAuth2 flow.
+type OAuthFlow struct {
+	AuthorizationURL string `json:"authorizationUrl,omitempty"`
+	TokenURL         string `json:"tokenUrl,omitempty"`
+	RefreshURL       string `json:"refreshUrl,omitempty"`
+	// Scopes maps scope names to their descriptions.
+	Scopes map[string]string `json:"scopes,omitempty"`
+}
+
+// Copy returns a deep copy of the scheme.
+func (s *AuthScheme) Copy() *AuthScheme {
+	if s == nil {
+		return nil
+	}
+	scheme := *s
+	scheme.Scopes = append([]string(nil), s.Scopes...)
+	if s.Flows != nil {
+		scheme.Flows = &OAuthFlows{
+			Implicit:          s.Flows.Implicit.copy(),
+			Password:          s.Flows.Password.copy(),
+			ClientCredentials: s.Flows.ClientCredentials.copy(),
+			AuthorizationCode: s.Flows.AuthorizationCode.copy(),
+		}
+	}
+	return &scheme
+}
+
+func (f *OAuthFlow) copy() *OAuthFlow {
+	if f == nil {
+		return nil
+	}
+	flow := *f
+	flow.Scopes = maps.Clone(f.Scopes)
+	return &flow
+}
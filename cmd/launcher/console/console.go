@@ -38,6 +38,7 @@ import (
 	"github.com/sjzsdu/adk-go/internal/cli/util"
 	"github.com/sjzsdu/adk-go/runner"
 	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/filesystem"
 )
 
 // consoleConfig contains command-line params for console launcher
@@ -46,6 +47,8 @@ type consoleConfig struct {
 	streamingModeString string // command-line param to be converted to agent.StreamingMode
 	otelToCloud         bool
 	shutdownTimeout     time.Duration
+	sessionDir          string // directory of the file-system session service
+	sessionID           string // session to resume or create
 }
 
 // consoleLauncher allows to interact with an agent in console
@@ -63,6 +66,8 @@ func NewLauncher() launcher.SubLauncher {
 		fmt.Sprintf("defines streaming mode (%s|%s)", agent.StreamingModeNone, agent.StreamingModeSSE))
 	fs.DurationVar(&config.shutdownTimeout, "shutdown-timeout", 2*time.Second, "Console shutdown timeout (i.e. '10s', '2m' - see time.ParseDuration for details) - for waiting for active requests to finish during shutdown")
 	fs.BoolVar(&config.otelToCloud, "otel_to_cloud", false, "Enables/disables OpenTelemetry export to GCP: telemetry.googleapis.com. See adk-go/telemetry package for details about supported options, credentials and environment variables.")
+	fs.StringVar(&config.sessionDir, "session_dir", "", "Directory persisting the sessions with the file-system session service, when the launcher config has no session service. Sessions are kept in memory if empty.")
+	fs.StringVar(&config.sessionID, "session_id", "", "ID of the session to resume, or to create if it does not exist. A new session is created if empty.")
 	return &consoleLauncher{config: config, flags: fs}
 }
 
@@ -88,21 +93,23 @@ func (l *consoleLauncher) Run(ctx context.Context, config *launcher.Config) erro
 
 	sessionService := config.SessionService
 	if sessionService == nil {
-		sessionService = session.InMemoryService()
+		if l.config.sessionDir != "" {
+			sessionService, err = filesystem.NewSessionService(filesystem.Config{Dir: l.config.sessionDir})
+			if err != nil {
+				return fmt.Errorf("failed to create the session service: %v", err)
+			}
+		} else {
+			sessionService = session.InMemoryService()
+		}
 	}
 
-	resp, err := sessionService.Create(ctx, &session.CreateRequest{
-		AppName: appName,
-		UserID:  userID,
-	})
+	session, err := l.openSession(ctx, sessionService, appName, userID)
 	if err != nil {
-		return fmt.Errorf("failed to create the session service: %v", err)
+		return err
 	}
 
 	rootAgent := config.AgentLoader.RootAgent()
 
-	session := resp.Session
-
 	r, err := runner.New(runner.Config{
 		AppName:         appName,
 		Agent:           rootAgent,
@@ -304,6 +311,31 @@ func (l *consoleLauncher) Run(ctx context.Context, config *launcher.Config) erro
 	}
 }
 
+// openSession resumes the session of the session_id flag if it exists, or
+// creates it.
+func (l *consoleLauncher) openSession(ctx context.Context, sessionService session.Service, appName, userID string) (session.Session, error) {
+	if l.config.sessionID != "" {
+		resp, err := sessionService.Get(ctx, &session.GetRequest{
+			AppName:   appName,
+			UserID:    userID,
+			SessionID: l.config.sessionID,
+		})
+		if err == nil {
+			fmt.Printf("Resuming session %s with %d events\n", l.config.sessionID, resp.Session.Events().Len())
+			return resp.Session, nil
+		}
+	}
+	resp, err := sessionService.Create(ctx, &session.CreateRequest{
+		AppName:   appName,
+		UserID:    userID,
+		SessionID: l.config.sessionID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the session: %v", err)
+	}
+	return resp.Session, nil
+}
+
 // Parse implements launcher.SubLauncher. After parsing console-specific
 // arguments returns remaining un-parsed arguments
 func (l *consoleLauncher) Parse(args []string) ([]string, error) {
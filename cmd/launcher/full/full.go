@@ -18,6 +18,7 @@ package full
 import (
 	"github.com/sjzsdu/adk-go/cmd/launcher"
 	"github.com/sjzsdu/adk-go/cmd/launcher/console"
+	"github.com/sjzsdu/adk-go/cmd/launcher/retention"
 	"github.com/sjzsdu/adk-go/cmd/launcher/universal"
 	"github.com/sjzsdu/adk-go/cmd/launcher/web"
 	"github.com/sjzsdu/adk-go/cmd/launcher/web/a2a"
@@ -27,5 +28,5 @@ import (
 
 // NewLauncher returnes the most versatile universal launcher with all options built-in.
 func NewLauncher() launcher.Launcher {
-	return universal.NewLauncher(console.NewLauncher(), web.NewLauncher(api.NewLauncher(), a2a.NewLauncher(), webui.NewLauncher()))
+	return universal.NewLauncher(console.NewLauncher(), web.NewLauncher(api.NewLauncher(), a2a.NewLauncher(), webui.NewLauncher()), retention.NewLauncher())
 }
@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retention provides a launcher applying session retention policies
+// on demand.
+package retention
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sjzsdu/adk-go/cmd/launcher"
+	"github.com/sjzsdu/adk-go/cmd/launcher/universal"
+	"github.com/sjzsdu/adk-go/internal/cli/util"
+	"github.com/sjzsdu/adk-go/session/retention"
+)
+
+// retentionConfig contains command-line params for retention launcher
+type retentionConfig struct {
+	apps       string
+	maxAge     time.Duration
+	maxEvents  int
+	archiveDir string
+}
+
+// retentionLauncher sweeps the sessions once with the policy given on the command line
+type retentionLauncher struct {
+	flags  *flag.FlagSet    // flags are used to parse command-line arguments
+	config *retentionConfig // config contains parsed command-line parameters
+}
+
+// NewLauncher creates new retention launcher
+func NewLauncher() launcher.SubLauncher {
+	config := &retentionConfig{}
+
+	fs := flag.NewFlagSet("retention", flag.ContinueOnError)
+	fs.StringVar(&config.apps, "apps", "", "Comma-separated names of the apps to sweep. Defaults to all the agents of the agent loader.")
+	fs.DurationVar(&config.maxAge, "max_age", 0, "Maximum time since the last update of a session (i.e. '720h' - see time.ParseDuration for details). Older sessions are deleted, or archived if -archive_dir is set. 0 means no limit.")
+	fs.IntVar(&config.maxEvents, "max_events", 0, "Maximum number of events of a session, the oldest events of the sessions with more events are deleted. 0 means no limit.")
+	fs.StringVar(&config.archiveDir, "archive_dir", "", "Directory to archive the expired sessions to as JSON files before deleting them. Archived sessions keep their artifacts.")
+	return &retentionLauncher{config: config, flags: fs}
+}
+
+// Run implements launcher.SubLauncher. It applies the retention policy once and prints the results.
+func (l *retentionLauncher) Run(ctx context.Context, config *launcher.Config) error {
+	if config.SessionService == nil {
+		return fmt.Errorf("session service is required")
+	}
+	var apps []string
+	if l.config.apps != "" {
+		apps = strings.Split(l.config.apps, ",")
+	} else if config.AgentLoader != nil {
+		apps = config.AgentLoader.ListAgents()
+	}
+	if len(apps) == 0 {
+		return fmt.Errorf("no apps to sweep: set -apps")
+	}
+
+	policy := retention.Policy{
+		MaxAge:    l.config.maxAge,
+		MaxEvents: l.config.maxEvents,
+		Archive:   l.config.archiveDir != "",
+	}
+	cfg := retention.Config{
+		SessionService:  config.SessionService,
+		ArtifactService: config.ArtifactService,
+		Policies:        make(map[string]retention.Policy),
+	}
+	for _, app := range apps {
+		cfg.Policies[strings.TrimSpace(app)] = policy
+	}
+	if policy.Archive {
+		cfg.Archiver = retention.DirArchiver(l.config.archiveDir)
+	}
+	sweeper, err := retention.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create the sweeper: %w", err)
+	}
+
+	stats, err := sweeper.Sweep(ctx)
+	fmt.Printf("Swept %d sessions: %d deleted, %d archived, %d events trimmed, %d artifacts deleted.\n",
+		stats.Sessions, stats.Deleted, stats.Archived, stats.TrimmedEvents, stats.DeletedArtifacts)
+	if err != nil {
+		return fmt.Errorf("retention sweep failed: %w", err)
+	}
+	return nil
+}
+
+// Parse implements launcher.SubLauncher. After parsing retention-specific arguments returns remaining un-parsed arguments
+func (l *retentionLauncher) Parse(args []string) ([]string, error) {
+	err := l.flags.Parse(args)
+	if err != nil || !l.flags.Parsed() {
+		return nil, fmt.Errorf("failed to parse flags: %v", err)
+	}
+	if l.config.maxAge < 0 || l.config.maxEvents < 0 {
+		return nil, fmt.Errorf("max_age and max_events must not be negative")
+	}
+	if l.config.maxAge == 0 && l.config.maxEvents == 0 {
+		return nil, fmt.Errorf("at least one of max_age and max_events is required")
+	}
+	return l.flags.Args(), nil
+}
+
+// Keyword implements launcher.SubLauncher. Returns the command-line keyword for retention launcher.
+func (l *retentionLauncher) Keyword() string {
+	return "retention"
+}
+
+// CommandLineSyntax implements launcher.SubLauncher. Returns the command-line syntax for the retention launcher.
+func (l *retentionLauncher) CommandLineSyntax() string {
+	return util.FormatFlagUsage(l.flags)
+}
+
+// SimpleDescription implements launcher.SubLauncher. Returns a simple description of the retention launcher.
+func (l *retentionLauncher) SimpleDescription() string {
+	return "applies a session retention policy once: deletes or archives expired sessions and trims long ones."
+}
+
+// Execute implements launcher.Launcher. It parses arguments and runs the launcher.
+func (l *retentionLauncher) Execute(ctx context.Context, config *launcher.Config, args []string) error {
+	remainingArgs, err := l.Parse(args)
+	if err != nil {
+		return fmt.Errorf("cannot parse args: %w", err)
+	}
+	// do not accept additional arguments
+	err = universal.ErrorOnUnparsedArgs(remainingArgs)
+	if err != nil {
+		return fmt.Errorf("cannot parse all the arguments: %w", err)
+	}
+	return l.Run(ctx, config)
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codeexecutor defines the interface for executing code generated by
+// the model.
+//
+// When a code executor is attached to an agent via llmagent.Config.CodeExecutor,
+// code blocks found in model responses are extracted and executed, and the
+// execution result is sent back to the model. This repeats until the model
+// replies without a code block.
+//
+// Implementations are available in subpackages, e.g. localexecutor.
+package codeexecutor
+
+import (
+	"github.com/sjzsdu/adk-go/agent"
+)
+
+// CodeExecutor executes code blocks extracted from model responses.
+type CodeExecutor interface {
+	// ExecuteCode executes the code and returns the result.
+	//
+	// An error is returned only if the code could not be executed at all.
+	// Failures of the code itself are reported in Result.Stderr.
+	ExecuteCode(ctx agent.InvocationContext, input *Input) (*Result, error)
+	// Options returns the options controlling how code is extracted from
+	// model responses.
+	Options() Options
+}
+
+// Language is the language of a code block.
+type Language string
+
+const (
+	// LanguagePython is the Python language.
+	LanguagePython Language = "python"
+	// LanguageShell is a POSIX shell script.
+	LanguageShell Language = "shell"
+)
+
+// Input is the input of a code execution.
+type Input struct {
+	// Code to execute.
+	Code string
+	// Language of the code.
+	Language Language
+	// InputFiles are made available in the working directory of the
+	// execution.
+	InputFiles []File
+}
+
+// Result is the result of a code execution.
+type Result struct {
+	// Stdout is the standard output of the execution.
+	Stdout string
+	// Stderr is the standard error of the execution. A non-empty Stderr is
+	// treated as a failed execution.
+	Stderr string
+	// OutputFiles are the files generated by the execution. They are saved
+	// as artifacts of the session.
+	OutputFiles []File
+}
+
+// File is a file used as input or produced as output of a code execution.
+type File struct {
+	// Name of the file. It must not contain path separators.
+	Name string
+	// MIMEType of the file content.
+	MIMEType string
+	// Content of the file.
+	Content []byte
+}
+
+// Delimiter marks the beginning and the end of a block in the model text.
+type Delimiter struct {
+	Start, End string
+}
+
+// CodeBlockDelimiter marks a code block of the given language.
+type CodeBlockDelimiter struct {
+	Delimiter
+	Language Language
+}
+
+// Options control how code is extracted from model responses.
+type Options struct {
+	// CodeBlockDelimiters are the delimiters of code blocks that are
+	// executed. The first Python delimiter is used to render executable code
+	// back to the model, code blocks of other languages are kept as text.
+	CodeBlockDelimiters []CodeBlockDelimiter
+	// ExecutionResultDelimiter is used to render execution results back to
+	// the model.
+	ExecutionResultDelimiter Delimiter
+	// ErrorRetryAttempts is the number of consecutive failed executions
+	// within an invocation after which code is no longer executed.
+	ErrorRetryAttempts int
+}
+
+// DefaultOptions returns the options used by the executors in this module
+// unless configured otherwise.
+func DefaultOptions() Options {
+	return Options{
+		CodeBlockDelimiters: []CodeBlockDelimiter{
+			{Delimiter: Delimiter{Start: "```tool_code\n", End: "\n```"}, Language: LanguagePython},
+			{Delimiter: Delimiter{Start: "```python\n", End: "\n```"}, Language: LanguagePython},
+			{Delimiter: Delimiter{Start: "```sh\n", End: "\n```"}, Language: LanguageShell},
+			{Delimiter: Delimiter{Start: "```bash\n", End: "\n```"}, Language: LanguageShell},
+		},
+		ExecutionResultDelimiter: Delimiter{Start: "```tool_output\n", End: "\n```"},
+		ErrorRetryAttempts:       2,
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package localexecutor provides a code executor that runs code in a local
+// subprocess.
+//
+// Every execution starts in a fresh working directory with a minimal
+// environment, a timeout and output size limits. Files written to the
+// working directory are returned as output files.
+//
+// NOTE: the code is not sandboxed. The working directory is only where the
+// code starts and where output files are collected from: the code can read
+// and write any file and use the network with the privileges of the current
+// process. Use a container or VM based executor for untrusted code.
+package localexecutor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/codeexecutor"
+)
+
+const (
+	defaultTimeout        = 30 * time.Second
+	defaultMaxOutputBytes = 64 * 1024
+	defaultMaxFileBytes   = 10 * 1024 * 1024
+)
+
+// Config is the configuration of the local executor.
+type Config struct {
+	// RootDir is the directory under which a working directory is created
+	// for each execution. Defaults to the OS temp directory.
+	RootDir string
+	// KeepWorkDirs keeps the working directories after execution.
+	KeepWorkDirs bool
+
+	// Timeout of a single execution. Defaults to 30 seconds.
+	Timeout time.Duration
+	// MaxOutputBytes limits the size of stdout and stderr each. Output
+	// beyond the limit is dropped. Defaults to 64KiB.
+	MaxOutputBytes int
+	// MaxFileBytes limits the size of a single output file. Larger files are
+	// not returned. Defaults to 10MiB.
+	MaxFileBytes int64
+
+	// PythonCommand is the command used to run Python code. Defaults to
+	// "python3".
+	PythonCommand string
+	// ShellCommand is the command used to run shell code. Defaults to
+	// "/bin/sh".
+	ShellCommand string
+	// Env is the environment of the subprocess in "key=value" form, in
+	// addition to PATH, HOME and TMPDIR. HOME and TMPDIR point to the working
+	// directory.
+	Env []string
+
+	// Options control how code is extracted from model responses. Defaults
+	// to codeexecutor.DefaultOptions().
+	Options *codeexecutor.Options
+}
+
+// New creates a local code executor.
+func New(cfg Config) (codeexecutor.CodeExecutor, error) {
+	if cfg.RootDir == "" {
+		cfg.RootDir = os.TempDir()
+	}
+	rootDir, err := filepath.Abs(cfg.RootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root dir %q: %w", cfg.RootDir, err)
+	}
+	if err := os.MkdirAll(rootDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create root dir %q: %w", rootDir, err)
+	}
+	cfg.RootDir = rootDir
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = defaultMaxOutputBytes
+	}
+	if cfg.MaxFileBytes <= 0 {
+		cfg.MaxFileBytes = defaultMaxFileBytes
+	}
+	if cfg.PythonCommand == "" {
+		cfg.PythonCommand = "python3"
+	}
+	if cfg.ShellCommand == "" {
+		cfg.ShellCommand = "/bin/sh"
+	}
+	options := codeexecutor.DefaultOptions()
+	if cfg.Options != nil {
+		options = *cfg.Options
+	}
+
+	return &executor{
+		cfg:     cfg,
+		options: options,
+	}, nil
+}
+
+type executor struct {
+	cfg     Config
+	options codeexecutor.Options
+}
+
+// Options implements codeexecutor.CodeExecutor.
+func (e *executor) Options() codeexecutor.Options {
+	return e.options
+}
+
+// ExecuteCode implements codeexecutor.CodeExecutor.
+func (e *executor) ExecuteCode(ctx agent.InvocationContext, input *codeexecutor.Input) (*codeexecutor.Result, error) {
+	if input == nil {
+		return nil, fmt.Errorf("code execution input is nil")
+	}
+
+	// Code the executor cannot run is a failure of the code, reported to
+	// the model like the other ones.
+	var name string
+	var args []string
+	switch input.Language {
+	case codeexecutor.LanguagePython:
+		name, args = e.cfg.PythonCommand, []string{"-c", input.Code}
+	case codeexecutor.LanguageShell:
+		name, args = e.cfg.ShellCommand, []string{"-c", input.Code}
+	case "":
+		return &codeexecutor.Result{Stderr: "the code language is not specified"}, nil
+	default:
+		return &codeexecutor.Result{Stderr: fmt.Sprintf("unsupported language %q", input.Language)}, nil
+	}
+
+	workDir, err := os.MkdirTemp(e.cfg.RootDir, "exec-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+	if !e.cfg.KeepWorkDirs {
+		defer os.RemoveAll(workDir)
+	}
+
+	inputNames := make(map[string]bool)
+	for _, f := range input.InputFiles {
+		if err := validateFileName(f.Name); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(workDir, f.Name), f.Content, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write input file %q: %w", f.Name, err)
+		}
+		inputNames[f.Name] = true
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(execCtx, name, args...)
+	cmd.Dir = workDir
+	cmd.Env = append([]string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + workDir,
+		"TMPDIR=" + workDir,
+	}, e.cfg.Env...)
+	stdout := &limitedBuffer{limit: e.cfg.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: e.cfg.MaxOutputBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+	cmd.WaitDelay = time.Second
+
+	runErr := cmd.Run()
+
+	result := &codeexecutor.Result{
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+	switch {
+	case runErr == nil:
+	// The invocation deadline or cancellation also ends the execution, only
+	// the executor's own timeout is reported to the model.
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case errors.Is(execCtx.Err(), context.DeadlineExceeded):
+		result.Stderr = appendLine(result.Stderr, fmt.Sprintf("execution timed out after %v", e.cfg.Timeout))
+	default:
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return nil, fmt.Errorf("failed to run %q: %w", name, runErr)
+		}
+		if result.Stderr == "" {
+			result.Stderr = fmt.Sprintf("process exited with code %d", exitErr.ExitCode())
+		}
+	}
+
+	files, err := e.collectOutputFiles(workDir, inputNames)
+	if err != nil {
+		return nil, err
+	}
+	result.OutputFiles = files
+	return result, nil
+}
+
+// collectOutputFiles returns the regular files in the top level of the
+// working directory that were not provided as input. Symlinks are skipped so
+// that files outside of the working directory are never read.
+func (e *executor) collectOutputFiles(workDir string, inputNames map[string]bool) ([]codeexecutor.File, error) {
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read working directory: %w", err)
+	}
+	var files []codeexecutor.File
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || inputNames[entry.Name()] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat output file %q: %w", entry.Name(), err)
+		}
+		if info.Size() > e.cfg.MaxFileBytes {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(workDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output file %q: %w", entry.Name(), err)
+		}
+		files = append(files, codeexecutor.File{
+			Name:     entry.Name(),
+			MIMEType: detectMIMEType(entry.Name(), content),
+			Content:  content,
+		})
+	}
+	return files, nil
+}
+
+func validateFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid input file name %q", name)
+	}
+	return nil
+}
+
+func detectMIMEType(name string, content []byte) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return http.DetectContentType(content)
+}
+
+func appendLine(s, line string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s + line
+	}
+	return s + "\n" + line
+}
+
+// limitedBuffer keeps at most limit bytes and drops the rest.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining < len(p) {
+		b.truncated = true
+		b.buf.Write(p[:max(remaining, 0)])
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return appendLine(b.buf.String(), "[output truncated]")
+	}
+	return b.buf.String()
+}
+
+var _ codeexecutor.CodeExecutor = (*executor)(nil)
@@ -0,0 +1,205 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package localexecutor_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/codeexecutor"
+	"github.com/sjzsdu/adk-go/codeexecutor/localexecutor"
+	icontext "github.com/sjzsdu/adk-go/internal/context"
+)
+
+func TestExecuteCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   localexecutor.Config
+		input *codeexecutor.Input
+		want  *codeexecutor.Result
+	}{
+		{
+			name:  "shell stdout",
+			input: &codeexecutor.Input{Code: "echo hello", Language: codeexecutor.LanguageShell},
+			want:  &codeexecutor.Result{Stdout: "hello\n"},
+		},
+		{
+			name:  "shell stderr",
+			input: &codeexecutor.Input{Code: "echo oops >&2", Language: codeexecutor.LanguageShell},
+			want:  &codeexecutor.Result{Stderr: "oops\n"},
+		},
+		{
+			name:  "exit code without stderr",
+			input: &codeexecutor.Input{Code: "exit 3", Language: codeexecutor.LanguageShell},
+			want:  &codeexecutor.Result{Stderr: "process exited with code 3"},
+		},
+		{
+			name:  "minimal environment",
+			cfg:   localexecutor.Config{Env: []string{"FOO=bar"}},
+			input: &codeexecutor.Input{Code: "echo $FOO ${SECRET:-unset}", Language: codeexecutor.LanguageShell},
+			want:  &codeexecutor.Result{Stdout: "bar unset\n"},
+		},
+		{
+			name:  "output limit",
+			cfg:   localexecutor.Config{MaxOutputBytes: 4},
+			input: &codeexecutor.Input{Code: "echo 0123456789", Language: codeexecutor.LanguageShell},
+			want:  &codeexecutor.Result{Stdout: "0123\n[output truncated]"},
+		},
+		{
+			name:  "timeout",
+			cfg:   localexecutor.Config{Timeout: 100 * time.Millisecond},
+			input: &codeexecutor.Input{Code: "sleep 10", Language: codeexecutor.LanguageShell},
+			want:  &codeexecutor.Result{Stderr: "execution timed out after 100ms"},
+		},
+		{
+			name:  "unsupported language",
+			input: &codeexecutor.Input{Code: "1", Language: "cobol"},
+			want:  &codeexecutor.Result{Stderr: `unsupported language "cobol"`},
+		},
+		{
+			name:  "unspecified language",
+			input: &codeexecutor.Input{Code: "print(1)"},
+			want:  &codeexecutor.Result{Stderr: "the code language is not specified"},
+		},
+		{
+			name: "input and output files",
+			input: &codeexecutor.Input{
+				Code:       "cat in.txt; tr a-z A-Z < in.txt > out.txt",
+				Language:   codeexecutor.LanguageShell,
+				InputFiles: []codeexecutor.File{{Name: "in.txt", Content: []byte("data")}},
+			},
+			want: &codeexecutor.Result{
+				Stdout:      "data",
+				OutputFiles: []codeexecutor.File{{Name: "out.txt", MIMEType: "text/plain; charset=utf-8", Content: []byte("DATA")}},
+			},
+		},
+		{
+			name: "oversized output files are skipped",
+			cfg:  localexecutor.Config{MaxFileBytes: 2},
+			input: &codeexecutor.Input{
+				Code:     "printf abc > big.txt; printf a > small.txt",
+				Language: codeexecutor.LanguageShell,
+			},
+			want: &codeexecutor.Result{
+				OutputFiles: []codeexecutor.File{{Name: "small.txt", MIMEType: "text/plain; charset=utf-8", Content: []byte("a")}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.RootDir = t.TempDir()
+			e, err := localexecutor.New(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := e.ExecuteCode(newInvocationContext(t), tt.input)
+			if err != nil {
+				t.Fatalf("ExecuteCode() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ExecuteCode() mismatch (-want +got):\n%s", diff)
+			}
+			entries, err := os.ReadDir(tt.cfg.RootDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("working directory was not removed: %v", entries)
+			}
+		})
+	}
+}
+
+func TestExecuteCode_Python(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not available")
+	}
+	e, err := localexecutor.New(localexecutor.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.ExecuteCode(newInvocationContext(t), &codeexecutor.Input{Code: "print(6 * 7)", Language: codeexecutor.LanguagePython})
+	if err != nil {
+		t.Fatalf("ExecuteCode() error = %v", err)
+	}
+	if diff := cmp.Diff(&codeexecutor.Result{Stdout: "42\n"}, got); diff != "" {
+		t.Errorf("ExecuteCode() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExecuteCode_Errors(t *testing.T) {
+	e, err := localexecutor.New(localexecutor.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		input   *codeexecutor.Input
+		wantErr string
+	}{
+		{
+			name:    "nil input",
+			wantErr: "input is nil",
+		},
+		{
+			name: "input file outside of working directory",
+			input: &codeexecutor.Input{
+				Code:       "true",
+				Language:   codeexecutor.LanguageShell,
+				InputFiles: []codeexecutor.File{{Name: "../escape.txt"}},
+			},
+			wantErr: "invalid input file name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := e.ExecuteCode(newInvocationContext(t), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExecuteCode() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteCode_InvocationDeadline(t *testing.T) {
+	e, err := localexecutor.New(localexecutor.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	invCtx := icontext.NewInvocationContext(ctx, icontext.InvocationContextParams{})
+
+	// The invocation deadline is not reported as a timeout of the execution.
+	got, err := e.ExecuteCode(invCtx, &codeexecutor.Input{Code: "sleep 10", Language: codeexecutor.LanguageShell})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ExecuteCode() = %v, %v, want error %v", got, err, context.DeadlineExceeded)
+	}
+}
+
+func newInvocationContext(t *testing.T) agent.InvocationContext {
+	t.Helper()
+	return icontext.NewInvocationContext(context.Background(), icontext.InvocationContextParams{})
+}
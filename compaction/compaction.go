@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package compaction configures the automatic compaction of the conversation
+// history sent to the model.
+//
+// When compaction is enabled via llmagent.Config.Compaction and the estimated
+// size of a model request exceeds the token budget, the older events of the
+// session are summarized by a designated model. The summary is stored in the
+// session as a compaction event (see session.EventCompaction) and replaces the
+// events it covers in all later model requests. A function call and its
+// response are always compacted together.
+package compaction
+
+import (
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+)
+
+// DefaultInstruction is the default system instruction of the summarization
+// request.
+const DefaultInstruction = `You are summarizing the earlier part of a conversation between a user and an AI agent, so that the agent can continue the conversation without the full history.
+Write a concise summary that preserves the user's goals and preferences, the facts and decisions established so far, the results of tool calls that are still relevant, and any open questions or pending tasks.
+Reply with the summary only.`
+
+// Config configures the compaction of an agent's history.
+type Config struct {
+	// Model summarizes the compacted events. Required.
+	Model model.LLM
+	// TokenBudget is the estimated number of tokens of a model request above
+	// which the history is compacted. Required.
+	TokenBudget int
+	// RetainTokens is the estimated number of tokens of the most recent
+	// events that are kept as they are. The latest event is always kept.
+	// Defaults to half of TokenBudget.
+	RetainTokens int
+	// Instruction is the system instruction of the summarization request.
+	// Defaults to DefaultInstruction.
+	Instruction string
+	// TokenEstimator estimates the number of tokens of the contents.
+	// Defaults to EstimateTokens.
+	TokenEstimator func(contents []*genai.Content) int
+}
+
+// Validate reports whether the config is complete.
+func (c *Config) Validate() error {
+	if c.Model == nil {
+		return errors.New("compaction: Model is required")
+	}
+	if c.TokenBudget <= 0 {
+		return errors.New("compaction: TokenBudget must be positive")
+	}
+	if c.RetainTokens < 0 || c.RetainTokens >= c.TokenBudget {
+		return errors.New("compaction: RetainTokens must be between 0 and TokenBudget")
+	}
+	return nil
+}
+
+// EstimateTokens estimates the number of tokens of the contents, assuming
+// about four bytes per token. Function calls and responses are counted by the
+// size of their JSON encoding, inline data by its size.
+func EstimateTokens(contents []*genai.Content) int {
+	n := 0
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		for _, part := range content.Parts {
+			if part == nil {
+				continue
+			}
+			n += len(part.Text)
+			if part.FunctionCall != nil {
+				n += len(part.FunctionCall.Name) + jsonSize(part.FunctionCall.Args)
+			}
+			if part.FunctionResponse != nil {
+				n += len(part.FunctionResponse.Name) + jsonSize(part.FunctionResponse.Response)
+			}
+			if part.InlineData != nil {
+				n += len(part.InlineData.Data)
+			}
+			if part.ExecutableCode != nil {
+				n += len(part.ExecutableCode.Code)
+			}
+			if part.CodeExecutionResult != nil {
+				n += len(part.CodeExecutionResult.Output)
+			}
+		}
+	}
+	return (n + 3) / 4
+}
+
+func jsonSize(v any) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
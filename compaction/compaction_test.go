@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compaction_test
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/compaction"
+	"github.com/sjzsdu/adk-go/internal/testutil"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	m := &testutil.MockModel{}
+	tests := []struct {
+		name    string
+		cfg     compaction.Config
+		wantErr bool
+	}{
+		{name: "valid", cfg: compaction.Config{Model: m, TokenBudget: 100, RetainTokens: 50}},
+		{name: "default retain tokens", cfg: compaction.Config{Model: m, TokenBudget: 100}},
+		{name: "no model", cfg: compaction.Config{TokenBudget: 100}, wantErr: true},
+		{name: "no budget", cfg: compaction.Config{Model: m}, wantErr: true},
+		{name: "retain exceeds budget", cfg: compaction.Config{Model: m, TokenBudget: 100, RetainTokens: 100}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []*genai.Content
+		want     int
+	}{
+		{name: "empty", want: 0},
+		{
+			name:     "text",
+			contents: []*genai.Content{genai.NewContentFromText(strings.Repeat("a", 40), genai.RoleUser)},
+			want:     10,
+		},
+		{
+			name: "function call and response",
+			contents: []*genai.Content{
+				genai.NewContentFromFunctionCall("f", map[string]any{"k": "v"}, genai.RoleModel),    // 1 + 9 bytes
+				genai.NewContentFromFunctionResponse("f", map[string]any{"k": "v"}, genai.RoleUser), // 1 + 9 bytes
+			},
+			want: 5,
+		},
+		{
+			name:     "rounds up",
+			contents: []*genai.Content{genai.NewContentFromText("a", genai.RoleUser)},
+			want:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compaction.EstimateTokens(tt.contents); got != tt.want {
+				t.Errorf("EstimateTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
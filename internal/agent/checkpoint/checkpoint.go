@@ -0,0 +1,158 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package checkpoint records the progress of the agents of a resumable
+// invocation in its events, so that an invocation paused by a long-running
+// function call can continue where it stopped.
+//
+// Agents that run other agents, i.e. workflow agents and LLM agents
+// transferring to another agent, record their state in an event (see
+// session.EventActions.AgentState) before running the next agent, and an
+// end-of-agent event once they finish. When the invocation is resumed, the
+// unfinished states are loaded from the events and each agent continues from
+// its state instead of starting over.
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/internal/utils"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// Checkpoints holds the agent states of a resumable invocation.
+type Checkpoints struct {
+	mu sync.Mutex
+	// pending maps agent names to the states to resume from.
+	pending map[string]map[string]any
+}
+
+// New returns the checkpoints of a new resumable invocation.
+func New() *Checkpoints {
+	return &Checkpoints{pending: make(map[string]map[string]any)}
+}
+
+// Load returns the checkpoints of the agents that did not finish within
+// the events of an invocation.
+func Load(events []*session.Event) *Checkpoints {
+	c := New()
+	for _, ev := range events {
+		switch {
+		case ev.Actions.EndOfAgent:
+			delete(c.pending, ev.Author)
+		case ev.Actions.AgentState != nil:
+			c.pending[ev.Author] = ev.Actions.AgentState
+		}
+	}
+	return c
+}
+
+// Has reports whether the agent has a state to resume from.
+func (c *Checkpoints) Has(agentName string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.pending[agentName]
+	return ok
+}
+
+// Resume decodes the state to resume the agent from into state, which must
+// be a pointer to a struct, and reports whether there was one. A state is
+// resumed from only once: when the agent runs again later in the invocation,
+// it starts over.
+func (c *Checkpoints) Resume(agentName string, state any) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	c.mu.Lock()
+	m, ok := c.pending[agentName]
+	delete(c.pending, agentName)
+	c.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode the checkpoint of agent %q: %w", agentName, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return false, fmt.Errorf("failed to decode the checkpoint of agent %q: %w", agentName, err)
+	}
+	return true, nil
+}
+
+// Event returns an event recording the state of the agent running in ctx.
+func Event(ctx agent.InvocationContext, state any) (*session.Event, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the checkpoint of agent %q: %w", ctx.Agent().Name(), err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to encode the checkpoint of agent %q: %w", ctx.Agent().Name(), err)
+	}
+	ev := session.NewEvent(ctx.InvocationID())
+	ev.Author = ctx.Agent().Name()
+	ev.Branch = ctx.Branch()
+	ev.Actions.AgentState = m
+	return ev, nil
+}
+
+// EndEvent returns an event recording that the agent running in ctx finished.
+func EndEvent(ctx agent.InvocationContext) *session.Event {
+	ev := session.NewEvent(ctx.InvocationID())
+	ev.Author = ctx.Agent().Name()
+	ev.Branch = ctx.Branch()
+	ev.Actions.EndOfAgent = true
+	return ev
+}
+
+// ShouldPause reports whether the event pauses a resumable invocation,
+// because it contains long-running function calls. The agents stop after the
+// current step and can be resumed once the function responses are available.
+func ShouldPause(ctx context.Context, ev *session.Event) bool {
+	if FromContext(ctx) == nil || ev == nil || len(ev.LongRunningToolIDs) == 0 {
+		return false
+	}
+	for _, call := range utils.FunctionCalls(ev.Content) {
+		if slices.Contains(ev.LongRunningToolIDs, call.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToContext returns a context for a resumable invocation with the given
+// checkpoints.
+func ToContext(ctx context.Context, c *Checkpoints) context.Context {
+	return context.WithValue(ctx, checkpointsCtxKey, c)
+}
+
+// FromContext returns the checkpoints of the invocation, or nil if the
+// invocation is not resumable.
+func FromContext(ctx context.Context) *Checkpoints {
+	c, _ := ctx.Value(checkpointsCtxKey).(*Checkpoints)
+	return c
+}
+
+type ctxKey int
+
+const checkpointsCtxKey ctxKey = 0
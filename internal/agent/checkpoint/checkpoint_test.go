@@ -0,0 +1,71 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+func TestLoad(t *testing.T) {
+	type state struct {
+		SubAgent  string `json:"sub_agent"`
+		Iteration int    `json:"iteration"`
+	}
+	events := []*session.Event{
+		{Author: "seq", Actions: session.EventActions{AgentState: map[string]any{"sub_agent": "loop"}}},
+		{Author: "loop", Actions: session.EventActions{AgentState: map[string]any{"sub_agent": "a", "iteration": 0.0}}},
+		{Author: "loop", Actions: session.EventActions{EndOfAgent: true}},
+		{Author: "seq", Actions: session.EventActions{AgentState: map[string]any{"sub_agent": "b", "iteration": 2.0}}},
+	}
+	c := Load(events)
+	if c.Has("loop") {
+		t.Error("finished agent has a checkpoint")
+	}
+	var got state
+	if ok, err := c.Resume("seq", &got); !ok || err != nil {
+		t.Fatalf("Resume() = %v, %v, want the checkpoint", ok, err)
+	}
+	if want := (state{SubAgent: "b", Iteration: 2}); got != want {
+		t.Errorf("Resume() decoded %+v, want %+v", got, want)
+	}
+	// A checkpoint is resumed from once.
+	if ok, _ := c.Resume("seq", &got); ok {
+		t.Error("second Resume() found a checkpoint")
+	}
+}
+
+func TestShouldPause(t *testing.T) {
+	call := &session.Event{
+		LLMResponse: model.LLMResponse{Content: &genai.Content{Parts: []*genai.Part{
+			{FunctionCall: &genai.FunctionCall{ID: "c1", Name: "approve"}},
+		}}},
+		LongRunningToolIDs: []string{"c1"},
+	}
+	resumable := ToContext(t.Context(), New())
+	if !ShouldPause(resumable, call) {
+		t.Error("ShouldPause() = false for a long-running call, want true")
+	}
+	if ShouldPause(t.Context(), call) {
+		t.Error("ShouldPause() = true outside of a resumable invocation, want false")
+	}
+	if ShouldPause(resumable, &session.Event{LongRunningToolIDs: []string{"c1"}}) {
+		t.Error("ShouldPause() = true for an event without the call, want false")
+	}
+}
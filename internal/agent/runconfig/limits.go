@@ -0,0 +1,190 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runconfig
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// Limits tracks the usage of an invocation against the limits of
+// agent.RunConfig. It is shared by all agents of the invocation and is safe
+// for concurrent use. A nil *Limits has no limits.
+//
+// Once a limit is exceeded, all further checks fail with the same error, so
+// that every agent of the invocation stops.
+type Limits struct {
+	maxLLMCalls       int
+	maxToolCalls      int
+	maxAgentTransfers int
+	timeout           time.Duration
+	deadline          time.Time
+
+	mu             sync.Mutex
+	llmCalls       int
+	toolCalls      int
+	agentTransfers int
+	exceeded       *agent.LimitExceededError
+	reported       bool
+}
+
+// NewLimits returns the limits of an invocation started at start, or nil if
+// cfg sets no limits.
+func NewLimits(cfg *agent.RunConfig, start time.Time) *Limits {
+	if cfg == nil || (cfg.MaxLLMCalls <= 0 && cfg.MaxToolCalls <= 0 && cfg.MaxAgentTransfers <= 0 && cfg.Timeout <= 0) {
+		return nil
+	}
+	l := &Limits{
+		maxLLMCalls:       cfg.MaxLLMCalls,
+		maxToolCalls:      cfg.MaxToolCalls,
+		maxAgentTransfers: cfg.MaxAgentTransfers,
+		timeout:           cfg.Timeout,
+	}
+	if cfg.Timeout > 0 {
+		l.deadline = start.Add(cfg.Timeout)
+	}
+	return l
+}
+
+// Deadline returns the deadline of the invocation, if any.
+func (l *Limits) Deadline() (time.Time, bool) {
+	if l == nil || l.deadline.IsZero() {
+		return time.Time{}, false
+	}
+	return l.deadline, true
+}
+
+// Check returns an error if a limit has been exceeded or the deadline has
+// passed.
+func (l *Limits) Check() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.checkLocked()
+}
+
+// AddLLMCall records an LLM call. It returns an error, without recording the
+// call, if the call would exceed a limit.
+func (l *Limits) AddLLMCall() error {
+	if l == nil {
+		return nil
+	}
+	return l.add(&l.llmCalls, 1, l.maxLLMCalls, agent.LimitLLMCalls)
+}
+
+// AddToolCalls records n tool calls, e.g. the function calls of a model
+// response. It returns an error, without recording any call, if the calls
+// would exceed a limit.
+func (l *Limits) AddToolCalls(n int) error {
+	if l == nil {
+		return nil
+	}
+	return l.add(&l.toolCalls, n, l.maxToolCalls, agent.LimitToolCalls)
+}
+
+// AddAgentTransfer records a transfer to another agent. It returns an error,
+// without recording the transfer, if the transfer would exceed a limit.
+func (l *Limits) AddAgentTransfer() error {
+	if l == nil {
+		return nil
+	}
+	return l.add(&l.agentTransfers, 1, l.maxAgentTransfers, agent.LimitAgentTransfers)
+}
+
+func (l *Limits) add(counter *int, n, max int, limit agent.Limit) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.checkLocked(); err != nil {
+		return err
+	}
+	if max > 0 && *counter+n > max {
+		l.exceeded = &agent.LimitExceededError{Limit: limit, Max: max}
+		return l.exceeded
+	}
+	*counter += n
+	return nil
+}
+
+func (l *Limits) checkLocked() error {
+	if l.exceeded != nil {
+		return l.exceeded
+	}
+	if !l.deadline.IsZero() && !time.Now().Before(l.deadline) {
+		l.exceeded = &agent.LimitExceededError{Limit: agent.LimitTimeout, Timeout: l.timeout}
+		return l.exceeded
+	}
+	return nil
+}
+
+// LimitsFromContext returns the limits of the invocation, or nil if there are
+// none.
+func LimitsFromContext(ctx context.Context) *Limits {
+	cfg := FromContext(ctx)
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Limits
+}
+
+// LimitExceeded returns the limit error if the invocation exceeded a limit,
+// otherwise err. Errors caused by the invocation deadline, such as
+// context.DeadlineExceeded from a model call, are replaced with the limit
+// error.
+//
+// The first time a limit error is returned within an invocation, it comes
+// with the event ending the invocation, which the caller must yield before
+// the error.
+func LimitExceeded(ctx agent.InvocationContext, err error) (*session.Event, error) {
+	l := LimitsFromContext(ctx)
+	if l == nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.checkLocked() == nil {
+		return nil, err
+	}
+	if l.reported {
+		return nil, l.exceeded
+	}
+	l.reported = true
+	return limitExceededEvent(ctx, l.exceeded), l.exceeded
+}
+
+// IsLimitExceeded reports whether err is caused by an exceeded limit.
+func IsLimitExceeded(err error) bool {
+	var limitErr *agent.LimitExceededError
+	return errors.As(err, &limitErr)
+}
+
+func limitExceededEvent(ctx agent.InvocationContext, err *agent.LimitExceededError) *session.Event {
+	event := session.NewEvent(ctx.InvocationID())
+	event.Author = ctx.Agent().Name()
+	event.Branch = ctx.Branch()
+	event.LLMResponse = model.LLMResponse{
+		ErrorCode:    agent.LimitExceededErrorCode,
+		ErrorMessage: err.Error(),
+		TurnComplete: true,
+	}
+	return event
+}
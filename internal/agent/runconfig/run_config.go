@@ -26,6 +26,7 @@ const (
 
 type RunConfig struct {
 	StreamingMode StreamingMode
+	Limits        *Limits
 }
 
 func ToContext(ctx context.Context, cfg *RunConfig) context.Context {
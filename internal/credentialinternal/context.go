@@ -0,0 +1,46 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentialinternal
+
+import (
+	"context"
+
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/auth/credential"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+func ToContext(ctx context.Context, svc credential.Service) context.Context {
+	return context.WithValue(ctx, credentialServiceCtxKey, svc)
+}
+
+func FromContext(ctx context.Context) credential.Service {
+	svc, ok := ctx.Value(credentialServiceCtxKey).(credential.Service)
+	if !ok {
+		return nil
+	}
+	return svc
+}
+
+// TempStateKey is the state key of a credential received within the current
+// invocation. Temp state is never persisted, so the credential is available
+// to tools even if no credential service is configured.
+func TempStateKey(cfg *auth.AuthConfig) string {
+	return session.KeyPrefixTemp + cfg.Key()
+}
+
+type ctxKey int
+
+const credentialServiceCtxKey ctxKey = 0
@@ -18,7 +18,11 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/codeexecutor"
+	"github.com/sjzsdu/adk-go/compaction"
 	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/planner"
+	"github.com/sjzsdu/adk-go/session"
 	"github.com/sjzsdu/adk-go/tool"
 )
 
@@ -33,7 +37,10 @@ type State struct {
 	Tools    []tool.Tool
 	Toolsets []tool.Toolset
 
-	IncludeContents string
+	IncludeContents          string
+	IncludeContentsTurns     int
+	IncludeContentsMaxTokens int
+	IncludeContentsProvider  IncludeContentsProvider
 
 	GenerateContentConfig *genai.GenerateContentConfig
 
@@ -49,10 +56,18 @@ type State struct {
 	OutputSchema *genai.Schema
 
 	OutputKey string
+
+	Planner planner.Planner
+
+	CodeExecutor codeexecutor.CodeExecutor
+
+	Compaction *compaction.Config
 }
 
 type InstructionProvider func(ctx agent.ReadonlyContext) (string, error)
 
+type IncludeContentsProvider func(ctx agent.ReadonlyContext, events []*session.Event) ([]*genai.Content, error)
+
 func (s *State) internal() *State { return s }
 
 func Reveal(a Agent) *State { return a.internal() }
@@ -0,0 +1,235 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llminternal
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
+	icontext "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/internal/converters"
+	"github.com/sjzsdu/adk-go/internal/credentialinternal"
+	"github.com/sjzsdu/adk-go/internal/utils"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/tool"
+)
+
+// authPreprocessor handles the credentials sent by the client in response to
+// adk_request_credential function calls. It stores the credentials and runs
+// the tools that requested them again.
+func authPreprocessor(ctx agent.InvocationContext, req *model.LLMRequest, f *Flow) iter.Seq2[*session.Event, error] {
+	// reference: adk-python src/google/adk/auth/auth_preprocessor.py
+	return func(yield func(*session.Event, error) bool) {
+		llmAgent := asLLMAgent(ctx.Agent())
+		if llmAgent == nil || ctx.Session() == nil {
+			return
+		}
+
+		var events []*session.Event
+		for e := range ctx.Session().Events().All() {
+			events = append(events, e)
+		}
+
+		// Find the credentials in the last event authored by user.
+		authResponses := make(map[string]*auth.AuthConfig)
+		authEventIndex := -1
+		for k := len(events) - 1; k >= 0; k-- {
+			event := events[k]
+			if event.Author != "user" {
+				continue
+			}
+			for _, funcResp := range utils.FunctionResponses(event.Content) {
+				if funcResp.Name != auth.FunctionCallName {
+					continue
+				}
+				cfg, err := authConfigFromResponse(funcResp.Response)
+				if err != nil {
+					yield(nil, fmt.Errorf("failed to parse credential function response for event id %q: %w", event.ID, err))
+					return
+				}
+				authResponses[funcResp.ID] = cfg
+			}
+			authEventIndex = k
+			break
+		}
+		if len(authResponses) == 0 {
+			return
+		}
+
+		// Find the adk_request_credential function calls the client responded
+		// to, and the function calls of the tools that requested them.
+		type pendingCall struct {
+			authConfig *auth.AuthConfig
+			call       *genai.FunctionCall
+		}
+		pending := make(map[string]*pendingCall)
+		for _, event := range events[:authEventIndex] {
+			for _, call := range utils.FunctionCalls(event.Content) {
+				if call.Name != auth.FunctionCallName {
+					continue
+				}
+				resp, ok := authResponses[call.ID]
+				if !ok {
+					continue
+				}
+				originalCallID, _ := call.Args["functionCallId"].(string)
+				requested, err := authConfigFromValue(call.Args["authConfig"])
+				if err != nil || originalCallID == "" {
+					continue
+				}
+				pending[originalCallID] = &pendingCall{authConfig: mergeAuthResponse(requested, resp)}
+			}
+		}
+		for _, event := range events[:authEventIndex] {
+			for _, call := range utils.FunctionCalls(event.Content) {
+				if p, ok := pending[call.ID]; ok && call.Name != auth.FunctionCallName {
+					p.call = call
+				}
+			}
+		}
+		// Skip the tools that have already been called again.
+		for _, event := range events[authEventIndex+1:] {
+			for _, resp := range utils.FunctionResponses(event.Content) {
+				delete(pending, resp.ID)
+			}
+		}
+		for id, p := range pending {
+			if p.call == nil {
+				delete(pending, id)
+			}
+		}
+		if len(pending) == 0 {
+			return
+		}
+
+		stateDelta := make(map[string]any)
+		cbCtx := icontext.NewCallbackContextWithDelta(ctx, stateDelta)
+		credentialService := credentialinternal.FromContext(ctx)
+		ids := slices.Sorted(maps.Keys(pending))
+		parts := make([]*genai.Part, 0, len(ids))
+		for _, id := range ids {
+			p := pending[id]
+			cred, err := auth.ExchangeCredential(ctx, p.authConfig)
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to exchange credential for function call %q: %w", id, err))
+				return
+			}
+			p.authConfig.ExchangedAuthCredential = cred
+			// Temp state is not persisted, it makes the credential available
+			// to the tool within this invocation.
+			if err := ctx.Session().State().Set(credentialinternal.TempStateKey(p.authConfig), cred); err != nil {
+				yield(nil, fmt.Errorf("failed to store credential: %w", err))
+				return
+			}
+			if credentialService != nil {
+				if err := credentialService.SaveCredential(cbCtx, p.authConfig); err != nil {
+					yield(nil, fmt.Errorf("failed to save credential: %w", err))
+					return
+				}
+			}
+			parts = append(parts, &genai.Part{FunctionCall: p.call})
+		}
+
+		if len(stateDelta) > 0 {
+			ev := session.NewEvent(ctx.InvocationID())
+			ev.Author = ctx.Agent().Name()
+			ev.Branch = ctx.Branch()
+			ev.Actions.StateDelta = stateDelta
+			if !yield(ev, nil) {
+				return
+			}
+		}
+
+		toolsmap := make(map[string]tool.Tool)
+		for _, t := range f.Tools {
+			toolsmap[t.Name()] = t
+		}
+		if err := runconfig.LimitsFromContext(ctx).AddToolCalls(len(parts)); err != nil {
+			yield(nil, err)
+			return
+		}
+		ev, err := f.handleFunctionCalls(ctx, toolsmap, &model.LLMResponse{
+			Content: &genai.Content{Parts: parts, Role: genai.RoleModel},
+		}, nil)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if ev != nil {
+			yield(ev, nil)
+		}
+	}
+}
+
+// mergeAuthResponse returns the requested config updated with the values
+// obtained by the client. The scheme and the client credentials are always
+// taken from the request, so that the client can't alter them.
+func mergeAuthResponse(requested, resp *auth.AuthConfig) *auth.AuthConfig {
+	cfg := requested.Copy()
+	got := resp.ExchangedAuthCredential
+	if got == nil {
+		return cfg
+	}
+	if got.OAuth2 == nil || cfg.ExchangedAuthCredential == nil || cfg.ExchangedAuthCredential.OAuth2 == nil {
+		cfg.ExchangedAuthCredential = got.Copy()
+		return cfg
+	}
+	oauth2 := cfg.ExchangedAuthCredential.OAuth2
+	oauth2.AuthResponseURI = got.OAuth2.AuthResponseURI
+	oauth2.AuthCode = got.OAuth2.AuthCode
+	oauth2.AccessToken = got.OAuth2.AccessToken
+	oauth2.RefreshToken = got.OAuth2.RefreshToken
+	oauth2.ExpiresAt = got.OAuth2.ExpiresAt
+	oauth2.ExpiresIn = got.OAuth2.ExpiresIn
+	return cfg
+}
+
+func authConfigFromResponse(resp map[string]any) (*auth.AuthConfig, error) {
+	// ADK web client will send a request that is always encapsulated in a 'response' key.
+	if val, ok := resp["response"]; ok && len(resp) == 1 {
+		jsonString, ok := val.(string)
+		if !ok {
+			return nil, fmt.Errorf("'response' key found but value is not a string")
+		}
+		var cfg auth.AuthConfig
+		if err := json.Unmarshal([]byte(jsonString), &cfg); err != nil {
+			return nil, err
+		}
+		return &cfg, nil
+	}
+	return authConfigFromValue(resp)
+}
+
+// authConfigFromValue converts a config stored in function call arguments,
+// which are decoded from JSON by some session services.
+func authConfigFromValue(val any) (*auth.AuthConfig, error) {
+	switch v := val.(type) {
+	case *auth.AuthConfig:
+		return v.Copy(), nil
+	case map[string]any:
+		return converters.FromMapStructure[auth.AuthConfig](v)
+	default:
+		return nil, fmt.Errorf("unexpected auth config type %T", val)
+	}
+}
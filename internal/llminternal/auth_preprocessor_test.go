@@ -0,0 +1,244 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llminternal_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/agent/llmagent"
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/auth/credential"
+	"github.com/sjzsdu/adk-go/internal/testutil"
+	"github.com/sjzsdu/adk-go/runner"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/tool"
+	"github.com/sjzsdu/adk-go/tool/functiontool"
+)
+
+func TestAuthFlow(t *testing.T) {
+	tests := []struct {
+		name              string
+		credentialService credential.Service
+		// whether the credential is reused by the next invocation.
+		wantReused bool
+	}{
+		{
+			name:       "without credential service",
+			wantReused: false,
+		},
+		{
+			name:              "with in-memory credential service",
+			credentialService: credential.InMemoryService(),
+			wantReused:        true,
+		},
+		{
+			name:              "with session state credential service",
+			credentialService: credential.SessionStateService(),
+			wantReused:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := testutil.NewFakeOAuthServer(t)
+			authConfig := &auth.AuthConfig{
+				AuthScheme: &auth.AuthScheme{
+					Type: auth.SchemeOAuth2,
+					Flows: &auth.OAuthFlows{
+						AuthorizationCode: &auth.OAuthFlow{
+							AuthorizationURL: server.AuthorizationURL(),
+							TokenURL:         server.TokenURL(),
+							Scopes:           map[string]string{"profile": "read profile"},
+						},
+					},
+				},
+				RawAuthCredential: &auth.AuthCredential{
+					AuthType: auth.CredentialOAuth2,
+					OAuth2: &auth.OAuth2Auth{
+						ClientID:     server.ClientID,
+						ClientSecret: server.ClientSecret,
+						RedirectURI:  "http://localhost/callback",
+					},
+				},
+			}
+
+			var gotTokens []string
+			profileTool, err := functiontool.New(functiontool.Config{
+				Name:        "get_profile",
+				Description: "returns the user profile",
+			}, func(ctx tool.Context, args struct{}) (map[string]any, error) {
+				cred, err := ctx.GetCredential(authConfig)
+				if err != nil {
+					return nil, err
+				}
+				if cred == nil {
+					return map[string]any{"status": "pending authorization"}, ctx.RequestCredential(authConfig)
+				}
+				gotTokens = append(gotTokens, cred.OAuth2.AccessToken)
+				return map[string]any{"name": "Alice"}, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			functionCall := func(id string) *genai.Content {
+				return &genai.Content{
+					Role:  genai.RoleModel,
+					Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: id, Name: "get_profile", Args: map[string]any{}}}},
+				}
+			}
+			mockModel := &testutil.MockModel{
+				Responses: []*genai.Content{
+					functionCall("call_1"),
+					genai.NewContentFromText("Hello Alice", genai.RoleModel),
+					functionCall("call_2"),
+					genai.NewContentFromText("Hello again", genai.RoleModel),
+				},
+			}
+			a, err := llmagent.New(llmagent.Config{
+				Name:  "profile_agent",
+				Model: mockModel,
+				Tools: []tool.Tool{profileTool},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sessionService := session.InMemoryService()
+			r, err := runner.New(runner.Config{
+				AppName:           "test_app",
+				Agent:             a,
+				SessionService:    sessionService,
+				CredentialService: tt.credentialService,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "test_app", UserID: "user"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			run := func(content *genai.Content) []*session.Event {
+				t.Helper()
+				var events []*session.Event
+				for event, err := range r.Run(t.Context(), "user", created.Session.ID(), content, agent.RunConfig{}) {
+					if err != nil {
+						t.Fatal(err)
+					}
+					events = append(events, event)
+				}
+				return events
+			}
+
+			// The tool requests the credential and the invocation ends.
+			events := run(genai.NewContentFromText("show my profile", genai.RoleUser))
+			requestCall, requested := findRequestCredentialCall(t, events)
+			if requestCall == nil {
+				t.Fatalf("no %s function call in events", auth.FunctionCallName)
+			}
+			if got, want := requestCall.Args["functionCallId"], "call_1"; got != want {
+				t.Errorf("functionCallId = %v, want %v", got, want)
+			}
+			if len(mockModel.Requests) != 1 {
+				t.Errorf("got %d model requests after the credential request, want 1", len(mockModel.Requests))
+			}
+
+			// The client authorizes and posts the redirect back.
+			requested.ExchangedAuthCredential.OAuth2.AuthResponseURI = server.Authorize(t, requested.ExchangedAuthCredential.OAuth2.AuthURI)
+			var response map[string]any
+			data, err := json.Marshal(requested)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(data, &response); err != nil {
+				t.Fatal(err)
+			}
+			events = run(&genai.Content{
+				Role: genai.RoleUser,
+				Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{
+					ID:       requestCall.ID,
+					Name:     auth.FunctionCallName,
+					Response: response,
+				}}},
+			})
+			if diff := cmp.Diff([]string{"access-code-1"}, gotTokens); diff != "" {
+				t.Errorf("tokens received by the tool mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("Hello Alice", lastText(events)); diff != "" {
+				t.Errorf("final response mismatch (-want +got):\n%s", diff)
+			}
+			// The credential exchange is not visible to the model.
+			for _, content := range mockModel.Requests[1].Contents {
+				for _, part := range content.Parts {
+					if (part.FunctionCall != nil && part.FunctionCall.Name == auth.FunctionCallName) ||
+						(part.FunctionResponse != nil && part.FunctionResponse.Name == auth.FunctionCallName) {
+						t.Errorf("credential request sent to the model: %+v", part)
+					}
+				}
+			}
+
+			// The next invocation reuses the stored credential.
+			events = run(genai.NewContentFromText("show it again", genai.RoleUser))
+			requestCall, _ = findRequestCredentialCall(t, events)
+			if got := requestCall == nil; got != tt.wantReused {
+				t.Errorf("credential reused = %v, want %v", got, tt.wantReused)
+			}
+			if diff := cmp.Diff([]string{"code-1"}, server.ExchangedCodes()); diff != "" {
+				t.Errorf("exchanged codes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func findRequestCredentialCall(t *testing.T, events []*session.Event) (*genai.FunctionCall, *auth.AuthConfig) {
+	t.Helper()
+	for _, event := range events {
+		if event.Content == nil {
+			continue
+		}
+		for _, part := range event.Content.Parts {
+			if part.FunctionCall == nil || part.FunctionCall.Name != auth.FunctionCallName {
+				continue
+			}
+			if diff := cmp.Diff([]string{part.FunctionCall.ID}, event.LongRunningToolIDs); diff != "" {
+				t.Errorf("long running tool ids mismatch (-want +got):\n%s", diff)
+			}
+			// Decode the config as a client would.
+			data, err := json.Marshal(part.FunctionCall.Args["authConfig"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cfg auth.AuthConfig
+			if err := json.Unmarshal(data, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			return part.FunctionCall, &cfg
+		}
+	}
+	return nil, nil
+}
+
+func lastText(events []*session.Event) string {
+	for i := len(events) - 1; i >= 0; i-- {
+		if c := events[i].Content; c != nil && len(c.Parts) > 0 && c.Parts[0].Text != "" {
+			return c.Parts[0].Text
+		}
+	}
+	return ""
+}
@@ -26,6 +26,8 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/internal/agent/checkpoint"
 	"github.com/sjzsdu/adk-go/internal/agent/parentmap"
 	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
 	icontext "github.com/sjzsdu/adk-go/internal/context"
@@ -59,7 +61,7 @@ type Flow struct {
 
 	Tools                 []tool.Tool
 	RequestProcessors     []func(ctx agent.InvocationContext, req *model.LLMRequest, f *Flow) iter.Seq2[*session.Event, error]
-	ResponseProcessors    []func(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) error
+	ResponseProcessors    []func(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) iter.Seq2[*session.Event, error]
 	BeforeModelCallbacks  []BeforeModelCallback
 	AfterModelCallbacks   []AfterModelCallback
 	OnModelErrorCallbacks []OnModelErrorCallback
@@ -87,7 +89,7 @@ var (
 		AgentTransferRequestProcessor,
 		removeDisplayNameIfExists,
 	}
-	DefaultResponseProcessors = []func(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) error{
+	DefaultResponseProcessors = []func(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) iter.Seq2[*session.Event, error]{
 		nlPlanningResponseProcessor,
 		codeExecutionResponseProcessor,
 	}
@@ -95,10 +97,40 @@ var (
 
 func (f *Flow) Run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
 	return func(yield func(*session.Event, error) bool) {
+		// A resumed invocation continues with the agent this agent transferred
+		// to before the invocation paused.
+		var state flowState
+		resumed, err := checkpoint.FromContext(ctx).Resume(ctx.Agent().Name(), &state)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if resumed && state.TransferTo != "" {
+			nextAgent := f.agentToRun(ctx, state.TransferTo)
+			if nextAgent == nil {
+				yield(nil, fmt.Errorf("failed to find agent: %s", state.TransferTo))
+				return
+			}
+			f.runTransferredAgent(ctx, nextAgent, yield)
+			return
+		}
 		for {
+			if limitEvent, err := runconfig.LimitExceeded(ctx, nil); err != nil {
+				if limitEvent == nil || yield(limitEvent, nil) {
+					yield(nil, err)
+				}
+				return
+			}
 			var lastEvent *session.Event
+			paused := false
 			for ev, err := range f.runOneStep(ctx) {
 				if err != nil {
+					// Errors caused by the invocation deadline end the run
+					// with the limit error.
+					limitEvent, err := runconfig.LimitExceeded(ctx, err)
+					if limitEvent != nil && !yield(limitEvent, nil) {
+						return
+					}
 					yield(nil, err)
 					return
 				}
@@ -107,6 +139,14 @@ func (f *Flow) Run(ctx agent.InvocationContext) iter.Seq2[*session.Event, error]
 					return
 				}
 				lastEvent = ev
+				if checkpoint.ShouldPause(ctx, ev) {
+					paused = true
+				}
+			}
+			if paused {
+				// The invocation is resumed once the long-running function
+				// calls have their responses.
+				return
 			}
 			if lastEvent == nil || lastEvent.IsFinalResponse() {
 				return
@@ -155,9 +195,14 @@ func (f *Flow) runOneStep(ctx agent.InvocationContext) iter.Seq2[*session.Event,
 				yield(nil, err)
 				return
 			}
-			if err := f.postprocess(ctx, req, resp); err != nil {
-				yield(nil, err)
-				return
+			for ev, err := range f.postprocess(ctx, req, resp) {
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if !yield(ev, nil) {
+					return
+				}
 			}
 			// Skip the model response event if there is no content and no error code.
 			// This is needed for the code executor to trigger another loop according to
@@ -178,13 +223,19 @@ func (f *Flow) runOneStep(ctx agent.InvocationContext) iter.Seq2[*session.Event,
 				tools[k] = tool
 			}
 
+			// The tool calls are counted before the function calls are
+			// yielded, so that a function call exceeding the limit is not
+			// left without response in the session.
+			if err := runconfig.LimitsFromContext(ctx).AddToolCalls(len(utils.FunctionCalls(resp.Content))); err != nil {
+				yield(nil, err)
+				return
+			}
+
 			// Build the event and yield.
 			modelResponseEvent := f.finalizeModelResponseEvent(ctx, resp, tools, stateDelta)
 			if !yield(modelResponseEvent, nil) {
 				return
 			}
-			// TODO: generate and yield an auth event if needed.
-
 			// Handle function calls.
 
 			ev, err := f.handleFunctionCalls(ctx, tools, resp, nil)
@@ -197,6 +248,13 @@ func (f *Flow) runOneStep(ctx agent.InvocationContext) iter.Seq2[*session.Event,
 				continue
 			}
 
+			authEvent := generateRequestCredentialEvent(ctx, modelResponseEvent, ev)
+			if authEvent != nil {
+				if !yield(authEvent, nil) {
+					return
+				}
+			}
+
 			toolConfirmationEvent := generateRequestConfirmationEvent(ctx, modelResponseEvent, ev)
 			if toolConfirmationEvent != nil {
 				if !yield(toolConfirmationEvent, nil) {
@@ -204,16 +262,18 @@ func (f *Flow) runOneStep(ctx agent.InvocationContext) iter.Seq2[*session.Event,
 				}
 			}
 
-			if !yield(ev, nil) {
-				return
-			}
-
-			// If the model response is structured, yield it as a final model response event.
-			outputSchemaResponse, err := retrieveStructuredModelResponse(ev)
+			// If the model response is structured, yield it as a final
+			// model response event after the function response. Invalid
+			// structured responses are replaced with the validation error
+			// before the function response is yielded.
+			outputSchemaResponse, err := structuredModelResponse(ctx, ev)
 			if err != nil {
 				yield(nil, err)
 				return
 			}
+			if !yield(ev, nil) {
+				return
+			}
 			if outputSchemaResponse != "" {
 				if !yield(createFinalModelResponseEvent(ctx, outputSchemaResponse), nil) {
 					return
@@ -232,15 +292,49 @@ func (f *Flow) runOneStep(ctx agent.InvocationContext) iter.Seq2[*session.Event,
 				yield(nil, fmt.Errorf("failed to find agent: %s", ev.Actions.TransferToAgent))
 				return
 			}
-			for ev, err := range nextAgent.Run(ctx) {
-				if !yield(ev, err) || err != nil { // forward
-					return
-				}
+			if err := runconfig.LimitsFromContext(ctx).AddAgentTransfer(); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !f.runTransferredAgent(ctx, nextAgent, yield) {
+				return
 			}
 		}
 	}
 }
 
+// flowState is the checkpoint of an LLM agent in a resumable invocation.
+type flowState struct {
+	// TransferTo is the name of the agent the agent transferred to.
+	TransferTo string `json:"transfer_to,omitempty"`
+}
+
+// runTransferredAgent runs the agent the current agent transferred to. In a
+// resumable invocation, the transfer is recorded so that a paused invocation
+// continues with the target agent. It reports whether the run may continue.
+func (f *Flow) runTransferredAgent(ctx agent.InvocationContext, nextAgent agent.Agent, yield func(*session.Event, error) bool) bool {
+	checkpoints := checkpoint.FromContext(ctx)
+	if checkpoints != nil {
+		ev, err := checkpoint.Event(ctx, flowState{TransferTo: nextAgent.Name()})
+		if !yield(ev, err) || err != nil {
+			return false
+		}
+	}
+	paused := false
+	for ev, err := range nextAgent.Run(ctx) {
+		if !yield(ev, err) || err != nil { // forward
+			return false
+		}
+		if checkpoint.ShouldPause(ctx, ev) {
+			paused = true
+		}
+	}
+	if checkpoints != nil && !paused {
+		return yield(checkpoint.EndEvent(ctx), nil)
+	}
+	return true
+}
+
 func (f *Flow) preprocess(ctx agent.InvocationContext, req *model.LLMRequest) iter.Seq2[*session.Event, error] {
 	return func(yield func(*session.Event, error) bool) {
 		// apply request processor functions to the request in the configured order.
@@ -251,7 +345,9 @@ func (f *Flow) preprocess(ctx agent.InvocationContext, req *model.LLMRequest) it
 					return
 				}
 				if ev != nil {
-					yield(ev, nil)
+					if !yield(ev, nil) {
+						return
+					}
 				}
 			}
 		}
@@ -304,6 +400,11 @@ func (f *Flow) callLLM(ctx agent.InvocationContext, req *model.LLMRequest, state
 			}
 		}
 
+		if err := runconfig.LimitsFromContext(ctx).AddLLMCall(); err != nil {
+			yield(nil, err)
+			return
+		}
+
 		// TODO: Set _ADK_AGENT_NAME_LABEL_KEY in req.GenerateConfig.Labels
 		// to help with slicing the billing reports on a per-agent basis.
 
@@ -446,14 +547,23 @@ func (f *Flow) runOnModelErrorCallbacks(ctx agent.InvocationContext, llmReq *mod
 	return nil, nil
 }
 
-func (f *Flow) postprocess(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) error {
-	// apply response processor functions to the response in the configured order.
-	for _, processor := range f.ResponseProcessors {
-		if err := processor(ctx, req, resp); err != nil {
-			return err
+func (f *Flow) postprocess(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		// apply response processor functions to the response in the configured order.
+		for _, processor := range f.ResponseProcessors {
+			for ev, err := range processor(ctx, req, resp) {
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+				if ev != nil {
+					if !yield(ev, nil) {
+						return
+					}
+				}
+			}
 		}
 	}
-	return nil
 }
 
 func (f *Flow) agentToRun(ctx agent.InvocationContext, agentName string) agent.Agent {
@@ -532,6 +642,7 @@ Suggested fixes:
 }
 
 // handleFunctionCalls calls the functions and returns the function response event.
+// The caller counts the calls against the tool call limit.
 //
 // TODO: accept filters to include/exclude function calls.
 // TODO: check feasibility of running tool.Run concurrently.
@@ -789,6 +900,12 @@ func mergeEventActions(base, other *session.EventActions) *session.EventActions
 		}
 		maps.Copy(base.RequestedToolConfirmations, other.RequestedToolConfirmations)
 	}
+	if other.RequestedAuthConfigs != nil {
+		if base.RequestedAuthConfigs == nil {
+			base.RequestedAuthConfigs = make(map[string]*auth.AuthConfig)
+		}
+		maps.Copy(base.RequestedAuthConfigs, other.RequestedAuthConfigs)
+	}
 	return base
 }
 
@@ -45,7 +45,7 @@ func basicRequestProcessor(ctx agent.InvocationContext, req *model.LLMRequest, f
 
 		// Set OutputSchema directly if no tools are present or native combo support exists.
 		// Otherwise, OutputSchemaRequestProcessor will be used to provide a tool-based workaround.
-		if state.OutputSchema != nil && !needOutputSchemaProcessor(state) {
+		if state.OutputSchema != nil && !needOutputSchemaProcessor(ctx, state) {
 			req.Config.ResponseSchema = state.OutputSchema
 			req.Config.ResponseMIMEType = "application/json"
 		}
@@ -0,0 +1,293 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llminternal
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/codeexecutor"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// codeExecutionErrorCountKey tracks the number of consecutive failed code
+// executions within an invocation.
+const codeExecutionErrorCountKey = session.KeyPrefixTemp + "_adk_code_execution_error_count"
+
+// codeExecutionRequestProcessor renders executable code and code execution
+// results of previous turns as text, so that any model can read them.
+func codeExecutionRequestProcessor(ctx agent.InvocationContext, req *model.LLMRequest, f *Flow) iter.Seq2[*session.Event, error] {
+	// reference: adk-python src/google/adk/flows/llm_flows/_code_execution.py
+	return func(yield func(*session.Event, error) bool) {
+		executor := agentCodeExecutor(ctx)
+		if executor == nil {
+			return
+		}
+		options := executor.Options()
+		for _, content := range req.Contents {
+			convertCodeExecutionParts(content, options)
+		}
+	}
+}
+
+// codeExecutionResponseProcessor extracts a code block from the model
+// response and executes it. It yields an event with the code and an event
+// with the execution result, and clears the response content so that the
+// flow calls the model again with the result.
+func codeExecutionResponseProcessor(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		executor := agentCodeExecutor(ctx)
+		if executor == nil || resp == nil || resp.Partial || resp.Content == nil {
+			return
+		}
+		options := executor.Options()
+
+		errorCount := codeExecutionErrorCount(ctx)
+		if errorCount >= options.ErrorRetryAttempts {
+			return
+		}
+
+		input := extractCodeAndTruncateContent(resp.Content, options.CodeBlockDelimiters)
+		if input == nil {
+			return
+		}
+
+		codeEvent := session.NewEvent(ctx.InvocationID())
+		codeEvent.Author = ctx.Agent().Name()
+		codeEvent.Branch = ctx.Branch()
+		codeEvent.LLMResponse = model.LLMResponse{Content: resp.Content}
+		if !yield(codeEvent, nil) {
+			return
+		}
+
+		result, err := executor.ExecuteCode(ctx, input)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to execute code: %w", err))
+			return
+		}
+
+		resultEvent := session.NewEvent(ctx.InvocationID())
+		resultEvent.Author = ctx.Agent().Name()
+		resultEvent.Branch = ctx.Branch()
+		resultEvent.LLMResponse = model.LLMResponse{
+			Content: &genai.Content{
+				Role:  genai.RoleModel,
+				Parts: []*genai.Part{buildCodeExecutionResultPart(result)},
+			},
+		}
+		if result.Stderr != "" {
+			errorCount++
+		} else {
+			errorCount = 0
+		}
+		resultEvent.Actions.StateDelta[codeExecutionErrorCountKey] = errorCount
+
+		if len(result.OutputFiles) > 0 {
+			if ctx.Artifacts() == nil {
+				yield(nil, fmt.Errorf("code execution produced output files, but artifact service is not configured"))
+				return
+			}
+			resultEvent.Actions.ArtifactDelta = make(map[string]int64)
+			for _, file := range result.OutputFiles {
+				saveResp, err := ctx.Artifacts().Save(ctx, file.Name, genai.NewPartFromBytes(file.Content, file.MIMEType))
+				if err != nil {
+					yield(nil, fmt.Errorf("failed to save output file %q: %w", file.Name, err))
+					return
+				}
+				resultEvent.Actions.ArtifactDelta[file.Name] = saveResp.Version
+			}
+		}
+
+		if !yield(resultEvent, nil) {
+			return
+		}
+		// The code and the result are already yielded, skip the model
+		// response event and let the flow call the model again.
+		resp.Content = nil
+	}
+}
+
+func agentCodeExecutor(ctx agent.InvocationContext) codeexecutor.CodeExecutor {
+	llmAgent := asLLMAgent(ctx.Agent())
+	if llmAgent == nil {
+		return nil
+	}
+	return llmAgent.internal().CodeExecutor
+}
+
+func codeExecutionErrorCount(ctx agent.InvocationContext) int {
+	val, err := ctx.Session().State().Get(codeExecutionErrorCountKey)
+	if err != nil {
+		return 0
+	}
+	switch v := val.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// extractCodeAndTruncateContent returns the first code to execute from the
+// content and truncates the content to end with that code as an executable
+// code part. It returns nil if the content has no code to execute.
+func extractCodeAndTruncateContent(content *genai.Content, delimiters []codeexecutor.CodeBlockDelimiter) *codeexecutor.Input {
+	// Executable code parts without a following result come from models
+	// with built-in code execution support, which only generate Python.
+	// Code of other or unspecified languages is not executed.
+	for i, part := range content.Parts {
+		if part == nil || part.ExecutableCode == nil {
+			continue
+		}
+		if i == len(content.Parts)-1 || content.Parts[i+1] == nil || content.Parts[i+1].CodeExecutionResult == nil {
+			if part.ExecutableCode.Language != genai.LanguagePython {
+				return nil
+			}
+			content.Parts = content.Parts[:i+1]
+			return &codeexecutor.Input{
+				Code:     part.ExecutableCode.Code,
+				Language: codeexecutor.LanguagePython,
+			}
+		}
+	}
+
+	var texts []string
+	for _, part := range content.Parts {
+		if part != nil && part.Text != "" && !part.Thought {
+			texts = append(texts, part.Text)
+		}
+	}
+	if len(texts) == 0 {
+		return nil
+	}
+	text := strings.Join(texts, "\n")
+
+	// Find the code block that starts first.
+	start, end := -1, -1
+	var found codeexecutor.CodeBlockDelimiter
+	for _, d := range delimiters {
+		i := strings.Index(text, d.Start)
+		if i < 0 || (start >= 0 && i >= start) {
+			continue
+		}
+		j := strings.Index(text[i+len(d.Start):], d.End)
+		if j < 0 {
+			continue
+		}
+		start, end, found = i, i+len(d.Start)+j, d
+	}
+	if start < 0 {
+		return nil
+	}
+	code := text[start+len(found.Start) : end]
+	if code == "" {
+		return nil
+	}
+
+	// Only Python code is stored as an executable code part, as genai has no
+	// other language. Code blocks of other languages are kept as text, the
+	// executor gets their language from the input.
+	var parts []*genai.Part
+	if found.Language != codeexecutor.LanguagePython {
+		parts = append(parts, genai.NewPartFromText(text[:end+len(found.End)]))
+	} else {
+		if prefix := text[:start]; prefix != "" {
+			parts = append(parts, genai.NewPartFromText(prefix))
+		}
+		parts = append(parts, &genai.Part{
+			ExecutableCode: &genai.ExecutableCode{
+				Code:     code,
+				Language: genai.LanguagePython,
+			},
+		})
+	}
+	content.Parts = parts
+	return &codeexecutor.Input{
+		Code:     code,
+		Language: found.Language,
+	}
+}
+
+// convertCodeExecutionParts converts trailing executable code and code
+// execution result parts to text using the executor's delimiters.
+func convertCodeExecutionParts(content *genai.Content, options codeexecutor.Options) {
+	if content == nil || len(content.Parts) == 0 {
+		return
+	}
+	last := content.Parts[len(content.Parts)-1]
+	switch {
+	case last.ExecutableCode != nil:
+		if last.ExecutableCode.Language != genai.LanguagePython {
+			return
+		}
+		d, ok := codeBlockDelimiterFor(codeexecutor.LanguagePython, options.CodeBlockDelimiters)
+		if !ok {
+			return
+		}
+		content.Parts[len(content.Parts)-1] = genai.NewPartFromText(d.Start + last.ExecutableCode.Code + d.End)
+	// Content with multiple parts is likely generated by the model, only
+	// results sent on their own are converted.
+	case len(content.Parts) == 1 && last.CodeExecutionResult != nil:
+		d := options.ExecutionResultDelimiter
+		content.Parts[0] = genai.NewPartFromText(d.Start + last.CodeExecutionResult.Output + d.End)
+		content.Role = genai.RoleUser
+	}
+}
+
+func codeBlockDelimiterFor(lang codeexecutor.Language, delimiters []codeexecutor.CodeBlockDelimiter) (codeexecutor.CodeBlockDelimiter, bool) {
+	for _, d := range delimiters {
+		if d.Language == lang {
+			return d, true
+		}
+	}
+	return codeexecutor.CodeBlockDelimiter{}, false
+}
+
+// buildCodeExecutionResultPart renders the execution result as a code
+// execution result part.
+func buildCodeExecutionResultPart(result *codeexecutor.Result) *genai.Part {
+	if result.Stderr != "" {
+		return &genai.Part{
+			CodeExecutionResult: &genai.CodeExecutionResult{
+				Outcome: genai.OutcomeFailed,
+				Output:  result.Stderr,
+			},
+		}
+	}
+	var sections []string
+	if result.Stdout != "" || len(result.OutputFiles) == 0 {
+		sections = append(sections, "Code execution result:\n"+result.Stdout+"\n")
+	}
+	if len(result.OutputFiles) > 0 {
+		names := make([]string, 0, len(result.OutputFiles))
+		for _, f := range result.OutputFiles {
+			names = append(names, "`"+f.Name+"`")
+		}
+		sections = append(sections, "Saved artifacts:\n"+strings.Join(names, ","))
+	}
+	return &genai.Part{
+		CodeExecutionResult: &genai.CodeExecutionResult{
+			Outcome: genai.OutcomeOK,
+			Output:  strings.Join(sections, "\n\n"),
+		},
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llminternal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/codeexecutor"
+)
+
+func TestExtractCodeAndTruncateContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     *genai.Content
+		want        *codeexecutor.Input
+		wantContent *genai.Content
+	}{
+		{
+			name:    "shell code block",
+			content: genai.NewContentFromText("Listing:\n```sh\nls\n```\nDone.", genai.RoleModel),
+			want:    &codeexecutor.Input{Code: "ls", Language: codeexecutor.LanguageShell},
+			wantContent: genai.NewContentFromText("Listing:\n```sh\nls\n```", genai.RoleModel),
+		},
+		{
+			name:    "python code block",
+			content: genai.NewContentFromText("Computing:\n```python\nprint(1)\n```\nDone.", genai.RoleModel),
+			want:    &codeexecutor.Input{Code: "print(1)", Language: codeexecutor.LanguagePython},
+			wantContent: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				genai.NewPartFromText("Computing:\n"),
+				{ExecutableCode: &genai.ExecutableCode{Code: "print(1)", Language: genai.LanguagePython}},
+			}},
+		},
+		{
+			name: "python executable code",
+			content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "print(1)", Language: genai.LanguagePython}},
+				genai.NewPartFromText("trailing"),
+			}},
+			want: &codeexecutor.Input{Code: "print(1)", Language: codeexecutor.LanguagePython},
+			wantContent: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "print(1)", Language: genai.LanguagePython}},
+			}},
+		},
+		{
+			name: "unspecified language is not executed",
+			content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "rm -rf /", Language: genai.LanguageUnspecified}},
+			}},
+			wantContent: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "rm -rf /", Language: genai.LanguageUnspecified}},
+			}},
+		},
+		{
+			name: "unknown language is not executed",
+			content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "ls", Language: "BASH"}},
+			}},
+			wantContent: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				{ExecutableCode: &genai.ExecutableCode{Code: "ls", Language: "BASH"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractCodeAndTruncateContent(tt.content, codeexecutor.DefaultOptions().CodeBlockDelimiters)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("extractCodeAndTruncateContent() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantContent, tt.content); diff != "" {
+				t.Errorf("extractCodeAndTruncateContent() content mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertCodeExecutionParts(t *testing.T) {
+	content := &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+		{ExecutableCode: &genai.ExecutableCode{Code: "x", Language: genai.LanguagePython}},
+	}}
+	convertCodeExecutionParts(content, codeexecutor.DefaultOptions())
+	if diff := cmp.Diff([]*genai.Part{genai.NewPartFromText("```tool_code\nx\n```")}, content.Parts); diff != "" {
+		t.Errorf("convertCodeExecutionParts() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Code of other languages is left as is, not rendered as Python.
+	for _, language := range []genai.Language{genai.LanguageUnspecified, "BASH"} {
+		part := &genai.Part{ExecutableCode: &genai.ExecutableCode{Code: "x", Language: language}}
+		content := &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{part}}
+		convertCodeExecutionParts(content, codeexecutor.DefaultOptions())
+		if content.Parts[0] != part {
+			t.Errorf("convertCodeExecutionParts() converted code of language %q: %v", language, content.Parts[0])
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llminternal
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/compaction"
+	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
+	"github.com/sjzsdu/adk-go/internal/utils"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// compactionSummaryHeader precedes the summary in the compacted content.
+const compactionSummaryHeader = "Summary of the earlier conversation:\n"
+
+// applyCompactions replaces the events covered by compaction events with the
+// compacted content. Newer compactions take precedence over the older ones
+// they overlap, since they summarize them already.
+//
+// The returned summary events keep the compaction in their actions, so that a
+// later compaction can cover them.
+func applyCompactions(events []*session.Event) []*session.Event {
+	index := make(map[string]int, len(events))
+	for i, ev := range events {
+		index[ev.ID] = i
+	}
+	type span struct {
+		start, end int
+		event      *session.Event
+	}
+	var spans []span
+	for i := len(events) - 1; i >= 0; i-- {
+		c := events[i].Actions.Compaction
+		if c == nil || c.CompactedContent == nil {
+			continue
+		}
+		start, ok := index[c.StartEventID]
+		if !ok {
+			continue
+		}
+		end, ok := index[c.EndEventID]
+		if !ok || start > end || end >= i {
+			continue
+		}
+		overlaps := false
+		for _, s := range spans {
+			if start <= s.end && s.start <= end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			spans = append(spans, span{start: start, end: end, event: events[i]})
+		}
+	}
+	if len(spans) == 0 {
+		return events
+	}
+
+	summaries := make(map[int]*session.Event, len(spans))
+	covered := make([]bool, len(events))
+	for _, s := range spans {
+		summaries[s.start] = s.event
+		for i := s.start; i <= s.end; i++ {
+			// Events of other branches were not visible to the agent that
+			// compacted the history.
+			if eventBelongsToBranch(s.event.Branch, events[i]) {
+				covered[i] = true
+			}
+		}
+	}
+	result := make([]*session.Event, 0, len(events))
+	for i, ev := range events {
+		if c, ok := summaries[i]; ok {
+			result = append(result, &session.Event{ // made-up event. Don't go through types.NewEvent.
+				ID:           c.ID,
+				Timestamp:    c.Timestamp,
+				InvocationID: c.InvocationID,
+				Branch:       c.Branch,
+				Author:       "user",
+				LLMResponse:  model.LLMResponse{Content: c.Actions.Compaction.CompactedContent},
+				Actions:      session.EventActions{Compaction: c.Actions.Compaction},
+			})
+		}
+		if !covered[i] {
+			result = append(result, ev)
+		}
+	}
+	return result
+}
+
+// compactHistory summarizes the older events visible to the agent so that the
+// most recent events fit cfg.RetainTokens, and returns the compaction event.
+// It returns nil if there is nothing to compact.
+//
+// The events must have compactions applied already.
+func compactHistory(ctx agent.InvocationContext, cfg *compaction.Config, events []*session.Event) (*session.Event, error) {
+	var visible []*session.Event
+	for _, ev := range events {
+		content := utils.Content(ev)
+		if content == nil || content.Role == "" || len(content.Parts) == 0 {
+			continue
+		}
+		if !eventBelongsToBranch(ctx.Branch(), ev) || isAuthEvent(ev) {
+			continue
+		}
+		visible = append(visible, ev)
+	}
+	if len(visible) < 2 {
+		return nil, nil
+	}
+
+	estimate := cfg.TokenEstimator
+	if estimate == nil {
+		estimate = compaction.EstimateTokens
+	}
+	retain := cfg.RetainTokens
+	if retain == 0 {
+		retain = cfg.TokenBudget / 2
+	}
+	// The latest event is always retained.
+	split := len(visible) - 1
+	tokens := estimate([]*genai.Content{utils.Content(visible[split])})
+	for split > 0 {
+		n := estimate([]*genai.Content{utils.Content(visible[split-1])})
+		if tokens+n > retain {
+			break
+		}
+		tokens += n
+		split--
+	}
+	split = keepFunctionCallsWithResponses(visible, split)
+	if split == 0 || (split == 1 && visible[0].Actions.Compaction != nil) {
+		// Nothing new to compact.
+		return nil, nil
+	}
+
+	compacted := visible[:split]
+	summary, err := summarize(ctx, cfg, compacted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact the history of agent %q: %w", ctx.Agent().Name(), err)
+	}
+	first, last := compacted[0], compacted[len(compacted)-1]
+	startID, endID := first.ID, last.ID
+	if c := first.Actions.Compaction; c != nil {
+		startID = c.StartEventID
+	}
+	if c := last.Actions.Compaction; c != nil {
+		endID = c.EndEventID
+	}
+
+	event := session.NewEvent(ctx.InvocationID())
+	event.Author = ctx.Agent().Name()
+	event.Branch = ctx.Branch()
+	event.Actions.Compaction = &session.EventCompaction{
+		StartEventID:     startID,
+		EndEventID:       endID,
+		CompactedContent: genai.NewContentFromText(compactionSummaryHeader+summary, genai.RoleUser),
+	}
+	return event, nil
+}
+
+// keepFunctionCallsWithResponses moves the split point back so that no
+// function call is separated from its response.
+func keepFunctionCallsWithResponses(events []*session.Event, split int) int {
+	for {
+		callIndex := make(map[string]int)
+		for i, ev := range events[:split] {
+			for _, call := range utils.FunctionCalls(utils.Content(ev)) {
+				callIndex[call.ID] = i
+			}
+		}
+		moved := split
+		for _, ev := range events[split:] {
+			for _, resp := range utils.FunctionResponses(utils.Content(ev)) {
+				if i, ok := callIndex[resp.ID]; ok && i < moved {
+					moved = i
+				}
+			}
+		}
+		if moved == split {
+			return split
+		}
+		split = moved
+	}
+}
+
+// summarize asks the compaction model to summarize the events.
+func summarize(ctx agent.InvocationContext, cfg *compaction.Config, events []*session.Event) (string, error) {
+	if err := runconfig.LimitsFromContext(ctx).AddLLMCall(); err != nil {
+		return "", err
+	}
+	instruction := cfg.Instruction
+	if instruction == "" {
+		instruction = compaction.DefaultInstruction
+	}
+	req := &model.LLMRequest{
+		Model: cfg.Model.Name(),
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(instruction, genai.RoleUser),
+		},
+		Contents: []*genai.Content{genai.NewContentFromText(transcript(events), genai.RoleUser)},
+	}
+	var summary string
+	for resp, err := range cfg.Model.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", err
+		}
+		if resp.ErrorCode != "" {
+			return "", fmt.Errorf("model error %s: %s", resp.ErrorCode, resp.ErrorMessage)
+		}
+		if resp.Partial || resp.Content == nil {
+			continue
+		}
+		var sb strings.Builder
+		for _, part := range resp.Content.Parts {
+			if part.Text != "" && !part.Thought {
+				sb.WriteString(part.Text)
+			}
+		}
+		summary = sb.String()
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("model %q returned an empty summary", cfg.Model.Name())
+	}
+	return summary, nil
+}
+
+// transcript renders the events as text for the summarization request.
+func transcript(events []*session.Event) string {
+	var sb strings.Builder
+	for _, ev := range events {
+		content := utils.Content(ev)
+		if ev.Actions.Compaction != nil {
+			for _, part := range content.Parts {
+				sb.WriteString(part.Text)
+				sb.WriteString("\n")
+			}
+			continue
+		}
+		for _, part := range content.Parts {
+			switch {
+			case part.Thought:
+			case part.Text != "":
+				fmt.Fprintf(&sb, "[%s]: %s\n", ev.Author, part.Text)
+			case part.FunctionCall != nil:
+				fmt.Fprintf(&sb, "[%s] called tool %q with parameters: %s\n", ev.Author, part.FunctionCall.Name, stringify(part.FunctionCall.Args))
+			case part.FunctionResponse != nil:
+				fmt.Fprintf(&sb, "[%s] %q tool returned result: %s\n", ev.Author, part.FunctionResponse.Name, stringify(part.FunctionResponse.Response))
+			case part.ExecutableCode != nil:
+				fmt.Fprintf(&sb, "[%s] executed code:\n%s\n", ev.Author, part.ExecutableCode.Code)
+			case part.CodeExecutionResult != nil:
+				fmt.Fprintf(&sb, "[%s] code execution result: %s\n", ev.Author, part.CodeExecutionResult.Output)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// estimateRequestTokens estimates the tokens of the system instruction and
+// the contents of the request.
+func estimateRequestTokens(cfg *compaction.Config, req *model.LLMRequest, contents []*genai.Content) int {
+	estimate := cfg.TokenEstimator
+	if estimate == nil {
+		estimate = compaction.EstimateTokens
+	}
+	all := contents
+	if req.Config != nil && req.Config.SystemInstruction != nil {
+		all = append([]*genai.Content{req.Config.SystemInstruction}, contents...)
+	}
+	return estimate(all)
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llminternal_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent/llmagent"
+	"github.com/sjzsdu/adk-go/compaction"
+	icontext "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/internal/llminternal"
+	"github.com/sjzsdu/adk-go/internal/testutil"
+	"github.com/sjzsdu/adk-go/internal/utils"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// countParts estimates one token per part, to make the tests independent of
+// the text lengths.
+func countParts(contents []*genai.Content) int {
+	n := 0
+	for _, c := range contents {
+		n += len(c.Parts)
+	}
+	return n
+}
+
+func TestContentsRequestProcessor_Compaction(t *testing.T) {
+	const agentName = "testAgent"
+	newEvent := func(id, author string, content *genai.Content) *session.Event {
+		return &session.Event{ID: id, Author: author, LLMResponse: model.LLMResponse{Content: content}}
+	}
+	call := &genai.Content{Role: "model", Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "c1", Name: "get_weather"}}}}
+	response := &genai.Content{Role: "user", Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{ID: "c1", Name: "get_weather", Response: map[string]any{"weather": "sunny"}}}}}
+	events := []*session.Event{
+		newEvent("e1", "user", genai.NewContentFromText("question one", "user")),
+		newEvent("e2", agentName, call),
+		newEvent("e3", agentName, response),
+		newEvent("e4", agentName, genai.NewContentFromText("answer one", "model")),
+		newEvent("e5", "user", genai.NewContentFromText("question two", "user")),
+	}
+
+	summarizer := &testutil.MockModel{Responses: []*genai.Content{
+		genai.NewContentFromText("first summary", "model"),
+		genai.NewContentFromText("second summary", "model"),
+	}}
+	testAgent := utils.Must(llmagent.New(llmagent.Config{
+		Name:  agentName,
+		Model: &testModel{},
+		Compaction: &compaction.Config{
+			Model:          summarizer,
+			TokenBudget:    4,
+			RetainTokens:   3,
+			TokenEstimator: countParts,
+		},
+	}))
+	run := func(t *testing.T, events []*session.Event) ([]*genai.Content, []*session.Event) {
+		t.Helper()
+		ctx := icontext.NewInvocationContext(t.Context(), icontext.InvocationContextParams{
+			Agent:   testAgent,
+			Session: &fakeSession{events: events},
+		})
+		req := &model.LLMRequest{}
+		var emitted []*session.Event
+		for ev, err := range llminternal.ContentsRequestProcessor(ctx, req, &llminternal.Flow{}) {
+			if err != nil {
+				t.Fatalf("ContentsRequestProcessor failed: %v", err)
+			}
+			emitted = append(emitted, ev)
+		}
+		return req.Contents, emitted
+	}
+
+	// The retained events would separate the function call from its
+	// response, so only the first event is compacted.
+	contents, emitted := run(t, events)
+	if len(emitted) != 1 || emitted[0].Actions.Compaction == nil {
+		t.Fatalf("got events %v, want one compaction event", emitted)
+	}
+	compactionEvent := emitted[0]
+	compactionEvent.ID = "c-1"
+	if diff := cmp.Diff(&session.EventCompaction{
+		StartEventID:     "e1",
+		EndEventID:       "e1",
+		CompactedContent: genai.NewContentFromText("Summary of the earlier conversation:\nfirst summary", "user"),
+	}, compactionEvent.Actions.Compaction); diff != "" {
+		t.Errorf("compaction mismatch (-want +got):\n%s", diff)
+	}
+	if got, want := summarizer.Requests[0].Contents[0].Parts[0].Text, "[user]: question one\n"; got != want {
+		t.Errorf("summarization transcript = %q, want %q", got, want)
+	}
+	if got := summarizer.Requests[0].Config.SystemInstruction.Parts[0].Text; got != compaction.DefaultInstruction {
+		t.Errorf("summarization instruction = %q, want the default", got)
+	}
+	want := []*genai.Content{
+		genai.NewContentFromText("Summary of the earlier conversation:\nfirst summary", "user"),
+		call,
+		response,
+		genai.NewContentFromText("answer one", "model"),
+		genai.NewContentFromText("question two", "user"),
+	}
+	if diff := cmp.Diff(want, contents); diff != "" {
+		t.Errorf("contents mismatch (-want +got):\n%s", diff)
+	}
+
+	// The stored compaction event replaces the events it covers. Once the
+	// budget is exceeded again, the summary is compacted together with the
+	// following events.
+	events = append(events,
+		compactionEvent,
+		newEvent("e6", agentName, genai.NewContentFromText("answer two", "model")),
+		newEvent("e7", "user", genai.NewContentFromText("question three", "user")),
+	)
+	contents, emitted = run(t, events)
+	if len(emitted) != 1 || emitted[0].Actions.Compaction == nil {
+		t.Fatalf("got events %v, want one compaction event", emitted)
+	}
+	if got, want := [2]string{emitted[0].Actions.Compaction.StartEventID, emitted[0].Actions.Compaction.EndEventID}, [2]string{"e1", "e4"}; got != want {
+		t.Errorf("compacted range = %v, want %v", got, want)
+	}
+	wantTranscript := "Summary of the earlier conversation:\nfirst summary\n" +
+		"[testAgent] called tool \"get_weather\" with parameters: null\n" +
+		"[testAgent] \"get_weather\" tool returned result: {\"weather\":\"sunny\"}\n" +
+		"[testAgent]: answer one\n"
+	if diff := cmp.Diff(wantTranscript, summarizer.Requests[1].Contents[0].Parts[0].Text); diff != "" {
+		t.Errorf("summarization transcript mismatch (-want +got):\n%s", diff)
+	}
+	want = []*genai.Content{
+		genai.NewContentFromText("Summary of the earlier conversation:\nsecond summary", "user"),
+		genai.NewContentFromText("question two", "user"),
+		genai.NewContentFromText("answer two", "model"),
+		genai.NewContentFromText("question three", "user"),
+	}
+	if diff := cmp.Diff(want, contents); diff != "" {
+		t.Errorf("contents mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContentsRequestProcessor_CompactionWithinBudget(t *testing.T) {
+	summarizer := &testutil.MockModel{}
+	testAgent := utils.Must(llmagent.New(llmagent.Config{
+		Name:  "testAgent",
+		Model: &testModel{},
+		Compaction: &compaction.Config{
+			Model:       summarizer,
+			TokenBudget: 1000,
+		},
+	}))
+	ctx := icontext.NewInvocationContext(t.Context(), icontext.InvocationContextParams{
+		Agent: testAgent,
+		Session: &fakeSession{events: []*session.Event{
+			{ID: "e1", Author: "user", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("hello", "user")}},
+			{ID: "e2", Author: "testAgent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("hi", "model")}},
+		}},
+	})
+	req := &model.LLMRequest{}
+	for ev, err := range llminternal.ContentsRequestProcessor(ctx, req, &llminternal.Flow{}) {
+		if err != nil || ev != nil {
+			t.Fatalf("ContentsRequestProcessor yielded %v, %v, want nothing", ev, err)
+		}
+	}
+	if len(req.Contents) != 2 {
+		t.Errorf("got %d contents, want 2", len(req.Contents))
+	}
+	if len(summarizer.Requests) != 0 {
+		t.Errorf("got %d summarization requests, want 0", len(summarizer.Requests))
+	}
+}
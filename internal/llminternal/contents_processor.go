@@ -26,6 +26,9 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/compaction"
+	icontext "github.com/sjzsdu/adk-go/internal/context"
 	"github.com/sjzsdu/adk-go/internal/utils"
 	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/session"
@@ -41,22 +44,64 @@ func ContentsRequestProcessor(ctx agent.InvocationContext, req *model.LLMRequest
 			// Do nothing.
 			return // In python, no error is yielded.
 		}
+		state := llmAgent.internal()
 		fn := buildContentsDefault // "" or "default".
-		if llmAgent.internal().IncludeContents == "none" {
+		compactionCfg := state.Compaction
+		switch {
+		case state.IncludeContentsProvider != nil:
+			fn = func(agentName, branch string, events []*session.Event) ([]*genai.Content, error) {
+				filtered, err := filterEvents(agentName, branch, events)
+				if err != nil {
+					return nil, err
+				}
+				return state.IncludeContentsProvider(icontext.NewReadonlyContext(ctx), filtered)
+			}
+			compactionCfg = nil
+		case state.IncludeContents == "none":
 			// Include current turn context only (no conversation history)
 			fn = buildContentsCurrentTurnContextOnly
+			compactionCfg = nil
+		case state.IncludeContents == "last_turns":
+			fn = func(agentName, branch string, events []*session.Event) ([]*genai.Content, error) {
+				return buildContentsLastTurns(agentName, branch, events, state.IncludeContentsTurns)
+			}
+			compactionCfg = nil
+		case state.IncludeContents == "token_budget":
+			fn = func(agentName, branch string, events []*session.Event) ([]*genai.Content, error) {
+				return buildContentsTokenBudget(agentName, branch, events, state.IncludeContentsMaxTokens)
+			}
+			compactionCfg = nil
 		}
-		var events []*session.Event
+		var rawEvents []*session.Event
 		if ctx.Session() != nil {
 			for e := range ctx.Session().Events().All() {
-				events = append(events, e)
+				rawEvents = append(rawEvents, e)
 			}
 		}
+		events := applyCompactions(rawEvents)
 		contents, err := fn(ctx.Agent().Name(), ctx.Branch(), events)
 		if err != nil {
 			yield(nil, err)
 			return
 		}
+		if compactionCfg != nil && estimateRequestTokens(compactionCfg, req, contents) > compactionCfg.TokenBudget {
+			compactionEvent, err := compactHistory(ctx, compactionCfg, events)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if compactionEvent != nil {
+				if !yield(compactionEvent, nil) {
+					return
+				}
+				events = applyCompactions(append(rawEvents, compactionEvent))
+				contents, err = fn(ctx.Agent().Name(), ctx.Branch(), events)
+				if err != nil {
+					yield(nil, err)
+					return
+				}
+			}
+		}
 		req.Contents = append(req.Contents, contents...)
 	}
 }
@@ -64,6 +109,68 @@ func ContentsRequestProcessor(ctx agent.InvocationContext, req *model.LLMRequest
 // buildContentsDefault returns the contents for the LLM request by applying
 // filtering, rearrangement, and content processing to the given events.
 func buildContentsDefault(agentName, invocationBranch string, events []*session.Event) ([]*genai.Content, error) {
+	filtered, err := filterEvents(agentName, invocationBranch, events)
+	if err != nil {
+		return nil, err
+	}
+	return eventsToContents(filtered), nil
+}
+
+// buildContentsLastTurns returns the contents of the last turns of the
+// conversation. A turn starts with a user message or with the reply of
+// another agent.
+func buildContentsLastTurns(agentName, invocationBranch string, events []*session.Event, turns int) ([]*genai.Content, error) {
+	filtered, err := filterEvents(agentName, invocationBranch, events)
+	if err != nil {
+		return nil, err
+	}
+	start := 0
+	for i := len(filtered) - 1; i >= 0; i-- {
+		ev := filtered[i]
+		// Replies of other agents are authored by the user after filtering.
+		if ev.Author != "user" || len(utils.FunctionResponses(ev.Content)) > 0 {
+			continue
+		}
+		turns--
+		if turns == 0 {
+			start = i
+			break
+		}
+	}
+	start = keepFunctionCallsWithResponses(filtered, start)
+	return eventsToContents(filtered[start:]), nil
+}
+
+// buildContentsTokenBudget returns the contents of the most recent events
+// that fit the token budget. The latest event is always included, and
+// function responses are never separated from their calls.
+func buildContentsTokenBudget(agentName, invocationBranch string, events []*session.Event, maxTokens int) ([]*genai.Content, error) {
+	filtered, err := filterEvents(agentName, invocationBranch, events)
+	if err != nil {
+		return nil, err
+	}
+	if len(filtered) == 0 {
+		return nil, nil
+	}
+	// eventsToContents drops empty contents, so count the tokens per event.
+	start := len(filtered) - 1
+	tokens := compaction.EstimateTokens(eventsToContents(filtered[start:]))
+	for start > 0 {
+		n := compaction.EstimateTokens(eventsToContents(filtered[start-1 : start]))
+		if tokens+n > maxTokens {
+			break
+		}
+		tokens += n
+		start--
+	}
+	start = keepFunctionCallsWithResponses(filtered, start)
+	return eventsToContents(filtered[start:]), nil
+}
+
+// filterEvents returns the events visible to the agent, with replies of other
+// agents converted to user messages and function responses placed right
+// after their calls.
+func filterEvents(agentName, invocationBranch string, events []*session.Event) ([]*session.Event, error) {
 	// parse the events, leaving the contents and the function calls and responses from the current agent.
 	var filtered []*session.Event
 	for _, ev := range events {
@@ -103,7 +210,11 @@ func buildContentsDefault(agentName, invocationBranch string, events []*session.
 	if err != nil {
 		return nil, err
 	}
+	return filtered, nil
+}
 
+// eventsToContents returns the contents of the filtered events.
+func eventsToContents(filtered []*session.Event) []*genai.Content {
 	var contents []*genai.Content
 	for _, ev := range filtered {
 		content := clone(utils.Content(ev))
@@ -122,7 +233,7 @@ func buildContentsDefault(agentName, invocationBranch string, events []*session.
 		utils.RemoveClientFunctionCallID(content)
 		contents = append(contents, content)
 	}
-	return contents, nil
+	return contents
 }
 
 func eventBelongsToBranch(invocationBranch string, event *session.Event) bool {
@@ -505,20 +616,16 @@ func stringify(v any) string {
 	return string(s)
 }
 
-// requestEUCFunctionCallName is a special function to handle credential
-// request.
-const requestEUCFunctionCallName = "adk_request_credential"
-
 func isAuthEvent(ev *session.Event) bool {
 	c := utils.Content(ev)
 	if c == nil {
 		return false
 	}
 	for _, p := range c.Parts {
-		if p.FunctionCall != nil && p.FunctionCall.Name == requestEUCFunctionCallName {
+		if p.FunctionCall != nil && p.FunctionCall.Name == auth.FunctionCallName {
 			return true
 		}
-		if p.FunctionResponse != nil && p.FunctionResponse.Name == requestEUCFunctionCallName {
+		if p.FunctionResponse != nil && p.FunctionResponse.Name == auth.FunctionCallName {
 			return true
 		}
 	}
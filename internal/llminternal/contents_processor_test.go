@@ -15,6 +15,7 @@
 package llminternal_test
 
 import (
+	"fmt"
 	"iter"
 	"slices"
 	"strings"
@@ -517,6 +518,153 @@ func TestConvertForeignEvent(t *testing.T) {
 	}
 }
 
+func TestContentsRequestProcessor_IncludeContentsModes(t *testing.T) {
+	const agentName = "testAgent"
+	newEvent := func(author string, content *genai.Content) *session.Event {
+		return &session.Event{Author: author, LLMResponse: model.LLMResponse{Content: content}}
+	}
+	call := &genai.Content{Role: "model", Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: "c1", Name: "f"}}}}
+	response := &genai.Content{Role: "user", Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{ID: "c1", Name: "f", Response: map[string]any{"r": "ok"}}}}}
+	foreign := &genai.Content{Role: "user", Parts: []*genai.Part{{Text: "For context:"}, {Text: "[otherAgent] said: o1"}}}
+	events := []*session.Event{
+		newEvent("user", genai.NewContentFromText("q1", "user")),
+		newEvent(agentName, call),
+		newEvent(agentName, response),
+		newEvent(agentName, genai.NewContentFromText("a1", "model")),
+		newEvent("otherAgent", genai.NewContentFromText("o1", "model")),
+		newEvent("user", genai.NewContentFromText("q2", "user")),
+		newEvent(agentName, genai.NewContentFromText("a2", "model")),
+		newEvent("user", genai.NewContentFromText("q3", "user")),
+	}
+	all := []*genai.Content{
+		genai.NewContentFromText("q1", "user"),
+		call,
+		response,
+		genai.NewContentFromText("a1", "model"),
+		foreign,
+		genai.NewContentFromText("q2", "user"),
+		genai.NewContentFromText("a2", "model"),
+		genai.NewContentFromText("q3", "user"),
+	}
+
+	testCases := []struct {
+		name    string
+		cfg     llmagent.Config
+		want    []*genai.Content
+		wantErr bool
+	}{
+		{
+			name: "last turn",
+			cfg:  llmagent.Config{IncludeContents: llmagent.IncludeContentsLastTurns, IncludeContentsTurns: 1},
+			want: all[7:],
+		},
+		{
+			name: "last two turns",
+			cfg:  llmagent.Config{IncludeContents: llmagent.IncludeContentsLastTurns, IncludeContentsTurns: 2},
+			want: all[5:],
+		},
+		{
+			name: "reply of another agent starts a turn",
+			cfg:  llmagent.Config{IncludeContents: llmagent.IncludeContentsLastTurns, IncludeContentsTurns: 3},
+			want: all[4:],
+		},
+		{
+			name: "more turns than the history",
+			cfg:  llmagent.Config{IncludeContents: llmagent.IncludeContentsLastTurns, IncludeContentsTurns: 10},
+			want: all,
+		},
+		{
+			name: "token budget",
+			cfg:  llmagent.Config{IncludeContents: llmagent.IncludeContentsTokenBudget, IncludeContentsMaxTokens: 3},
+			want: all[5:],
+		},
+		{
+			name: "token budget smaller than the latest event",
+			cfg:  llmagent.Config{IncludeContents: llmagent.IncludeContentsTokenBudget, IncludeContentsMaxTokens: 1},
+			want: all[7:],
+		},
+		{
+			name: "token budget keeps function calls with their responses",
+			cfg:  llmagent.Config{IncludeContents: llmagent.IncludeContentsTokenBudget, IncludeContentsMaxTokens: 16},
+			want: all[1:],
+		},
+		{
+			name: "provider",
+			cfg: llmagent.Config{
+				IncludeContents: llmagent.IncludeContentsNone, // ignored
+				IncludeContentsProvider: func(ctx agent.ReadonlyContext, events []*session.Event) ([]*genai.Content, error) {
+					if ctx.AgentName() != agentName {
+						return nil, fmt.Errorf("unexpected agent %q", ctx.AgentName())
+					}
+					var contents []*genai.Content
+					for _, ev := range events {
+						if ev.Author == "user" && ev.Content.Parts[0].FunctionResponse == nil {
+							contents = append(contents, ev.Content)
+						}
+					}
+					return contents, nil
+				},
+			},
+			want: []*genai.Content{all[0], all[4], all[5], all[7]},
+		},
+		{
+			name: "provider error",
+			cfg: llmagent.Config{
+				IncludeContentsProvider: func(agent.ReadonlyContext, []*session.Event) ([]*genai.Content, error) {
+					return nil, fmt.Errorf("provider failed")
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.Name = agentName
+			tc.cfg.Model = &testModel{}
+			testAgent := utils.Must(llmagent.New(tc.cfg))
+			ctx := icontext.NewInvocationContext(t.Context(), icontext.InvocationContextParams{
+				Agent:   testAgent,
+				Session: &fakeSession{events: events},
+			})
+			req := &model.LLMRequest{}
+			var gotErr error
+			for ev, err := range llminternal.ContentsRequestProcessor(ctx, req, &llminternal.Flow{}) {
+				if ev != nil {
+					t.Fatal("ContentsRequestProcessor generated an unexpected event")
+				}
+				gotErr = err
+			}
+			if (gotErr != nil) != tc.wantErr {
+				t.Fatalf("ContentsRequestProcessor error = %v, wantErr %v", gotErr, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, req.Contents); diff != "" {
+				t.Errorf("LLMRequest contents mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIncludeContentsConfig(t *testing.T) {
+	testCases := []struct {
+		name    string
+		cfg     llmagent.Config
+		wantErr bool
+	}{
+		{name: "last turns without turns", cfg: llmagent.Config{IncludeContents: llmagent.IncludeContentsLastTurns}, wantErr: true},
+		{name: "token budget without budget", cfg: llmagent.Config{IncludeContents: llmagent.IncludeContentsTokenBudget}, wantErr: true},
+		{name: "unknown mode", cfg: llmagent.Config{IncludeContents: "everything"}, wantErr: true},
+		{name: "last turns", cfg: llmagent.Config{IncludeContents: llmagent.IncludeContentsLastTurns, IncludeContentsTurns: 2}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.Name = "testAgent"
+			if _, err := llmagent.New(tc.cfg); (err != nil) != tc.wantErr {
+				t.Errorf("llmagent.New() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
 func TestContentsRequestProcessor_NonLLMAgent(t *testing.T) {
 	testAgent := utils.Must(agent.New(agent.Config{
 		Name: "test_agent",
@@ -20,6 +20,7 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
 	"github.com/sjzsdu/adk-go/internal/utils"
 	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/session"
@@ -93,3 +94,58 @@ func generateRequestConfirmationEvent(
 		Actions:            session.EventActions{},
 	}
 }
+
+// generateRequestCredentialEvent creates a new Event containing
+// adk_request_credential function calls for the credentials requested by
+// tools via tool.Context.RequestCredential.
+func generateRequestCredentialEvent(
+	invocationContext agent.InvocationContext,
+	functionCallEvent *session.Event,
+	functionResponseEvent *session.Event,
+) *session.Event {
+	if functionResponseEvent == nil || len(functionResponseEvent.Actions.RequestedAuthConfigs) == 0 {
+		return nil
+	}
+	if functionCallEvent == nil || functionCallEvent.Content == nil {
+		return nil
+	}
+
+	var parts []*genai.Part
+	var longRunningToolIDs []string
+	// Follow the order of the function calls to keep the event deterministic.
+	for _, call := range utils.FunctionCalls(functionCallEvent.Content) {
+		authConfig, ok := functionResponseEvent.Actions.RequestedAuthConfigs[call.ID]
+		if !ok {
+			continue
+		}
+		requestCredentialFC := &genai.FunctionCall{
+			ID:   utils.GenerateFunctionCallID(),
+			Name: auth.FunctionCallName,
+			Args: map[string]any{
+				"functionCallId": call.ID,
+				"authConfig":     authConfig,
+			},
+		}
+		parts = append(parts, &genai.Part{FunctionCall: requestCredentialFC})
+		longRunningToolIDs = append(longRunningToolIDs, requestCredentialFC.ID)
+	}
+
+	if len(parts) == 0 {
+		return nil
+	}
+
+	return &session.Event{
+		InvocationID: invocationContext.InvocationID(),
+		Author:       invocationContext.Agent().Name(),
+		Branch:       invocationContext.Branch(),
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{
+				Parts: parts,
+				Role:  genai.RoleModel,
+			},
+		},
+		Timestamp:          time.Now(),
+		LongRunningToolIDs: longRunningToolIDs,
+		Actions:            session.EventActions{},
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package llminternal
+
+import (
+	"iter"
+
+	"github.com/sjzsdu/adk-go/agent"
+	icontext "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/internal/utils"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/planner"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// nlPlanningRequestProcessor adds the planning instruction of the agent's
+// planner to the request.
+func nlPlanningRequestProcessor(ctx agent.InvocationContext, req *model.LLMRequest, f *Flow) iter.Seq2[*session.Event, error] {
+	// reference: adk-python src/google/adk/flows/llm_flows/_nl_planning.py
+	return func(yield func(*session.Event, error) bool) {
+		p := agentPlanner(ctx)
+		if p == nil {
+			return
+		}
+
+		if instruction := p.BuildPlanningInstruction(icontext.NewReadonlyContext(ctx), req); instruction != "" {
+			utils.AppendInstructions(req, instruction)
+		}
+
+		// Planning contents from previous turns were marked as thoughts by the
+		// response processor, unmark them so the model sees its own plan.
+		for _, content := range req.Contents {
+			if content == nil {
+				continue
+			}
+			for _, part := range content.Parts {
+				if part != nil {
+					part.Thought = false
+				}
+			}
+		}
+	}
+}
+
+// nlPlanningResponseProcessor lets the agent's planner post-process the
+// response parts. If the planner changes the state, an event carrying the
+// state delta is yielded.
+func nlPlanningResponseProcessor(ctx agent.InvocationContext, req *model.LLMRequest, resp *model.LLMResponse) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		if resp == nil || resp.Content == nil || len(resp.Content.Parts) == 0 {
+			return
+		}
+		p := agentPlanner(ctx)
+		if p == nil {
+			return
+		}
+
+		stateDelta := make(map[string]any)
+		cctx := icontext.NewCallbackContextWithDelta(ctx, stateDelta)
+		if parts := p.ProcessPlanningResponse(cctx, resp.Content.Parts); parts != nil {
+			resp.Content.Parts = parts
+		}
+
+		if len(stateDelta) > 0 {
+			ev := session.NewEvent(ctx.InvocationID())
+			ev.Author = ctx.Agent().Name()
+			ev.Branch = ctx.Branch()
+			ev.Actions.StateDelta = stateDelta
+			yield(ev, nil)
+		}
+	}
+}
+
+func agentPlanner(ctx agent.InvocationContext) planner.Planner {
+	llmAgent := asLLMAgent(ctx.Agent())
+	if llmAgent == nil {
+		return nil
+	}
+	return llmAgent.internal().Planner
+}
@@ -22,6 +22,7 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/internal/agent/parentmap"
 	"github.com/sjzsdu/adk-go/internal/llminternal/googlellm"
 	"github.com/sjzsdu/adk-go/internal/toolinternal/toolutils"
 	"github.com/sjzsdu/adk-go/internal/utils"
@@ -48,7 +49,7 @@ func outputSchemaRequestProcessor(ctx agent.InvocationContext, req *model.LLMReq
 
 		state := llmAgent.internal()
 		// Check if we need the processor in the first place.
-		if state.OutputSchema == nil || !needOutputSchemaProcessor(state) {
+		if state.OutputSchema == nil || !needOutputSchemaProcessor(ctx, state) {
 			return
 		}
 
@@ -77,6 +78,39 @@ func createFinalModelResponseEvent(invocationContext agent.InvocationContext, re
 	return finalEvent
 }
 
+// structuredModelResponse returns the final response of the agent if ev
+// contains a call of the set_model_response tool whose response matches the
+// output schema. A response that does not match, e.g. altered by an after
+// tool callback, is replaced in ev with the validation error, so that the
+// model can try again. Error responses of the tool are left as is.
+func structuredModelResponse(ctx agent.InvocationContext, ev *session.Event) (string, error) {
+	response, err := retrieveStructuredModelResponse(ev)
+	if err != nil || response == "" {
+		return "", err
+	}
+	llmAgent := asLLMAgent(ctx.Agent())
+	if llmAgent == nil || llmAgent.internal().OutputSchema == nil {
+		return response, nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(response), &m); err != nil {
+		return "", fmt.Errorf("failed to unmarshal set_model_response: %w", err)
+	}
+	err = utils.ValidateMapOnSchema(m, llmAgent.internal().OutputSchema, false)
+	if err == nil {
+		return response, nil
+	}
+	for _, part := range ev.Content.Parts {
+		if fr := part.FunctionResponse; fr != nil && fr.Name == "set_model_response" {
+			if _, ok := fr.Response["error"]; !ok || len(fr.Response) != 1 {
+				fr.Response = map[string]any{"error": fmt.Sprintf("invalid output schema: %v", err)}
+			}
+			break
+		}
+	}
+	return "", nil
+}
+
 // retrieveStructuredModelResponse checks if function response contains set_model_response tool and extract JSON.
 func retrieveStructuredModelResponse(ev *session.Event) (string, error) {
 	if ev == nil || ev.LLMResponse.Content == nil {
@@ -96,11 +130,18 @@ func retrieveStructuredModelResponse(ev *session.Event) (string, error) {
 	return "", nil
 }
 
-func needOutputSchemaProcessor(state *State) bool {
+// needOutputSchemaProcessor reports whether the output schema is enforced via
+// the set_model_response tool, because the agent has tools or can transfer to
+// other agents and the model cannot combine function calling with a response
+// schema.
+func needOutputSchemaProcessor(ctx agent.InvocationContext, state *State) bool {
 	if state == nil || state.Model == nil {
 		return false
 	}
 	hasTools := len(state.Tools) > 0 || len(state.Toolsets) > 0
+	if !hasTools && shouldUseAutoFlow(ctx.Agent()) {
+		hasTools = len(transferTargets(ctx.Agent(), parentmap.FromContext(ctx)[ctx.Agent().Name()])) > 0
+	}
 	return hasTools && googlellm.NeedsOutputSchemaProcessor(state.Model)
 }
 
@@ -22,6 +22,7 @@ import (
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
 	"github.com/sjzsdu/adk-go/internal/utils"
 	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/session"
@@ -161,6 +162,10 @@ func RequestConfirmationRequestProcessor(ctx agent.InvocationContext, req *model
 				toolsToResumeConfirmation[callID] = cc.confirmation
 			}
 
+			if err := runconfig.LimitsFromContext(ctx).AddToolCalls(len(parts)); err != nil {
+				yield(nil, err)
+				return
+			}
 			ev, err := f.handleFunctionCalls(ctx, toolsmap, &model.LLMResponse{
 				Content: &genai.Content{Parts: parts, Role: genai.RoleUser},
 			}, toolsToResumeConfirmation)
@@ -0,0 +1,97 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessionutils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pageTokenPrefix = "offset:"
+
+// EncodePageToken returns the opaque token of the list page starting at
+// offset.
+func EncodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pageTokenPrefix + strconv.Itoa(offset)))
+}
+
+// DecodePageToken returns the offset of the list page of a token returned
+// by EncodePageToken, or 0 for the empty token.
+func DecodePageToken(token string) (int, error) {
+	if token == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid page token %q", token)
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), pageTokenPrefix))
+	if err != nil || !strings.HasPrefix(string(data), pageTokenPrefix) || offset < 0 {
+		return 0, fmt.Errorf("invalid page token %q", token)
+	}
+	return offset, nil
+}
+
+// Paginate returns the page of the items starting at the offset of the page
+// token, with at most pageSize items, or all the remaining items if
+// pageSize is 0, and the token of the next page, empty for the last page.
+func Paginate[T any](items []T, pageToken string, pageSize int) ([]T, string, error) {
+	offset, err := DecodePageToken(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if pageSize < 0 {
+		return nil, "", fmt.Errorf("page size must not be negative, got %d", pageSize)
+	}
+	if offset >= len(items) {
+		return items[:0], "", nil
+	}
+	items = items[offset:]
+	if pageSize == 0 || pageSize >= len(items) {
+		return items, "", nil
+	}
+	return items[:pageSize], EncodePageToken(offset + pageSize), nil
+}
+
+// StateMatches reports whether the state has all the keys of the filter,
+// with values having the same JSON encodings.
+func StateMatches(state, filter map[string]any) bool {
+	for key, want := range filter {
+		got, ok := state[key]
+		if !ok {
+			return false
+		}
+		wantJSON, err := json.Marshal(want)
+		if err != nil {
+			return false
+		}
+		gotJSON, err := json.Marshal(got)
+		if err != nil || !bytes.Equal(gotJSON, wantJSON) {
+			return false
+		}
+	}
+	return true
+}
+
+// InTimeRange reports whether t is at or after the start and before the
+// end of a range. A zero start or end leaves the range open.
+func InTimeRange(t, start, end time.Time) bool {
+	return (start.IsZero() || !t.Before(start)) && (end.IsZero() || t.Before(end))
+}
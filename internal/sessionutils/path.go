@@ -0,0 +1,31 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessionutils
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// PathSegment escapes a name, e.g. an app name, user ID or session ID, to be
+// used as a file path element. It fails for the names which would not stay
+// in their directory.
+func PathSegment(name string) (string, error) {
+	segment := url.PathEscape(name)
+	if segment == "" || segment == "." || segment == ".." {
+		return "", fmt.Errorf("invalid name %q", name)
+	}
+	return segment, nil
+}
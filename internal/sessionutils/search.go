@@ -0,0 +1,73 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sessionutils
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genai"
+)
+
+// SearchText returns the searchable text of the content of an event: its
+// text parts, and the names and JSON-encoded arguments of its function
+// calls, one per line.
+func SearchText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var lines []string
+	for _, part := range content.Parts {
+		if part == nil {
+			continue
+		}
+		if part.Text != "" {
+			lines = append(lines, part.Text)
+		}
+		if call := part.FunctionCall; call != nil {
+			lines = append(lines, call.Name)
+			if len(call.Args) > 0 {
+				if args, err := json.Marshal(call.Args); err == nil {
+					lines = append(lines, string(args))
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SearchTerms returns the lowercased words of a text, the sequences of
+// letters and digits, like the unicode61 tokenizer of SQLite FTS5.
+func SearchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// MatchesTerms reports whether the text has all the terms, returned by
+// SearchTerms, as words.
+func MatchesTerms(text string, terms []string) bool {
+	words := make(map[string]bool)
+	for _, word := range SearchTerms(text) {
+		words[word] = true
+	}
+	for _, term := range terms {
+		if !words[term] {
+			return false
+		}
+	}
+	return true
+}
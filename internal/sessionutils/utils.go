@@ -72,3 +72,46 @@ func MergeStates(appState, userState, sessionState map[string]any) map[string]an
 
 	return mergedState
 }
+
+// ReplayStateDeltas returns the session state resulting from applying the
+// state deltas in order to the initial session state. App, user and
+// temporary keys are ignored.
+func ReplayStateDeltas(initialState map[string]any, deltas []map[string]any) map[string]any {
+	state := maps.Clone(initialState)
+	if state == nil {
+		state = make(map[string]any)
+	}
+	for _, delta := range deltas {
+		_, _, sessionDelta := ExtractStateDeltas(delta)
+		maps.Copy(state, sessionDelta)
+	}
+	return state
+}
+
+// RevertedArtifactVersions returns the artifacts changed by the removed
+// artifact deltas, mapped to their versions before the removed events: their
+// last versions in the kept artifact deltas, else in the initial artifact
+// versions, the versions before the kept events, or 0 if they did not exist.
+func RevertedArtifactVersions(initial map[string]int64, kept, removed []map[string]int64) map[string]int64 {
+	before := LatestArtifactVersions(initial, kept)
+	versions := make(map[string]int64)
+	for _, delta := range removed {
+		for name := range delta {
+			versions[name] = before[name]
+		}
+	}
+	return versions
+}
+
+// LatestArtifactVersions returns the initial artifact versions updated by
+// the artifact deltas: the artifacts mapped to their last versions.
+func LatestArtifactVersions(initial map[string]int64, deltas []map[string]int64) map[string]int64 {
+	versions := maps.Clone(initial)
+	if versions == nil {
+		versions = make(map[string]int64)
+	}
+	for _, delta := range deltas {
+		maps.Copy(versions, delta)
+	}
+	return versions
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// FakeOAuthServer is a minimal OAuth2 authorization server implementing the
+// authorization code flow. Authorization is granted without user interaction.
+type FakeOAuthServer struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	mu        sync.Mutex
+	nextCode  int
+	codes     map[string]bool
+	exchanged []string
+}
+
+// NewFakeOAuthServer starts a FakeOAuthServer that is closed when the test
+// ends.
+func NewFakeOAuthServer(t *testing.T) *FakeOAuthServer {
+	t.Helper()
+	s := &FakeOAuthServer{
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		codes:        make(map[string]bool),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AuthorizationURL is the authorization endpoint of the server.
+func (s *FakeOAuthServer) AuthorizationURL() string { return s.URL + "/authorize" }
+
+// TokenURL is the token endpoint of the server.
+func (s *FakeOAuthServer) TokenURL() string { return s.URL + "/token" }
+
+// ExchangedCodes returns the authorization codes exchanged for tokens.
+func (s *FakeOAuthServer) ExchangedCodes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.exchanged...)
+}
+
+// Authorize visits the authorization URI as the user's browser would and
+// returns the URI the user is redirected to.
+func (s *FakeOAuthServer) Authorize(t *testing.T, authURI string) string {
+	t.Helper()
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(authURI)
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize returned status %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+	return resp.Header.Get("Location")
+}
+
+func (s *FakeOAuthServer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != s.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.nextCode++
+	code := fmt.Sprintf("code-%d", s.nextCode)
+	s.codes[code] = true
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *FakeOAuthServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	valid := s.codes[code]
+	delete(s.codes, code)
+	if valid {
+		s.exchanged = append(s.exchanged, code)
+	}
+	s.mu.Unlock()
+	if !valid {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token":  "access-" + code,
+		"refresh_token": "refresh-" + code,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+	})
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -23,7 +23,9 @@ import (
 
 	"github.com/sjzsdu/adk-go/agent"
 	"github.com/sjzsdu/adk-go/artifact"
+	"github.com/sjzsdu/adk-go/auth"
 	contextinternal "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/internal/credentialinternal"
 	"github.com/sjzsdu/adk-go/memory"
 	"github.com/sjzsdu/adk-go/session"
 	"github.com/sjzsdu/adk-go/tool"
@@ -131,3 +133,48 @@ func (c *toolContext) RequestConfirmation(hint string, payload any) error {
 	c.eventActions.SkipSummarization = true
 	return nil
 }
+
+func (c *toolContext) GetCredential(cfg *auth.AuthConfig) (*auth.AuthCredential, error) {
+	if cfg == nil || cfg.AuthScheme == nil {
+		return nil, fmt.Errorf("auth scheme is required")
+	}
+	// API keys and HTTP credentials known in advance need no user interaction.
+	if raw := cfg.RawAuthCredential; raw != nil && (raw.AuthType == auth.CredentialAPIKey || raw.AuthType == auth.CredentialHTTP) {
+		return raw.Copy(), nil
+	}
+	if svc := credentialinternal.FromContext(c.invocationContext); svc != nil {
+		cred, err := svc.LoadCredential(c, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credential: %w", err)
+		}
+		if cred != nil {
+			return cred, nil
+		}
+	}
+	val, err := c.invocationContext.Session().State().Get(credentialinternal.TempStateKey(cfg))
+	if err != nil {
+		return nil, nil
+	}
+	cred, ok := val.(*auth.AuthCredential)
+	if !ok {
+		return nil, nil
+	}
+	return cred.Copy(), nil
+}
+
+func (c *toolContext) RequestCredential(cfg *auth.AuthConfig) error {
+	if c.functionCallID == "" {
+		return fmt.Errorf("error function call id not set when requesting credential for tool")
+	}
+	req, err := auth.GenerateAuthRequest(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to generate auth request: %w", err)
+	}
+	if c.eventActions.RequestedAuthConfigs == nil {
+		c.eventActions.RequestedAuthConfigs = make(map[string]*auth.AuthConfig)
+	}
+	c.eventActions.RequestedAuthConfigs[c.functionCallID] = req
+	// Stop the agent loop after this tool call, see RequestConfirmation.
+	c.eventActions.SkipSummarization = true
+	return nil
+}
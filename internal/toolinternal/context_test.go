@@ -17,8 +17,12 @@ package toolinternal
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
 	contextinternal "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/internal/credentialinternal"
 	"github.com/sjzsdu/adk-go/session"
 )
 
@@ -89,3 +93,69 @@ func TestRequestConfirmation_AutoGeneratesIDWhenEmpty(t *testing.T) {
 		}
 	}
 }
+
+func TestRequestCredential(t *testing.T) {
+	inv := contextinternal.NewInvocationContext(t.Context(), contextinternal.InvocationContextParams{})
+	actions := &session.EventActions{}
+	toolCtx := NewToolContext(inv, "fn1", actions, nil)
+
+	cfg := &auth.AuthConfig{
+		AuthScheme: &auth.AuthScheme{Type: auth.SchemeAPIKey, In: "header", Name: "X-API-Key"},
+	}
+	if err := toolCtx.RequestCredential(cfg); err != nil {
+		t.Fatalf("RequestCredential returned unexpected error: %v", err)
+	}
+	if !actions.SkipSummarization {
+		t.Error("RequestCredential did not set SkipSummarization to true")
+	}
+	if diff := cmp.Diff(map[string]*auth.AuthConfig{"fn1": cfg}, actions.RequestedAuthConfigs); diff != "" {
+		t.Errorf("RequestedAuthConfigs mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := toolCtx.RequestCredential(&auth.AuthConfig{}); err == nil {
+		t.Error("RequestCredential without auth scheme succeeded, want error")
+	}
+}
+
+func TestGetCredential(t *testing.T) {
+	sessionService := session.InMemoryService()
+	resp, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv := contextinternal.NewInvocationContext(t.Context(), contextinternal.InvocationContextParams{Session: resp.Session})
+	toolCtx := NewToolContext(inv, "fn1", &session.EventActions{}, nil)
+
+	apiKey := &auth.AuthConfig{
+		AuthScheme:        &auth.AuthScheme{Type: auth.SchemeAPIKey, In: "header", Name: "X-API-Key"},
+		RawAuthCredential: &auth.AuthCredential{AuthType: auth.CredentialAPIKey, APIKey: "key"},
+	}
+	got, err := toolCtx.GetCredential(apiKey)
+	if err != nil {
+		t.Fatalf("GetCredential returned unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(apiKey.RawAuthCredential, got); diff != "" {
+		t.Errorf("GetCredential mismatch (-want +got):\n%s", diff)
+	}
+
+	oauth2 := &auth.AuthConfig{
+		AuthScheme:        &auth.AuthScheme{Type: auth.SchemeOAuth2},
+		RawAuthCredential: &auth.AuthCredential{AuthType: auth.CredentialOAuth2, OAuth2: &auth.OAuth2Auth{ClientID: "id"}},
+	}
+	got, err = toolCtx.GetCredential(oauth2)
+	if err != nil || got != nil {
+		t.Errorf("GetCredential before authorization = %v, %v, want nil, nil", got, err)
+	}
+
+	cred := &auth.AuthCredential{AuthType: auth.CredentialOAuth2, OAuth2: &auth.OAuth2Auth{AccessToken: "token"}}
+	if err := resp.Session.State().Set(credentialinternal.TempStateKey(oauth2), cred); err != nil {
+		t.Fatal(err)
+	}
+	got, err = toolCtx.GetCredential(oauth2)
+	if err != nil {
+		t.Fatalf("GetCredential returned unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(cred, got); diff != "" {
+		t.Errorf("GetCredential mismatch (-want +got):\n%s", diff)
+	}
+}
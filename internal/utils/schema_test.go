@@ -18,6 +18,8 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/jsonschema-go/jsonschema"
 	"google.golang.org/genai"
 )
 
@@ -352,3 +354,60 @@ func TestValidateOutputSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestGenaiSchemaFromJSONSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  *jsonschema.Schema
+		want    *genai.Schema
+		wantErr bool
+	}{
+		{
+			name:   "nil schema",
+			schema: nil,
+			want:   nil,
+		},
+		{
+			name: "object",
+			schema: &jsonschema.Schema{
+				Type:        "object",
+				Description: "a person",
+				Properties: map[string]*jsonschema.Schema{
+					"name": {Type: "string", MinLength: jsonschema.Ptr(1)},
+					"tags": {Types: []string{"null", "array"}, Items: &jsonschema.Schema{Type: "string", Enum: []any{"a", "b"}}},
+				},
+				Required: []string{"name"},
+			},
+			want: &genai.Schema{
+				Type:        genai.TypeObject,
+				Description: "a person",
+				Properties: map[string]*genai.Schema{
+					"name": {Type: genai.TypeString, MinLength: genai.Ptr[int64](1)},
+					"tags": {Type: genai.TypeArray, Nullable: genai.Ptr(true), Items: &genai.Schema{Type: genai.TypeString, Enum: []string{"a", "b"}}},
+				},
+				Required: []string{"name"},
+			},
+		},
+		{
+			name:    "multiple types",
+			schema:  &jsonschema.Schema{Types: []string{"string", "integer"}},
+			wantErr: true,
+		},
+		{
+			name:    "reference",
+			schema:  &jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{"next": {Ref: "#"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenaiSchemaFromJSONSchema(tt.schema)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenaiSchemaFromJSONSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GenaiSchemaFromJSONSchema() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"slices"
 	"strings"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"google.golang.org/genai"
 )
 
@@ -136,3 +138,88 @@ func ValidateOutputSchema(output string, schema *genai.Schema) (map[string]any,
 	}
 	return outputMap, nil
 }
+
+// GenaiSchemaFromJSONSchema converts a JSON schema, e.g. one inferred from a
+// Go type by jsonschema.For, to a genai.Schema.
+//
+// Keywords that have no counterpart in genai.Schema, such as
+// additionalProperties, are dropped.
+func GenaiSchemaFromJSONSchema(s *jsonschema.Schema) (*genai.Schema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if s.Ref != "" {
+		return nil, fmt.Errorf("schema references are not supported: %q", s.Ref)
+	}
+	out := &genai.Schema{
+		Title:       s.Title,
+		Description: s.Description,
+		Format:      s.Format,
+		Pattern:     s.Pattern,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		Required:    slices.Clone(s.Required),
+	}
+
+	types := s.Types
+	if s.Type != "" {
+		types = []string{s.Type}
+	}
+	for _, t := range types {
+		switch t {
+		case "null":
+			out.Nullable = genai.Ptr(true)
+			continue
+		case "string", "integer", "number", "boolean", "array", "object":
+		default:
+			return nil, fmt.Errorf("unsupported schema type %q", t)
+		}
+		if out.Type != "" {
+			return nil, fmt.Errorf("schemas with multiple types are not supported: %v", types)
+		}
+		out.Type = genai.Type(strings.ToUpper(t))
+	}
+
+	for _, v := range s.Enum {
+		out.Enum = append(out.Enum, fmt.Sprint(v))
+	}
+	if s.MinLength != nil {
+		out.MinLength = genai.Ptr(int64(*s.MinLength))
+	}
+	if s.MaxLength != nil {
+		out.MaxLength = genai.Ptr(int64(*s.MaxLength))
+	}
+	if s.MinItems != nil {
+		out.MinItems = genai.Ptr(int64(*s.MinItems))
+	}
+	if s.MaxItems != nil {
+		out.MaxItems = genai.Ptr(int64(*s.MaxItems))
+	}
+	if s.MinProperties != nil {
+		out.MinProperties = genai.Ptr(int64(*s.MinProperties))
+	}
+	if s.MaxProperties != nil {
+		out.MaxProperties = genai.Ptr(int64(*s.MaxProperties))
+	}
+
+	var err error
+	if out.Items, err = GenaiSchemaFromJSONSchema(s.Items); err != nil {
+		return nil, fmt.Errorf("items: %w", err)
+	}
+	if len(s.Properties) > 0 {
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			if out.Properties[name], err = GenaiSchemaFromJSONSchema(prop); err != nil {
+				return nil, fmt.Errorf("property %q: %w", name, err)
+			}
+		}
+	}
+	for i, sub := range s.AnyOf {
+		converted, err := GenaiSchemaFromJSONSchema(sub)
+		if err != nil {
+			return nil, fmt.Errorf("anyOf[%d]: %w", i, err)
+		}
+		out.AnyOf = append(out.AnyOf, converted)
+	}
+	return out, nil
+}
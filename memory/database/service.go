@@ -0,0 +1,283 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package database provides a memory.Service storing the memories in a
+// relational database (e.g., PostgreSQL, Spanner, SQLite) via the GORM
+// library, like the session service of session/database.
+//
+// With SQLite, the memories are searched with an FTS5 full-text index and
+// ranked by BM25. With the other databases, or if SQLite was built without
+// FTS5, they are searched with LIKE and ranked by the number of words of
+// the query they contain.
+package database
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
+	"github.com/sjzsdu/adk-go/memory"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+const (
+	// searchIndexTable is the SQLite FTS5 table indexing the text of the
+	// memories, an external content table of memory_entries.
+	searchIndexTable = "memory_entries_fts"
+	// maxSearchResults is the maximum number of memories returned by
+	// Search.
+	maxSearchResults = 50
+)
+
+// storageEntry corresponds to the 'memory_entries' table. A memory is an
+// event of a session.
+type storageEntry struct {
+	// ID is the rowid of the entry in the search index.
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	AppName   string    `gorm:"uniqueIndex:idx_memory_entries_event;not null"`
+	UserID    string    `gorm:"uniqueIndex:idx_memory_entries_event;not null"`
+	SessionID string    `gorm:"uniqueIndex:idx_memory_entries_event;not null"`
+	EventID   string    `gorm:"uniqueIndex:idx_memory_entries_event;not null"`
+	Author    string    `gorm:"not null"`
+	Timestamp time.Time `gorm:"precision:6"`
+	// Content is the content of the event.
+	Content *genai.Content `gorm:"serializer:json"`
+	// Text is the searchable text of the content, see
+	// sessionutils.SearchText.
+	Text string `gorm:"not null"`
+}
+
+// TableName explicitly sets the table name for the storageEntry struct.
+func (storageEntry) TableName() string {
+	return "memory_entries"
+}
+
+// databaseService is a database implementation of memory.Service.
+type databaseService struct {
+	db *gorm.DB
+}
+
+// NewMemoryService creates a new [memory.Service] implementation that uses a
+// relational database via the GORM library. The memories of a user are
+// isolated from the other users.
+//
+// It requires a [gorm.Dialector] to specify the database connection and
+// accepts optional [gorm.Option] values for further GORM configuration.
+// The schema is created by [AutoMigrate].
+func NewMemoryService(dialector gorm.Dialector, opts ...gorm.Option) (memory.Service, error) {
+	db, err := gorm.Open(dialector, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating database memory service: %w", err)
+	}
+	return &databaseService{db: db}, nil
+}
+
+// AutoMigrate runs the GORM auto-migration tool to ensure the database schema
+// matches the internal storage models. With SQLite, it also creates the FTS5
+// index of the memories, indexing the existing ones, if FTS5 is available.
+//
+// It returns an error if the provided memory.Service was not created by
+// NewMemoryService.
+func AutoMigrate(service memory.Service) error {
+	dbservice, ok := service.(*databaseService)
+	if !ok {
+		return fmt.Errorf("invalid memory service type")
+	}
+	if err := dbservice.db.AutoMigrate(&storageEntry{}); err != nil {
+		return fmt.Errorf("auto migrate failed: %w", err)
+	}
+	if dbservice.db.Dialector.Name() != "sqlite" || dbservice.db.Migrator().HasTable(searchIndexTable) {
+		return nil
+	}
+	err := dbservice.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("CREATE VIRTUAL TABLE " + searchIndexTable + " USING fts5(text, content='memory_entries', content_rowid='id')").Error
+		if err != nil {
+			// SQLite was built without FTS5: Search uses LIKE.
+			return errNoFTS5
+		}
+		for _, stmt := range []string{
+			`CREATE TRIGGER memory_entries_fts_insert AFTER INSERT ON memory_entries BEGIN
+	INSERT INTO ` + searchIndexTable + `(rowid, text) VALUES (new.id, new.text);
+END`,
+			`CREATE TRIGGER memory_entries_fts_delete AFTER DELETE ON memory_entries BEGIN
+	INSERT INTO ` + searchIndexTable + `(` + searchIndexTable + `, rowid, text) VALUES ('delete', old.id, old.text);
+END`,
+			`CREATE TRIGGER memory_entries_fts_update AFTER UPDATE ON memory_entries BEGIN
+	INSERT INTO ` + searchIndexTable + `(` + searchIndexTable + `, rowid, text) VALUES ('delete', old.id, old.text);
+	INSERT INTO ` + searchIndexTable + `(rowid, text) VALUES (new.id, new.text);
+END`,
+			// Indexes the existing memories.
+			`INSERT INTO ` + searchIndexTable + `(` + searchIndexTable + `) VALUES ('rebuild')`,
+		} {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("failed to create search index: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errNoFTS5) {
+		return fmt.Errorf("auto migrate failed: %w", err)
+	}
+	return nil
+}
+
+// errNoFTS5 rolls back the creation of the search index when SQLite has no
+// FTS5.
+var errNoFTS5 = errors.New("fts5 is not available")
+
+// AddSession adds the events of the session with text to the memories.
+// Only the events which are not memories yet are added, so AddSession can
+// be called repeatedly during the lifetime of a session, and with only its
+// recent events. The memories of the events no longer in the session are
+// kept.
+func (s *databaseService) AddSession(ctx context.Context, curSession session.Session) error {
+	appName, userID, sessionID := curSession.AppName(), curSession.UserID(), curSession.ID()
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var eventIDs []string
+		err := tx.Model(&storageEntry{}).
+			Where("app_name = ? AND user_id = ? AND session_id = ?", appName, userID, sessionID).
+			Pluck("event_id", &eventIDs).Error
+		if err != nil {
+			return fmt.Errorf("database error while fetching memories: %w", err)
+		}
+		added := make(map[string]bool, len(eventIDs))
+		for _, id := range eventIDs {
+			added[id] = true
+		}
+
+		var entries []storageEntry
+		for event := range curSession.Events().All() {
+			if event.Partial || added[event.ID] {
+				continue
+			}
+			text := sessionutils.SearchText(event.Content)
+			if text == "" {
+				continue
+			}
+			entries = append(entries, storageEntry{
+				AppName:   appName,
+				UserID:    userID,
+				SessionID: sessionID,
+				EventID:   event.ID,
+				Author:    event.Author,
+				Timestamp: event.Timestamp,
+				Content:   event.Content,
+				Text:      text,
+			})
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		// The events added concurrently by another call are skipped.
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entries).Error; err != nil {
+			return fmt.Errorf("failed to save memories: %w", err)
+		}
+		return nil
+	})
+}
+
+// Search returns the memories of the user with words of the query, the
+// most relevant first, at most maxSearchResults.
+func (s *databaseService) Search(ctx context.Context, req *memory.SearchRequest) (*memory.SearchResponse, error) {
+	terms := slices.Compact(slices.Sorted(slices.Values(sessionutils.SearchTerms(req.Query))))
+	if len(terms) == 0 {
+		return &memory.SearchResponse{}, nil
+	}
+	db := s.db.WithContext(ctx)
+	var entries []storageEntry
+	var err error
+	if db.Dialector.Name() == "sqlite" && db.Migrator().HasTable(searchIndexTable) {
+		entries, err = searchFTS(db, req.AppName, req.UserID, terms)
+	} else {
+		entries, err = searchLike(db, req.AppName, req.UserID, terms)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error while searching memories: %w", err)
+	}
+
+	resp := &memory.SearchResponse{Memories: make([]memory.Entry, 0, len(entries))}
+	for _, e := range entries {
+		resp.Memories = append(resp.Memories, memory.Entry{
+			Content:   e.Content,
+			Author:    e.Author,
+			Timestamp: e.Timestamp,
+		})
+	}
+	return resp, nil
+}
+
+// searchFTS returns the memories of the user with any of the terms, ranked
+// by BM25.
+func searchFTS(db *gorm.DB, appName, userID string, terms []string) ([]storageEntry, error) {
+	// Each term is quoted as a string, so that it is not an FTS5 operator.
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `"`
+	}
+	var entries []storageEntry
+	err := db.Table(searchIndexTable).
+		Select("memory_entries.*").
+		Joins("JOIN memory_entries ON memory_entries.id = "+searchIndexTable+".rowid").
+		Where(searchIndexTable+" MATCH ?", strings.Join(match, " OR ")).
+		Where("memory_entries.app_name = ? AND memory_entries.user_id = ?", appName, userID).
+		Order("bm25(" + searchIndexTable + "), memory_entries.timestamp DESC").
+		Limit(maxSearchResults).
+		Find(&entries).Error
+	return entries, err
+}
+
+// searchLike returns the memories of the user containing any of the terms,
+// ranked by the number of terms they contain.
+func searchLike(db *gorm.DB, appName, userID string, terms []string) ([]storageEntry, error) {
+	// The terms are letters and digits, which LIKE matches literally.
+	conds := make([]string, len(terms))
+	args := make([]any, len(terms))
+	for i, term := range terms {
+		conds[i] = "LOWER(text) LIKE ?"
+		args[i] = "%" + term + "%"
+	}
+	var entries []storageEntry
+	err := db.Where("app_name = ? AND user_id = ?", appName, userID).
+		Where("("+strings.Join(conds, " OR ")+")", args...).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make(map[int64]int, len(entries))
+	for _, e := range entries {
+		text := strings.ToLower(e.Text)
+		for _, term := range terms {
+			if strings.Contains(text, term) {
+				matches[e.ID]++
+			}
+		}
+	}
+	slices.SortFunc(entries, func(a, b storageEntry) int {
+		return cmp.Or(cmp.Compare(matches[b.ID], matches[a.ID]), b.Timestamp.Compare(a.Timestamp))
+	})
+	if len(entries) > maxSearchResults {
+		entries = entries[:maxSearchResults]
+	}
+	return entries, nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/memory"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// newService returns a migrated memory service of a test database. Without
+// fts, the search index is dropped, so that Search uses LIKE.
+func newService(t *testing.T, name string, fts bool) *databaseService {
+	t.Helper()
+	service, err := NewMemoryService(sqlite.Open("file:" + name + "?mode=memory&cache=shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AutoMigrate(service); err != nil {
+		t.Fatal(err)
+	}
+	dbservice := service.(*databaseService)
+	if !fts {
+		dropSearchIndex(t, dbservice)
+	}
+	db, err := dbservice.db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return dbservice
+}
+
+func dropSearchIndex(t *testing.T, s *databaseService) {
+	t.Helper()
+	for _, stmt := range []string{
+		"DROP TRIGGER memory_entries_fts_insert",
+		"DROP TRIGGER memory_entries_fts_delete",
+		"DROP TRIGGER memory_entries_fts_update",
+		"DROP TABLE " + searchIndexTable,
+	} {
+		if err := s.db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// addEvents appends events with the texts to the session.
+func addEvents(t *testing.T, sessions session.Service, sess session.Session, texts ...string) {
+	t.Helper()
+	for _, text := range texts {
+		ev := session.NewEvent("inv")
+		ev.Author = "user"
+		ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleUser)}
+		if err := sessions.AppendEvent(t.Context(), sess, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newSession(t *testing.T, sessions session.Service, userID, sessionID string, texts ...string) session.Session {
+	t.Helper()
+	created, err := sessions.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: userID, SessionID: sessionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addEvents(t, sessions, created.Session, texts...)
+	return created.Session
+}
+
+func search(t *testing.T, s memory.Service, userID, query string) []string {
+	t.Helper()
+	resp, err := s.Search(t.Context(), &memory.SearchRequest{AppName: "app", UserID: userID, Query: query})
+	if err != nil {
+		t.Fatalf("Search() failed: %v", err)
+	}
+	var got []string
+	for _, m := range resp.Memories {
+		got = append(got, m.Content.Parts[0].Text)
+	}
+	return got
+}
+
+func TestMemoryService_Search(t *testing.T) {
+	for _, fts := range []bool{true, false} {
+		name := map[bool]string{true: "fts5", false: "like"}[fts]
+		t.Run(name, func(t *testing.T) {
+			s := newService(t, "search_"+name, fts)
+			sessions := session.InMemoryService()
+			for _, sess := range []session.Session{
+				newSession(t, sessions, "alice", "s1", "The quick brown fox", "jumps over the lazy dog", "A fox, a fox and a fox in the den"),
+				newSession(t, sessions, "alice", "s2", "Hello world"),
+				newSession(t, sessions, "bob", "s1", "The fox of bob"),
+			} {
+				if err := s.AddSession(t.Context(), sess); err != nil {
+					t.Fatalf("AddSession() failed: %v", err)
+				}
+			}
+
+			tests := []struct {
+				name   string
+				userID string
+				query  string
+				want   []string
+			}{
+				{
+					name:   "most relevant first",
+					userID: "alice",
+					query:  "fox den",
+					want:   []string{"A fox, a fox and a fox in the den", "The quick brown fox"},
+				},
+				{
+					name:   "any word",
+					userID: "alice",
+					query:  "LAZY world",
+					want:   []string{"Hello world", "jumps over the lazy dog"},
+				},
+				{
+					name:   "operators are words",
+					userID: "alice",
+					query:  "NOT hello",
+					want:   []string{"Hello world"},
+				},
+				{
+					name:   "user isolation",
+					userID: "bob",
+					query:  "fox",
+					want:   []string{"The fox of bob"},
+				},
+				{
+					name:   "no match",
+					userID: "alice",
+					query:  "cat",
+				},
+				{
+					name:   "no words",
+					userID: "alice",
+					query:  "?!",
+				},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					if diff := cmp.Diff(tt.want, search(t, s, tt.userID, tt.query)); diff != "" {
+						t.Errorf("Search() memories mismatch (-want +got):\n%s", diff)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestMemoryService_AddSession(t *testing.T) {
+	ctx := t.Context()
+	s := newService(t, "add_session", true)
+	sessions := session.InMemoryService()
+	sess := newSession(t, sessions, "alice", "s1", "The quick brown fox", "jumps over the lazy dog")
+	count := func() int64 {
+		t.Helper()
+		var n int64
+		if err := s.db.Model(&storageEntry{}).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	for range 2 {
+		if err := s.AddSession(ctx, sess); err != nil {
+			t.Fatalf("AddSession() failed: %v", err)
+		}
+	}
+	if got := count(); got != 2 {
+		t.Errorf("memories after AddSession() twice = %d, want 2", got)
+	}
+
+	// Only the new events are added, even from a session with only its
+	// recent events.
+	addEvents(t, sessions, sess, "the fox sleeps")
+	recent, err := sessions.Get(ctx, &session.GetRequest{AppName: "app", UserID: "alice", SessionID: "s1", NumRecentEvents: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddSession(ctx, recent.Session); err != nil {
+		t.Fatalf("AddSession() failed: %v", err)
+	}
+	if got := count(); got != 3 {
+		t.Errorf("memories after AddSession() of a new event = %d, want 3", got)
+	}
+	if diff := cmp.Diff([]string{"the fox sleeps", "The quick brown fox"}, search(t, s, "alice", "fox")); diff != "" {
+		t.Errorf("Search() memories mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAutoMigrate_IndexesExistingMemories(t *testing.T) {
+	s := newService(t, "migrate", false)
+	sessions := session.InMemoryService()
+	if err := s.AddSession(t.Context(), newSession(t, sessions, "alice", "s1", "The quick brown fox")); err != nil {
+		t.Fatal(err)
+	}
+	if err := AutoMigrate(s); err != nil {
+		t.Fatal(err)
+	}
+	if !s.db.Migrator().HasTable(searchIndexTable) {
+		t.Fatal("AutoMigrate() did not create the search index")
+	}
+	if diff := cmp.Diff([]string{"The quick brown fox"}, search(t, s, "alice", "fox")); diff != "" {
+		t.Errorf("Search() memories mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAutoMigrate_InvalidService(t *testing.T) {
+	if err := AutoMigrate(memory.InMemoryService()); err == nil {
+		t.Error("AutoMigrate() succeeded, want error")
+	}
+}
+
+func TestStorageEntry_Timestamp(t *testing.T) {
+	s := newService(t, "timestamp", true)
+	sessions := session.InMemoryService()
+	created, err := sessions.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	ev := &session.Event{ID: "e1", Author: "agent", Timestamp: ts, LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("remember the milk", genai.RoleModel)}}
+	if err := sessions.AppendEvent(t.Context(), created.Session, ev); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddSession(t.Context(), created.Session); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.Search(t.Context(), &memory.SearchRequest{AppName: "app", UserID: "alice", Query: "milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []memory.Entry{{Content: genai.NewContentFromText("remember the milk", genai.RoleModel), Author: "agent", Timestamp: ts}}
+	if diff := cmp.Diff(want, resp.Memories); diff != "" {
+		t.Errorf("Search() memories mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vector
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// Embedder computes the embeddings of texts. The embeddings of the
+// documents and of the queries must be comparable: same model, same
+// dimension.
+type Embedder interface {
+	// EmbedDocuments returns the embeddings of the texts, in order.
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+	// EmbedQuery returns the embedding of a search query. Some models
+	// embed the queries differently from the documents they match.
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+}
+
+// genAIBatchSize is the maximum number of texts embedded by a request of
+// GenAIEmbedder.
+const genAIBatchSize = 100
+
+// GenAIEmbedder returns an Embedder using an embedding model of the Gemini
+// API or Vertex AI, e.g. "gemini-embedding-001".
+func GenAIEmbedder(client *genai.Client, model string) Embedder {
+	return &genAIEmbedder{client: client, model: model}
+}
+
+type genAIEmbedder struct {
+	client *genai.Client
+	model  string
+}
+
+func (e *genAIEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	var embeddings [][]float32
+	for start := 0; start < len(texts); start += genAIBatchSize {
+		batch, err := e.embed(ctx, texts[start:min(start+genAIBatchSize, len(texts))], "RETRIEVAL_DOCUMENT")
+		if err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, batch...)
+	}
+	return embeddings, nil
+}
+
+func (e *genAIEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.embed(ctx, []string{text}, "RETRIEVAL_QUERY")
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (e *genAIEmbedder) embed(ctx context.Context, texts []string, taskType string) ([][]float32, error) {
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	resp, err := e.client.Models.EmbedContent(ctx, e.model, contents, &genai.EmbedContentConfig{TaskType: taskType})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed texts: %w", err)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Embeddings), len(texts))
+	}
+	embeddings := make([][]float32, len(texts))
+	for i, embedding := range resp.Embeddings {
+		embeddings[i] = embedding.Values
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,347 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vector provides a semantic memory.Service, which finds the
+// memories by the similarity of their embeddings to the query.
+//
+// The text of the events of the sessions is split into chunks, embedded by
+// an Embedder, and kept in a Store, in memory or in SQLite. Search ranks
+// the chunks of the user by the cosine similarity of their embeddings to
+// the embedding of the query, optionally combined with the keyword overlap,
+// and returns the best ones.
+package vector
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
+	"github.com/sjzsdu/adk-go/memory"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+const (
+	// DefaultChunkSize is the default number of words of the chunks, see
+	// Config.ChunkSize.
+	DefaultChunkSize = 200
+	// DefaultChunkOverlap is the default number of words shared by
+	// consecutive chunks, see Config.ChunkOverlap.
+	DefaultChunkOverlap = 20
+	// DefaultTopK is the default maximum number of memories returned by
+	// Search, see Config.TopK.
+	DefaultTopK = 10
+)
+
+// Config is the configuration of the vector memory service.
+type Config struct {
+	// Embedder computes the embeddings of the chunks and of the queries.
+	Embedder Embedder
+	// Store keeps the chunks.
+	// Optional: if nil, the chunks are kept in memory, see InMemoryStore.
+	Store Store
+	// ChunkSize is the maximum number of words of a chunk. The text of an
+	// event longer than that is split into several chunks.
+	// Optional: if zero, DefaultChunkSize is used.
+	ChunkSize int
+	// ChunkOverlap is the number of words shared by consecutive chunks of
+	// an event, so that a sentence cut by a chunk boundary is still found.
+	// Optional: if zero, DefaultChunkOverlap is used, up to half the chunk
+	// size, if negative, chunks do not overlap.
+	ChunkOverlap int
+	// TopK is the maximum number of memories returned by Search.
+	// Optional: if zero, DefaultTopK is used.
+	TopK int
+	// MinScore is the minimum score of the memories returned by Search.
+	// The score of a memory is the cosine similarity of its embedding to
+	// the embedding of the query, in [-1, 1], combined with its keyword
+	// score, see KeywordWeight. Optional: if zero, the memories with a
+	// positive score are returned.
+	MinScore float64
+	// KeywordWeight is the weight of the keyword score in the score of the
+	// memories, in [0, 1], the weight of the cosine similarity being
+	// 1-KeywordWeight. The keyword score is the fraction of the words of
+	// the query found in the memory. Optional: if zero, the memories are
+	// ranked by their cosine similarity only.
+	KeywordWeight float64
+}
+
+// vectorService is the memory.Service returned by NewMemoryService.
+type vectorService struct {
+	embedder      Embedder
+	store         Store
+	chunkSize     int
+	chunkOverlap  int
+	topK          int
+	minScore      float64
+	keywordWeight float64
+}
+
+// NewMemoryService creates a memory.Service searching the memories by the
+// similarity of their embeddings to the query.
+//
+// AddSession can be called repeatedly for the same session: the chunks
+// already stored are neither embedded nor stored again, and the chunks of
+// the events no longer in the session are deleted.
+func NewMemoryService(cfg Config) (memory.Service, error) {
+	if cfg.Embedder == nil {
+		return nil, fmt.Errorf("embedder is required")
+	}
+	s := &vectorService{
+		embedder:      cfg.Embedder,
+		store:         cfg.Store,
+		chunkSize:     cmp.Or(cfg.ChunkSize, DefaultChunkSize),
+		chunkOverlap:  max(cfg.ChunkOverlap, 0),
+		topK:          cmp.Or(cfg.TopK, DefaultTopK),
+		minScore:      cfg.MinScore,
+		keywordWeight: cfg.KeywordWeight,
+	}
+	if s.store == nil {
+		s.store = InMemoryStore()
+	}
+	if s.chunkSize < 0 {
+		return nil, fmt.Errorf("chunk size must not be negative, got %d", cfg.ChunkSize)
+	}
+	if cfg.ChunkOverlap == 0 {
+		s.chunkOverlap = min(DefaultChunkOverlap, s.chunkSize/2)
+	}
+	if s.chunkOverlap >= s.chunkSize {
+		return nil, fmt.Errorf("chunk overlap %d must be less than the chunk size %d", s.chunkOverlap, s.chunkSize)
+	}
+	if s.topK < 0 {
+		return nil, fmt.Errorf("top k must not be negative, got %d", cfg.TopK)
+	}
+	if s.keywordWeight < 0 || s.keywordWeight > 1 {
+		return nil, fmt.Errorf("keyword weight must be in [0, 1], got %v", cfg.KeywordWeight)
+	}
+	return s, nil
+}
+
+// AddSession chunks the text of the events of the session, embeds the new
+// chunks, and replaces the chunks of the session in the store.
+func (s *vectorService) AddSession(ctx context.Context, curSession session.Session) error {
+	appName, userID, sessionID := curSession.AppName(), curSession.UserID(), curSession.ID()
+
+	var chunks []Chunk
+	seen := make(map[string]bool)
+	for event := range curSession.Events().All() {
+		if event.Partial || event.Content == nil {
+			continue
+		}
+		var texts []string
+		for _, part := range event.Content.Parts {
+			if part != nil && part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		for _, text := range s.split(strings.Join(texts, "\n")) {
+			hash := chunkHash(text)
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			chunks = append(chunks, Chunk{
+				Hash:      hash,
+				EventID:   event.ID,
+				Author:    event.Author,
+				Role:      event.Content.Role,
+				Timestamp: event.Timestamp,
+				Text:      text,
+			})
+		}
+	}
+
+	stored, err := s.store.SessionChunks(ctx, appName, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	vectors := make(map[string][]float32)
+	for _, c := range stored {
+		vectors[c.Hash] = c.Vector
+	}
+	var newTexts []string
+	var newChunks []*Chunk
+	for i := range chunks {
+		if v, ok := vectors[chunks[i].Hash]; ok {
+			chunks[i].Vector = v
+			continue
+		}
+		newTexts = append(newTexts, chunks[i].Text)
+		newChunks = append(newChunks, &chunks[i])
+	}
+	if len(newTexts) > 0 {
+		embeddings, err := s.embedder.EmbedDocuments(ctx, newTexts)
+		if err != nil {
+			return fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		if len(embeddings) != len(newTexts) {
+			return fmt.Errorf("embedder returned %d embeddings for %d chunks", len(embeddings), len(newTexts))
+		}
+		for i, c := range newChunks {
+			c.Vector = embeddings[i]
+		}
+	}
+	return s.store.ReplaceSessionChunks(ctx, appName, userID, sessionID, chunks)
+}
+
+// split splits a text into chunks of at most s.chunkSize words, sharing
+// s.chunkOverlap words.
+func (s *vectorService) split(text string) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) <= s.chunkSize {
+		return []string{strings.Join(words, " ")}
+	}
+	var chunks []string
+	for start := 0; ; start += s.chunkSize - s.chunkOverlap {
+		end := min(start+s.chunkSize, len(words))
+		chunks = append(chunks, strings.Join(words[start:end], " "))
+		if end == len(words) {
+			return chunks
+		}
+	}
+}
+
+// chunkHash returns the hash identifying the text of a chunk.
+func chunkHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// scoredChunk is a chunk with its score for a query.
+type scoredChunk struct {
+	*Chunk
+	score float64
+}
+
+// Search returns the chunks of the user with the best scores for the
+// query, the best first. Chunks with the same text, e.g. from different
+// sessions, are returned once.
+func (s *vectorService) Search(ctx context.Context, req *memory.SearchRequest) (*memory.SearchResponse, error) {
+	chunks, err := s.store.UserChunks(ctx, req.AppName, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || strings.TrimSpace(req.Query) == "" {
+		return &memory.SearchResponse{}, nil
+	}
+
+	var query []float32
+	if s.keywordWeight < 1 {
+		query, err = s.embedder.EmbedQuery(ctx, req.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+	}
+	terms := sessionutils.SearchTerms(req.Query)
+
+	best := make(map[string]scoredChunk)
+	for i := range chunks {
+		c := &chunks[i]
+		var score float64
+		if s.keywordWeight < 1 {
+			similarity, err := cosine(query, c.Vector)
+			if err != nil {
+				return nil, fmt.Errorf("chunk %q of session %q: %w", c.Hash, c.SessionID, err)
+			}
+			score = (1 - s.keywordWeight) * similarity
+		}
+		if s.keywordWeight > 0 {
+			score += s.keywordWeight * keywordScore(c.Text, terms)
+		}
+		if score < s.minScore || (s.minScore == 0 && score <= 0) {
+			continue
+		}
+		// Of the chunks with the same text, the most recent is kept.
+		if prev, ok := best[c.Hash]; !ok || c.Timestamp.After(prev.Timestamp) {
+			best[c.Hash] = scoredChunk{Chunk: c, score: score}
+		}
+	}
+
+	scored := make([]scoredChunk, 0, len(best))
+	for _, c := range best {
+		scored = append(scored, c)
+	}
+	slices.SortFunc(scored, func(a, b scoredChunk) int {
+		return cmp.Or(
+			cmp.Compare(b.score, a.score),
+			b.Timestamp.Compare(a.Timestamp),
+			strings.Compare(a.SessionID, b.SessionID),
+			strings.Compare(a.Hash, b.Hash),
+		)
+	})
+	if len(scored) > s.topK {
+		scored = scored[:s.topK]
+	}
+
+	resp := &memory.SearchResponse{Memories: make([]memory.Entry, 0, len(scored))}
+	for _, c := range scored {
+		resp.Memories = append(resp.Memories, memory.Entry{
+			Content:   genai.NewContentFromText(c.Text, genai.Role(c.Role)),
+			Author:    c.Author,
+			Timestamp: c.Timestamp,
+		})
+	}
+	return resp, nil
+}
+
+// cosine returns the cosine similarity of two vectors, 0 if one of them is
+// zero.
+func cosine(a, b []float32) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("embedding dimension %d differs from the dimension %d of the query", len(b), len(a))
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0, nil
+	}
+	return dot / math.Sqrt(normA*normB), nil
+}
+
+// keywordScore returns the fraction of the distinct terms found in the
+// text.
+func keywordScore(text string, terms []string) float64 {
+	distinct := make(map[string]bool)
+	for _, term := range terms {
+		distinct[term] = true
+	}
+	if len(distinct) == 0 {
+		return 0
+	}
+	words := make(map[string]bool)
+	for _, word := range sessionutils.SearchTerms(text) {
+		words[word] = true
+	}
+	var found int
+	for term := range distinct {
+		if words[term] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(distinct))
+}
@@ -0,0 +1,322 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vector_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/memory"
+	"github.com/sjzsdu/adk-go/memory/vector"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// concepts are the dimensions of the embeddings of fakeEmbedder, and the
+// words embedded along them.
+var concepts = [][]string{
+	{"dog", "puppy", "bark", "barks"},
+	{"cat", "kitten", "meow"},
+	{"car", "vehicle", "drive", "engine"},
+	{"pizza", "food", "eat", "dinner"},
+}
+
+// fakeEmbedder embeds the texts along the concepts of their words.
+type fakeEmbedder struct {
+	mu sync.Mutex
+	// embedded are the documents embedded.
+	embedded []string
+}
+
+func (e *fakeEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.embedded = append(e.embedded, texts...)
+	e.mu.Unlock()
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i], _ = e.EmbedQuery(ctx, text)
+	}
+	return embeddings, nil
+}
+
+func (e *fakeEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	v := make([]float32, len(concepts))
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		word = strings.Trim(word, ".,?!")
+		for i, words := range concepts {
+			for _, w := range words {
+				if w == word {
+					v[i]++
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+func (e *fakeEmbedder) reset() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	embedded := e.embedded
+	e.embedded = nil
+	return embedded
+}
+
+func stores(t *testing.T) map[string]vector.Store {
+	t.Helper()
+	sqliteStore, err := vector.SQLiteStore("file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]vector.Store{
+		"inmemory": vector.InMemoryStore(),
+		"sqlite":   sqliteStore,
+	}
+}
+
+func makeSession(t *testing.T, service session.Service, userID, sessionID string, texts ...string) session.Session {
+	t.Helper()
+	ctx := t.Context()
+	created, err := service.Create(ctx, &session.CreateRequest{AppName: "app", UserID: userID, SessionID: sessionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, text := range texts {
+		ev := session.NewEvent("inv")
+		ev.Author = "user"
+		ev.Timestamp = start.Add(time.Duration(i) * time.Hour)
+		ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleUser)}
+		if err := service.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := service.Get(ctx, &session.GetRequest{AppName: "app", UserID: userID, SessionID: sessionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Session
+}
+
+func texts(resp *memory.SearchResponse) []string {
+	var got []string
+	for _, m := range resp.Memories {
+		got = append(got, m.Content.Parts[0].Text)
+	}
+	return got
+}
+
+func TestMemoryService_Search(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			sessions := session.InMemoryService()
+			embedder := &fakeEmbedder{}
+			sess := makeSession(t, sessions, "user", "s1",
+				"My dog barks at night.",
+				"I ate pizza for dinner.",
+				"The engine of my car is broken.",
+				"The engine of my car is broken.",
+			)
+			other := makeSession(t, sessions, "other", "s2", "My puppy is cute.")
+
+			tests := []struct {
+				name string
+				cfg  vector.Config
+				// query is the search query.
+				query string
+				want  []string
+			}{
+				{
+					name:  "semantic match",
+					cfg:   vector.Config{},
+					query: "Tell me about the puppy",
+					want:  []string{"My dog barks at night."},
+				},
+				{
+					name:  "top k",
+					cfg:   vector.Config{TopK: 1},
+					query: "puppy and food",
+					want:  []string{"I ate pizza for dinner."},
+				},
+				{
+					name:  "ranked by similarity",
+					cfg:   vector.Config{},
+					query: "dinner with pizza and a dog",
+					want:  []string{"I ate pizza for dinner.", "My dog barks at night."},
+				},
+				{
+					name:  "min score",
+					cfg:   vector.Config{MinScore: 0.8},
+					query: "dinner with pizza and a dog",
+					want:  []string{"I ate pizza for dinner."},
+				},
+				{
+					name:  "no match",
+					cfg:   vector.Config{},
+					query: "kitten",
+				},
+				{
+					name:  "hybrid",
+					cfg:   vector.Config{KeywordWeight: 0.8},
+					query: "night vehicle",
+					// The keyword "night" ranks the dog first, although the
+					// vehicle is semantically closer.
+					want: []string{"My dog barks at night.", "The engine of my car is broken."},
+				},
+				{
+					name:  "keywords only",
+					cfg:   vector.Config{KeywordWeight: 1},
+					query: "broken",
+					want:  []string{"The engine of my car is broken."},
+				},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					tt.cfg.Embedder = embedder
+					tt.cfg.Store = store
+					service, err := vector.NewMemoryService(tt.cfg)
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, s := range []session.Session{sess, other} {
+						if err := service.AddSession(ctx, s); err != nil {
+							t.Fatalf("AddSession() failed: %v", err)
+						}
+					}
+					resp, err := service.Search(ctx, &memory.SearchRequest{AppName: "app", UserID: "user", Query: tt.query})
+					if err != nil {
+						t.Fatalf("Search() failed: %v", err)
+					}
+					if diff := cmp.Diff(tt.want, texts(resp)); diff != "" {
+						t.Errorf("Search() memories mismatch (-want +got):\n%s", diff)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestMemoryService_AddSession(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			sessions := session.InMemoryService()
+			embedder := &fakeEmbedder{}
+			service, err := vector.NewMemoryService(vector.Config{Embedder: embedder, Store: store, ChunkSize: 4, ChunkOverlap: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			search := func(query string) []string {
+				t.Helper()
+				resp, err := service.Search(ctx, &memory.SearchRequest{AppName: "app", UserID: "user", Query: query})
+				if err != nil {
+					t.Fatalf("Search() failed: %v", err)
+				}
+				return texts(resp)
+			}
+
+			sess := makeSession(t, sessions, "user", "s1", "my dog barks a lot at the cat", "pizza")
+			if err := service.AddSession(ctx, sess); err != nil {
+				t.Fatal(err)
+			}
+			wantEmbedded := []string{"my dog barks a", "a lot at the", "the cat", "pizza"}
+			if diff := cmp.Diff(wantEmbedded, embedder.reset()); diff != "" {
+				t.Errorf("AddSession() embedded chunks mismatch (-want +got):\n%s", diff)
+			}
+
+			// Adding the session again embeds and stores only the new
+			// chunks.
+			ev := session.NewEvent("inv2")
+			ev.LLMResponse = model.LLMResponse{Content: genai.NewContentFromText("a kitten", genai.RoleModel)}
+			if err := sessions.AppendEvent(ctx, sess, ev); err != nil {
+				t.Fatal(err)
+			}
+			for range 2 {
+				if err := service.AddSession(ctx, sess); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := cmp.Diff([]string{"a kitten"}, embedder.reset()); diff != "" {
+				t.Errorf("AddSession() embedded chunks mismatch (-want +got):\n%s", diff)
+			}
+			// Of equal scores, the most recent memory is first.
+			if diff := cmp.Diff([]string{"a kitten", "the cat"}, search("cat")); diff != "" {
+				t.Errorf("Search() memories mismatch (-want +got):\n%s", diff)
+			}
+
+			// The chunks of the events removed from the session are deleted.
+			rewound, err := sessions.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "s1", InvocationID: ev.InvocationID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := service.AddSession(ctx, rewound.Session); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff([]string{"the cat"}, search("cat")); diff != "" {
+				t.Errorf("Search() memories after rewind mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMemoryService_UserIsolation(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			service, err := vector.NewMemoryService(vector.Config{Embedder: &fakeEmbedder{}, Store: store})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sess := makeSession(t, session.InMemoryService(), "user", "s1", "My dog barks at night.")
+			if err := service.AddSession(ctx, sess); err != nil {
+				t.Fatal(err)
+			}
+			// An empty user ID matches no other user.
+			resp, err := service.Search(ctx, &memory.SearchRequest{AppName: "app", Query: "dog"})
+			if err != nil {
+				t.Fatalf("Search() failed: %v", err)
+			}
+			if got := texts(resp); len(got) != 0 {
+				t.Errorf("Search() of another user = %q, want no memories", got)
+			}
+		})
+	}
+}
+
+func TestNewMemoryService(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  vector.Config
+	}{
+		{name: "no embedder", cfg: vector.Config{}},
+		{name: "overlap too large", cfg: vector.Config{Embedder: &fakeEmbedder{}, ChunkSize: 10, ChunkOverlap: 10}},
+		{name: "negative top k", cfg: vector.Config{Embedder: &fakeEmbedder{}, TopK: -1}},
+		{name: "keyword weight out of range", cfg: vector.Config{Embedder: &fakeEmbedder{}, KeywordWeight: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := vector.NewMemoryService(tt.cfg); err == nil {
+				t.Error("NewMemoryService() succeeded, want error")
+			}
+		})
+	}
+}
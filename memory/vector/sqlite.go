@@ -0,0 +1,171 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vector
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// storageChunk corresponds to the 'memory_chunks' table.
+type storageChunk struct {
+	AppName   string `gorm:"primaryKey"`
+	UserID    string `gorm:"primaryKey"`
+	SessionID string `gorm:"primaryKey"`
+	Hash      string `gorm:"primaryKey"`
+	EventID   string
+	Author    string
+	Role      string
+	Timestamp time.Time `gorm:"precision:6"`
+	Text      string
+	// Vector is the embedding, as little-endian float32 values.
+	Vector []byte
+}
+
+// TableName explicitly sets the table name for the storageChunk struct.
+func (storageChunk) TableName() string {
+	return "memory_chunks"
+}
+
+// SQLiteStore returns a Store keeping the chunks in a SQLite database,
+// opened with the data source name dsn, e.g. the path of the database
+// file. The table of the chunks is created if it does not exist.
+func SQLiteStore(dsn string, opts ...gorm.Option) (Store, error) {
+	db, err := gorm.Open(sqlite.Open(dsn), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error opening memory database: %w", err)
+	}
+	if err := db.AutoMigrate(&storageChunk{}); err != nil {
+		return nil, fmt.Errorf("auto migrate failed: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// sessionCond selects the chunks of a session. The conditions are explicit
+// rather than struct conditions, which skip empty fields.
+const sessionCond = "app_name = ? AND user_id = ? AND session_id = ?"
+
+type sqliteStore struct {
+	db *gorm.DB
+}
+
+func (s *sqliteStore) SessionChunks(ctx context.Context, appName, userID, sessionID string) ([]Chunk, error) {
+	return s.find(s.db.WithContext(ctx).Where(sessionCond, appName, userID, sessionID))
+}
+
+// ReplaceSessionChunks only writes the chunks which are not already
+// stored, and deletes the others.
+func (s *sqliteStore) ReplaceSessionChunks(ctx context.Context, appName, userID, sessionID string, chunks []Chunk) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var hashes []string
+		if err := tx.Model(&storageChunk{}).Where(sessionCond, appName, userID, sessionID).Pluck("hash", &hashes).Error; err != nil {
+			return fmt.Errorf("database error while fetching chunks: %w", err)
+		}
+		stored := make(map[string]bool)
+		for _, hash := range hashes {
+			stored[hash] = true
+		}
+		kept := make(map[string]bool)
+		var created []storageChunk
+		for _, c := range chunks {
+			kept[c.Hash] = true
+			if !stored[c.Hash] {
+				created = append(created, storageChunk{
+					AppName:   appName,
+					UserID:    userID,
+					SessionID: sessionID,
+					Hash:      c.Hash,
+					EventID:   c.EventID,
+					Author:    c.Author,
+					Role:      c.Role,
+					Timestamp: c.Timestamp,
+					Text:      c.Text,
+					Vector:    encodeVector(c.Vector),
+				})
+			}
+		}
+		var deleted []string
+		for _, hash := range hashes {
+			if !kept[hash] {
+				deleted = append(deleted, hash)
+			}
+		}
+		if len(deleted) > 0 {
+			if err := tx.Where(sessionCond, appName, userID, sessionID).Where("hash IN ?", deleted).Delete(&storageChunk{}).Error; err != nil {
+				return fmt.Errorf("failed to delete chunks: %w", err)
+			}
+		}
+		if len(created) > 0 {
+			if err := tx.Create(&created).Error; err != nil {
+				return fmt.Errorf("failed to save chunks: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) UserChunks(ctx context.Context, appName, userID string) ([]Chunk, error) {
+	return s.find(s.db.WithContext(ctx).Where("app_name = ? AND user_id = ?", appName, userID))
+}
+
+func (s *sqliteStore) find(query *gorm.DB) ([]Chunk, error) {
+	var stored []storageChunk
+	if err := query.Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("database error while fetching chunks: %w", err)
+	}
+	chunks := make([]Chunk, len(stored))
+	for i, c := range stored {
+		vector, err := decodeVector(c.Vector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector of chunk %q of session %q: %w", c.Hash, c.SessionID, err)
+		}
+		chunks[i] = Chunk{
+			SessionID: c.SessionID,
+			Hash:      c.Hash,
+			EventID:   c.EventID,
+			Author:    c.Author,
+			Role:      c.Role,
+			Timestamp: c.Timestamp,
+			Text:      c.Text,
+			Vector:    vector,
+		}
+	}
+	return chunks, nil
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decodeVector(b []byte) ([]float32, error) {
+	if len(b)%4 != 0 {
+		return nil, fmt.Errorf("length %d is not a multiple of 4", len(b))
+	}
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v, nil
+}
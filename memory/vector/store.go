@@ -0,0 +1,103 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vector
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Chunk is a piece of the text of an event of a session, with its
+// embedding.
+type Chunk struct {
+	SessionID string
+	// Hash identifies the text of the chunk in its session: a session has
+	// a single chunk with a given text.
+	Hash string
+	// EventID, Author, Role and Timestamp describe the event the chunk is
+	// from.
+	EventID   string
+	Author    string
+	Role      string
+	Timestamp time.Time
+	Text      string
+	Vector    []float32
+}
+
+// Store stores the chunks of the sessions of the users.
+type Store interface {
+	// SessionChunks returns the chunks of a session.
+	SessionChunks(ctx context.Context, appName, userID, sessionID string) ([]Chunk, error)
+	// ReplaceSessionChunks replaces the chunks of a session. Their
+	// SessionID is ignored.
+	ReplaceSessionChunks(ctx context.Context, appName, userID, sessionID string, chunks []Chunk) error
+	// UserChunks returns the chunks of all the sessions of a user.
+	UserChunks(ctx context.Context, appName, userID string) ([]Chunk, error)
+}
+
+// InMemoryStore returns a Store keeping the chunks in memory. Thread-safe.
+func InMemoryStore() Store {
+	return &inMemoryStore{chunks: make(map[userKey]map[string][]Chunk)}
+}
+
+type userKey struct {
+	appName, userID string
+}
+
+type inMemoryStore struct {
+	mu sync.RWMutex
+	// chunks are the chunks of the sessions, by user and session ID.
+	chunks map[userKey]map[string][]Chunk
+}
+
+func (s *inMemoryStore) SessionChunks(ctx context.Context, appName, userID, sessionID string) ([]Chunk, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.chunks[userKey{appName, userID}][sessionID]), nil
+}
+
+func (s *inMemoryStore) ReplaceSessionChunks(ctx context.Context, appName, userID, sessionID string, chunks []Chunk) error {
+	chunks = slices.Clone(chunks)
+	for i := range chunks {
+		chunks[i].SessionID = sessionID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := userKey{appName, userID}
+	sessions, ok := s.chunks[k]
+	if !ok {
+		sessions = make(map[string][]Chunk)
+		s.chunks[k] = sessions
+	}
+	if len(chunks) == 0 {
+		delete(sessions, sessionID)
+		return nil
+	}
+	sessions[sessionID] = chunks
+	return nil
+}
+
+func (s *inMemoryStore) UserChunks(ctx context.Context, appName, userID string) ([]Chunk, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var chunks []Chunk
+	for _, sessionChunks := range s.chunks[userKey{appName, userID}] {
+		chunks = append(chunks, sessionChunks...)
+	}
+	return chunks, nil
+}
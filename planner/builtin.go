@@ -0,0 +1,56 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/model"
+)
+
+// NewBuiltIn creates a planner that relies on the model's built-in thinking
+// features.
+//
+// The given thinking config is set on every request. The model is expected
+// to support thinking, otherwise the request fails.
+func NewBuiltIn(thinkingConfig *genai.ThinkingConfig) Planner {
+	return &builtInPlanner{
+		thinkingConfig: thinkingConfig,
+	}
+}
+
+type builtInPlanner struct {
+	thinkingConfig *genai.ThinkingConfig
+}
+
+// BuildPlanningInstruction implements Planner. It sets the thinking config
+// on the request and does not add any instruction.
+func (p *builtInPlanner) BuildPlanningInstruction(ctx agent.ReadonlyContext, req *model.LLMRequest) string {
+	if p.thinkingConfig == nil {
+		return ""
+	}
+	if req.Config == nil {
+		req.Config = &genai.GenerateContentConfig{}
+	}
+	req.Config.ThinkingConfig = p.thinkingConfig
+	return ""
+}
+
+// ProcessPlanningResponse implements Planner. Responses are left unchanged,
+// since the model marks its thoughts itself.
+func (p *builtInPlanner) ProcessPlanningResponse(ctx agent.CallbackContext, parts []*genai.Part) []*genai.Part {
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package planner provides planners that let an LLM agent plan before acting.
+//
+// A planner is attached to an agent via llmagent.Config.Planner. Before each
+// model call the planner can add planning instructions to the request, and
+// after each model response it can post-process the response parts, e.g. to
+// separate the model's reasoning from the user-visible answer.
+package planner
+
+import (
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/model"
+)
+
+// Planner guides the agent to generate plans for the queries.
+type Planner interface {
+	// BuildPlanningInstruction returns the planning instruction that is
+	// appended to the system instruction of the request. An empty string
+	// means no instruction is added.
+	//
+	// Implementations may also adjust the request itself, e.g. to configure
+	// model thinking.
+	BuildPlanningInstruction(ctx agent.ReadonlyContext, req *model.LLMRequest) string
+	// ProcessPlanningResponse post-processes the parts of an LLM response.
+	// If it returns nil, the response is left unchanged.
+	ProcessPlanningResponse(ctx agent.CallbackContext, parts []*genai.Part) []*genai.Part
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/planner"
+)
+
+func TestPlanReAct_ProcessPlanningResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		parts []*genai.Part
+		want  []*genai.Part
+	}{
+		{
+			name:  "empty",
+			parts: nil,
+			want:  nil,
+		},
+		{
+			name: "splits final answer",
+			parts: []*genai.Part{
+				{Text: planner.PlanningTag + " 1. think " + planner.FinalAnswerTag + " 42"},
+			},
+			want: []*genai.Part{
+				{Text: planner.PlanningTag + " 1. think " + planner.FinalAnswerTag, Thought: true},
+				{Text: " 42"},
+			},
+		},
+		{
+			name: "marks tagged parts as thoughts",
+			parts: []*genai.Part{
+				{Text: planner.PlanningTag + " plan"},
+				{Text: planner.ReasoningTag + " reason"},
+				{Text: "plain text"},
+			},
+			want: []*genai.Part{
+				{Text: planner.PlanningTag + " plan", Thought: true},
+				{Text: planner.ReasoningTag + " reason", Thought: true},
+				{Text: "plain text"},
+			},
+		},
+		{
+			name: "stops after first group of function calls",
+			parts: []*genai.Part{
+				{Text: planner.ActionTag + " call tools"},
+				{FunctionCall: &genai.FunctionCall{Name: ""}},
+				{FunctionCall: &genai.FunctionCall{Name: "a"}},
+				{FunctionCall: &genai.FunctionCall{Name: "b"}},
+				{Text: "dropped"},
+				{FunctionCall: &genai.FunctionCall{Name: "c"}},
+			},
+			want: []*genai.Part{
+				{Text: planner.ActionTag + " call tools", Thought: true},
+				{FunctionCall: &genai.FunctionCall{Name: "a"}},
+				{FunctionCall: &genai.FunctionCall{Name: "b"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planner.NewPlanReAct().ProcessPlanningResponse(nil, tt.parts)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ProcessPlanningResponse() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPlanReAct_BuildPlanningInstruction(t *testing.T) {
+	got := planner.NewPlanReAct().BuildPlanningInstruction(nil, &model.LLMRequest{})
+	for _, tag := range []string{planner.PlanningTag, planner.ReplanningTag, planner.ReasoningTag, planner.ActionTag, planner.FinalAnswerTag} {
+		if !strings.Contains(got, tag) {
+			t.Errorf("instruction does not mention %s", tag)
+		}
+	}
+}
+
+func TestBuiltIn(t *testing.T) {
+	thinkingConfig := &genai.ThinkingConfig{IncludeThoughts: true}
+	p := planner.NewBuiltIn(thinkingConfig)
+
+	req := &model.LLMRequest{}
+	if got := p.BuildPlanningInstruction(nil, req); got != "" {
+		t.Errorf("BuildPlanningInstruction() = %q, want empty", got)
+	}
+	if req.Config == nil || req.Config.ThinkingConfig != thinkingConfig {
+		t.Errorf("thinking config not applied to request: %+v", req.Config)
+	}
+
+	parts := []*genai.Part{{Text: "answer"}}
+	if got := p.ProcessPlanningResponse(nil, parts); got != nil {
+		t.Errorf("ProcessPlanningResponse() = %v, want nil", got)
+	}
+}
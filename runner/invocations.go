@@ -0,0 +1,208 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CancelledErrorCode is the error code of the event that ends a cancelled
+// invocation.
+const CancelledErrorCode = "CANCELLED"
+
+var (
+	// ErrInvocationCancelled is the error yielded by a run whose invocation
+	// was cancelled, see Runner.Cancel.
+	ErrInvocationCancelled = errors.New("invocation cancelled")
+	// ErrInvocationNotFound is returned when cancelling an invocation that
+	// is not in flight.
+	ErrInvocationNotFound = errors.New("invocation not found")
+	// ErrInvocationInterrupted is the error yielded by a run interrupted by
+	// a newer run of the session, see SessionConcurrencyInterrupt. It wraps
+	// ErrInvocationCancelled.
+	ErrInvocationInterrupted = fmt.Errorf("%w: interrupted by a newer run of the session", ErrInvocationCancelled)
+)
+
+// SessionConcurrency is the policy for a run of a session while another run
+// of the session is in flight.
+type SessionConcurrency int
+
+const (
+	// SessionConcurrencyAllow runs both runs concurrently. Their events and
+	// state changes may interleave.
+	SessionConcurrencyAllow SessionConcurrency = iota
+	// SessionConcurrencyReject fails the new run with a *SessionBusyError.
+	SessionConcurrencyReject
+	// SessionConcurrencyQueue waits for the prior runs to finish.
+	SessionConcurrencyQueue
+	// SessionConcurrencyInterrupt cancels the prior run, which ends with
+	// ErrInvocationInterrupted, and runs once it finished.
+	SessionConcurrencyInterrupt
+)
+
+// SessionBusyError is the error yielded by a run rejected because of
+// another run of the session, see SessionConcurrencyReject.
+type SessionBusyError struct {
+	AppName   string
+	UserID    string
+	SessionID string
+	// InvocationID is the ID of the invocation in flight, if it started.
+	InvocationID string
+}
+
+func (e *SessionBusyError) Error() string {
+	if e.InvocationID == "" {
+		return fmt.Sprintf("session %q is busy with another run", e.SessionID)
+	}
+	return fmt.Sprintf("session %q is busy with invocation %q", e.SessionID, e.InvocationID)
+}
+
+// InvocationInfo describes an invocation in flight.
+type InvocationInfo struct {
+	InvocationID string
+	AppName      string
+	UserID       string
+	SessionID    string
+	// AgentName is the name of the agent the invocation started with.
+	AgentName string
+	StartTime time.Time
+}
+
+// InvocationRegistry tracks the invocations in flight, so that they can be
+// listed and cancelled. A registry can be shared by several runners, see
+// Config.Invocations.
+type InvocationRegistry struct {
+	mu     sync.Mutex
+	active map[string]*activeInvocation
+	// sessions holds the sessions locked by a run, see SessionConcurrency.
+	sessions map[sessionKey]*sessionLock
+}
+
+type sessionKey struct {
+	appName, userID, sessionID string
+}
+
+// sessionLock is held by a run of a session.
+type sessionLock struct {
+	// cancel cancels the run holding the lock.
+	cancel context.CancelCauseFunc
+	// released is closed once the run released the lock.
+	released chan struct{}
+}
+
+type activeInvocation struct {
+	info   InvocationInfo
+	cancel context.CancelCauseFunc
+}
+
+// NewInvocationRegistry creates an empty InvocationRegistry.
+func NewInvocationRegistry() *InvocationRegistry {
+	return &InvocationRegistry{
+		active:   make(map[string]*activeInvocation),
+		sessions: make(map[sessionKey]*sessionLock),
+	}
+}
+
+// ListActive returns the invocations in flight, the oldest first.
+func (r *InvocationRegistry) ListActive() []InvocationInfo {
+	r.mu.Lock()
+	infos := make([]InvocationInfo, 0, len(r.active))
+	for _, inv := range r.active {
+		infos = append(infos, inv.info)
+	}
+	r.mu.Unlock()
+	slices.SortFunc(infos, func(a, b InvocationInfo) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return infos
+}
+
+// Cancel cancels the invocation in flight with the given ID. The run ends
+// with a terminal event with CancelledErrorCode, which is stored in the
+// session, followed by ErrInvocationCancelled.
+func (r *InvocationRegistry) Cancel(invocationID string) error {
+	r.mu.Lock()
+	inv, ok := r.active[invocationID]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvocationNotFound, invocationID)
+	}
+	inv.cancel(ErrInvocationCancelled)
+	return nil
+}
+
+// register adds an invocation in flight and returns the function removing
+// it.
+func (r *InvocationRegistry) register(info InvocationInfo, cancel context.CancelCauseFunc) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inv := &activeInvocation{info: info, cancel: cancel}
+	r.active[info.InvocationID] = inv
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		// A resumed invocation may have registered again under the same ID.
+		if r.active[info.InvocationID] == inv {
+			delete(r.active, info.InvocationID)
+		}
+	}
+}
+
+// lockSession locks the session for a run according to the policy and
+// returns the function unlocking it. The run is cancelled with cancel if a
+// newer run interrupts it.
+func (r *InvocationRegistry) lockSession(ctx context.Context, key sessionKey, policy SessionConcurrency, cancel context.CancelCauseFunc) (func(), error) {
+	for {
+		r.mu.Lock()
+		held, ok := r.sessions[key]
+		if !ok {
+			lock := &sessionLock{cancel: cancel, released: make(chan struct{})}
+			r.sessions[key] = lock
+			r.mu.Unlock()
+			return func() {
+				r.mu.Lock()
+				delete(r.sessions, key)
+				r.mu.Unlock()
+				close(lock.released)
+			}, nil
+		}
+		switch policy {
+		case SessionConcurrencyReject:
+			busy := &SessionBusyError{AppName: key.appName, UserID: key.userID, SessionID: key.sessionID}
+			for _, inv := range r.active {
+				if inv.info.AppName == key.appName && inv.info.UserID == key.userID && inv.info.SessionID == key.sessionID {
+					busy.InvocationID = inv.info.InvocationID
+				}
+			}
+			r.mu.Unlock()
+			return nil, busy
+		case SessionConcurrencyInterrupt:
+			held.cancel(ErrInvocationInterrupted)
+		}
+		r.mu.Unlock()
+
+		// Another waiting run may take the lock first, so check again.
+		select {
+		case <-held.released:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/agent/llmagent"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/plugin"
+	"github.com/sjzsdu/adk-go/runner"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// blockingModel blocks until the request context is done.
+type blockingModel struct{}
+
+func (blockingModel) Name() string { return "blocking-model" }
+
+func (blockingModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		<-ctx.Done()
+		yield(nil, ctx.Err())
+	}
+}
+
+func TestRunner_Cancel(t *testing.T) {
+	a, err := llmagent.New(llmagent.Config{Name: "slow_agent", Model: blockingModel{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	afterRun := make(chan error, 1)
+	p, err := plugin.New(plugin.Config{
+		Name: "after_run",
+		AfterRunCallback: func(ctx agent.InvocationContext) {
+			afterRun <- ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	registry := runner.NewInvocationRegistry()
+	r, err := runner.New(runner.Config{
+		AppName:        "app",
+		Agent:          a,
+		SessionService: sessionService,
+		PluginConfig:   runner.PluginConfig{Plugins: []*plugin.Plugin{p}},
+		Invocations:    registry,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Cancel("unknown"); !errors.Is(err, runner.ErrInvocationNotFound) {
+		t.Errorf("Cancel() of an unknown invocation = %v, want ErrInvocationNotFound", err)
+	}
+
+	type result struct {
+		events []*session.Event
+		errs   []error
+	}
+	done := make(chan result)
+	go func() {
+		var res result
+		for ev, err := range r.Run(t.Context(), "user", created.Session.ID(), genai.NewContentFromText("hi", genai.RoleUser), agent.RunConfig{}) {
+			if err != nil {
+				res.errs = append(res.errs, err)
+				continue
+			}
+			res.events = append(res.events, ev)
+		}
+		done <- res
+	}()
+
+	var active []runner.InvocationInfo
+	for deadline := time.Now().Add(5 * time.Second); len(active) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the invocation is not listed as active")
+		}
+		time.Sleep(time.Millisecond)
+		active = r.ListActive()
+	}
+	info := active[0]
+	if info.AppName != "app" || info.UserID != "user" || info.SessionID != created.Session.ID() || info.AgentName != "slow_agent" {
+		t.Errorf("ListActive() = %+v, want the running invocation", active)
+	}
+	if got := registry.ListActive(); len(got) != 1 {
+		t.Errorf("registry.ListActive() = %+v, want the running invocation", got)
+	}
+
+	if err := r.Cancel(info.InvocationID); err != nil {
+		t.Fatalf("Cancel() failed: %v", err)
+	}
+	res := <-done
+
+	if len(res.errs) != 1 || !errors.Is(res.errs[0], runner.ErrInvocationCancelled) {
+		t.Errorf("got errors %v, want ErrInvocationCancelled", res.errs)
+	}
+	if len(res.events) != 1 || res.events[0].ErrorCode != runner.CancelledErrorCode || res.events[0].InvocationID != info.InvocationID {
+		t.Fatalf("got events %+v, want the cancelled event", res.events)
+	}
+	if err := <-afterRun; err != nil {
+		t.Errorf("after run callback got a done context: %v", err)
+	}
+	if got := r.ListActive(); len(got) != 0 {
+		t.Errorf("ListActive() after the run = %+v, want none", got)
+	}
+
+	resp, err := sessionService.Get(t.Context(), &session.GetRequest{AppName: "app", UserID: "user", SessionID: created.Session.ID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := resp.Session.Events()
+	if last := events.At(events.Len() - 1); last.ErrorCode != runner.CancelledErrorCode {
+		t.Errorf("last stored event = %+v, want the cancelled event", last)
+	}
+}
+
+// gatedModel answers once released, and reports each request on started.
+type gatedModel struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *gatedModel) Name() string { return "gated-model" }
+
+func (m *gatedModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		m.started <- struct{}{}
+		select {
+		case <-m.release:
+			yield(&model.LLMResponse{Content: genai.NewContentFromText("done", genai.RoleModel)}, nil)
+		case <-ctx.Done():
+			yield(nil, ctx.Err())
+		}
+	}
+}
+
+func TestRunner_SessionConcurrency(t *testing.T) {
+	type result struct {
+		events []*session.Event
+		err    error
+	}
+	tests := []struct {
+		name   string
+		policy runner.SessionConcurrency
+		// check checks the results of the first and the second run. The
+		// model is released once the second run started or finished.
+		check func(t *testing.T, first, second <-chan result, m *gatedModel, activeID string)
+	}{
+		{
+			name:   "reject",
+			policy: runner.SessionConcurrencyReject,
+			check: func(t *testing.T, first, second <-chan result, m *gatedModel, activeID string) {
+				var busy *runner.SessionBusyError
+				if res := <-second; !errors.As(res.err, &busy) || busy.InvocationID != activeID {
+					t.Errorf("second run error = %v, want a SessionBusyError for invocation %q", res.err, activeID)
+				}
+				close(m.release)
+				if res := <-first; res.err != nil {
+					t.Errorf("first run error = %v", res.err)
+				}
+			},
+		},
+		{
+			name:   "queue",
+			policy: runner.SessionConcurrencyQueue,
+			check: func(t *testing.T, first, second <-chan result, m *gatedModel, activeID string) {
+				select {
+				case <-m.started:
+					t.Fatal("second run started before the first one finished")
+				case <-time.After(50 * time.Millisecond):
+				}
+				close(m.release)
+				if res := <-first; res.err != nil {
+					t.Errorf("first run error = %v", res.err)
+				}
+				<-m.started
+				if res := <-second; res.err != nil {
+					t.Errorf("second run error = %v", res.err)
+				}
+			},
+		},
+		{
+			name:   "interrupt",
+			policy: runner.SessionConcurrencyInterrupt,
+			check: func(t *testing.T, first, second <-chan result, m *gatedModel, activeID string) {
+				res := <-first
+				if !errors.Is(res.err, runner.ErrInvocationInterrupted) || !errors.Is(res.err, runner.ErrInvocationCancelled) {
+					t.Errorf("first run error = %v, want ErrInvocationInterrupted", res.err)
+				}
+				if n := len(res.events); n == 0 || res.events[n-1].ErrorCode != runner.CancelledErrorCode {
+					t.Errorf("first run events = %+v, want the cancelled event last", res.events)
+				}
+				<-m.started
+				close(m.release)
+				if res := <-second; res.err != nil {
+					t.Errorf("second run error = %v", res.err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &gatedModel{started: make(chan struct{}, 2), release: make(chan struct{})}
+			a, err := llmagent.New(llmagent.Config{Name: "agent", Model: m})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sessionService := session.InMemoryService()
+			r, err := runner.New(runner.Config{
+				AppName:            "app",
+				Agent:              a,
+				SessionService:     sessionService,
+				SessionConcurrency: tt.policy,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			run := func() <-chan result {
+				done := make(chan result, 1)
+				go func() {
+					var res result
+					for ev, err := range r.Run(t.Context(), "user", created.Session.ID(), genai.NewContentFromText("hi", genai.RoleUser), agent.RunConfig{}) {
+						if err != nil {
+							res.err = err
+							break
+						}
+						res.events = append(res.events, ev)
+					}
+					done <- res
+				}()
+				return done
+			}
+
+			first := run()
+			<-m.started
+			activeID := r.ListActive()[0].InvocationID
+			tt.check(t, first, run(), m, activeID)
+
+			// The events of the runs did not interleave.
+			resp, err := sessionService.Get(t.Context(), &session.GetRequest{AppName: "app", UserID: "user", SessionID: created.Session.ID()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var invocations []string
+			for ev := range resp.Session.Events().All() {
+				if len(invocations) == 0 || invocations[len(invocations)-1] != ev.InvocationID {
+					invocations = append(invocations, ev.InvocationID)
+				}
+			}
+			if len(invocations) > 2 || invocations[0] != activeID {
+				t.Errorf("session events are from invocations %v, want the first run's then the second run's", invocations)
+			}
+		})
+	}
+}
+
+func TestRunner_SessionConcurrency_QueueTimeout(t *testing.T) {
+	m := &gatedModel{started: make(chan struct{}, 2), release: make(chan struct{})}
+	a, err := llmagent.New(llmagent.Config{Name: "agent", Model: m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	r, err := runner.New(runner.Config{
+		AppName:            "app",
+		Agent:              a,
+		SessionService:     sessionService,
+		SessionConcurrency: runner.SessionConcurrencyQueue,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(cfg agent.RunConfig) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			var runErr error
+			for _, err := range r.Run(t.Context(), "user", created.Session.ID(), genai.NewContentFromText("hi", genai.RoleUser), cfg) {
+				if err != nil {
+					runErr = err
+					break
+				}
+			}
+			done <- runErr
+		}()
+		return done
+	}
+
+	first := run(agent.RunConfig{})
+	<-m.started
+	// The second run waits longer than its timeout for the first one, which
+	// does not count against the timeout.
+	second := run(agent.RunConfig{Timeout: 200 * time.Millisecond})
+	time.Sleep(400 * time.Millisecond)
+	close(m.release)
+	if err := <-first; err != nil {
+		t.Errorf("first run error = %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Errorf("second run error = %v, want no timeout", err)
+	}
+}
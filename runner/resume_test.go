@@ -0,0 +1,182 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner_test
+
+import (
+	"iter"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/agent/llmagent"
+	"github.com/sjzsdu/adk-go/agent/workflowagents/loopagent"
+	"github.com/sjzsdu/adk-go/agent/workflowagents/sequentialagent"
+	"github.com/sjzsdu/adk-go/internal/testutil"
+	"github.com/sjzsdu/adk-go/runner"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/tool"
+	"github.com/sjzsdu/adk-go/tool/functiontool"
+)
+
+func TestRunner_Resume(t *testing.T) {
+	approveTool, err := functiontool.New(functiontool.Config{
+		Name:          "approve",
+		Description:   "asks a human for approval",
+		IsLongRunning: true,
+	}, func(tool.Context, struct{}) (map[string]any, error) {
+		return map[string]any{"status": "pending"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAgent := func(name string, m *testutil.MockModel, tools ...tool.Tool) agent.Agent {
+		t.Helper()
+		a, err := llmagent.New(llmagent.Config{Name: name, Model: m, Tools: tools})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	text := func(s string) *genai.Content { return genai.NewContentFromText(s, genai.RoleModel) }
+
+	firstModel := &testutil.MockModel{Responses: []*genai.Content{text("first done")}}
+	workerModel := &testutil.MockModel{Responses: []*genai.Content{
+		genai.NewContentFromFunctionCall("approve", map[string]any{}, genai.RoleModel),
+		text("iteration 1 done"),
+		text("iteration 2 done"),
+	}}
+	lastModel := &testutil.MockModel{Responses: []*genai.Content{text("last done")}}
+	loop, err := loopagent.New(loopagent.Config{
+		AgentConfig:   agent.Config{Name: "loop", SubAgents: []agent.Agent{newAgent("worker", workerModel, approveTool)}},
+		MaxIterations: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := sequentialagent.New(sequentialagent.Config{
+		AgentConfig: agent.Config{Name: "pipeline", SubAgents: []agent.Agent{newAgent("first", firstModel), loop, newAgent("last", lastModel)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionService := session.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: "app", Agent: pipeline, SessionService: sessionService, Resumable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := created.Session.ID()
+
+	collect := func(t *testing.T, stream iter.Seq2[*session.Event, error]) []*session.Event {
+		t.Helper()
+		var events []*session.Event
+		for ev, err := range stream {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			events = append(events, ev)
+		}
+		return events
+	}
+	// summary describes the events as author: text, function call or checkpoint.
+	summary := func(events []*session.Event) []string {
+		var got []string
+		for _, ev := range events {
+			s := ev.Author + ": "
+			switch {
+			case ev.Actions.EndOfAgent:
+				s += "end"
+			case ev.Actions.AgentState != nil:
+				s += "checkpoint"
+			case ev.Content.Parts[0].FunctionCall != nil:
+				s += "call " + ev.Content.Parts[0].FunctionCall.Name
+			case ev.Content.Parts[0].FunctionResponse != nil:
+				s += "response " + ev.Content.Parts[0].FunctionResponse.Name
+			default:
+				s += ev.Content.Parts[0].Text
+			}
+			got = append(got, s)
+		}
+		return got
+	}
+
+	// The long-running call pauses the whole invocation.
+	events := collect(t, r.Run(t.Context(), "user", sessionID, genai.NewContentFromText("go", genai.RoleUser), agent.RunConfig{}))
+	want := []string{
+		"pipeline: checkpoint",
+		"first: first done",
+		"pipeline: checkpoint",
+		"loop: checkpoint",
+		"worker: call approve",
+		"worker: response approve",
+	}
+	if diff := cmp.Diff(want, summary(events)); diff != "" {
+		t.Fatalf("events before the pause mismatch (-want +got):\n%s", diff)
+	}
+	invocationID := events[0].InvocationID
+	callID := events[4].Content.Parts[0].FunctionCall.ID
+
+	// Resuming requires a response to a pending call.
+	for ev, err := range r.Resume(t.Context(), "user", sessionID, invocationID, []*genai.FunctionResponse{{ID: "unknown", Name: "approve"}}, agent.RunConfig{}) {
+		if err == nil {
+			t.Fatalf("Resume() with an unknown call ID yielded %v, want error", ev)
+		}
+	}
+
+	response := &genai.FunctionResponse{ID: callID, Name: "approve", Response: map[string]any{"status": "approved"}}
+	events = collect(t, r.Resume(t.Context(), "user", sessionID, invocationID, []*genai.FunctionResponse{response}, agent.RunConfig{}))
+	want = []string{
+		"worker: iteration 1 done",
+		"loop: checkpoint",
+		"worker: iteration 2 done",
+		"loop: end",
+		"pipeline: checkpoint",
+		"last: last done",
+		"pipeline: end",
+	}
+	if diff := cmp.Diff(want, summary(events)); diff != "" {
+		t.Errorf("events after resuming mismatch (-want +got):\n%s", diff)
+	}
+	for _, ev := range events {
+		if ev.InvocationID != invocationID {
+			t.Errorf("event %q has invocation ID %q, want %q", ev.Author, ev.InvocationID, invocationID)
+		}
+	}
+
+	// The completed steps did not run again.
+	if got := len(firstModel.Requests); got != 1 {
+		t.Errorf("first agent called the model %d times, want 1", got)
+	}
+	if got := len(workerModel.Requests); got != 3 {
+		t.Errorf("worker agent called the model %d times, want 3", got)
+	}
+	contents := workerModel.Requests[1].Contents
+	if got := contents[len(contents)-1].Parts[0].FunctionResponse; got == nil || got.Response["status"] != "approved" {
+		t.Errorf("worker resumed with %v, want the approved function response", contents[len(contents)-1].Parts[0])
+	}
+
+	// The invocation completed, so it cannot be resumed again.
+	for ev, err := range r.Resume(t.Context(), "user", sessionID, invocationID, []*genai.FunctionResponse{response}, agent.RunConfig{}) {
+		if err == nil {
+			t.Fatalf("Resume() of a completed invocation yielded %v, want error", ev)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent/llmagent"
+	"github.com/sjzsdu/adk-go/artifact"
+	"github.com/sjzsdu/adk-go/internal/testutil"
+	"github.com/sjzsdu/adk-go/runner"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// appendInvocation appends an event of the invocation saving a new version
+// of the artifacts.
+func appendInvocation(t *testing.T, sessionService session.Service, artifactService artifact.Service, sess session.Session, invocationID string, artifacts ...string) {
+	t.Helper()
+	ev := session.NewEvent(invocationID)
+	ev.Actions.ArtifactDelta = make(map[string]int64)
+	for _, name := range artifacts {
+		resp, err := artifactService.Save(t.Context(), &artifact.SaveRequest{
+			AppName: "app", UserID: "user", SessionID: sess.ID(), FileName: name,
+			Part: genai.NewPartFromText(invocationID),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev.Actions.ArtifactDelta[name] = resp.Version
+	}
+	ev.Timestamp = time.Now()
+	if err := sessionService.AppendEvent(t.Context(), sess, ev); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunner_Rewind(t *testing.T) {
+	ctx := t.Context()
+	a, err := llmagent.New(llmagent.Config{Name: "agent", Model: &testutil.MockModel{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	artifactService := artifact.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: "app", Agent: a, SessionService: sessionService, ArtifactService: artifactService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := created.Session.ID()
+
+	// Each invocation saves a new version of the artifacts it changes.
+	appendInvocation(t, sessionService, artifactService, created.Session, "inv1", "a.txt")
+	appendInvocation(t, sessionService, artifactService, created.Session, "inv2", "a.txt", "b.txt")
+
+	rewound, err := r.Rewind(ctx, "user", sessionID, "inv2")
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if got := rewound.Events().Len(); got != 1 {
+		t.Errorf("Rewind() kept %d events, want 1", got)
+	}
+
+	versions, err := artifactService.Versions(ctx, &artifact.VersionsRequest{AppName: "app", UserID: "user", SessionID: sessionID, FileName: "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int64{1}, versions.Versions); diff != "" {
+		t.Errorf("versions of a.txt after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+	_, err = artifactService.Load(ctx, &artifact.LoadRequest{AppName: "app", UserID: "user", SessionID: sessionID, FileName: "b.txt"})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load() of the artifact created after the rewind point error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestRunner_Rewind_AfterTrimEvents(t *testing.T) {
+	ctx := t.Context()
+	a, err := llmagent.New(llmagent.Config{Name: "agent", Model: &testutil.MockModel{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	artifactService := artifact.InMemoryService()
+	r, err := runner.New(runner.Config{AppName: "app", Agent: a, SessionService: sessionService, ArtifactService: artifactService})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := created.Session.ID()
+	appendInvocation(t, sessionService, artifactService, created.Session, "inv1", "a.txt", "b.txt")
+	appendInvocation(t, sessionService, artifactService, created.Session, "inv2", "a.txt", "c.txt")
+
+	// The event of inv1 is deleted, the artifacts it saved are not.
+	_, err = sessionService.(session.EventTrimmer).TrimEvents(ctx, &session.TrimEventsRequest{AppName: "app", UserID: "user", SessionID: sessionID, MaxEvents: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Rewind(ctx, "user", sessionID, "inv2"); err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+
+	for name, want := range map[string][]int64{"a.txt": {1}, "b.txt": {1}} {
+		versions, err := artifactService.Versions(ctx, &artifact.VersionsRequest{AppName: "app", UserID: "user", SessionID: sessionID, FileName: name})
+		if err != nil {
+			t.Fatalf("Versions() of %s failed: %v", name, err)
+		}
+		if diff := cmp.Diff(want, versions.Versions); diff != "" {
+			t.Errorf("versions of %s after Rewind() mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+	_, err = artifactService.Load(ctx, &artifact.LoadRequest{AppName: "app", UserID: "user", SessionID: sessionID, FileName: "c.txt"})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load() of the artifact created after the rewind point error = %v, want fs.ErrNotExist", err)
+	}
+}
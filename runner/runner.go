@@ -17,19 +17,25 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"iter"
 	"log"
+	"slices"
 	"time"
 
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
 	"github.com/sjzsdu/adk-go/artifact"
+	"github.com/sjzsdu/adk-go/auth/credential"
+	"github.com/sjzsdu/adk-go/internal/agent/checkpoint"
 	"github.com/sjzsdu/adk-go/internal/agent/parentmap"
 	"github.com/sjzsdu/adk-go/internal/agent/runconfig"
 	artifactinternal "github.com/sjzsdu/adk-go/internal/artifact"
 	icontext "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/internal/credentialinternal"
 	"github.com/sjzsdu/adk-go/internal/llminternal"
 	imemory "github.com/sjzsdu/adk-go/internal/memory"
 	"github.com/sjzsdu/adk-go/internal/plugininternal"
@@ -38,6 +44,7 @@ import (
 	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/plugin"
 	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/state"
 )
 
 // Config is used to create a [Runner].
@@ -53,6 +60,35 @@ type Config struct {
 	MemoryService memory.Service
 	// optional
 	PluginConfig PluginConfig
+	// optional
+	// CredentialService stores the credentials obtained for tools, see
+	// tool.Context.RequestCredential. Without it, credentials are only
+	// available within the invocation that received them.
+	CredentialService credential.Service
+	// optional
+	// Resumable makes the invocations resumable with Runner.Resume. An
+	// invocation pauses when an agent makes long-running function calls,
+	// e.g. to request a tool confirmation. The agents that run other agents
+	// record their progress in the session, so that a resumed invocation
+	// continues where it paused. Sub-agents of parallel agents are not
+	// tracked and run again.
+	Resumable bool
+	// optional
+	// Invocations tracks the invocations in flight, so that they can be
+	// cancelled. Share a registry to cancel invocations of several runners.
+	// Defaults to a registry of the runner.
+	Invocations *InvocationRegistry
+	// optional
+	// SessionConcurrency is the policy for a run of a session while another
+	// run of the session is in flight, e.g. rejecting it. The runs of the
+	// runners sharing Invocations are taken into account. Defaults to
+	// SessionConcurrencyAllow.
+	SessionConcurrency SessionConcurrency
+	// optional
+	// StateSchema validates the session state of the app. An event whose
+	// state delta would make the state invalid is not stored, and the run
+	// fails with an error wrapping state.ErrInvalidState.
+	StateSchema *state.Schema
 }
 
 type PluginConfig struct {
@@ -83,14 +119,24 @@ func New(cfg Config) (*Runner, error) {
 		return nil, fmt.Errorf("failed to create plugin manager: %w", err)
 	}
 
+	invocations := cfg.Invocations
+	if invocations == nil {
+		invocations = NewInvocationRegistry()
+	}
+
 	return &Runner{
-		appName:         cfg.AppName,
-		rootAgent:       cfg.Agent,
-		sessionService:  cfg.SessionService,
-		artifactService: cfg.ArtifactService,
-		memoryService:   cfg.MemoryService,
-		parents:         parents,
-		pluginManager:   pluginManager,
+		invocations:       invocations,
+		concurrency:       cfg.SessionConcurrency,
+		appName:           cfg.AppName,
+		rootAgent:         cfg.Agent,
+		sessionService:    cfg.SessionService,
+		artifactService:   cfg.ArtifactService,
+		memoryService:     cfg.MemoryService,
+		credentialService: cfg.CredentialService,
+		resumable:         cfg.Resumable,
+		stateSchema:       cfg.StateSchema,
+		parents:           parents,
+		pluginManager:     pluginManager,
 	}, nil
 }
 
@@ -98,11 +144,16 @@ func New(cfg Config) (*Runner, error) {
 // processing, event generation, and interaction with various services like
 // artifact storage, session management, and memory.
 type Runner struct {
-	appName         string
-	rootAgent       agent.Agent
-	sessionService  session.Service
-	artifactService artifact.Service
-	memoryService   memory.Service
+	appName           string
+	rootAgent         agent.Agent
+	sessionService    session.Service
+	artifactService   artifact.Service
+	memoryService     memory.Service
+	credentialService credential.Service
+	resumable         bool
+	invocations       *InvocationRegistry
+	concurrency       SessionConcurrency
+	stateSchema       *state.Schema
 
 	parents       parentmap.Map
 	pluginManager *plugininternal.PluginManager
@@ -116,6 +167,22 @@ func (r *Runner) Run(ctx context.Context, userID, sessionID string, msg *genai.C
 	//   see adk-python/src/google/adk/runners.py Runner._new_invocation_context.
 	// TODO: setup tracer.
 	return func(yield func(*session.Event, error) bool) {
+		ctx, unlock, err := r.lockSession(ctx, userID, sessionID)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer unlock()
+
+		// The timeout starts once the session is locked, the time spent
+		// queued behind other runs does not count.
+		limits := runconfig.NewLimits(&cfg, time.Now())
+		if deadline, ok := limits.Deadline(); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
 		resp, err := r.sessionService.Get(ctx, &session.GetRequest{
 			AppName:   r.appName,
 			UserID:    userID,
@@ -134,101 +201,410 @@ func (r *Runner) Run(ctx context.Context, userID, sessionID string, msg *genai.C
 			return
 		}
 
-		ctx = parentmap.ToContext(ctx, r.parents)
-		ctx = runconfig.ToContext(ctx, &runconfig.RunConfig{
-			StreamingMode: runconfig.StreamingMode(cfg.StreamingMode),
+		var checkpoints *checkpoint.Checkpoints
+		if r.resumable {
+			checkpoints = checkpoint.New()
+		}
+		r.run(ctx, storedSession, agentToRun, invocation{
+			userContent: msg,
+			message:     msg,
+			limits:      limits,
+			checkpoints: checkpoints,
+		}, cfg, yield)
+	}
+}
+
+// lockSession locks the session for a run according to
+// Config.SessionConcurrency. It returns the context of the run, which is
+// cancelled when a newer run interrupts it, and the function unlocking the
+// session.
+func (r *Runner) lockSession(ctx context.Context, userID, sessionID string) (context.Context, func(), error) {
+	if r.concurrency == SessionConcurrencyAllow {
+		return ctx, func() {}, nil
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	unlock, err := r.invocations.lockSession(ctx, sessionKey{appName: r.appName, userID: userID, sessionID: sessionID}, r.concurrency, cancel)
+	if err != nil {
+		cancel(nil)
+		return nil, nil, err
+	}
+	return ctx, func() {
+		unlock()
+		cancel(nil)
+	}, nil
+}
+
+// ListActive returns the invocations of the app in flight, the oldest first.
+func (r *Runner) ListActive() []InvocationInfo {
+	var infos []InvocationInfo
+	for _, info := range r.invocations.ListActive() {
+		if info.AppName == r.appName {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// Cancel cancels the invocation in flight with the given ID, see
+// InvocationRegistry.Cancel.
+func (r *Runner) Cancel(invocationID string) error {
+	return r.invocations.Cancel(invocationID)
+}
+
+// Resume resumes an invocation that paused for long-running function calls,
+// see Config.Resumable. The function responses answer some or all of the
+// pending calls, e.g. the confirmation requests of tools. The agents that
+// finished before the invocation paused are not run again.
+func (r *Runner) Resume(ctx context.Context, userID, sessionID, invocationID string, responses []*genai.FunctionResponse, cfg agent.RunConfig) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		if !r.resumable {
+			yield(nil, fmt.Errorf("runner of app %q is not resumable", r.appName))
+			return
+		}
+		if len(responses) == 0 {
+			yield(nil, fmt.Errorf("no function responses to resume invocation %q with", invocationID))
+			return
+		}
+		ctx, unlock, err := r.lockSession(ctx, userID, sessionID)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer unlock()
+
+		// The timeout starts once the session is locked, the time spent
+		// queued behind other runs does not count.
+		limits := runconfig.NewLimits(&cfg, time.Now())
+		if deadline, ok := limits.Deadline(); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
+		resp, err := r.sessionService.Get(ctx, &session.GetRequest{
+			AppName:   r.appName,
+			UserID:    userID,
+			SessionID: sessionID,
 		})
-		ctx = plugininternal.ToContext(ctx, r.pluginManager)
-
-		var artifacts agent.Artifacts
-		if r.artifactService != nil {
-			artifacts = &artifactinternal.Artifacts{
-				Service:   r.artifactService,
-				SessionID: storedSession.ID(),
-				AppName:   storedSession.AppName(),
-				UserID:    storedSession.UserID(),
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		storedSession := resp.Session
+
+		var events []*session.Event
+		for ev := range storedSession.Events().All() {
+			if ev.InvocationID == invocationID {
+				events = append(events, ev)
+			}
+		}
+		if len(events) == 0 {
+			yield(nil, fmt.Errorf("invocation %q not found in session %q", invocationID, sessionID))
+			return
+		}
+
+		pending := pendingFunctionCalls(events)
+		var pausedAgent agent.Agent
+		for _, fr := range responses {
+			author, ok := pending[fr.ID]
+			if !ok {
+				yield(nil, fmt.Errorf("invocation %q has no pending function call with ID %q", invocationID, fr.ID))
+				return
+			}
+			if pausedAgent == nil {
+				pausedAgent = findAgent(r.rootAgent, author)
 			}
 		}
+		if pausedAgent == nil {
+			yield(nil, fmt.Errorf("the agent that paused invocation %q is not in the agent tree", invocationID))
+			return
+		}
 
-		var memoryImpl agent.Memory = nil
-		if r.memoryService != nil {
-			memoryImpl = &imemory.Memory{
-				Service:   r.memoryService,
-				SessionID: storedSession.ID(),
-				UserID:    storedSession.UserID(),
-				AppName:   storedSession.AppName(),
+		// Continue with the outermost agent that did not finish: it runs
+		// the other unfinished agents down to the paused one.
+		checkpoints := checkpoint.Load(events)
+		agentToRun := pausedAgent
+		for cur := r.parents[pausedAgent.Name()]; cur != nil; cur = r.parents[cur.Name()] {
+			if checkpoints.Has(cur.Name()) {
+				agentToRun = cur
 			}
 		}
 
-		ctx := icontext.NewInvocationContext(ctx, icontext.InvocationContextParams{
-			Artifacts:   artifacts,
-			Memory:      memoryImpl,
-			Session:     storedSession,
-			Agent:       agentToRun,
-			UserContent: msg,
-			RunConfig:   &cfg,
+		var userContent *genai.Content
+		for _, ev := range events {
+			if ev.Author == "user" {
+				userContent = ev.Content
+				break
+			}
+		}
+		msg := &genai.Content{Role: genai.RoleUser}
+		for _, fr := range responses {
+			msg.Parts = append(msg.Parts, &genai.Part{FunctionResponse: fr})
+		}
+		r.run(ctx, storedSession, agentToRun, invocation{
+			id:          invocationID,
+			userContent: userContent,
+			message:     msg,
+			limits:      limits,
+			checkpoints: checkpoints,
+		}, cfg, yield)
+	}
+}
+
+// Rewind rewinds the session to before the invocation, e.g. to edit and
+// resend an earlier message, see session.RewindRequest. The artifacts saved
+// by the removed events are reverted to their versions before the
+// invocation: the later versions are deleted.
+func (r *Runner) Rewind(ctx context.Context, userID, sessionID, invocationID string) (session.Session, error) {
+	ctx, unlock, err := r.lockSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	resp, err := r.sessionService.Rewind(ctx, &session.RewindRequest{
+		AppName:      r.appName,
+		UserID:       userID,
+		SessionID:    sessionID,
+		InvocationID: invocationID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind session %q: %w", sessionID, err)
+	}
+	if r.artifactService == nil {
+		return resp.Session, nil
+	}
+	for name, version := range resp.ArtifactVersions {
+		versions, err := r.artifactService.Versions(ctx, &artifact.VersionsRequest{
+			AppName:   r.appName,
+			UserID:    userID,
+			SessionID: sessionID,
+			FileName:  name,
 		})
-		ctx, err = r.appendMessageToSession(ctx, storedSession, msg, cfg.SaveInputBlobsAsArtifacts, r.pluginManager)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
 		if err != nil {
-			yield(nil, err)
+			return nil, fmt.Errorf("failed to list versions of artifact %q: %w", name, err)
+		}
+		for _, v := range versions.Versions {
+			if v <= version {
+				continue
+			}
+			err := r.artifactService.Delete(ctx, &artifact.DeleteRequest{
+				AppName:   r.appName,
+				UserID:    userID,
+				SessionID: sessionID,
+				FileName:  name,
+				Version:   v,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to revert artifact %q: %w", name, err)
+			}
+		}
+	}
+	return resp.Session, nil
+}
+
+// pendingFunctionCalls returns the long-running function calls of the events
+// that have no response from the user yet, mapped to the agents that made
+// them.
+func pendingFunctionCalls(events []*session.Event) map[string]string {
+	pending := make(map[string]string)
+	for _, ev := range events {
+		if ev.Author == "user" {
+			for _, fr := range utils.FunctionResponses(ev.Content) {
+				delete(pending, fr.ID)
+			}
+			continue
+		}
+		for _, call := range utils.FunctionCalls(ev.Content) {
+			if slices.Contains(ev.LongRunningToolIDs, call.ID) {
+				pending[call.ID] = ev.Author
+			}
+		}
+	}
+	return pending
+}
+
+// invocation describes the invocation to run.
+type invocation struct {
+	// id is the ID of a resumed invocation, empty for a new one.
+	id string
+	// userContent is the user message that started the invocation.
+	userContent *genai.Content
+	// message is the message to append to the session before running the
+	// agent.
+	message     *genai.Content
+	limits      *runconfig.Limits
+	checkpoints *checkpoint.Checkpoints
+}
+
+// run runs the agent for the invocation, yielding events from agents.
+func (r *Runner) run(parent context.Context, storedSession session.Session, agentToRun agent.Agent, inv invocation, cfg agent.RunConfig, yield func(*session.Event, error) bool) {
+	msg := inv.message
+	parent = parentmap.ToContext(parent, r.parents)
+	parent = runconfig.ToContext(parent, &runconfig.RunConfig{
+		StreamingMode: runconfig.StreamingMode(cfg.StreamingMode),
+		Limits:        inv.limits,
+	})
+	parent = plugininternal.ToContext(parent, r.pluginManager)
+	if r.credentialService != nil {
+		parent = credentialinternal.ToContext(parent, r.credentialService)
+	}
+	if inv.checkpoints != nil {
+		parent = checkpoint.ToContext(parent, inv.checkpoints)
+	}
+	parent, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	var artifacts agent.Artifacts
+	if r.artifactService != nil {
+		artifacts = &artifactinternal.Artifacts{
+			Service:   r.artifactService,
+			SessionID: storedSession.ID(),
+			AppName:   storedSession.AppName(),
+			UserID:    storedSession.UserID(),
+		}
+	}
+
+	var memoryImpl agent.Memory = nil
+	if r.memoryService != nil {
+		memoryImpl = &imemory.Memory{
+			Service:   r.memoryService,
+			SessionID: storedSession.ID(),
+			UserID:    storedSession.UserID(),
+			AppName:   storedSession.AppName(),
+		}
+	}
+
+	ctx := icontext.NewInvocationContext(parent, icontext.InvocationContextParams{
+		Artifacts:    artifacts,
+		Memory:       memoryImpl,
+		Session:      storedSession,
+		Agent:        agentToRun,
+		UserContent:  inv.userContent,
+		RunConfig:    &cfg,
+		InvocationID: inv.id,
+	})
+	unregister := r.invocations.register(InvocationInfo{
+		InvocationID: ctx.InvocationID(),
+		AppName:      r.appName,
+		UserID:       storedSession.UserID(),
+		SessionID:    storedSession.ID(),
+		AgentName:    agentToRun.Name(),
+		StartTime:    time.Now(),
+	}, cancel)
+	defer unregister()
+
+	ctx, err := r.appendMessageToSession(ctx, storedSession, msg, cfg.SaveInputBlobsAsArtifacts, r.pluginManager)
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+
+	pluginManager := r.pluginManager
+	if pluginManager != nil {
+		// Defer the after run callbacks to perform global cleanup tasks or finalizing logs and metrics data.
+		// This does NOT emit any event. The callbacks run for cancelled invocations too.
+		defer func() {
+			pluginManager.RunAfterRunCallback(ctx.WithContext(context.WithoutCancel(ctx)))
+		}()
+
+		earlyExitResult, err := pluginManager.RunBeforeRunCallback(ctx)
+		if earlyExitResult != nil || err != nil {
+			earlyExitEvent := session.NewEvent(ctx.InvocationID())
+			earlyExitEvent.Author = "user"
+			earlyExitEvent.LLMResponse = model.LLMResponse{
+				Content: msg,
+			}
+			if err := r.appendEvent(ctx, storedSession, earlyExitEvent); err != nil {
+				yield(nil, fmt.Errorf("failed to add event to session: %w", err))
+				return
+			}
+			yield(earlyExitEvent, err)
 			return
 		}
+	}
 
-		pluginManager := r.pluginManager
-		if pluginManager != nil {
-			// Defer the after run callbacks to perform global cleanup tasks or finalizing logs and metrics data.
-			// This does NOT emit any event.
-			defer pluginManager.RunAfterRunCallback(ctx)
-
-			earlyExitResult, err := pluginManager.RunBeforeRunCallback(ctx)
-			if earlyExitResult != nil || err != nil {
-				earlyExitEvent := session.NewEvent(ctx.InvocationID())
-				earlyExitEvent.Author = "user"
-				earlyExitEvent.LLMResponse = model.LLMResponse{
-					Content: msg,
-				}
-				if err := r.sessionService.AppendEvent(ctx, storedSession, earlyExitEvent); err != nil {
-					yield(nil, fmt.Errorf("failed to add event to session: %w", err))
-					return
-				}
-				yield(earlyExitEvent, err)
+	for event, err := range agentToRun.Run(ctx) {
+		if isCancelled(ctx) {
+			break
+		}
+		if err != nil {
+			if !yield(event, err) {
 				return
 			}
+			continue
 		}
 
-		for event, err := range agentToRun.Run(ctx) {
+		if pluginManager != nil {
+			modifiedEvent, err := pluginManager.RunOnEventCallback(ctx, event)
 			if err != nil {
-				if !yield(event, err) {
+				if !yield(nil, err) {
 					return
 				}
 				continue
 			}
-
-			if pluginManager != nil {
-				modifiedEvent, err := pluginManager.RunOnEventCallback(ctx, event)
-				if err != nil {
-					if !yield(nil, err) {
-						return
-					}
-					continue
-				}
-				if modifiedEvent != nil {
-					event = modifiedEvent
-				}
+			if modifiedEvent != nil {
+				event = modifiedEvent
 			}
+		}
 
-			// only commit non-partial event to a session service
-			if !event.LLMResponse.Partial {
-				if err := r.sessionService.AppendEvent(ctx, storedSession, event); err != nil {
-					yield(nil, fmt.Errorf("failed to add event to session: %w", err))
-					return
-				}
+		// only commit non-partial event to a session service
+		if !event.LLMResponse.Partial {
+			appendCtx := context.Context(ctx)
+			if event.ErrorCode == agent.LimitExceededErrorCode {
+				// The event ending an invocation that ran out of time must
+				// still be stored.
+				appendCtx = context.WithoutCancel(ctx)
 			}
-
-			if !yield(event, nil) {
+			if err := r.appendEvent(appendCtx, storedSession, event); err != nil {
+				yield(nil, fmt.Errorf("failed to add event to session: %w", err))
 				return
 			}
 		}
+
+		if !yield(event, nil) {
+			return
+		}
+	}
+	if isCancelled(ctx) {
+		r.endCancelledInvocation(ctx, storedSession, yield)
+	}
+}
+
+// appendEvent stores an event in the session, after validating its state
+// delta with the state schema of the app.
+func (r *Runner) appendEvent(ctx context.Context, storedSession session.Session, event *session.Event) error {
+	if r.stateSchema != nil && len(event.Actions.StateDelta) > 0 {
+		if err := r.stateSchema.ValidateDelta(storedSession.State(), event.Actions.StateDelta); err != nil {
+			return err
+		}
+	}
+	return r.sessionService.AppendEvent(ctx, storedSession, event)
+}
+
+// isCancelled reports whether the invocation was cancelled via Cancel.
+func isCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrInvocationCancelled)
+}
+
+// endCancelledInvocation stores and yields the event ending a cancelled
+// invocation, followed by the cause of the cancellation, i.e.
+// ErrInvocationCancelled or ErrInvocationInterrupted.
+func (r *Runner) endCancelledInvocation(ctx agent.InvocationContext, storedSession session.Session, yield func(*session.Event, error) bool) {
+	event := session.NewEvent(ctx.InvocationID())
+	event.Author = ctx.Agent().Name()
+	event.ErrorCode = CancelledErrorCode
+	event.ErrorMessage = "the invocation was cancelled"
+	event.TurnComplete = true
+	if err := r.appendEvent(context.WithoutCancel(ctx), storedSession, event); err != nil {
+		yield(nil, fmt.Errorf("failed to add event to session: %w", err))
+		return
+	}
+	if yield(event, nil) {
+		yield(nil, context.Cause(ctx))
 	}
 }
 
@@ -280,7 +656,7 @@ func (r *Runner) appendMessageToSession(ctx agent.InvocationContext, storedSessi
 		Content: msg,
 	}
 
-	if err := r.sessionService.AppendEvent(ctx, storedSession, event); err != nil {
+	if err := r.appendEvent(ctx, storedSession, event); err != nil {
 		return ctx, fmt.Errorf("failed to append event to sessionService: %w", err)
 	}
 	return ctx, nil
@@ -17,11 +17,13 @@ package runner
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"iter"
 	"strings"
 	"testing"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"google.golang.org/genai"
 
 	"github.com/sjzsdu/adk-go/agent"
@@ -29,6 +31,7 @@ import (
 	"github.com/sjzsdu/adk-go/artifact"
 	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/state"
 )
 
 func TestRunner_findAgentToRun(t *testing.T) {
@@ -337,6 +340,75 @@ func TestRunner_SaveInputBlobsAsArtifacts(t *testing.T) {
 	}
 }
 
+func TestRunner_StateSchema(t *testing.T) {
+	ctx := t.Context()
+	sessionService := session.InMemoryService()
+	schema, err := state.NewSchema(&jsonschema.Schema{
+		Type:       "object",
+		Properties: map[string]*jsonschema.Schema{"count": {Type: "integer"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var delta map[string]any
+	testAgent := must(agent.New(agent.Config{
+		Name: "test_agent",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				ev := session.NewEvent(ctx.InvocationID())
+				ev.Author = "test_agent"
+				ev.Actions.StateDelta = delta
+				yield(ev, nil)
+			}
+		},
+	}))
+	r, err := New(Config{
+		AppName:        "testApp",
+		Agent:          testAgent,
+		SessionService: sessionService,
+		StateSchema:    schema,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "testApp", UserID: "testUser", SessionID: "testSession"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		delta     map[string]any
+		wantErr   error
+		wantCount any
+	}{
+		{name: "valid delta", delta: map[string]any{"count": 1}, wantCount: 1},
+		{name: "invalid delta", delta: map[string]any{"count": "two"}, wantErr: state.ErrInvalidState, wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta = tt.delta
+			var gotErr error
+			for _, err := range r.Run(ctx, "testUser", "testSession", genai.NewContentFromText("hi", genai.RoleUser), agent.RunConfig{}) {
+				if err != nil {
+					gotErr = err
+				}
+			}
+			if !errors.Is(gotErr, tt.wantErr) {
+				t.Fatalf("Run() error = %v, want %v", gotErr, tt.wantErr)
+			}
+
+			resp, err := sessionService.Get(ctx, &session.GetRequest{AppName: "testApp", UserID: "testUser", SessionID: "testSession"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := resp.Session.State().Get("count"); got != tt.wantCount {
+				t.Errorf("stored count = %v, want %v", got, tt.wantCount)
+			}
+		})
+	}
+}
+
 // creates agentTree for tests and returns references to the agents
 func agentTree(t *testing.T) agentTreeStruct {
 	t.Helper()
@@ -266,6 +266,8 @@ func getDefaultAgentDescription(state *iagent.State) string {
 		return "A sequential workflow agent"
 	case iagent.TypeParallelAgent:
 		return "A parallel workflow agent"
+	case iagent.TypeMapAgent:
+		return "A map workflow agent"
 	case iagent.TypeLLMAgent:
 		return "An LLM-based agent"
 	default:
@@ -281,6 +283,8 @@ func getAgentTypeTag(state *iagent.State) string {
 		return "sequential_workflow"
 	case iagent.TypeParallelAgent:
 		return "parallel_workflow"
+	case iagent.TypeMapAgent:
+		return "map_workflow"
 	case iagent.TypeLLMAgent:
 		return "llm_agent"
 	default:
@@ -307,6 +311,6 @@ func getInternalState(agent agent.Agent) *iagent.State {
 }
 
 func isWorkflowAgent(state *iagent.State) bool {
-	workflowAgents := []iagent.Type{iagent.TypeLoopAgent, iagent.TypeSequentialAgent, iagent.TypeParallelAgent}
+	workflowAgents := []iagent.Type{iagent.TypeLoopAgent, iagent.TypeSequentialAgent, iagent.TypeParallelAgent, iagent.TypeMapAgent}
 	return slices.Contains(workflowAgents, state.AgentType)
 }
@@ -16,6 +16,7 @@ package adka2a
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/a2aproject/a2a-go/a2a"
@@ -94,11 +95,18 @@ var _ a2asrv.AgentExecutor = (*Executor)(nil)
 //     Else produce a TaskStatusUpdateEvent with TaskStateCompleted.
 type Executor struct {
 	config ExecutorConfig
+	// invocations tracks the runs of all Execute calls, so that Cancel can
+	// stop them.
+	invocations *runner.InvocationRegistry
 }
 
 // NewExecutor creates an initialized [Executor] instance.
 func NewExecutor(config ExecutorConfig) *Executor {
-	return &Executor{config: config}
+	invocations := config.RunnerConfig.Invocations
+	if invocations == nil {
+		invocations = runner.NewInvocationRegistry()
+	}
+	return &Executor{config: config, invocations: invocations}
 }
 
 func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue) error {
@@ -115,6 +123,7 @@ func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, q
 	if err != nil {
 		return fmt.Errorf("failed to install a2a-executor plugin: %w", err)
 	}
+	runnerCfg.Invocations = e.invocations
 
 	r, err := runner.New(runnerCfg)
 	if err != nil {
@@ -161,7 +170,20 @@ func (e *Executor) Execute(ctx context.Context, reqCtx *a2asrv.RequestContext, q
 	return e.process(executorContext, r, processor, queue)
 }
 
+// Cancel cancels the invocations in flight of the task's session and
+// produces a TaskStatusUpdateEvent with TaskStateCanceled. The cancelled
+// invocations end with a cancelled event stored in the session.
 func (e *Executor) Cancel(ctx context.Context, reqCtx *a2asrv.RequestContext, queue eventqueue.Queue) error {
+	userID, sessionID := toUserAndSessionID(ctx, reqCtx)
+	for _, info := range e.invocations.ListActive() {
+		if info.AppName != e.config.RunnerConfig.AppName || info.UserID != userID || info.SessionID != sessionID {
+			continue
+		}
+		// The invocation may have finished in the meantime.
+		if err := e.invocations.Cancel(info.InvocationID); err != nil && !errors.Is(err, runner.ErrInvocationNotFound) {
+			return fmt.Errorf("failed to cancel invocation %q: %w", info.InvocationID, err)
+		}
+	}
 	event := a2a.NewStatusUpdateEvent(reqCtx, a2a.TaskStateCanceled, nil)
 	event.Final = true
 	return queue.Write(ctx, event)
@@ -171,6 +193,10 @@ func (e *Executor) Cancel(ctx context.Context, reqCtx *a2asrv.RequestContext, qu
 func (e *Executor) process(ctx ExecutorContext, r *runner.Runner, processor *eventProcessor, q eventqueue.Queue) error {
 	meta := processor.meta
 	for adkEvent, adkErr := range r.Run(ctx, meta.userID, meta.sessionID, ctx.UserContent(), e.config.RunConfig) {
+		if errors.Is(adkErr, runner.ErrInvocationCancelled) {
+			// Cancel produces the final status.
+			return nil
+		}
 		if adkErr != nil {
 			event := processor.makeTaskFailedEvent(fmt.Errorf("agent run failed: %w", adkErr), nil)
 			return e.writeFinalTaskStatus(ctx, q, processor.makeFinalArtifactUpdate(), event, adkErr)
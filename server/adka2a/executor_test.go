@@ -275,6 +275,68 @@ func TestExecutor_Cancel(t *testing.T) {
 	}
 }
 
+func TestExecutor_Cancel_Invocation(t *testing.T) {
+	agent, err := agent.New(agent.Config{
+		Name: "test",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				<-ctx.Done()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v, want nil", err)
+	}
+	sessionService := session.InMemoryService()
+	invocations := runner.NewInvocationRegistry()
+	config := ExecutorConfig{RunnerConfig: runner.Config{
+		AppName:        agent.Name(),
+		Agent:          agent,
+		SessionService: sessionService,
+		Invocations:    invocations,
+	}}
+	executor := NewExecutor(config)
+	task := &a2a.Task{ID: a2a.NewTaskID(), ContextID: a2a.NewContextID()}
+	msg := a2a.NewMessageForTask(a2a.MessageRoleUser, task, a2a.TextPart{Text: "hi"})
+	reqCtx := &a2asrv.RequestContext{TaskID: task.ID, ContextID: task.ContextID, Message: msg, StoredTask: task}
+
+	executeErr := make(chan error)
+	executeQueue := &testQueue{Queue: newInMemoryQueue(t)}
+	go func() { executeErr <- executor.Execute(t.Context(), reqCtx, executeQueue) }()
+	for deadline := time.Now().Add(5 * time.Second); len(invocations.ListActive()) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the invocation is not listed as active")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancelQueue := &testQueue{Queue: newInMemoryQueue(t)}
+	if err := executor.Cancel(t.Context(), reqCtx, cancelQueue); err != nil {
+		t.Fatalf("executor.Cancel() error = %v, want nil", err)
+	}
+	if err := <-executeErr; err != nil {
+		t.Fatalf("executor.Execute() error = %v, want nil", err)
+	}
+	if len(cancelQueue.events) != 1 || cancelQueue.events[0].(*a2a.TaskStatusUpdateEvent).Status.State != a2a.TaskStateCanceled {
+		t.Errorf("executor.Cancel() = %v, want a single TaskStateCanceled update", cancelQueue.events)
+	}
+	for _, event := range executeQueue.events {
+		if update, ok := event.(*a2a.TaskStatusUpdateEvent); ok && update.Status.State == a2a.TaskStateFailed {
+			t.Errorf("executor.Execute() produced %v, want no failed update", update)
+		}
+	}
+
+	meta := toInvocationMeta(t.Context(), config, reqCtx)
+	resp, err := sessionService.Get(t.Context(), &session.GetRequest{AppName: agent.Name(), UserID: meta.userID, SessionID: meta.sessionID})
+	if err != nil {
+		t.Fatalf("sessionService.Get() error = %v, want nil", err)
+	}
+	events := resp.Session.Events()
+	if last := events.At(events.Len() - 1); last.ErrorCode != runner.CancelledErrorCode {
+		t.Errorf("last session event = %v, want the cancelled event", last)
+	}
+}
+
 func TestExecutor_SessionReuse(t *testing.T) {
 	ctx := t.Context()
 	agent, err := newEventReplayAgent([]*session.Event{}, nil)
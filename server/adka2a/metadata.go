@@ -58,14 +58,7 @@ type invocationMeta struct {
 }
 
 func toInvocationMeta(ctx context.Context, config ExecutorConfig, reqCtx *a2asrv.RequestContext) invocationMeta {
-	userID, sessionID := "A2A_USER_"+reqCtx.ContextID, reqCtx.ContextID
-
-	// a2a sdk attaches authn info to the call context, use it when provided
-	if callCtx, ok := a2asrv.CallContextFrom(ctx); ok {
-		if callCtx.User != nil && callCtx.User.Name() != "" {
-			userID = callCtx.User.Name()
-		}
-	}
+	userID, sessionID := toUserAndSessionID(ctx, reqCtx)
 
 	meta := map[string]any{
 		ToA2AMetaKey("app_name"):   config.RunnerConfig.AppName,
@@ -82,6 +75,20 @@ func toInvocationMeta(ctx context.Context, config ExecutorConfig, reqCtx *a2asrv
 	}
 }
 
+// toUserAndSessionID returns the IDs of the user and the session the request
+// runs in.
+func toUserAndSessionID(ctx context.Context, reqCtx *a2asrv.RequestContext) (string, string) {
+	userID, sessionID := "A2A_USER_"+reqCtx.ContextID, reqCtx.ContextID
+
+	// a2a sdk attaches authn info to the call context, use it when provided
+	if callCtx, ok := a2asrv.CallContextFrom(ctx); ok {
+		if callCtx.User != nil && callCtx.User.Name() != "" {
+			userID = callCtx.User.Name()
+		}
+	}
+	return userID, sessionID
+}
+
 func toEventMeta(meta invocationMeta, event *session.Event) (map[string]any, error) {
 	result := make(map[string]any)
 	maps.Copy(result, meta.eventMeta)
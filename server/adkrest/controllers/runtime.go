@@ -17,10 +17,14 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
+	"github.com/gorilla/mux"
+
 	"github.com/sjzsdu/adk-go/agent"
 	"github.com/sjzsdu/adk-go/artifact"
 	"github.com/sjzsdu/adk-go/memory"
@@ -37,11 +41,14 @@ type RuntimeAPIController struct {
 	artifactService artifact.Service
 	agentLoader     agent.Loader
 	pluginConfig    runner.PluginConfig
+	// invocations is shared by the runners of all requests, so that a run
+	// can be cancelled from another request.
+	invocations *runner.InvocationRegistry
 }
 
 // NewRuntimeAPIController creates the controller for the Runtime API.
 func NewRuntimeAPIController(sessionService session.Service, memoryService memory.Service, agentLoader agent.Loader, artifactService artifact.Service, sseTimeout time.Duration, pluginConfig runner.PluginConfig) *RuntimeAPIController {
-	return &RuntimeAPIController{sessionService: sessionService, memoryService: memoryService, agentLoader: agentLoader, artifactService: artifactService, sseTimeout: sseTimeout, pluginConfig: pluginConfig}
+	return &RuntimeAPIController{sessionService: sessionService, memoryService: memoryService, agentLoader: agentLoader, artifactService: artifactService, sseTimeout: sseTimeout, pluginConfig: pluginConfig, invocations: runner.NewInvocationRegistry()}
 }
 
 // RunAgent executes a non-streaming agent run for a given session and message.
@@ -78,6 +85,10 @@ func (c *RuntimeAPIController) runAgent(ctx context.Context, runAgentRequest mod
 
 	var events []*session.Event
 	for event, err := range resp {
+		if errors.Is(err, runner.ErrInvocationCancelled) {
+			// The events end with the cancelled event.
+			break
+		}
 		if err != nil {
 			return nil, newStatusError(fmt.Errorf("failed to run agent: %w", err), http.StatusInternalServerError)
 		}
@@ -139,6 +150,62 @@ func (c *RuntimeAPIController) RunSSEHandler(rw http.ResponseWriter, req *http.R
 	return nil
 }
 
+// CancelInvocationHandler cancels an invocation in flight of the session.
+func (c *RuntimeAPIController) CancelInvocationHandler(rw http.ResponseWriter, req *http.Request) error {
+	params := mux.Vars(req)
+	sessionID, err := models.SessionIDFromHTTPParameters(params)
+	if err != nil {
+		return newStatusError(err, http.StatusBadRequest)
+	}
+	invocationID := params["invocation_id"]
+	if sessionID.ID == "" || invocationID == "" {
+		return newStatusError(fmt.Errorf("session_id and invocation_id parameters are required"), http.StatusBadRequest)
+	}
+	found := slices.ContainsFunc(c.invocations.ListActive(), func(info runner.InvocationInfo) bool {
+		return info.InvocationID == invocationID && info.AppName == sessionID.AppName &&
+			info.UserID == sessionID.UserID && info.SessionID == sessionID.ID
+	})
+	if !found {
+		return newStatusError(fmt.Errorf("%w: %q", runner.ErrInvocationNotFound, invocationID), http.StatusNotFound)
+	}
+	if err := c.invocations.Cancel(invocationID); err != nil {
+		return newStatusError(err, http.StatusNotFound)
+	}
+	EncodeJSONResponse(nil, http.StatusOK, rw)
+	return nil
+}
+
+// RewindHandler rewinds the session to before the invocation, see
+// runner.Runner.Rewind, and returns the rewound session.
+func (c *RuntimeAPIController) RewindHandler(rw http.ResponseWriter, req *http.Request) error {
+	params := mux.Vars(req)
+	sessionID, err := models.SessionIDFromHTTPParameters(params)
+	if err != nil {
+		return newStatusError(err, http.StatusBadRequest)
+	}
+	invocationID := params["invocation_id"]
+	if sessionID.ID == "" || invocationID == "" {
+		return newStatusError(fmt.Errorf("session_id and invocation_id parameters are required"), http.StatusBadRequest)
+	}
+	if err := c.validateSessionExists(req.Context(), sessionID.AppName, sessionID.UserID, sessionID.ID); err != nil {
+		return err
+	}
+	r, _, err := c.getRunner(models.RunAgentRequest{AppName: sessionID.AppName})
+	if err != nil {
+		return err
+	}
+	rewound, err := r.Rewind(req.Context(), sessionID.UserID, sessionID.ID, invocationID)
+	if err != nil {
+		return newStatusError(err, http.StatusInternalServerError)
+	}
+	resp, err := models.FromSession(rewound)
+	if err != nil {
+		return newStatusError(err, http.StatusInternalServerError)
+	}
+	EncodeJSONResponse(resp, http.StatusOK, rw)
+	return nil
+}
+
 func flashEvent(rc *http.ResponseController, rw http.ResponseWriter, event session.Event) error {
 	_, err := fmt.Fprintf(rw, "data: ")
 	if err != nil {
@@ -184,6 +251,7 @@ func (c *RuntimeAPIController) getRunner(req models.RunAgentRequest) (*runner.Ru
 		MemoryService:   c.memoryService,
 		ArtifactService: c.artifactService,
 		PluginConfig:    c.pluginConfig,
+		Invocations:     c.invocations,
 	},
 	)
 	if err != nil {
@@ -15,11 +15,20 @@
 package controllers
 
 import (
+	"iter"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/agent"
 	"github.com/sjzsdu/adk-go/plugin"
 	"github.com/sjzsdu/adk-go/runner"
+	"github.com/sjzsdu/adk-go/server/adkrest/internal/models"
+	"github.com/sjzsdu/adk-go/session"
 )
 
 func TestNewRuntimeAPIController_PluginsAssignment(t *testing.T) {
@@ -76,3 +85,73 @@ func TestNewRuntimeAPIController_PluginsAssignment(t *testing.T) {
 		})
 	}
 }
+
+func TestCancelInvocationHandler(t *testing.T) {
+	// The agent runs until its invocation is cancelled.
+	a, err := agent.New(agent.Config{
+		Name: "slow_agent",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				<-ctx.Done()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionService := session.InMemoryService()
+	created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "slow_agent", UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewRuntimeAPIController(sessionService, nil, agent.NewSingleLoader(a), nil, 10*time.Second, runner.PluginConfig{})
+
+	runEvents := make(chan []*session.Event)
+	go func() {
+		events, err := c.runAgent(t.Context(), models.RunAgentRequest{
+			AppName:    "slow_agent",
+			UserId:     "user",
+			SessionId:  created.Session.ID(),
+			NewMessage: *genai.NewContentFromText("hi", genai.RoleUser),
+		})
+		if err != nil {
+			t.Errorf("runAgent() failed: %v", err)
+		}
+		runEvents <- events
+	}()
+	var active []runner.InvocationInfo
+	for deadline := time.Now().Add(5 * time.Second); len(active) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the invocation is not listed as active")
+		}
+		time.Sleep(time.Millisecond)
+		active = c.invocations.ListActive()
+	}
+	invocationID := active[0].InvocationID
+
+	cancel := func(sessionID, invocationID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req = mux.SetURLVars(req, map[string]string{
+			"app_name":      "slow_agent",
+			"user_id":       "user",
+			"session_id":    sessionID,
+			"invocation_id": invocationID,
+		})
+		rr := httptest.NewRecorder()
+		NewErrorHandler(c.CancelInvocationHandler)(rr, req)
+		return rr.Code
+	}
+	if got := cancel("other_session", invocationID); got != http.StatusNotFound {
+		t.Errorf("cancel in another session: got status %d, want %d", got, http.StatusNotFound)
+	}
+	if got := cancel(created.Session.ID(), "unknown"); got != http.StatusNotFound {
+		t.Errorf("cancel of an unknown invocation: got status %d, want %d", got, http.StatusNotFound)
+	}
+	if got := cancel(created.Session.ID(), invocationID); got != http.StatusOK {
+		t.Fatalf("cancel: got status %d, want %d", got, http.StatusOK)
+	}
+	events := <-runEvents
+	if len(events) == 0 || events[len(events)-1].ErrorCode != runner.CancelledErrorCode {
+		t.Errorf("runAgent() = %+v, want events ending with the cancelled event", events)
+	}
+}
@@ -17,7 +17,14 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -135,7 +142,154 @@ func (c *SessionsAPIController) GetSessionHandler(rw http.ResponseWriter, req *h
 	EncodeJSONResponse(session, http.StatusOK, rw)
 }
 
+// getFullSession gets the session of the request path with all its events.
+func (c *SessionsAPIController) getFullSession(req *http.Request) (session.Session, int, error) {
+	sessionID, err := models.SessionIDFromHTTPParameters(mux.Vars(req))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if sessionID.ID == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("session_id parameter is required")
+	}
+	resp, err := c.service.Get(req.Context(), &session.GetRequest{
+		AppName:   sessionID.AppName,
+		UserID:    sessionID.UserID,
+		SessionID: sessionID.ID,
+	})
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return resp.Session, http.StatusOK, nil
+}
+
+// StateHistoryHandler returns the changes of the state key of the query
+// parameter key by the events of a session.
+func (c *SessionsAPIController) StateHistoryHandler(rw http.ResponseWriter, req *http.Request) {
+	key := req.URL.Query().Get("key")
+	if key == "" {
+		http.Error(rw, "key parameter is required", http.StatusBadRequest)
+		return
+	}
+	sess, status, err := c.getFullSession(req)
+	if err != nil {
+		http.Error(rw, err.Error(), status)
+		return
+	}
+	changes := []models.StateChange{}
+	for _, change := range session.StateHistory(sess, key) {
+		changes = append(changes, models.FromStateChange(change))
+	}
+	EncodeJSONResponse(changes, http.StatusOK, rw)
+}
+
+// SessionStateHandler returns the state of a session right after the event
+// of the query parameter event_id, or the current state without it.
+func (c *SessionsAPIController) SessionStateHandler(rw http.ResponseWriter, req *http.Request) {
+	sess, status, err := c.getFullSession(req)
+	if err != nil {
+		http.Error(rw, err.Error(), status)
+		return
+	}
+	eventID := req.URL.Query().Get("event_id")
+	if eventID == "" {
+		EncodeJSONResponse(maps.Collect(sess.State().All()), http.StatusOK, rw)
+		return
+	}
+	state, err := session.StateAt(sess, eventID)
+	switch {
+	case errors.Is(err, session.ErrEventNotFound):
+		http.Error(rw, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, errors.ErrUnsupported):
+		http.Error(rw, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	EncodeJSONResponse(state, http.StatusOK, rw)
+}
+
+// SearchEventsHandler searches the events of the sessions of an app for the
+// words of the query parameter q. The query parameters user_id and author
+// filter the events by user and author, after and before (RFC 3339 times)
+// by time, and limit is the maximum number of events to return.
+func (c *SessionsAPIController) SearchEventsHandler(rw http.ResponseWriter, req *http.Request) {
+	appName := mux.Vars(req)["app_name"]
+	if appName == "" {
+		http.Error(rw, "app_name parameter is required", http.StatusBadRequest)
+		return
+	}
+	searchReq, err := searchRequestFromQuery(appName, req.URL.Query())
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	searcher, ok := c.service.(session.EventSearcher)
+	if !ok {
+		http.Error(rw, "the session service does not support searching events", http.StatusNotImplemented)
+		return
+	}
+	resp, err := searcher.SearchEvents(req.Context(), searchReq)
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+		http.Error(rw, err.Error(), http.StatusNotImplemented)
+		return
+	case err != nil:
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	results := []models.SearchResult{}
+	for _, result := range resp.Results {
+		results = append(results, models.FromSearchResult(result))
+	}
+	EncodeJSONResponse(results, http.StatusOK, rw)
+}
+
+// searchRequestFromQuery returns the request searching the events of the
+// app with the options of the query parameters, see SearchEventsHandler.
+func searchRequestFromQuery(appName string, query url.Values) (*session.SearchEventsRequest, error) {
+	searchReq := &session.SearchEventsRequest{
+		AppName: appName,
+		UserID:  query.Get("user_id"),
+		Query:   query.Get("q"),
+		Author:  query.Get("author"),
+	}
+	if strings.TrimSpace(searchReq.Query) == "" {
+		return nil, fmt.Errorf("q parameter is required")
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("limit parameter must be a non-negative integer")
+		}
+		searchReq.Limit = limit
+	}
+	for name, field := range map[string]*time.Time{
+		"after":  &searchReq.After,
+		"before": &searchReq.Before,
+	} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s parameter must be an RFC 3339 time: %w", name, err)
+			}
+			*field = t
+		}
+	}
+	return searchReq, nil
+}
+
+// NextPageTokenHeader is the response header of ListSessionsHandler holding
+// the token of the next page of sessions, absent on the last page.
+const NextPageTokenHeader = "X-Next-Page-Token"
+
 // ListSessions handles listing all sessions for a given app and user.
+// The query parameters page_size and page_token paginate the sessions,
+// order_by orders them ("update_time" or "update_time desc"), and
+// created_after and created_before (RFC 3339 times) and state.<key> (a JSON
+// value, or a string if it is not valid JSON) filter them. With
+// metadata_only=true, the sessions have no state and events.
 func (c *SessionsAPIController) ListSessionsHandler(rw http.ResponseWriter, req *http.Request) {
 	params := mux.Vars(req)
 	sessionID, err := models.SessionIDFromHTTPParameters(params)
@@ -143,15 +297,20 @@ func (c *SessionsAPIController) ListSessionsHandler(rw http.ResponseWriter, req
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
+	listReq, err := listRequestFromQuery(sessionID, req.URL.Query())
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var sessions []models.Session
-	resp, err := c.service.List(req.Context(), &session.ListRequest{
-		AppName: sessionID.AppName,
-		UserID:  sessionID.UserID,
-	})
+	resp, err := c.service.List(req.Context(), listReq)
 	if err != nil {
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if resp.NextPageToken != "" {
+		rw.Header().Set(NextPageTokenHeader, resp.NextPageToken)
+	}
 	for _, session := range resp.Sessions {
 		respSession, err := models.FromSession(session)
 		if err != nil {
@@ -162,3 +321,64 @@ func (c *SessionsAPIController) ListSessionsHandler(rw http.ResponseWriter, req
 	}
 	EncodeJSONResponse(sessions, http.StatusOK, rw)
 }
+
+// listRequestFromQuery returns the request listing the sessions of the user
+// with the options of the query parameters, see ListSessionsHandler.
+func listRequestFromQuery(sessionID models.SessionID, query url.Values) (*session.ListRequest, error) {
+	listReq := &session.ListRequest{
+		AppName:   sessionID.AppName,
+		UserID:    sessionID.UserID,
+		PageToken: query.Get("page_token"),
+	}
+	if v := query.Get("page_size"); v != "" {
+		pageSize, err := strconv.Atoi(v)
+		if err != nil || pageSize < 0 {
+			return nil, fmt.Errorf("page_size parameter must be a non-negative integer")
+		}
+		listReq.PageSize = pageSize
+	}
+	switch orderBy := query.Get("order_by"); orderBy {
+	case "":
+		listReq.OrderBy = session.ListOrderID
+	case "update_time", "update_time asc":
+		listReq.OrderBy = session.ListOrderUpdateTimeAsc
+	case "update_time desc":
+		listReq.OrderBy = session.ListOrderUpdateTimeDesc
+	default:
+		return nil, fmt.Errorf("unsupported order_by parameter %q", orderBy)
+	}
+	for name, field := range map[string]*time.Time{
+		"created_after":  &listReq.CreatedAfter,
+		"created_before": &listReq.CreatedBefore,
+	} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s parameter must be an RFC 3339 time: %w", name, err)
+			}
+			*field = t
+		}
+	}
+	if v := query.Get("metadata_only"); v != "" {
+		metadataOnly, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("metadata_only parameter must be a boolean")
+		}
+		listReq.MetadataOnly = metadataOnly
+	}
+	for name, values := range query {
+		key, ok := strings.CutPrefix(name, "state.")
+		if !ok || key == "" {
+			continue
+		}
+		if listReq.StateFilter == nil {
+			listReq.StateFilter = make(map[string]any)
+		}
+		var value any
+		if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+			value = values[0]
+		}
+		listReq.StateFilter[key] = value
+	}
+	return listReq, nil
+}
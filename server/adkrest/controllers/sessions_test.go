@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -27,10 +29,13 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/gorilla/mux"
+	"google.golang.org/genai"
 
+	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/server/adkrest/controllers"
 	"github.com/sjzsdu/adk-go/server/adkrest/internal/fakes"
 	"github.com/sjzsdu/adk-go/server/adkrest/internal/models"
+	"github.com/sjzsdu/adk-go/session"
 )
 
 func TestGetSession(t *testing.T) {
@@ -436,6 +441,362 @@ func TestListSessions(t *testing.T) {
 	}
 }
 
+func TestListSessions_Query(t *testing.T) {
+	sessionService := session.InMemoryService()
+	for i, sessionID := range []string{"s1", "s2", "s3"} {
+		created, err := sessionService.Create(t.Context(), &session.CreateRequest{
+			AppName:   "testApp",
+			UserID:    "testUser",
+			SessionID: sessionID,
+			State:     map[string]any{"kind": "chat", "n": i},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev := session.NewEvent("inv")
+		ev.Timestamp = time.Now().Add(time.Duration(i) * time.Minute)
+		if err := sessionService.AppendEvent(t.Context(), created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apiController := controllers.NewSessionsAPIController(sessionService)
+
+	list := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "/apps/testApp/users/testUser/sessions?"+query, nil)
+		if err != nil {
+			t.Fatalf("new request: %v", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{
+			"app_name": "testApp",
+			"user_id":  "testUser",
+		})
+		rr := httptest.NewRecorder()
+		apiController.ListSessionsHandler(rr, req)
+		return rr
+	}
+
+	tc := []struct {
+		name       string
+		query      string
+		wantIDs    []string
+		wantStatus int
+	}{
+		{
+			name:       "state filter",
+			query:      "state.kind=chat&state.n=1",
+			wantIDs:    []string{"s2"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "order by update time",
+			query:      "order_by=update_time+desc",
+			wantIDs:    []string{"s3", "s2", "s1"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "created before",
+			query:      "created_before=2000-01-01T00:00:00Z",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid page size",
+			query:      "page_size=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid order",
+			query:      "order_by=id",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid time",
+			query:      "created_after=yesterday",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := list(tt.query)
+			if status := rr.Code; status != tt.wantStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []models.Session
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			var ids []string
+			for _, s := range got {
+				ids = append(ids, s.ID)
+			}
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("ListSessions() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		var ids []string
+		query := "page_size=2&metadata_only=true"
+		for range 3 {
+			rr := list(query)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+			var got []models.Session
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			for _, s := range got {
+				if len(s.State) != 0 {
+					t.Errorf("session %q has state %v in metadata only mode, want none", s.ID, s.State)
+				}
+				ids = append(ids, s.ID)
+			}
+			token := rr.Header().Get(controllers.NextPageTokenHeader)
+			if token == "" {
+				break
+			}
+			query = "page_size=2&metadata_only=true&page_token=" + token
+		}
+		if diff := cmp.Diff([]string{"s1", "s2", "s3"}, ids); diff != "" {
+			t.Errorf("ListSessions() pages mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestSessionStateHandlers(t *testing.T) {
+	sessionService := session.InMemoryService()
+	created, err := sessionService.Create(t.Context(), &session.CreateRequest{
+		AppName:   "testApp",
+		UserID:    "testUser",
+		SessionID: "testSession",
+		State:     map[string]any{"color": "red"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, ev := range []*session.Event{
+		{ID: "e1", InvocationID: "inv1", Author: "planner", Timestamp: now, Actions: session.EventActions{StateDelta: map[string]any{"color": "blue"}}},
+		{ID: "e2", InvocationID: "inv1", Author: "writer", Timestamp: now, Actions: session.EventActions{StateDelta: map[string]any{"color": "green"}}},
+	} {
+		if err := sessionService.AppendEvent(t.Context(), created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apiController := controllers.NewSessionsAPIController(sessionService)
+	vars := sessionVars(fakes.SessionKey{AppName: "testApp", UserID: "testUser", SessionID: "testSession"})
+
+	tc := []struct {
+		name       string
+		handler    http.HandlerFunc
+		query      string
+		vars       map[string]string
+		want       any
+		wantStatus int
+	}{
+		{
+			name:    "history",
+			handler: apiController.StateHistoryHandler,
+			query:   "key=color",
+			vars:    vars,
+			want: []models.StateChange{
+				{EventID: "e1", InvocationID: "inv1", Author: "planner", Time: now.Unix(), OldValue: "red", NewValue: "blue"},
+				{EventID: "e2", InvocationID: "inv1", Author: "writer", Time: now.Unix(), OldValue: "blue", NewValue: "green"},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "history of an unchanged key",
+			handler:    apiController.StateHistoryHandler,
+			query:      "key=size",
+			vars:       vars,
+			want:       []models.StateChange{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "history without key",
+			handler:    apiController.StateHistoryHandler,
+			vars:       vars,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "current state",
+			handler:    apiController.SessionStateHandler,
+			vars:       vars,
+			want:       map[string]any{"color": "green"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "state at event",
+			handler:    apiController.SessionStateHandler,
+			query:      "event_id=e1",
+			vars:       vars,
+			want:       map[string]any{"color": "blue"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "state at unknown event",
+			handler:    apiController.SessionStateHandler,
+			query:      "event_id=e3",
+			vars:       vars,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "unknown session",
+			handler:    apiController.SessionStateHandler,
+			vars:       sessionVars(fakes.SessionKey{AppName: "testApp", UserID: "testUser", SessionID: "unknown"}),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/state?"+tt.query, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			req = mux.SetURLVars(req, tt.vars)
+			rr := httptest.NewRecorder()
+			tt.handler(rr, req)
+			if status := rr.Code; status != tt.wantStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			got := reflect.New(reflect.TypeOf(tt.want))
+			if err := json.NewDecoder(rr.Body).Decode(got.Interface()); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got.Elem().Interface()); diff != "" {
+				t.Errorf("handler response mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchEventsHandler(t *testing.T) {
+	sessionService := session.InMemoryService()
+	now := time.Now()
+	for _, userID := range []string{"alice", "bob"} {
+		created, err := sessionService.Create(t.Context(), &session.CreateRequest{AppName: "testApp", UserID: userID, SessionID: "testSession"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev := &session.Event{
+			ID:          "e-" + userID,
+			Author:      "agent",
+			Timestamp:   now,
+			LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("Hello "+userID+", how are you?", genai.RoleModel)},
+		}
+		if err := sessionService.AppendEvent(t.Context(), created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apiController := controllers.NewSessionsAPIController(sessionService)
+	vars := map[string]string{"app_name": "testApp"}
+	result := func(userID string) models.SearchResult {
+		return models.SearchResult{
+			UserID:    userID,
+			SessionID: "testSession",
+			Event: models.Event{
+				ID:      "e-" + userID,
+				Time:    now.Unix(),
+				Author:  "agent",
+				Content: genai.NewContentFromText("Hello "+userID+", how are you?", genai.RoleModel),
+			},
+		}
+	}
+
+	tc := []struct {
+		name       string
+		controller *controllers.SessionsAPIController
+		query      string
+		want       []models.SearchResult
+		wantStatus int
+	}{
+		{
+			name:       "words",
+			controller: apiController,
+			query:      "q=hello+bob",
+			want:       []models.SearchResult{result("bob")},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "user",
+			controller: apiController,
+			query:      "q=hello&user_id=alice",
+			want:       []models.SearchResult{result("alice")},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no match",
+			controller: apiController,
+			query:      "q=hello&author=user",
+			want:       []models.SearchResult{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "time range",
+			controller: apiController,
+			query:      "q=hello&after=" + url.QueryEscape(now.Add(time.Hour).Format(time.RFC3339)),
+			want:       []models.SearchResult{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "without query",
+			controller: apiController,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid limit",
+			controller: apiController,
+			query:      "q=hello&limit=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid time",
+			controller: apiController,
+			query:      "q=hello&before=yesterday",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unsupported",
+			controller: controllers.NewSessionsAPIController(&fakes.FakeSessionService{}),
+			query:      "q=hello",
+			wantStatus: http.StatusNotImplemented,
+		},
+	}
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/events/search?"+tt.query, nil)
+			if err != nil {
+				t.Fatalf("new request: %v", err)
+			}
+			req = mux.SetURLVars(req, vars)
+			rr := httptest.NewRecorder()
+			tt.controller.SearchEventsHandler(rr, req)
+			if status := rr.Code; status != tt.wantStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got []models.SearchResult
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("handler response mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func sessionVars(sessionID fakes.SessionKey) map[string]string {
 	return map[string]string{
 		"app_name":   sessionID.AppName,
@@ -185,4 +185,36 @@ func (s *FakeSessionService) AppendEvent(ctx context.Context, curSession session
 	return nil
 }
 
+func (s *FakeSessionService) Rewind(ctx context.Context, req *session.RewindRequest) (*session.RewindResponse, error) {
+	id := SessionKey{
+		AppName:   req.AppName,
+		UserID:    req.UserID,
+		SessionID: req.SessionID,
+	}
+	testSession, ok := s.Sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	for i, event := range testSession.SessionEvents {
+		if event.InvocationID == req.InvocationID {
+			testSession.SessionEvents = testSession.SessionEvents[:i]
+			s.Sessions[id] = testSession
+			return &session.RewindResponse{Session: &testSession}, nil
+		}
+	}
+	return nil, fmt.Errorf("invocation not found")
+}
+
+func (s *FakeSessionService) Fork(ctx context.Context, req *session.ForkRequest) (*session.ForkResponse, error) {
+	parent, ok := s.Sessions[SessionKey{AppName: req.AppName, UserID: req.UserID, SessionID: req.SessionID}]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	forked := parent
+	forked.Id.SessionID = req.NewSessionID
+	forked.UpdatedAt = time.Now()
+	s.Sessions[forked.Id] = forked
+	return &session.ForkResponse{Session: &forked}, nil
+}
+
 var _ session.Service = (*FakeSessionService)(nil)
@@ -31,6 +31,10 @@ type Session struct {
 	UpdatedAt int64          `json:"lastUpdateTime"`
 	Events    []Event        `json:"events"`
 	State     map[string]any `json:"state"`
+	// ParentSessionID and ForkEventID tell where the session was forked
+	// from, see session.ForkOriginOf.
+	ParentSessionID string `json:"parentSessionId,omitempty"`
+	ForkEventID     string `json:"forkEventId,omitempty"`
 }
 
 type CreateSessionRequest struct {
@@ -66,21 +70,25 @@ func SessionIDFromHTTPParameters(vars map[string]string) (SessionID, error) {
 	return sessionID, nil
 }
 
-func FromSession(session session.Session) (Session, error) {
+func FromSession(sess session.Session) (Session, error) {
 	state := map[string]any{}
-	maps.Insert(state, session.State().All())
+	maps.Insert(state, sess.State().All())
 	events := []Event{}
-	for event := range session.Events().All() {
+	for event := range sess.Events().All() {
 		events = append(events, FromSessionEvent(*event))
 	}
 	mappedSession := Session{
-		ID:        session.ID(),
-		AppName:   session.AppName(),
-		UserID:    session.UserID(),
-		UpdatedAt: session.LastUpdateTime().Unix(),
+		ID:        sess.ID(),
+		AppName:   sess.AppName(),
+		UserID:    sess.UserID(),
+		UpdatedAt: sess.LastUpdateTime().Unix(),
 		Events:    events,
 		State:     state,
 	}
+	if origin := session.ForkOriginOf(sess); origin != nil {
+		mappedSession.ParentSessionID = origin.SessionID
+		mappedSession.ForkEventID = origin.EventID
+	}
 	return mappedSession, mappedSession.Validate()
 }
 
@@ -105,3 +113,43 @@ func (s Session) Validate() error {
 	}
 	return nil
 }
+
+// StateChange represents a change of a state key by an event, see
+// session.StateHistory.
+type StateChange struct {
+	EventID      string `json:"eventId"`
+	InvocationID string `json:"invocationId"`
+	Author       string `json:"author"`
+	Time         int64  `json:"time"`
+	OldValue     any    `json:"oldValue"`
+	NewValue     any    `json:"newValue"`
+}
+
+// FromStateChange maps session.StateChange to StateChange data struct
+func FromStateChange(change session.StateChange) StateChange {
+	return StateChange{
+		EventID:      change.EventID,
+		InvocationID: change.InvocationID,
+		Author:       change.Author,
+		Time:         change.Timestamp.Unix(),
+		OldValue:     change.OldValue,
+		NewValue:     change.NewValue,
+	}
+}
+
+// SearchResult represents an event matching a search, see
+// session.SearchEventsRequest.
+type SearchResult struct {
+	UserID    string `json:"userId"`
+	SessionID string `json:"sessionId"`
+	Event     Event  `json:"event"`
+}
+
+// FromSearchResult maps session.SearchResult to SearchResult data struct
+func FromSearchResult(result session.SearchResult) SearchResult {
+	return SearchResult{
+		UserID:    result.UserID,
+		SessionID: result.SessionID,
+		Event:     FromSessionEvent(*result.Event),
+	}
+}
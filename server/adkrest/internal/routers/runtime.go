@@ -45,5 +45,17 @@ func (r *RuntimeAPIRouter) Routes() Routes {
 			Pattern:     "/run_sse",
 			HandlerFunc: controllers.NewErrorHandler(r.runtimeController.RunSSEHandler),
 		},
+		Route{
+			Name:        "CancelInvocation",
+			Methods:     []string{http.MethodPost, http.MethodOptions},
+			Pattern:     "/apps/{app_name}/users/{user_id}/sessions/{session_id}/invocations/{invocation_id:[^/:]+}:cancel",
+			HandlerFunc: controllers.NewErrorHandler(r.runtimeController.CancelInvocationHandler),
+		},
+		Route{
+			Name:        "RewindSession",
+			Methods:     []string{http.MethodPost, http.MethodOptions},
+			Pattern:     "/apps/{app_name}/users/{user_id}/sessions/{session_id}/invocations/{invocation_id:[^/:]+}:rewind",
+			HandlerFunc: controllers.NewErrorHandler(r.runtimeController.RewindHandler),
+		},
 	}
 }
@@ -63,5 +63,23 @@ func (r *SessionsAPIRouter) Routes() Routes {
 			Pattern:     "/apps/{app_name}/users/{user_id}/sessions",
 			HandlerFunc: r.sessionController.ListSessionsHandler,
 		},
+		Route{
+			Name:        "GetSessionState",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/apps/{app_name}/users/{user_id}/sessions/{session_id}/state",
+			HandlerFunc: r.sessionController.SessionStateHandler,
+		},
+		Route{
+			Name:        "GetSessionStateHistory",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/apps/{app_name}/users/{user_id}/sessions/{session_id}/state/history",
+			HandlerFunc: r.sessionController.StateHistoryHandler,
+		},
+		Route{
+			Name:        "SearchEvents",
+			Methods:     []string{http.MethodGet},
+			Pattern:     "/apps/{app_name}/events/search",
+			HandlerFunc: r.sessionController.SearchEventsHandler,
+		},
 	}
 }
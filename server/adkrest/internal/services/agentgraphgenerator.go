@@ -39,6 +39,7 @@ var supportedClusterAgents = []agentinternal.Type{
 	agentinternal.TypeLoopAgent,
 	agentinternal.TypeSequentialAgent,
 	agentinternal.TypeParallelAgent,
+	agentinternal.TypeMapAgent,
 }
 
 type namedInstance interface {
@@ -0,0 +1,488 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// encryptedField is the only field of the JSON object holding an encrypted
+// value in place of the plaintext JSON value.
+const encryptedField = "$encrypted"
+
+// maxCachedDataKeys is the number of decrypted data keys kept by an
+// encryptor, so that reading values encrypted with the same data key does
+// not decrypt it again.
+const maxCachedDataKeys = 1024
+
+// reencryptBatchSize is the number of rows Reencrypt encrypts in a
+// transaction.
+const reencryptBatchSize = 100
+
+// envelope is an encrypted value, stored as the JSON object
+// {"$encrypted": envelope}.
+type envelope struct {
+	// KeyID is the ID of the key encryption key of the KeyProvider which
+	// encrypted the data key.
+	KeyID string `json:"keyId"`
+	// WrappedKey is the encrypted data key.
+	WrappedKey []byte `json:"wrappedKey"`
+	// Data is the value encrypted with the data key.
+	Data []byte `json:"data"`
+}
+
+type encryptedValue struct {
+	Envelope *envelope `json:"$encrypted"`
+}
+
+// additionalData returns the additional data authenticated with the
+// encrypted value of a column of a row: the table, the primary key of the
+// row and the column. A value copied to another row or column, e.g. of
+// another session or user, no longer decrypts.
+func additionalData(table string, key []string, column string) []byte {
+	data, _ := json.Marshal(append(append([]string{table}, key...), column))
+	return data
+}
+
+func (ss *storageSession) additionalData(column string) []byte {
+	return additionalData(ss.TableName(), []string{ss.AppName, ss.UserID, ss.ID}, column)
+}
+
+func (se *storageEvent) additionalData(column string) []byte {
+	return additionalData(se.TableName(), []string{se.AppName, se.UserID, se.SessionID, se.ID}, column)
+}
+
+func (sa *storageAppState) additionalData() []byte {
+	return additionalData(sa.TableName(), []string{sa.AppName}, "state")
+}
+
+func (su *storageUserState) additionalData() []byte {
+	return additionalData(su.TableName(), []string{su.AppName, su.UserID}, "state")
+}
+
+// parseEnvelope returns the envelope of an encrypted JSON value, nil if the
+// value is not encrypted.
+func parseEnvelope(data []byte) (*envelope, error) {
+	if !bytes.Contains(data, []byte(`"`+encryptedField+`"`)) {
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || len(fields) != 1 {
+		// A value containing the field name.
+		return nil, nil
+	}
+	var v encryptedValue
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	return v.Envelope, nil
+}
+
+// encryptor encrypts the values stored by a session service with data keys
+// encrypted by a KeyProvider. A nil encryptor stores the values in
+// plaintext.
+type encryptor struct {
+	keys KeyProvider
+
+	mu sync.Mutex
+	// dataKeys are the decrypted data keys, by encrypted data key.
+	dataKeys map[string]cipher.AEAD
+}
+
+func newEncryptor(keys KeyProvider) *encryptor {
+	return &encryptor{keys: keys, dataKeys: make(map[string]cipher.AEAD)}
+}
+
+// newSealer returns a sealer encrypting values with a new data key, nil if
+// the encryptor is nil.
+func (e *encryptor) newSealer(ctx context.Context) (*sealer, error) {
+	if e == nil {
+		return nil, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := e.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+	return &sealer{keyID: keyID, wrappedKey: wrapped, aead: aead}, nil
+}
+
+// open returns the plaintext of a JSON value, decrypting it if it is
+// encrypted. The additional data must be the one it was encrypted with.
+func (e *encryptor) open(ctx context.Context, data, additionalData []byte) ([]byte, error) {
+	env, err := parseEnvelope(data)
+	if err != nil || env == nil {
+		return data, err
+	}
+	if e == nil {
+		return nil, fmt.Errorf("value is encrypted, the session service has no key provider")
+	}
+	aead, err := e.dataKey(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, env.Data, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+// dataKey returns the decrypted data key of an envelope.
+func (e *encryptor) dataKey(ctx context.Context, env *envelope) (cipher.AEAD, error) {
+	cacheKey := env.KeyID + "/" + string(env.WrappedKey)
+	e.mu.Lock()
+	aead, ok := e.dataKeys[cacheKey]
+	e.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	dataKey, err := e.keys.UnwrapKey(ctx, env.KeyID, env.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	aead, err = newAEAD(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key: %w", err)
+	}
+	e.mu.Lock()
+	if len(e.dataKeys) >= maxCachedDataKeys {
+		clear(e.dataKeys)
+	}
+	e.dataKeys[cacheKey] = aead
+	e.mu.Unlock()
+	return aead, nil
+}
+
+// openState returns the plaintext of a state, decrypting it if it is
+// encrypted.
+func (e *encryptor) openState(ctx context.Context, state stateMap, additionalData []byte) (stateMap, error) {
+	if _, ok := state[encryptedField]; !ok || len(state) != 1 {
+		return state, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := e.open(ctx, data, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	var opened stateMap
+	if err := opened.Scan(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal decrypted state: %w", err)
+	}
+	return opened, nil
+}
+
+// openSession decrypts the states of a stored session.
+func (e *encryptor) openSession(ctx context.Context, ss *storageSession) error {
+	var err error
+	if ss.State, err = e.openState(ctx, ss.State, ss.additionalData("state")); err != nil {
+		return fmt.Errorf("failed to decrypt session state: %w", err)
+	}
+	if ss.InitialState != nil {
+		if ss.InitialState, err = e.openState(ctx, ss.InitialState, ss.additionalData("initial_state")); err != nil {
+			return fmt.Errorf("failed to decrypt session initial state: %w", err)
+		}
+	}
+	return nil
+}
+
+// openEvent returns a copy of a stored event with its fields decrypted.
+func (e *encryptor) openEvent(ctx context.Context, se *storageEvent) (*storageEvent, error) {
+	opened := *se
+	var err error
+	if len(se.Actions) > 0 {
+		if opened.Actions, err = e.open(ctx, se.Actions, se.additionalData("actions")); err != nil {
+			return nil, fmt.Errorf("failed to decrypt event actions: %w", err)
+		}
+	}
+	for column, field := range opened.encryptedFields() {
+		if len(*field) == 0 {
+			continue
+		}
+		plaintext, err := e.open(ctx, *field, se.additionalData(column))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt event: %w", err)
+		}
+		*field = plaintext
+	}
+	return &opened, nil
+}
+
+// encryptedFields returns the JSON fields of the event which are encrypted,
+// besides the actions, by column.
+func (se *storageEvent) encryptedFields() map[string]*dynamicJSON {
+	return map[string]*dynamicJSON{
+		"content":            &se.Content,
+		"grounding_metadata": &se.GroundingMetadata,
+		"custom_metadata":    &se.CustomMetadata,
+		"citation_metadata":  &se.CitationMetadata,
+	}
+}
+
+// sealer encrypts values with a data key. A nil sealer returns the values
+// unchanged.
+type sealer struct {
+	keyID      string
+	wrappedKey []byte
+	aead       cipher.AEAD
+}
+
+// seal returns the encrypted JSON value of a JSON value, bound to the
+// additional data.
+func (s *sealer) seal(data, additionalData []byte) ([]byte, error) {
+	if s == nil || len(data) == 0 {
+		return data, nil
+	}
+	ciphertext, err := seal(s.aead, data, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encryptedValue{Envelope: &envelope{
+		KeyID:      s.keyID,
+		WrappedKey: s.wrappedKey,
+		Data:       ciphertext,
+	}})
+}
+
+// sealState returns the encrypted value of a state, bound to the additional
+// data.
+func (s *sealer) sealState(state stateMap, additionalData []byte) (stateMap, error) {
+	if s == nil {
+		return state, nil
+	}
+	data, err := state.Value()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.seal([]byte(data.(string)), additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt state: %w", err)
+	}
+	var v stateMap
+	if err := json.Unmarshal(sealed, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// sealSession returns a copy of a stored session with its states encrypted.
+func (s *sealer) sealSession(ss *storageSession) (*storageSession, error) {
+	sealed := *ss
+	var err error
+	if sealed.State, err = s.sealState(ss.State, ss.additionalData("state")); err != nil {
+		return nil, err
+	}
+	if ss.InitialState != nil {
+		if sealed.InitialState, err = s.sealState(ss.InitialState, ss.additionalData("initial_state")); err != nil {
+			return nil, err
+		}
+	}
+	return &sealed, nil
+}
+
+// sealEvent encrypts the fields of a stored event.
+func (s *sealer) sealEvent(se *storageEvent) error {
+	var err error
+	if se.Actions, err = s.seal(se.Actions, se.additionalData("actions")); err != nil {
+		return fmt.Errorf("failed to encrypt event actions: %w", err)
+	}
+	for column, field := range se.encryptedFields() {
+		if *field, err = s.seal(*field, se.additionalData(column)); err != nil {
+			return fmt.Errorf("failed to encrypt event: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reencrypt encrypts again the states and the events stored by a session
+// service created by NewEncryptedSessionService, with new data keys
+// encrypted with the current key encryption key of its KeyProvider. Once it
+// returns, the previous key encryption keys are no longer used and can be
+// retired. The values stored in plaintext are encrypted too. It returns the
+// number of rows encrypted again.
+//
+// The rows are encrypted by batches, each in a transaction locking the rows
+// of the batch, so the service can be used meanwhile.
+func Reencrypt(ctx context.Context, service session.Service) (int, error) {
+	dbservice, ok := service.(*databaseService)
+	if !ok {
+		return 0, fmt.Errorf("invalid session service type")
+	}
+	e := dbservice.enc
+	if e == nil {
+		return 0, fmt.Errorf("session service does not encrypt")
+	}
+	db := dbservice.db.WithContext(ctx)
+
+	var total int
+	n, err := reencryptTable(ctx, db, e, []string{"app_name", "user_id", "id"},
+		func(ss *storageSession) []any { return []any{ss.AppName, ss.UserID, ss.ID} },
+		func(sealer *sealer, ss *storageSession) (map[string]any, error) {
+			if err := e.openSession(ctx, ss); err != nil {
+				return nil, err
+			}
+			sealed, err := sealer.sealSession(ss)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{"state": sealed.State, "initial_state": sealed.InitialState}, nil
+		})
+	total += n
+	if err != nil {
+		return total, fmt.Errorf("failed to reencrypt sessions: %w", err)
+	}
+
+	n, err = reencryptTable(ctx, db, e, []string{"app_name", "user_id", "session_id", "id"},
+		func(se *storageEvent) []any { return []any{se.AppName, se.UserID, se.SessionID, se.ID} },
+		func(sealer *sealer, se *storageEvent) (map[string]any, error) {
+			opened, err := e.openEvent(ctx, se)
+			if err != nil {
+				return nil, err
+			}
+			if err := sealer.sealEvent(opened); err != nil {
+				return nil, err
+			}
+			return map[string]any{
+				"actions":            opened.Actions,
+				"content":            opened.Content,
+				"grounding_metadata": opened.GroundingMetadata,
+				"custom_metadata":    opened.CustomMetadata,
+				"citation_metadata":  opened.CitationMetadata,
+			}, nil
+		})
+	total += n
+	if err != nil {
+		return total, fmt.Errorf("failed to reencrypt events: %w", err)
+	}
+
+	n, err = reencryptTable(ctx, db, e, []string{"app_name"},
+		func(sa *storageAppState) []any { return []any{sa.AppName} },
+		func(sealer *sealer, sa *storageAppState) (map[string]any, error) {
+			return reencryptState(ctx, e, sealer, sa.State, sa.additionalData())
+		})
+	total += n
+	if err != nil {
+		return total, fmt.Errorf("failed to reencrypt app states: %w", err)
+	}
+
+	n, err = reencryptTable(ctx, db, e, []string{"app_name", "user_id"},
+		func(su *storageUserState) []any { return []any{su.AppName, su.UserID} },
+		func(sealer *sealer, su *storageUserState) (map[string]any, error) {
+			return reencryptState(ctx, e, sealer, su.State, su.additionalData())
+		})
+	total += n
+	if err != nil {
+		return total, fmt.Errorf("failed to reencrypt user states: %w", err)
+	}
+	return total, nil
+}
+
+// reencryptState returns the update of the state column of a row,
+// encrypting its state again.
+func reencryptState(ctx context.Context, e *encryptor, sealer *sealer, state stateMap, additionalData []byte) (map[string]any, error) {
+	opened, err := e.openState(ctx, state, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := sealer.sealState(opened, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"state": sealed}, nil
+}
+
+// reencryptTable encrypts again the rows of a table, by batches in the
+// order of their key columns, returning the number of rows. The
+// reencrypt function returns the updated columns of a row.
+func reencryptTable[T any](ctx context.Context, db *gorm.DB, e *encryptor, keyColumns []string, key func(*T) []any, reencrypt func(*sealer, *T) (map[string]any, error)) (int, error) {
+	var n int
+	var after []any
+	for {
+		var rows []T
+		err := db.Transaction(func(tx *gorm.DB) error {
+			query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Order(strings.Join(keyColumns, ", ")).
+				Limit(reencryptBatchSize)
+			if after != nil {
+				cond, args := keyAfter(keyColumns, after)
+				query = query.Where(cond, args...)
+			}
+			if err := query.Find(&rows).Error; err != nil {
+				return err
+			}
+			if len(rows) == 0 {
+				return nil
+			}
+			sealer, err := e.newSealer(ctx)
+			if err != nil {
+				return err
+			}
+			for i := range rows {
+				updates, err := reencrypt(sealer, &rows[i])
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&rows[i]).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return n, err
+		}
+		n += len(rows)
+		if len(rows) < reencryptBatchSize {
+			return n, nil
+		}
+		after = key(&rows[len(rows)-1])
+	}
+}
+
+// keyAfter returns the condition selecting the rows whose key columns are
+// after the values, in the order of the columns.
+func keyAfter(columns []string, values []any) (string, []any) {
+	last := len(columns) - 1
+	cond := columns[last] + " > ?"
+	args := []any{values[last]}
+	for i := last - 1; i >= 0; i-- {
+		cond = fmt.Sprintf("%s > ? OR (%s = ? AND (%s))", columns[i], columns[i], cond)
+		args = append([]any{values[i], values[i]}, args...)
+	}
+	return "(" + cond + ")", args
+}
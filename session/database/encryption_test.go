@@ -0,0 +1,417 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"errors"
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/database/fakekms"
+)
+
+// openService opens the session service of a test database, encrypting
+// with keys if it is not nil.
+func openService(t *testing.T, name string, keys KeyProvider) session.Service {
+	t.Helper()
+	dialector := sqlite.Open("file:" + name + "?mode=memory&cache=shared")
+	var service session.Service
+	var err error
+	if keys != nil {
+		service, err = NewEncryptedSessionService(dialector, keys)
+	} else {
+		service, err = NewSessionService(dialector)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AutoMigrate(service); err != nil {
+		t.Fatal(err)
+	}
+	db, err := service.(*databaseService).db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return service
+}
+
+// appendSecret appends an event with secret content and state to the
+// session.
+func appendSecret(t *testing.T, service session.Service, appName, userID, sessionID, secret string) {
+	t.Helper()
+	resp, err := service.Get(t.Context(), &session.GetRequest{AppName: appName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := session.NewEvent("inv-" + secret)
+	ev.Author = "user"
+	ev.LLMResponse = model.LLMResponse{
+		Content:        genai.NewContentFromText("my secret is "+secret, genai.RoleUser),
+		CustomMetadata: map[string]any{"note": secret},
+	}
+	ev.Actions.StateDelta = map[string]any{"secret": secret, "user:secret": secret, "app:secret": secret}
+	if err := service.AppendEvent(t.Context(), resp.Session, ev); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// storedValues returns the raw values of the encrypted columns.
+func storedValues(t *testing.T, service session.Service) []string {
+	t.Helper()
+	db := service.(*databaseService).db
+	var values []string
+	for _, query := range []string{
+		"SELECT state FROM sessions",
+		"SELECT initial_state FROM sessions",
+		"SELECT state FROM app_states",
+		"SELECT state FROM user_states",
+		"SELECT CAST(actions AS TEXT) FROM events",
+		"SELECT content FROM events",
+		"SELECT custom_metadata FROM events",
+	} {
+		var column []string
+		if err := db.Raw(query).Scan(&column).Error; err != nil {
+			t.Fatalf("%s failed: %v", query, err)
+		}
+		values = append(values, column...)
+	}
+	return values
+}
+
+func TestEncryptedService(t *testing.T) {
+	ctx := t.Context()
+	kms := fakekms.New("projects/p/keyRings/r/cryptoKeys/sessions")
+	service := openService(t, "encrypted", kms)
+
+	if _, err := service.Create(ctx, &session.CreateRequest{
+		AppName:   "app",
+		UserID:    "user",
+		SessionID: "session",
+		State:     map[string]any{"initial": "hunter2", "user:initial": "hunter2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	appendSecret(t, service, "app", "user", "session", "swordfish")
+
+	for _, value := range storedValues(t, service) {
+		if strings.Contains(value, "swordfish") || strings.Contains(value, "hunter2") {
+			t.Errorf("stored value is not encrypted: %s", value)
+		}
+	}
+
+	wantState := map[string]any{
+		"initial":      "hunter2",
+		"user:initial": "hunter2",
+		"secret":       "swordfish",
+		"user:secret":  "swordfish",
+		"app:secret":   "swordfish",
+	}
+	got, err := service.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session"})
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if diff := cmp.Diff(wantState, maps.Collect(got.Session.State().All())); diff != "" {
+		t.Errorf("Get() state mismatch (-want +got):\n%s", diff)
+	}
+	ev := got.Session.Events().At(0)
+	if text := ev.Content.Parts[0].Text; text != "my secret is swordfish" {
+		t.Errorf("Get() event content = %q, want %q", text, "my secret is swordfish")
+	}
+	if diff := cmp.Diff(map[string]any{"note": "swordfish"}, ev.CustomMetadata); diff != "" {
+		t.Errorf("Get() event custom metadata mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]any{"initial": "hunter2"}, session.InitialStateOf(got.Session)); diff != "" {
+		t.Errorf("Get() initial state mismatch (-want +got):\n%s", diff)
+	}
+
+	listed, err := service.List(ctx, &session.ListRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if diff := cmp.Diff(wantState, maps.Collect(listed.Sessions[0].State().All())); diff != "" {
+		t.Errorf("List() state mismatch (-want +got):\n%s", diff)
+	}
+
+	// The data keys are decrypted once.
+	calls := kms.UnwrapCalls()
+	if _, err := service.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := kms.UnwrapCalls(); got != calls {
+		t.Errorf("Get() decrypted %d data keys again, want 0", got-calls)
+	}
+
+	// The events of a fork remain encrypted, and the state of a rewind is
+	// encrypted.
+	forked, err := service.Fork(ctx, &session.ForkRequest{AppName: "app", UserID: "user", SessionID: "session", NewSessionID: "fork"})
+	if err != nil {
+		t.Fatalf("Fork() failed: %v", err)
+	}
+	if text := forked.Session.Events().At(0).Content.Parts[0].Text; text != "my secret is swordfish" {
+		t.Errorf("Fork() event content = %q, want %q", text, "my secret is swordfish")
+	}
+	rewound, err := service.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "fork", InvocationID: "inv-swordfish"})
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if got, _ := rewound.Session.State().Get("initial"); got != "hunter2" {
+		t.Errorf("Rewind() state initial = %v, want hunter2", got)
+	}
+	for _, value := range storedValues(t, service) {
+		if strings.Contains(value, "swordfish") || strings.Contains(value, "hunter2") {
+			t.Errorf("stored value is not encrypted: %s", value)
+		}
+	}
+}
+
+func TestEncryptedService_Rotation(t *testing.T) {
+	ctx := t.Context()
+	kms := fakekms.New("projects/p/keyRings/r/cryptoKeys/sessions")
+	firstKey := kms.Rotate()
+	service := openService(t, "rotation", kms)
+	if _, err := service.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session"}); err != nil {
+		t.Fatal(err)
+	}
+	appendSecret(t, service, "app", "user", "session", "first")
+
+	kms.Rotate()
+	appendSecret(t, service, "app", "user", "session", "second")
+
+	// Without the previous key, the values it encrypted are lost: a new
+	// service has no decrypted data keys.
+	kms.Disable(firstKey)
+	if _, err := openService(t, "rotation", kms).Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session"}); err == nil {
+		t.Fatal("Get() without the previous key succeeded, want error")
+	}
+
+	// Reencrypt only needs the previous key until it returns.
+	kms = fakekms.New("projects/p/keyRings/r/cryptoKeys/sessions")
+	service = openService(t, "reencrypt", kms)
+	if _, err := service.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	appendSecret(t, service, "app", "user", "other", "first")
+	oldKey := kms.Rotate()
+	kms.Rotate()
+	n, err := Reencrypt(ctx, service)
+	if err != nil {
+		t.Fatalf("Reencrypt() failed: %v", err)
+	}
+	// The rows of the session, its event, the app and the user.
+	if want := 4; n != want {
+		t.Errorf("Reencrypt() = %d, want %d", n, want)
+	}
+	kms.Disable(oldKey)
+	kms.Disable("projects/p/keyRings/r/cryptoKeys/sessions/cryptoKeyVersions/1")
+	got, err := openService(t, "reencrypt", kms).Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "other"})
+	if err != nil {
+		t.Fatalf("Get() after Reencrypt() failed: %v", err)
+	}
+	if got, _ := got.Session.State().Get("user:secret"); got != "first" {
+		t.Errorf("Get() state user:secret = %v, want first", got)
+	}
+}
+
+func TestEncryptedService_ForkAfterRotation(t *testing.T) {
+	ctx := t.Context()
+	kms := fakekms.New("projects/p/keyRings/r/cryptoKeys/sessions")
+	service := openService(t, "fork_rotation", kms)
+	if _, err := service.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session", State: map[string]any{"initial": "hunter2"}}); err != nil {
+		t.Fatal(err)
+	}
+	appendSecret(t, service, "app", "user", "session", "swordfish")
+	if _, err := service.Fork(ctx, &session.ForkRequest{AppName: "app", UserID: "user", SessionID: "session", NewSessionID: "fork"}); err != nil {
+		t.Fatalf("Fork() failed: %v", err)
+	}
+
+	oldKey := kms.Rotate()
+	kms.Rotate()
+	if _, err := Reencrypt(ctx, service); err != nil {
+		t.Fatalf("Reencrypt() failed: %v", err)
+	}
+	kms.Disable(oldKey)
+	kms.Disable("projects/p/keyRings/r/cryptoKeys/sessions/cryptoKeyVersions/1")
+
+	reopened := openService(t, "fork_rotation", kms)
+	for _, sessionID := range []string{"session", "fork"} {
+		got, err := reopened.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: sessionID})
+		if err != nil {
+			t.Fatalf("Get(%q) after Reencrypt() failed: %v", sessionID, err)
+		}
+		if text := got.Session.Events().At(0).Content.Parts[0].Text; text != "my secret is swordfish" {
+			t.Errorf("Get(%q) event content = %q, want %q", sessionID, text, "my secret is swordfish")
+		}
+		if diff := cmp.Diff(map[string]any{"initial": "hunter2"}, session.InitialStateOf(got.Session)); diff != "" {
+			t.Errorf("Get(%q) initial state mismatch (-want +got):\n%s", sessionID, diff)
+		}
+	}
+}
+
+func TestEncryptedService_SwappedValues(t *testing.T) {
+	ctx := t.Context()
+	tests := []struct {
+		name string
+		// copy copies the encrypted value of a row of bob to the row of
+		// alice.
+		copy string
+	}{
+		{
+			name: "event content",
+			copy: "UPDATE events SET content = (SELECT content FROM events WHERE user_id = 'bob') WHERE user_id = 'alice'",
+		},
+		{
+			name: "event column",
+			copy: "UPDATE events SET custom_metadata = content WHERE user_id = 'alice'",
+		},
+		{
+			name: "session state",
+			copy: "UPDATE sessions SET state = (SELECT state FROM sessions WHERE user_id = 'bob') WHERE user_id = 'alice'",
+		},
+		{
+			name: "user state",
+			copy: "UPDATE user_states SET state = (SELECT state FROM user_states WHERE user_id = 'bob') WHERE user_id = 'alice'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := openService(t, "swapped_"+strings.ReplaceAll(tt.name, " ", "_"), fakekms.New("key"))
+			for _, userID := range []string{"alice", "bob"} {
+				if _, err := service.Create(ctx, &session.CreateRequest{AppName: "app", UserID: userID, SessionID: "session"}); err != nil {
+					t.Fatal(err)
+				}
+				appendSecret(t, service, "app", userID, "session", userID)
+			}
+			if err := service.(*databaseService).db.Exec(tt.copy).Error; err != nil {
+				t.Fatal(err)
+			}
+			if _, err := service.Get(ctx, &session.GetRequest{AppName: "app", UserID: "alice", SessionID: "session"}); err == nil {
+				t.Error("Get() of a session with a value of another row succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestEncryptedService_Plaintext(t *testing.T) {
+	ctx := t.Context()
+	plain := openService(t, "plaintext", nil)
+	if _, err := plain.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "plain", State: map[string]any{"k": "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	appendSecret(t, plain, "app", "user", "plain", "plain")
+
+	// The values stored before the encryption is enabled are read, and
+	// encrypted by Reencrypt.
+	encrypted := openService(t, "plaintext", fakekms.New("key"))
+	got, err := encrypted.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "plain"})
+	if err != nil {
+		t.Fatalf("Get() of a plaintext session failed: %v", err)
+	}
+	if got, _ := got.Session.State().Get("k"); got != "v" {
+		t.Errorf("Get() state k = %v, want v", got)
+	}
+	if _, err := Reencrypt(ctx, encrypted); err != nil {
+		t.Fatalf("Reencrypt() failed: %v", err)
+	}
+	for _, value := range storedValues(t, encrypted) {
+		if strings.Contains(value, "plain") {
+			t.Errorf("stored value is not encrypted: %s", value)
+		}
+	}
+
+	if _, err := plain.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "plain"}); err == nil {
+		t.Error("Get() of an encrypted session without key provider succeeded, want error")
+	}
+	if _, err := Reencrypt(ctx, plain); err == nil {
+		t.Error("Reencrypt() of a plaintext service succeeded, want error")
+	}
+}
+
+func TestKeyFileProvider(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if _, err := NewKeyFileProvider(path); err == nil {
+		t.Error("NewKeyFileProvider() of a missing file succeeded, want error")
+	}
+
+	firstID, err := RotateKeyFile(path)
+	if err != nil {
+		t.Fatalf("RotateKeyFile() failed: %v", err)
+	}
+	first, err := NewKeyFileProvider(path)
+	if err != nil {
+		t.Fatalf("NewKeyFileProvider() failed: %v", err)
+	}
+	dataKey := []byte(strings.Repeat("k", 32))
+	keyID, wrapped, err := first.WrapKey(ctx, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey() failed: %v", err)
+	}
+	if keyID != firstID {
+		t.Errorf("WrapKey() key ID = %q, want %q", keyID, firstID)
+	}
+
+	secondID, err := RotateKeyFile(path)
+	if err != nil {
+		t.Fatalf("RotateKeyFile() failed: %v", err)
+	}
+	second, err := NewKeyFileProvider(path)
+	if err != nil {
+		t.Fatalf("NewKeyFileProvider() failed: %v", err)
+	}
+	if keyID, _, err := second.WrapKey(ctx, dataKey); err != nil || keyID != secondID {
+		t.Errorf("WrapKey() after rotation = %q, %v, want %q", keyID, err, secondID)
+	}
+	unwrapped, err := second.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapKey() with the previous key failed: %v", err)
+	}
+	if string(unwrapped) != string(dataKey) {
+		t.Errorf("UnwrapKey() = %q, want %q", unwrapped, dataKey)
+	}
+	if _, err := second.UnwrapKey(ctx, "unknown", wrapped); err == nil {
+		t.Error("UnwrapKey() with an unknown key succeeded, want error")
+	}
+}
+
+func TestKeyAfter(t *testing.T) {
+	cond, args := keyAfter([]string{"a", "b", "c"}, []any{1, 2, time.Time{}})
+	if want := "(a > ? OR (a = ? AND (b > ? OR (b = ? AND (c > ?)))))"; cond != want {
+		t.Errorf("keyAfter() condition = %q, want %q", cond, want)
+	}
+	if diff := cmp.Diff([]any{1, 1, 2, 2, time.Time{}}, args); diff != "" {
+		t.Errorf("keyAfter() args mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncryptedService_SearchEvents(t *testing.T) {
+	service := openService(t, "search", fakekms.New("key"))
+	_, err := service.(*databaseService).SearchEvents(t.Context(), &session.SearchEventsRequest{AppName: "app", Query: "secret"})
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("SearchEvents() error = %v, want errors.ErrUnsupported", err)
+	}
+	if service.(*databaseService).db.Migrator().HasTable(searchIndexTable) {
+		t.Error("AutoMigrate() created the search index of an encrypted service")
+	}
+}
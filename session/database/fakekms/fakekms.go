@@ -0,0 +1,127 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakekms provides an in-memory key management service for tests,
+// implementing database.KeyProvider.
+package fakekms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"sync"
+)
+
+// KMS is an in-memory key management service holding the versions of a
+// key, whose key material never leaves it, like a cloud KMS. Thread-safe.
+type KMS struct {
+	name string
+
+	mu sync.Mutex
+	// versions are the versions of the key, by ID.
+	versions map[string]*version
+	primary  string
+	// unwrapCalls is the number of calls to UnwrapKey.
+	unwrapCalls int
+}
+
+type version struct {
+	aead     cipher.AEAD
+	disabled bool
+}
+
+// New returns a KMS holding a key with the given name and a first version.
+func New(name string) *KMS {
+	k := &KMS{name: name, versions: make(map[string]*version)}
+	k.Rotate()
+	return k
+}
+
+// Rotate creates a new version of the key, which becomes the primary one
+// encrypting the data keys, and returns its ID. The previous versions still
+// decrypt the data keys they encrypted.
+func (k *KMS) Rotate() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	id := fmt.Sprintf("%s/cryptoKeyVersions/%d", k.name, len(k.versions)+1)
+	k.versions[id] = &version{aead: aead}
+	k.primary = id
+	return id
+}
+
+// Disable disables a version of the key, so that it no longer decrypts
+// data keys, as when a retired version is destroyed.
+func (k *KMS) Disable(keyID string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if v, ok := k.versions[keyID]; ok {
+		v.disabled = true
+	}
+}
+
+// UnwrapCalls returns the number of calls to UnwrapKey.
+func (k *KMS) UnwrapCalls() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.unwrapCalls
+}
+
+// WrapKey encrypts a data key with the primary version of the key.
+func (k *KMS) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	v := k.versions[k.primary]
+	if v.disabled {
+		return "", nil, fmt.Errorf("key version %q is disabled", k.primary)
+	}
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return k.primary, v.aead.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+// UnwrapKey decrypts a data key encrypted with the version keyID of the key.
+func (k *KMS) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.unwrapCalls++
+	v, ok := k.versions[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key version %q not found", keyID)
+	}
+	if v.disabled {
+		return nil, fmt.Errorf("key version %q is disabled", keyID)
+	}
+	if len(wrapped) < v.aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	nonce, ciphertext := wrapped[:v.aead.NonceSize()], wrapped[v.aead.NonceSize():]
+	return v.aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -94,6 +94,65 @@ func (sm stateMap) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
 	return gorm.Expr("?", string(data))
 }
 
+// versionMap is a custom type for the versions of artifacts by name, stored
+// as JSON like stateMap.
+type versionMap map[string]int64
+
+// GormDataType defines the generic fallback data type, implements GormDataTypeInterface
+func (versionMap) GormDataType() string {
+	return "text"
+}
+
+// GormDBDataType defines database specific data types, implements GormDBDataTypeInterface
+func (versionMap) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "JSONB"
+	case "mysql":
+		return "LONGTEXT"
+	case "spanner":
+		return "STRING(MAX)"
+	default:
+		return ""
+	}
+}
+
+// Value implements the gorm.Serializer Value method.
+func (vm versionMap) Value() (driver.Value, error) {
+	if vm == nil {
+		vm = make(map[string]int64) // Serialize as '{}' instead of NULL
+	}
+	b, err := json.Marshal(vm)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements the gorm.Serializer Scan method.
+func (vm *versionMap) Scan(value any) error {
+	var bytes []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte: // Postgres, MySQL
+		bytes = v
+	case string: // Some drivers
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("failed to unmarshal JSON value: %T", value)
+	}
+	if len(bytes) == 0 {
+		*vm = make(map[string]int64)
+		return nil
+	}
+	return json.Unmarshal(bytes, vm)
+}
+
+func (vm versionMap) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	data, _ := vm.Value()
+	return gorm.Expr("?", data)
+}
+
 // dynamicJSON defined JSON data type, that implements driver.Valuer, sql.Scanner interface
 type dynamicJSON json.RawMessage
 
@@ -0,0 +1,177 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// KeyProvider encrypts the data keys which encrypt the stored values, with
+// key encryption keys it manages, e.g. in a KMS. See
+// NewEncryptedSessionService.
+type KeyProvider interface {
+	// WrapKey encrypts a data key with the current key encryption key,
+	// and returns the ID of that key.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key encrypted with the key encryption key
+	// keyID, which may no longer be the current one.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// keyFile is the content of a key file, see NewKeyFileProvider.
+type keyFile struct {
+	// Primary is the ID of the current key.
+	Primary string          `json:"primary"`
+	Keys    []keyFileRecord `json:"keys"`
+}
+
+// keyFileRecord is a key of a key file.
+type keyFileRecord struct {
+	ID  string `json:"id"`
+	Key []byte `json:"key"`
+}
+
+// NewKeyFileProvider returns a KeyProvider using the AES-256 keys of a
+// local key file, created and rotated by RotateKeyFile. The file is read
+// once: create a new provider after rotating the keys.
+func NewKeyFileProvider(path string) (KeyProvider, error) {
+	kf, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &keyFileProvider{primary: kf.Primary, keys: make(map[string]cipher.AEAD)}
+	for _, k := range kf.Keys {
+		aead, err := newAEAD(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in key file: %w", k.ID, err)
+		}
+		p.keys[k.ID] = aead
+	}
+	if _, ok := p.keys[kf.Primary]; !ok {
+		return nil, fmt.Errorf("primary key %q not found in key file", kf.Primary)
+	}
+	return p, nil
+}
+
+// RotateKeyFile adds a new random key to the key file, creating it if it
+// does not exist, and makes it the current key. It returns the ID of the
+// key. The previous keys are kept to decrypt the values encrypted with
+// them, see Reencrypt.
+func RotateKeyFile(path string) (string, error) {
+	kf, err := readKeyFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	id := uuid.NewString()
+	kf.Primary = id
+	kf.Keys = append(kf.Keys, keyFileRecord{ID: id, Key: key})
+
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keys-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write key file: %w", err)
+	}
+	return id, nil
+}
+
+func readKeyFile(path string) (keyFile, error) {
+	var kf keyFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return kf, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return kf, fmt.Errorf("failed to parse key file: %w", err)
+	}
+	return kf, nil
+}
+
+// keyFileProvider is the KeyProvider returned by NewKeyFileProvider.
+type keyFileProvider struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+func (p *keyFileProvider) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(p.keys[p.primary], dataKey, nil)
+	return p.primary, wrapped, err
+}
+
+func (p *keyFileProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found in key file", keyID)
+	}
+	return open(aead, wrapped, nil)
+}
+
+// newAEAD returns the AES-GCM cipher of a key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts and authenticates the plaintext, and authenticates the
+// additional data, with a random nonce, which prefixes the returned
+// ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a ciphertext returned by seal, with the same additional
+// data.
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
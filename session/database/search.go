@@ -0,0 +1,219 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	"gorm.io/gorm"
+
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+const (
+	// searchIndexTable is the SQLite FTS5 table indexing the searchable
+	// text of the events, see sessionutils.SearchText. Its rowids are the
+	// IDs of the storageSearchKey of the events.
+	searchIndexTable = "events_fts"
+	// searchIndexBatchSize is the number of events indexed at once when the
+	// index is created for existing events.
+	searchIndexBatchSize = 100
+)
+
+// storageSearchKey corresponds to the 'event_search_keys' table, mapping
+// the events to the rows of the search index. The rowids of the events
+// themselves are not stable, e.g. VACUUM may change them.
+type storageSearchKey struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	AppName   string `gorm:"uniqueIndex:idx_event_search_keys_event;not null"`
+	UserID    string `gorm:"uniqueIndex:idx_event_search_keys_event;not null"`
+	SessionID string `gorm:"uniqueIndex:idx_event_search_keys_event;not null"`
+	EventID   string `gorm:"uniqueIndex:idx_event_search_keys_event;not null"`
+}
+
+// TableName explicitly sets the table name for the storageSearchKey struct.
+func (storageSearchKey) TableName() string {
+	return "event_search_keys"
+}
+
+// supportsSearch reports whether the events of the database can be
+// indexed for SearchEvents: the index is plaintext, so it is not kept for
+// encrypted events, and it requires SQLite FTS5.
+func (s *databaseService) supportsSearch() bool {
+	return s.enc == nil && s.db.Dialector.Name() == "sqlite"
+}
+
+// migrateSearchIndex creates the search index, and indexes the existing
+// events, if it does not exist.
+func (s *databaseService) migrateSearchIndex() error {
+	if !s.supportsSearch() || s.db.Migrator().HasTable(searchIndexTable) {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&storageSearchKey{}); err != nil {
+			return err
+		}
+		if err := tx.Exec("CREATE VIRTUAL TABLE " + searchIndexTable + " USING fts5(text)").Error; err != nil {
+			return fmt.Errorf("failed to create search index: %w", err)
+		}
+		// The index rows of the events are deleted with them, including by
+		// Rewind, TrimEvents and the deletion of their session.
+		err := tx.Exec(`CREATE TRIGGER IF NOT EXISTS events_search_delete AFTER DELETE ON events BEGIN
+	DELETE FROM ` + searchIndexTable + ` WHERE rowid IN (SELECT id FROM event_search_keys
+		WHERE app_name = old.app_name AND user_id = old.user_id AND session_id = old.session_id AND event_id = old.id);
+	DELETE FROM event_search_keys
+		WHERE app_name = old.app_name AND user_id = old.user_id AND session_id = old.session_id AND event_id = old.id;
+END`).Error
+		if err != nil {
+			return fmt.Errorf("failed to create search index trigger: %w", err)
+		}
+		keyColumns := []string{"app_name", "user_id", "session_id", "id"}
+		var after []any
+		for {
+			var batch []storageEvent
+			query := tx.Order(strings.Join(keyColumns, ", ")).Limit(searchIndexBatchSize)
+			if after != nil {
+				cond, args := keyAfter(keyColumns, after)
+				query = query.Where(cond, args...)
+			}
+			if err := query.Find(&batch).Error; err != nil {
+				return fmt.Errorf("database error while fetching events: %w", err)
+			}
+			if err := indexEvents(tx, batch); err != nil {
+				return err
+			}
+			if len(batch) < searchIndexBatchSize {
+				return nil
+			}
+			last := batch[len(batch)-1]
+			after = []any{last.AppName, last.UserID, last.SessionID, last.ID}
+		}
+	})
+}
+
+// searchIndexed reports whether the events appended in the transaction
+// must be indexed.
+func (s *databaseService) searchIndexed(tx *gorm.DB) bool {
+	return s.supportsSearch() && tx.Migrator().HasTable(searchIndexTable)
+}
+
+// indexEvents adds plaintext stored events to the search index.
+func indexEvents(tx *gorm.DB, events []storageEvent) error {
+	for _, se := range events {
+		var content *genai.Content
+		if len(se.Content) > 0 {
+			if err := json.Unmarshal(se.Content, &content); err != nil {
+				return fmt.Errorf("failed to unmarshal content of event %q: %w", se.ID, err)
+			}
+		}
+		text := sessionutils.SearchText(content)
+		if text == "" {
+			continue
+		}
+		key := &storageSearchKey{AppName: se.AppName, UserID: se.UserID, SessionID: se.SessionID, EventID: se.ID}
+		if err := tx.Create(key).Error; err != nil {
+			return fmt.Errorf("failed to index event %q: %w", se.ID, err)
+		}
+		if err := tx.Exec("INSERT INTO "+searchIndexTable+"(rowid, text) VALUES (?, ?)", key.ID, text).Error; err != nil {
+			return fmt.Errorf("failed to index event %q: %w", se.ID, err)
+		}
+	}
+	return nil
+}
+
+// SearchEvents searches the events with the SQLite FTS5 index created by
+// AutoMigrate. See session.SearchEventsRequest.
+//
+// It fails with an error wrapping errors.ErrUnsupported for the other
+// databases, and for the encrypted events, see NewEncryptedSessionService.
+func (s *databaseService) SearchEvents(ctx context.Context, req *session.SearchEventsRequest) (*session.SearchEventsResponse, error) {
+	appName, userID := req.AppName, req.UserID
+	if appName == "" {
+		return nil, fmt.Errorf("app_name is required, got app_name: %q", appName)
+	}
+	terms := sessionutils.SearchTerms(req.Query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("query has no words to search for, got query: %q", req.Query)
+	}
+	if req.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative, got %d", req.Limit)
+	}
+	if s.enc != nil {
+		return nil, fmt.Errorf("%w: encrypted events are not searchable", errors.ErrUnsupported)
+	}
+	if !s.supportsSearch() {
+		return nil, fmt.Errorf("%w: searching events requires SQLite, got %s", errors.ErrUnsupported, s.db.Dialector.Name())
+	}
+
+	db := s.db.WithContext(ctx)
+	if !db.Migrator().HasTable(searchIndexTable) {
+		return nil, fmt.Errorf("search index not found, see AutoMigrate")
+	}
+	// Each term is quoted as a string, so that it is not an FTS5 operator.
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `"`
+	}
+	query := db.Table(searchIndexTable).
+		Select("events.*").
+		Joins("JOIN event_search_keys ON event_search_keys.id = "+searchIndexTable+".rowid").
+		Joins("JOIN events ON events.app_name = event_search_keys.app_name AND events.user_id = event_search_keys.user_id AND events.session_id = event_search_keys.session_id AND events.id = event_search_keys.event_id").
+		Joins("JOIN sessions ON sessions.app_name = events.app_name AND sessions.user_id = events.user_id AND sessions.id = events.session_id").
+		Where(searchIndexTable+" MATCH ?", strings.Join(match, " ")).
+		Where("event_search_keys.app_name = ?", appName)
+	if userID != "" {
+		query = query.Where("event_search_keys.user_id = ?", userID)
+	}
+	if req.Author != "" {
+		query = query.Where("events.author = ?", req.Author)
+	}
+	// The timestamps are stored with microsecond precision, see
+	// AppendEvent.
+	if !req.After.IsZero() {
+		query = query.Where("events.timestamp >= ?", req.After.Truncate(time.Microsecond))
+	}
+	if !req.Before.IsZero() {
+		query = query.Where("events.timestamp < ?", req.Before.Truncate(time.Microsecond))
+	}
+	query = query.Order("events.timestamp DESC")
+	if req.Limit > 0 {
+		query = query.Limit(req.Limit)
+	}
+
+	var storageEvents []storageEvent
+	if err := query.Find(&storageEvents).Error; err != nil {
+		return nil, fmt.Errorf("database error while searching events: %w", err)
+	}
+	resp := &session.SearchEventsResponse{Results: make([]session.SearchResult, 0, len(storageEvents))}
+	for i := range storageEvents {
+		ev, err := s.eventFromStorage(ctx, &storageEvents[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to map storage event: %w", err)
+		}
+		resp.Results = append(resp.Results, session.SearchResult{
+			UserID:    storageEvents[i].UserID,
+			SessionID: storageEvents[i].SessionID,
+			Event:     ev,
+		})
+	}
+	return resp, nil
+}
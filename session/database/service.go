@@ -19,18 +19,23 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
 	"github.com/sjzsdu/adk-go/session"
 )
 
 // databaseService is an database implementation of sessionService.Service.
 type databaseService struct {
 	db *gorm.DB
+	// enc encrypts the stored states and events, nil if they are stored in
+	// plaintext.
+	enc *encryptor
 }
 
 // NewSessionService creates a new [session.Service] implementation that uses a
@@ -49,8 +54,33 @@ func NewSessionService(dialector gorm.Dialector, opts ...gorm.Option) (session.S
 	return &databaseService{db: db}, nil
 }
 
+// NewEncryptedSessionService creates a [session.Service] like
+// [NewSessionService], which encrypts the states and the events at rest.
+//
+// The states, and the actions, content and metadata of the events are
+// encrypted with AES-256-GCM data keys, themselves encrypted by keys. The
+// IDs, authors, timestamps and flags of the events are stored in plaintext.
+// Each encrypted value is bound to its row and column, so it does not
+// decrypt once copied to another session or user. Values stored in
+// plaintext, e.g. before the encryption was enabled, are still read.
+// Rotating the key encryption key of keys only applies to the values stored
+// afterwards, see [Reencrypt] for the existing ones. The events are not
+// indexed for SearchEvents, which would store their text in plaintext.
+func NewEncryptedSessionService(dialector gorm.Dialector, keys KeyProvider, opts ...gorm.Option) (session.Service, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("key provider is required")
+	}
+	db, err := gorm.Open(dialector, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating database session service: %w", err)
+	}
+	return &databaseService{db: db, enc: newEncryptor(keys)}, nil
+}
+
 // AutoMigrate runs the GORM auto-migration tool to ensure the database schema
 // matches the internal storage models (e.g., storageSession, storageEvent).
+// With SQLite, it also creates the full-text index of the events used by
+// SearchEvents, indexing the existing events.
 //
 // NOTE: This function relies on a type assertion to the concrete *databaseService
 // implementation. It will return an error if the provided session.Service is
@@ -64,6 +94,9 @@ func AutoMigrate(service session.Service) error {
 	if err != nil {
 		return fmt.Errorf("auto migrate failed: %w", err)
 	}
+	if err := dbservice.migrateSearchIndex(); err != nil {
+		return fmt.Errorf("auto migrate failed: %w", err)
+	}
 	return nil
 }
 
@@ -93,13 +126,17 @@ func (s *databaseService) Create(ctx context.Context, req *session.CreateRequest
 	if err != nil {
 		return nil, err
 	}
+	sealer, err := s.enc.newSealer(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		storageApp, err := fetchStorageAppState(tx, req.AppName)
+		storageApp, err := s.fetchStorageAppState(tx, req.AppName)
 		if err != nil {
 			return fmt.Errorf("error on create session: %w", err)
 		}
-		storageUser, err := fetchStorageUserState(tx, req.AppName, req.UserID)
+		storageUser, err := s.fetchStorageUserState(tx, req.AppName, req.UserID)
 		if err != nil {
 			return fmt.Errorf("error on create session: %w", err)
 		}
@@ -109,23 +146,29 @@ func (s *databaseService) Create(ctx context.Context, req *session.CreateRequest
 		// apply state delta
 		if len(appDelta) > 0 {
 			maps.Copy(storageApp.State, appDelta)
-			if err := tx.Save(&storageApp).Error; err != nil {
-				return fmt.Errorf("failed to save app state: %w", err)
+			if err := saveAppState(tx, sealer, storageApp); err != nil {
+				return err
 			}
 		}
 		if len(userDelta) > 0 {
 			maps.Copy(storageUser.State, userDelta)
-			if err := tx.Save(&storageUser).Error; err != nil {
-				return fmt.Errorf("failed to save user state: %w", err)
+			if err := saveUserState(tx, sealer, storageUser); err != nil {
+				return err
 			}
 		}
 		createdSession.State = sessionState
+		createdSession.InitialState = maps.Clone(sessionState)
 
-		if err := tx.Create(createdSession).Error; err != nil {
+		sealedSession, err := sealer.sealSession(createdSession)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(sealedSession).Error; err != nil {
 			return fmt.Errorf("error creating session on database: %w", err)
 		}
 
 		val.state = mergeStates(storageApp.State, storageUser.State, sessionState)
+		val.initialState = maps.Clone(sessionState)
 		val.updatedAt = createdSession.UpdateTime
 		return nil
 	})
@@ -158,6 +201,9 @@ func (s *databaseService) Get(ctx context.Context, req *session.GetRequest) (*se
 		// For any error including ErrRecordNotFound, return it as a system error.
 		return nil, fmt.Errorf("database error while fetching session: %w", err)
 	}
+	if err := s.enc.openSession(ctx, &foundSession); err != nil {
+		return nil, err
+	}
 
 	// Fetch events
 	eventQuery := s.db.WithContext(ctx).
@@ -185,11 +231,11 @@ func (s *databaseService) Get(ctx context.Context, req *session.GetRequest) (*se
 	}
 
 	// fetch app and user states
-	storageApp, err := fetchStorageAppState(s.db.WithContext(ctx), appName)
+	storageApp, err := s.fetchStorageAppState(s.db.WithContext(ctx), appName)
 	if err != nil {
 		return nil, fmt.Errorf("error on get session: %w", err)
 	}
-	storageUser, err := fetchStorageUserState(s.db.WithContext(ctx), appName, userID)
+	storageUser, err := s.fetchStorageUserState(s.db.WithContext(ctx), appName, userID)
 	if err != nil {
 		return nil, fmt.Errorf("error on get session: %w", err)
 	}
@@ -205,13 +251,16 @@ func (s *databaseService) Get(ctx context.Context, req *session.GetRequest) (*se
 	// Convert storage events to response events
 	responseEvents := make([]*session.Event, 0, len(storageEvents))
 	for i := len(storageEvents) - 1; i >= 0; i-- {
-		evt, err := createEventFromStorageEvent(&storageEvents[i])
+		evt, err := s.eventFromStorage(ctx, &storageEvents[i])
 		if err != nil {
 			return nil, fmt.Errorf("failed to map storage event: %w", err)
 		}
 		responseEvents = append(responseEvents, evt)
 	}
 	responseSession.events = responseEvents
+	if req.NumRecentEvents <= 0 && req.After.IsZero() && foundSession.InitialState != nil {
+		responseSession.initialState = maps.Clone(foundSession.InitialState)
+	}
 
 	return &session.GetResponse{
 		Session: responseSession,
@@ -224,6 +273,13 @@ func (s *databaseService) List(ctx context.Context, req *session.ListRequest) (*
 	if appName == "" {
 		return nil, fmt.Errorf("app_name is required, got app_name: %q", req.AppName)
 	}
+	if req.PageSize < 0 {
+		return nil, fmt.Errorf("page size must not be negative, got %d", req.PageSize)
+	}
+	offset, err := sessionutils.DecodePageToken(req.PageToken)
+	if err != nil {
+		return nil, err
+	}
 
 	var foundSessions []storageSession
 	listQuery := s.db.WithContext(ctx).
@@ -236,8 +292,39 @@ func (s *databaseService) List(ctx context.Context, req *session.ListRequest) (*
 			UserID: userID,
 		})
 	}
+	if !req.CreatedAfter.IsZero() {
+		listQuery = listQuery.Where("create_time >= ?", req.CreatedAfter)
+	}
+	if !req.CreatedBefore.IsZero() {
+		listQuery = listQuery.Where("create_time < ?", req.CreatedBefore)
+	}
+	switch req.OrderBy {
+	case session.ListOrderID:
+		listQuery = listQuery.Order("user_id ASC, id ASC")
+	case session.ListOrderUpdateTimeDesc:
+		listQuery = listQuery.Order("update_time DESC, user_id ASC, id ASC")
+	case session.ListOrderUpdateTimeAsc:
+		listQuery = listQuery.Order("update_time ASC, user_id ASC, id ASC")
+	default:
+		return nil, fmt.Errorf("unknown list order %d", req.OrderBy)
+	}
+
+	// State filters apply to the state merged with the app and user states,
+	// so they are applied, and the page cut, after the query. Otherwise the
+	// page is cut by the query, fetching one more session to know whether
+	// there is a next page.
+	filterState := len(req.StateFilter) > 0
+	if !filterState {
+		listQuery = listQuery.Offset(offset)
+		if req.PageSize > 0 {
+			listQuery = listQuery.Limit(req.PageSize + 1)
+		}
+		if req.MetadataOnly {
+			listQuery = listQuery.Omit("state", "initial_state")
+		}
+	}
 
-	err := listQuery.Find(&foundSessions).Error
+	err = listQuery.Find(&foundSessions).Error
 	if err != nil {
 		// Specifically check if the error is "record not found".
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -249,47 +336,86 @@ func (s *databaseService) List(ctx context.Context, req *session.ListRequest) (*
 		// For any other error (e.g., connection lost), return it as a system error.
 		return nil, fmt.Errorf("database error while fetching session: %w", err)
 	}
+	var nextPageToken string
+	if !filterState && req.PageSize > 0 && len(foundSessions) > req.PageSize {
+		foundSessions = foundSessions[:req.PageSize]
+		nextPageToken = sessionutils.EncodePageToken(offset + req.PageSize)
+	}
 
-	storageApp, err := fetchStorageAppState(s.db.WithContext(ctx), appName)
+	// Create response sessions, transform the storageSessions into
+	responseSessions := make([]*localSession, 0, len(foundSessions))
+	for _, storage := range foundSessions {
+		if err := s.enc.openSession(ctx, &storage); err != nil {
+			return nil, fmt.Errorf("failed to map storage object for session %s: %w", storage.ID, err)
+		}
+		sess, err := createSessionFromStorageSession(&storage)
+		if err != nil {
+			// If we encounter a single mapping error, we fail the whole request.
+			return nil, fmt.Errorf("failed to map storage object for session %s: %w", storage.ID, err)
+		}
+		responseSessions = append(responseSessions, sess)
+	}
+
+	if !req.MetadataOnly || filterState {
+		if err := s.mergeListedStates(ctx, appName, userID, responseSessions); err != nil {
+			return nil, fmt.Errorf("error on list sessions: %w", err)
+		}
+	}
+	if filterState {
+		responseSessions = slices.DeleteFunc(responseSessions, func(sess *localSession) bool {
+			return !sessionutils.StateMatches(sess.state, req.StateFilter)
+		})
+		responseSessions, nextPageToken, err = sessionutils.Paginate(responseSessions, req.PageToken, req.PageSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if req.MetadataOnly {
+		for _, sess := range responseSessions {
+			sess.state = make(map[string]any)
+		}
+	}
+
+	resp := &session.ListResponse{
+		Sessions:      make([]session.Session, 0, len(responseSessions)),
+		NextPageToken: nextPageToken,
+	}
+	for _, sess := range responseSessions {
+		resp.Sessions = append(resp.Sessions, sess)
+	}
+	return resp, nil
+}
+
+// mergeListedStates merges the app and user states into the states of the
+// listed sessions.
+func (s *databaseService) mergeListedStates(ctx context.Context, appName, userID string, sessions []*localSession) error {
+	storageApp, err := s.fetchStorageAppState(s.db.WithContext(ctx), appName)
 	if err != nil {
-		return nil, fmt.Errorf("error on list sessions: %w", err)
+		return err
 	}
 
 	var userStates map[string]*storageUserState
 	if userID != "" {
-		userState, err := fetchStorageUserState(s.db.WithContext(ctx), appName, userID)
+		userState, err := s.fetchStorageUserState(s.db.WithContext(ctx), appName, userID)
 		if err != nil {
-			return nil, fmt.Errorf("error on list sessions: %w", err)
+			return err
 		}
 		userStates = map[string]*storageUserState{userID: userState}
 	} else {
-		userStates, err = fetchAllAppStorageUserState(s.db.WithContext(ctx), appName)
+		userStates, err = s.fetchAllAppStorageUserState(s.db.WithContext(ctx), appName)
 		if err != nil {
-			return nil, fmt.Errorf("error on list sessions: %w", err)
+			return err
 		}
 	}
 
-	// Create response sessions, transform the storageSessions into
-	responseSessions := make([]session.Session, 0, len(foundSessions))
-	for _, storage := range foundSessions {
-		s := storage
-		sess, err := createSessionFromStorageSession(&s)
-		if err != nil {
-			// If we encounter a single mapping error, we fail the whole request.
-			return nil, fmt.Errorf("failed to map storage object for session %s: %w", s.ID, err)
-		}
-
+	for _, sess := range sessions {
 		userState, ok := userStates[sess.UserID()]
 		if !ok {
 			userState = &storageUserState{AppName: appName, UserID: userID, State: make(map[string]any)}
 		}
 		sess.state = mergeStates(storageApp.State, userState.State, sess.state)
-		responseSessions = append(responseSessions, sess)
 	}
-
-	return &session.ListResponse{
-		Sessions: responseSessions,
-	}, nil
+	return nil
 }
 
 // Delete, deletes a session given a specific id returning error on failure, implements session.Service
@@ -316,6 +442,292 @@ func (s *databaseService) Delete(ctx context.Context, req *session.DeleteRequest
 	})
 }
 
+// Rewind removes the events of an invocation and the later ones, and
+// restores the session state, implements session.Service
+func (s *databaseService) Rewind(ctx context.Context, req *session.RewindRequest) (*session.RewindResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" || req.InvocationID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id, invocation_id are required, got app_name: %q, user_id: %q, session_id: %q, invocation_id: %q", appName, userID, sessionID, req.InvocationID)
+	}
+
+	var artifactVersions map[string]int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var storageSess storageSession
+		err := tx.Where(&storageSession{AppName: appName, UserID: userID, ID: sessionID}).
+			First(&storageSess).Error
+		if err != nil {
+			return fmt.Errorf("database error while fetching session: %w", err)
+		}
+		if err := s.enc.openSession(ctx, &storageSess); err != nil {
+			return err
+		}
+
+		var storageEvents []storageEvent
+		err = tx.Where(&storageEvent{AppName: appName, UserID: userID, SessionID: sessionID}).
+			Order("timestamp ASC").
+			Find(&storageEvents).Error
+		if err != nil {
+			return fmt.Errorf("database error while fetching events: %w", err)
+		}
+		i := slices.IndexFunc(storageEvents, func(ev storageEvent) bool {
+			return ev.InvocationID == req.InvocationID
+		})
+		if i < 0 {
+			return fmt.Errorf("invocation %q not found in session %q", req.InvocationID, sessionID)
+		}
+
+		var stateDeltas []map[string]any
+		var keptArtifacts, removedArtifacts []map[string]int64
+		var removedIDs []string
+		for j := range storageEvents {
+			ev, err := s.eventFromStorage(ctx, &storageEvents[j])
+			if err != nil {
+				return fmt.Errorf("failed to map storage event: %w", err)
+			}
+			if j < i {
+				stateDeltas = append(stateDeltas, ev.Actions.StateDelta)
+				keptArtifacts = append(keptArtifacts, ev.Actions.ArtifactDelta)
+				continue
+			}
+			removedArtifacts = append(removedArtifacts, ev.Actions.ArtifactDelta)
+			removedIDs = append(removedIDs, ev.ID)
+		}
+		artifactVersions = sessionutils.RevertedArtifactVersions(storageSess.InitialArtifacts, keptArtifacts, removedArtifacts)
+
+		err = tx.Where(&storageEvent{AppName: appName, UserID: userID, SessionID: sessionID}).
+			Where("id IN ?", removedIDs).
+			Delete(&storageEvent{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete events: %w", err)
+		}
+
+		sealer, err := s.enc.newSealer(ctx)
+		if err != nil {
+			return err
+		}
+		state, err := sealer.sealState(sessionutils.ReplayStateDeltas(storageSess.InitialState, stateDeltas), storageSess.additionalData("state"))
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&storageSession{}).
+			Where(&storageSession{AppName: appName, UserID: userID, ID: sessionID}).
+			Where("version = ?", storageSess.Version).
+			Updates(map[string]any{
+				"state":       state,
+				"update_time": time.Now(),
+				"version":     gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to save session state: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: session %q was updated concurrently", session.ErrStaleSession, sessionID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Get(ctx, &session.GetRequest{AppName: appName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return nil, err
+	}
+	return &session.RewindResponse{
+		Session:          resp.Session,
+		ArtifactVersions: artifactVersions,
+	}, nil
+}
+
+// Fork creates a new session from a prefix of the events of a session,
+// implements session.Service
+func (s *databaseService) Fork(ctx context.Context, req *session.ForkRequest) (*session.ForkResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+	newSessionID := req.NewSessionID
+	if newSessionID == "" {
+		newSessionID = uuid.NewString()
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var parent storageSession
+		err := tx.Where(&storageSession{AppName: appName, UserID: userID, ID: sessionID}).
+			First(&parent).Error
+		if err != nil {
+			return fmt.Errorf("database error while fetching session: %w", err)
+		}
+		if err := s.enc.openSession(ctx, &parent); err != nil {
+			return err
+		}
+
+		var storageEvents []storageEvent
+		err = tx.Where(&storageEvent{AppName: appName, UserID: userID, SessionID: sessionID}).
+			Order("timestamp ASC").
+			Find(&storageEvents).Error
+		if err != nil {
+			return fmt.Errorf("database error while fetching events: %w", err)
+		}
+		if req.EventID != "" {
+			i := slices.IndexFunc(storageEvents, func(ev storageEvent) bool { return ev.ID == req.EventID })
+			if i < 0 {
+				return fmt.Errorf("event %q not found in session %q", req.EventID, sessionID)
+			}
+			storageEvents = storageEvents[:i+1]
+		}
+
+		// The copied events are encrypted again, as their values are bound to
+		// their rows.
+		sealer, err := s.enc.newSealer(ctx)
+		if err != nil {
+			return err
+		}
+		origin := &session.ForkOrigin{SessionID: sessionID}
+		var stateDeltas []map[string]any
+		var artifactDeltas []map[string]int64
+		for i := range storageEvents {
+			opened, err := s.enc.openEvent(ctx, &storageEvents[i])
+			if err != nil {
+				return err
+			}
+			ev, err := createEventFromStorageEvent(opened)
+			if err != nil {
+				return fmt.Errorf("failed to map storage event: %w", err)
+			}
+			stateDeltas = append(stateDeltas, ev.Actions.StateDelta)
+			artifactDeltas = append(artifactDeltas, ev.Actions.ArtifactDelta)
+			origin.EventID = ev.ID
+			opened.SessionID = newSessionID
+			if err := sealer.sealEvent(opened); err != nil {
+				return err
+			}
+			storageEvents[i] = *opened
+		}
+		origin.Artifacts = sessionutils.LatestArtifactVersions(parent.InitialArtifacts, artifactDeltas)
+
+		now := time.Now()
+		forked := &storageSession{
+			AppName:      appName,
+			UserID:       userID,
+			ID:           newSessionID,
+			State:        sessionutils.ReplayStateDeltas(parent.InitialState, stateDeltas),
+			CreateTime:   now,
+			UpdateTime:   now,
+			InitialState: parent.InitialState,
+			ForkOrigin:   origin,
+		}
+		if forked, err = sealer.sealSession(forked); err != nil {
+			return err
+		}
+		if err := tx.Create(forked).Error; err != nil {
+			return fmt.Errorf("error creating session on database: %w", err)
+		}
+		if len(storageEvents) > 0 {
+			if err := tx.Create(&storageEvents).Error; err != nil {
+				return fmt.Errorf("failed to save events: %w", err)
+			}
+			if s.searchIndexed(tx) {
+				if err := indexEvents(tx, storageEvents); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Get(ctx, &session.GetRequest{AppName: appName, UserID: userID, SessionID: newSessionID})
+	if err != nil {
+		return nil, err
+	}
+	return &session.ForkResponse{Session: resp.Session}, nil
+}
+
+// TrimEvents deletes the oldest events of a session, keeping at most
+// req.MaxEvents events. See session.TrimEventsRequest.
+func (s *databaseService) TrimEvents(ctx context.Context, req *session.TrimEventsRequest) (*session.TrimEventsResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+	if req.MaxEvents < 0 {
+		return nil, fmt.Errorf("max_events must not be negative, got %d", req.MaxEvents)
+	}
+
+	var deleted int
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var storageSess storageSession
+		err := tx.Where(&storageSession{AppName: appName, UserID: userID, ID: sessionID}).
+			First(&storageSess).Error
+		if err != nil {
+			return fmt.Errorf("database error while fetching session: %w", err)
+		}
+		if err := s.enc.openSession(ctx, &storageSess); err != nil {
+			return err
+		}
+
+		var storageEvents []storageEvent
+		err = tx.Where(&storageEvent{AppName: appName, UserID: userID, SessionID: sessionID}).
+			Order("timestamp ASC").
+			Find(&storageEvents).Error
+		if err != nil {
+			return fmt.Errorf("database error while fetching events: %w", err)
+		}
+		n := len(storageEvents) - req.MaxEvents
+		if n <= 0 {
+			return nil
+		}
+
+		var stateDeltas []map[string]any
+		var artifactDeltas []map[string]int64
+		var removedIDs []string
+		for i := range storageEvents[:n] {
+			ev, err := s.eventFromStorage(ctx, &storageEvents[i])
+			if err != nil {
+				return fmt.Errorf("failed to map storage event: %w", err)
+			}
+			stateDeltas = append(stateDeltas, ev.Actions.StateDelta)
+			artifactDeltas = append(artifactDeltas, ev.Actions.ArtifactDelta)
+			removedIDs = append(removedIDs, ev.ID)
+		}
+
+		err = tx.Where(&storageEvent{AppName: appName, UserID: userID, SessionID: sessionID}).
+			Where("id IN ?", removedIDs).
+			Delete(&storageEvent{}).Error
+		if err != nil {
+			return fmt.Errorf("failed to delete events: %w", err)
+		}
+		// The session state and version are unchanged: the trimmed events
+		// only move into the initial state and artifacts.
+		sealer, err := s.enc.newSealer(ctx)
+		if err != nil {
+			return err
+		}
+		initialState, err := sealer.sealState(sessionutils.ReplayStateDeltas(storageSess.InitialState, stateDeltas), storageSess.additionalData("initial_state"))
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&storageSession{}).
+			Where(&storageSession{AppName: appName, UserID: userID, ID: sessionID}).
+			Updates(map[string]any{
+				"initial_state":     initialState,
+				"initial_artifacts": versionMap(sessionutils.LatestArtifactVersions(storageSess.InitialArtifacts, artifactDeltas)),
+			}).Error
+		if err != nil {
+			return fmt.Errorf("failed to save session initial state: %w", err)
+		}
+		deleted = n
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session.TrimEventsResponse{Deleted: deleted}, nil
+}
+
 func (s *databaseService) AppendEvent(ctx context.Context, curSession session.Session, event *session.Event) error {
 	if curSession == nil {
 		return fmt.Errorf("session is nil")
@@ -355,12 +767,16 @@ func (s *databaseService) AppendEvent(ctx context.Context, curSession session.Se
 
 // applyEvent fetches the session, validates it, applies state changes from an
 // event, and saves the event atomically.
-func (s *databaseService) applyEvent(ctx context.Context, session *localSession, event *session.Event) error {
+func (s *databaseService) applyEvent(ctx context.Context, sess *localSession, event *session.Event) error {
+	sealer, err := s.enc.newSealer(ctx)
+	if err != nil {
+		return err
+	}
 	// Wrap database operations in a single transaction.
-	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Fetch the session object from storage.
 		var storageSess storageSession
-		err := tx.Where(&storageSession{AppName: session.AppName(), UserID: session.UserID(), ID: session.ID()}).
+		err := tx.Where(&storageSession{AppName: sess.AppName(), UserID: sess.UserID(), ID: sess.ID()}).
 			First(&storageSess).Error
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -368,25 +784,22 @@ func (s *databaseService) applyEvent(ctx context.Context, session *localSession,
 			}
 			return fmt.Errorf("failed to get session: %w", err)
 		}
+		if err := s.enc.openSession(ctx, &storageSess); err != nil {
+			return err
+		}
 
 		// Ensure the session object is not stale.
-		// We use UnixMicro() for microsecond-level precision, matching the Python code.
-		storageUpdateTime := storageSess.UpdateTime.UnixMicro()
-		sessionUpdateTime := session.updatedAt.UnixMicro()
-		if storageUpdateTime > sessionUpdateTime {
-			return fmt.Errorf(
-				"stale session error: last update time from request (%s) is older than in database (%s)",
-				time.Unix(0, sessionUpdateTime).Format(time.RFC3339Nano),
-				time.Unix(0, storageUpdateTime).Format(time.RFC3339Nano),
-			)
+		if storageSess.Version != sess.version {
+			return fmt.Errorf("%w: session %q has version %d in the database, the request has version %d",
+				session.ErrStaleSession, sess.ID(), storageSess.Version, sess.version)
 		}
 
 		// Fetch App and User states.
-		storageApp, err := fetchStorageAppState(tx, session.AppName())
+		storageApp, err := s.fetchStorageAppState(tx, sess.AppName())
 		if err != nil {
 			return err
 		}
-		storageUser, err := fetchStorageUserState(tx, session.AppName(), session.UserID())
+		storageUser, err := s.fetchStorageUserState(tx, sess.AppName(), sess.UserID())
 		if err != nil {
 			return err
 		}
@@ -397,14 +810,14 @@ func (s *databaseService) applyEvent(ctx context.Context, session *localSession,
 		// GORM's .Save() method will correctly perform an INSERT or UPDATE.
 		if len(appDelta) > 0 {
 			maps.Copy(storageApp.State, appDelta)
-			if err := tx.Save(&storageApp).Error; err != nil {
-				return fmt.Errorf("failed to save app state: %w", err)
+			if err := saveAppState(tx, sealer, storageApp); err != nil {
+				return err
 			}
 		}
 		if len(userDelta) > 0 {
 			maps.Copy(storageUser.State, userDelta)
-			if err := tx.Save(&storageUser).Error; err != nil {
-				return fmt.Errorf("failed to save user state: %w", err)
+			if err := saveUserState(tx, sealer, storageUser); err != nil {
+				return err
 			}
 		}
 		if len(sessionDelta) > 0 {
@@ -413,21 +826,45 @@ func (s *databaseService) applyEvent(ctx context.Context, session *localSession,
 		}
 
 		// Create the new event record in the database.
-		storageEv, err := createStorageEvent(session, event)
+		storageEv, err := createStorageEvent(sess, event)
 		if err != nil {
 			return fmt.Errorf("failed to map event to storage model: %w", err)
 		}
+		if err := sealer.sealEvent(storageEv); err != nil {
+			return err
+		}
 		if err := tx.Create(storageEv).Error; err != nil {
 			return fmt.Errorf("failed to save event: %w", err)
 		}
+		if s.searchIndexed(tx) {
+			if err := indexEvents(tx, []storageEvent{*storageEv}); err != nil {
+				return err
+			}
+		}
 
-		storageSess.UpdateTime = event.Timestamp
-		// Save the session to update its state and UpdateTime.
-		if err := tx.Save(&storageSess).Error; err != nil {
-			return fmt.Errorf("failed to save session state: %w", err)
+		// Update the session only if no other transaction did in the
+		// meantime.
+		state, err := sealer.sealState(storageSess.State, storageSess.additionalData("state"))
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&storageSession{}).
+			Where(&storageSession{AppName: sess.AppName(), UserID: sess.UserID(), ID: sess.ID()}).
+			Where("version = ?", storageSess.Version).
+			Updates(map[string]any{
+				"state":       state,
+				"update_time": event.Timestamp,
+				"version":     gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to save session state: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: session %q was updated concurrently", session.ErrStaleSession, sess.ID())
 		}
 
-		session.updatedAt = storageSess.UpdateTime
+		sess.updatedAt = event.Timestamp
+		sess.version = storageSess.Version + 1
 
 		return nil // Returning nil commits the transaction.
 	})
@@ -435,7 +872,16 @@ func (s *databaseService) applyEvent(ctx context.Context, session *localSession,
 	return err
 }
 
-func fetchStorageAppState(tx *gorm.DB, appName string) (*storageAppState, error) {
+// eventFromStorage maps a stored event, decrypting it, to an event.
+func (s *databaseService) eventFromStorage(ctx context.Context, se *storageEvent) (*session.Event, error) {
+	opened, err := s.enc.openEvent(ctx, se)
+	if err != nil {
+		return nil, err
+	}
+	return createEventFromStorageEvent(opened)
+}
+
+func (s *databaseService) fetchStorageAppState(tx *gorm.DB, appName string) (*storageAppState, error) {
 	var storageApp storageAppState
 	if err := tx.First(&storageApp, "app_name = ?", appName).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -444,10 +890,14 @@ func fetchStorageAppState(tx *gorm.DB, appName string) (*storageAppState, error)
 		// If not found, initialize a new object to be created later.
 		storageApp = storageAppState{AppName: appName, State: make(map[string]any)}
 	}
+	var err error
+	if storageApp.State, err = s.enc.openState(tx.Statement.Context, storageApp.State, storageApp.additionalData()); err != nil {
+		return nil, fmt.Errorf("failed to decrypt app state: %w", err)
+	}
 	return &storageApp, nil
 }
 
-func fetchStorageUserState(tx *gorm.DB, appName, userID string) (*storageUserState, error) {
+func (s *databaseService) fetchStorageUserState(tx *gorm.DB, appName, userID string) (*storageUserState, error) {
 	var storageUser storageUserState
 	if err := tx.First(&storageUser, "app_name = ? AND user_id = ?", appName, userID).Error; err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -456,10 +906,14 @@ func fetchStorageUserState(tx *gorm.DB, appName, userID string) (*storageUserSta
 		// If not found, initialize a new object.
 		storageUser = storageUserState{AppName: appName, UserID: userID, State: make(map[string]any)}
 	}
+	var err error
+	if storageUser.State, err = s.enc.openState(tx.Statement.Context, storageUser.State, storageUser.additionalData()); err != nil {
+		return nil, fmt.Errorf("failed to decrypt user state: %w", err)
+	}
 	return &storageUser, nil
 }
 
-func fetchAllAppStorageUserState(tx *gorm.DB, appName string) (map[string]*storageUserState, error) {
+func (s *databaseService) fetchAllAppStorageUserState(tx *gorm.DB, appName string) (map[string]*storageUserState, error) {
 	var storageUserStates []storageUserState
 
 	if err := tx.Find(&storageUserStates, "app_name = ?", appName).Error; err != nil {
@@ -470,11 +924,41 @@ func fetchAllAppStorageUserState(tx *gorm.DB, appName string) (map[string]*stora
 	}
 	statesByUserId := make(map[string]*storageUserState, len(storageUserStates))
 	for _, storageUserState := range storageUserStates {
+		var err error
+		if storageUserState.State, err = s.enc.openState(tx.Statement.Context, storageUserState.State, storageUserState.additionalData()); err != nil {
+			return nil, fmt.Errorf("failed to decrypt user state: %w", err)
+		}
 		statesByUserId[storageUserState.UserID] = &storageUserState
 	}
 	return statesByUserId, nil
 }
 
+// saveAppState saves an app state, encrypted by sealer.
+func saveAppState(tx *gorm.DB, sealer *sealer, storageApp *storageAppState) error {
+	sealed := *storageApp
+	var err error
+	if sealed.State, err = sealer.sealState(storageApp.State, storageApp.additionalData()); err != nil {
+		return err
+	}
+	if err := tx.Save(&sealed).Error; err != nil {
+		return fmt.Errorf("failed to save app state: %w", err)
+	}
+	return nil
+}
+
+// saveUserState saves a user state, encrypted by sealer.
+func saveUserState(tx *gorm.DB, sealer *sealer, storageUser *storageUserState) error {
+	sealed := *storageUser
+	var err error
+	if sealed.State, err = sealer.sealState(storageUser.State, storageUser.additionalData()); err != nil {
+		return err
+	}
+	if err := tx.Save(&sealed).Error; err != nil {
+		return fmt.Errorf("failed to save user state: %w", err)
+	}
+	return nil
+}
+
 // extractStateDeltas splits a single state delta map into three separate maps
 // for app, user, and session states based on key prefixes.
 // Temporary keys (starting with TempStatePrefix) are ignored.
@@ -525,3 +1009,9 @@ func mergeStates(appState, userState, sessionState map[string]any) map[string]an
 
 	return mergedState
 }
+
+var (
+	_ session.Service       = (*databaseService)(nil)
+	_ session.EventTrimmer  = (*databaseService)(nil)
+	_ session.EventSearcher = (*databaseService)(nil)
+)
@@ -15,6 +15,7 @@
 package database
 
 import (
+	"errors"
 	"maps"
 	"strconv"
 	"testing"
@@ -350,7 +351,7 @@ func Test_databaseService_Get(t *testing.T) {
 			if tt.wantResponse != nil {
 				if diff := cmp.Diff(tt.wantResponse, got,
 					cmp.AllowUnexported(localSession{}),
-					cmpopts.IgnoreFields(localSession{}, "mu", "updatedAt")); diff != "" {
+					cmpopts.IgnoreFields(localSession{}, "mu", "updatedAt", "version", "initialState")); diff != "" {
 					t.Errorf("Get session mismatch: (-want +got):\n%s", diff)
 				}
 			}
@@ -460,7 +461,7 @@ func Test_databaseService_List(t *testing.T) {
 				// Sort slices for stable comparison
 				opts := []cmp.Option{
 					cmp.AllowUnexported(localSession{}),
-					cmpopts.IgnoreFields(localSession{}, "mu", "updatedAt"),
+					cmpopts.IgnoreFields(localSession{}, "mu", "updatedAt", "version", "initialState"),
 					cmpopts.SortSlices(func(a, b session.Session) bool {
 						return a.ID() < b.ID()
 					}),
@@ -522,6 +523,8 @@ func Test_databaseService_AppendEvent(t *testing.T) {
 				appName:   "app2",
 				userID:    "user2",
 				sessionID: "session2",
+				// The session was updated by the existing event.
+				version: 1,
 			},
 			event: &session.Event{
 				ID: "new_event1",
@@ -751,7 +754,7 @@ func Test_databaseService_AppendEvent(t *testing.T) {
 			// Define comparison options
 			opts := []cmp.Option{
 				cmp.AllowUnexported(localSession{}),
-				cmpopts.IgnoreFields(localSession{}, "mu", "updatedAt"),
+				cmpopts.IgnoreFields(localSession{}, "mu", "updatedAt", "version", "initialState"),
 				cmpopts.IgnoreFields(session.Event{}, "Timestamp"),
 				// Add sorters if event order is not guaranteed
 				cmpopts.SortSlices(func(a, b *session.Event) bool {
@@ -766,6 +769,47 @@ func Test_databaseService_AppendEvent(t *testing.T) {
 	}
 }
 
+func Test_databaseService_Get_LegacyActions(t *testing.T) {
+	ctx := t.Context()
+	s := emptyService(t)
+	if _, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session"}); err != nil {
+		t.Fatal(err)
+	}
+	// A row written before the actions had JSON tags, with the Go field
+	// names as keys.
+	legacy := &storageEvent{
+		ID:           "e1",
+		AppName:      "app",
+		UserID:       "user",
+		SessionID:    "session",
+		InvocationID: "inv1",
+		Author:       "agent",
+		Actions:      []byte(`{"StateDelta":{"k":"v"},"ArtifactDelta":{"a.txt":1},"RequestedToolConfirmations":null,"RequestedAuthConfigs":null,"SkipSummarization":true,"TransferToAgent":"other","Escalate":false,"Compaction":{"StartEventID":"e0","EndEventID":"e0","CompactedContent":null},"AgentState":null,"EndOfAgent":false}`),
+		Timestamp:    time.Now(),
+	}
+	if err := s.db.Create(legacy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session"})
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	if got.Session.Events().Len() != 1 {
+		t.Fatalf("Get() returned %d events, want 1", got.Session.Events().Len())
+	}
+	want := session.EventActions{
+		StateDelta:        map[string]any{"k": "v"},
+		ArtifactDelta:     map[string]int64{"a.txt": 1},
+		SkipSummarization: true,
+		TransferToAgent:   "other",
+		Compaction:        &session.EventCompaction{StartEventID: "e0", EndEventID: "e0"},
+	}
+	if diff := cmp.Diff(want, got.Session.Events().At(0).Actions); diff != "" {
+		t.Errorf("Get() event actions mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func Test_databaseService_StateManagement(t *testing.T) {
 	ctx := t.Context()
 	appName := "my_app"
@@ -890,6 +934,172 @@ func Test_databaseService_StateManagement(t *testing.T) {
 	})
 }
 
+func Test_databaseService_StaleSession(t *testing.T) {
+	ctx := t.Context()
+	s := serviceDbWithData(t)
+	get := func() session.Session {
+		t.Helper()
+		resp, err := s.Get(ctx, &session.GetRequest{AppName: "app1", UserID: "user1", SessionID: "session1"})
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return resp.Session
+	}
+
+	// Two runs read the session, the first one appends an event.
+	first, second := get(), get()
+	if err := s.AppendEvent(ctx, first, &session.Event{ID: "e1", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+	// The first session is up to date with its own writes.
+	if err := s.AppendEvent(ctx, first, &session.Event{ID: "e2", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("AppendEvent() to the updated session error = %v", err)
+	}
+
+	// The second session is stale, even with a newer event timestamp.
+	err := s.AppendEvent(ctx, second, &session.Event{ID: "e3", Timestamp: time.Now().Add(time.Hour)})
+	if !errors.Is(err, session.ErrStaleSession) {
+		t.Fatalf("AppendEvent() to a stale session error = %v, want ErrStaleSession", err)
+	}
+	if err := s.AppendEvent(ctx, get(), &session.Event{ID: "e3", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("AppendEvent() after reading the session again error = %v", err)
+	}
+	if got := get().Events().Len(); got != 3 {
+		t.Errorf("got %d stored events, want 3", got)
+	}
+}
+
+func Test_databaseService_Rewind(t *testing.T) {
+	ctx := t.Context()
+	s := emptyService(t)
+	created, err := s.Create(ctx, &session.CreateRequest{
+		AppName:   "app",
+		UserID:    "user",
+		SessionID: "session",
+		State:     map[string]any{"k": "initial", "app:shared": 1.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ev := range []*session.Event{
+		{ID: "e1", InvocationID: "inv1", Actions: session.EventActions{
+			StateDelta:    map[string]any{"k": "v1"},
+			ArtifactDelta: map[string]int64{"a.txt": 1},
+		}},
+		{ID: "e2", InvocationID: "inv2", Actions: session.EventActions{
+			StateDelta:    map[string]any{"k": "v2", "added": true, "app:shared": 2.0},
+			ArtifactDelta: map[string]int64{"a.txt": 2, "b.txt": 1},
+		}},
+		{ID: "e3", InvocationID: "inv3", Actions: session.EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	} {
+		ev.Timestamp = time.Now().Add(time.Duration(i) * time.Second)
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "unknown"}); err == nil {
+		t.Error("Rewind() to an unknown invocation succeeded, want error")
+	}
+
+	resp, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "inv2"})
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]int64{"a.txt": 1, "b.txt": 0}, resp.ArtifactVersions); diff != "" {
+		t.Errorf("Rewind() artifact versions mismatch (-want +got):\n%s", diff)
+	}
+	var ids []string
+	for ev := range resp.Session.Events().All() {
+		ids = append(ids, ev.ID)
+	}
+	if diff := cmp.Diff([]string{"e1"}, ids); diff != "" {
+		t.Errorf("events after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+	// The app state is shared with other sessions, so it is not rewound.
+	wantState := map[string]any{"k": "v1", "app:shared": 2.0}
+	if diff := cmp.Diff(wantState, maps.Collect(resp.Session.State().All())); diff != "" {
+		t.Errorf("state after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The session read before rewinding is stale.
+	err = s.AppendEvent(ctx, created.Session, &session.Event{ID: "e4", Timestamp: time.Now()})
+	if !errors.Is(err, session.ErrStaleSession) {
+		t.Errorf("AppendEvent() to the session read before Rewind() error = %v, want ErrStaleSession", err)
+	}
+}
+
+func Test_databaseService_Fork(t *testing.T) {
+	ctx := t.Context()
+	s := emptyService(t)
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "parent", State: map[string]any{"k": "initial"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ev := range []*session.Event{
+		{ID: "e1", Actions: session.EventActions{StateDelta: map[string]any{"k": "v1"}, ArtifactDelta: map[string]int64{"a.txt": 1}}},
+		{ID: "e2", Actions: session.EventActions{StateDelta: map[string]any{"k": "v2"}, ArtifactDelta: map[string]int64{"a.txt": 2, "b.txt": 1}}},
+		{ID: "e3", Actions: session.EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	} {
+		ev.Timestamp = time.Now().Add(time.Duration(i) * time.Second)
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, req := range []*session.ForkRequest{
+		{AppName: "app", UserID: "user", SessionID: "parent", EventID: "unknown"},
+		{AppName: "app", UserID: "user", SessionID: "parent", NewSessionID: "parent"},
+		{AppName: "app", UserID: "user", SessionID: "unknown"},
+	} {
+		if _, err := s.Fork(ctx, req); err == nil {
+			t.Errorf("Fork(%+v) succeeded, want error", req)
+		}
+	}
+
+	resp, err := s.Fork(ctx, &session.ForkRequest{AppName: "app", UserID: "user", SessionID: "parent", EventID: "e2", NewSessionID: "child"})
+	if err != nil {
+		t.Fatalf("Fork() failed: %v", err)
+	}
+	// The fork is read back from the database.
+	got, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "child"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(resp.Session, got.Session, cmp.AllowUnexported(localSession{}), cmpopts.IgnoreFields(localSession{}, "mu")); diff != "" {
+		t.Errorf("Fork() and Get() sessions mismatch (-fork +get):\n%s", diff)
+	}
+	var ids []string
+	for ev := range got.Session.Events().All() {
+		ids = append(ids, ev.ID)
+	}
+	if diff := cmp.Diff([]string{"e1", "e2"}, ids); diff != "" {
+		t.Errorf("forked events mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]any{"k": "v2"}, maps.Collect(got.Session.State().All())); diff != "" {
+		t.Errorf("forked state mismatch (-want +got):\n%s", diff)
+	}
+	wantOrigin := &session.ForkOrigin{SessionID: "parent", EventID: "e2", Artifacts: map[string]int64{"a.txt": 2, "b.txt": 1}}
+	if diff := cmp.Diff(wantOrigin, session.ForkOriginOf(got.Session)); diff != "" {
+		t.Errorf("ForkOriginOf() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The sessions diverge.
+	if err := s.AppendEvent(ctx, got.Session, &session.Event{ID: "e4", Timestamp: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	parent, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "parent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parent.Session.Events().Len(); got != 3 {
+		t.Errorf("parent session has %d events after appending to the fork, want 3", got)
+	}
+	if origin := session.ForkOriginOf(parent.Session); origin != nil {
+		t.Errorf("ForkOriginOf() of the parent session = %+v, want nil", origin)
+	}
+}
+
 func serviceDbWithData(t *testing.T) *databaseService {
 	t.Helper()
 
@@ -1018,3 +1228,284 @@ func emptyService(t *testing.T) *databaseService {
 
 	return dbservice
 }
+
+func Test_databaseService_TrimEvents(t *testing.T) {
+	ctx := t.Context()
+	s := emptyService(t)
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session", State: map[string]any{"k": "initial"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ev := range []*session.Event{
+		{ID: "e1", InvocationID: "inv1", Actions: session.EventActions{StateDelta: map[string]any{"k": "v1", "old": true}, ArtifactDelta: map[string]int64{"a.txt": 1, "b.txt": 1}}},
+		{ID: "e2", InvocationID: "inv2", Actions: session.EventActions{StateDelta: map[string]any{"k": "v2"}, ArtifactDelta: map[string]int64{"a.txt": 2, "c.txt": 1}}},
+		{ID: "e3", InvocationID: "inv3", Actions: session.EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	} {
+		ev.Timestamp = time.Now().Add(time.Duration(i) * time.Second)
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := s.TrimEvents(ctx, &session.TrimEventsRequest{AppName: "app", UserID: "user", SessionID: "session", MaxEvents: 2})
+	if err != nil {
+		t.Fatalf("TrimEvents() failed: %v", err)
+	}
+	if resp.Deleted != 1 {
+		t.Errorf("TrimEvents() deleted %d events, want 1", resp.Deleted)
+	}
+
+	// Trimming does not make the sessions read before stale.
+	if err := s.AppendEvent(ctx, created.Session, &session.Event{ID: "e4", InvocationID: "inv4", Timestamp: time.Now().Add(3 * time.Second)}); err != nil {
+		t.Errorf("AppendEvent() after TrimEvents() failed: %v", err)
+	}
+
+	rewound, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "inv2"})
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if got := rewound.Session.Events().Len(); got != 0 {
+		t.Errorf("events after Rewind() = %d, want 0", got)
+	}
+	wantState := map[string]any{"k": "v1", "old": true}
+	if diff := cmp.Diff(wantState, maps.Collect(rewound.Session.State().All())); diff != "" {
+		t.Errorf("state after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+	// The artifacts saved by the trimmed events are reverted to their
+	// versions before the rewound invocation, not deleted.
+	wantVersions := map[string]int64{"a.txt": 1, "c.txt": 0}
+	if diff := cmp.Diff(wantVersions, rewound.ArtifactVersions); diff != "" {
+		t.Errorf("Rewind() artifact versions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_databaseService_List_Options(t *testing.T) {
+	ctx := t.Context()
+	s := emptyService(t)
+	start := time.Now()
+	for i, sessionID := range []string{"s1", "s2", "s3", "s4"} {
+		created, err := s.Create(ctx, &session.CreateRequest{
+			AppName:   "app",
+			UserID:    "user",
+			SessionID: sessionID,
+			State:     map[string]any{"parity": i % 2, "app:shared": true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// s4 is the least recently updated, s1 the most.
+		ev := session.NewEvent("inv")
+		ev.Timestamp = start.Add(time.Duration(-i) * time.Minute)
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Millisecond)
+	middle := time.Now()
+	time.Sleep(time.Millisecond)
+	if _, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "s5", State: map[string]any{"parity": 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// listAll lists all the pages of a request.
+	listAll := func(req *session.ListRequest) ([]string, []int) {
+		t.Helper()
+		var ids []string
+		var pageSizes []int
+		for {
+			resp, err := s.List(ctx, req)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			for _, sess := range resp.Sessions {
+				ids = append(ids, sess.ID())
+			}
+			pageSizes = append(pageSizes, len(resp.Sessions))
+			if resp.NextPageToken == "" {
+				return ids, pageSizes
+			}
+			req.PageToken = resp.NextPageToken
+		}
+	}
+
+	tests := []struct {
+		name          string
+		req           *session.ListRequest
+		wantIDs       []string
+		wantPageSizes []int
+	}{
+		{
+			name:          "pages",
+			req:           &session.ListRequest{AppName: "app", PageSize: 2},
+			wantIDs:       []string{"s1", "s2", "s3", "s4", "s5"},
+			wantPageSizes: []int{2, 2, 1},
+		},
+		{
+			name:          "exact pages",
+			req:           &session.ListRequest{AppName: "app", UserID: "user", PageSize: 5},
+			wantIDs:       []string{"s1", "s2", "s3", "s4", "s5"},
+			wantPageSizes: []int{5},
+		},
+		{
+			name:          "update time ascending",
+			req:           &session.ListRequest{AppName: "app", OrderBy: session.ListOrderUpdateTimeAsc, PageSize: 3},
+			wantIDs:       []string{"s4", "s3", "s2", "s1", "s5"},
+			wantPageSizes: []int{3, 2},
+		},
+		{
+			name:          "update time descending",
+			req:           &session.ListRequest{AppName: "app", OrderBy: session.ListOrderUpdateTimeDesc},
+			wantIDs:       []string{"s5", "s1", "s2", "s3", "s4"},
+			wantPageSizes: []int{5},
+		},
+		{
+			name:          "state filter pages",
+			req:           &session.ListRequest{AppName: "app", StateFilter: map[string]any{"parity": 0, "app:shared": true}, PageSize: 2},
+			wantIDs:       []string{"s1", "s3", "s5"},
+			wantPageSizes: []int{2, 1},
+		},
+		{
+			name:          "created before",
+			req:           &session.ListRequest{AppName: "app", CreatedBefore: middle},
+			wantIDs:       []string{"s1", "s2", "s3", "s4"},
+			wantPageSizes: []int{4},
+		},
+		{
+			name:          "created after",
+			req:           &session.ListRequest{AppName: "app", CreatedAfter: middle},
+			wantIDs:       []string{"s5"},
+			wantPageSizes: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, pageSizes := listAll(tt.req)
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("List() session IDs mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantPageSizes, pageSizes); diff != "" {
+				t.Errorf("List() page sizes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("metadata only", func(t *testing.T) {
+		resp, err := s.List(ctx, &session.ListRequest{AppName: "app", MetadataOnly: true, PageSize: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := maps.Collect(resp.Sessions[0].State().All()); len(got) != 0 {
+			t.Errorf("List() state in metadata only mode = %v, want empty", got)
+		}
+		if got := resp.Sessions[0].LastUpdateTime(); got.Sub(start).Abs() > time.Millisecond {
+			t.Errorf("List() last update time = %v, want %v", got, start)
+		}
+	})
+}
+
+func Test_databaseService_SearchEvents(t *testing.T) {
+	ctx := t.Context()
+	s := emptyService(t)
+	start := time.Now()
+	for _, sess := range []struct {
+		userID, sessionID string
+		events            []*session.Event
+	}{
+		{userID: "alice", sessionID: "s1", events: []*session.Event{
+			{ID: "e1", InvocationID: "inv1", Author: "user", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("What is the weather in Paris?", genai.RoleUser)}},
+			{ID: "e2", InvocationID: "inv1", Author: "agent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromFunctionCall("get_weather", map[string]any{"city": "Paris"}, genai.RoleModel)}},
+			{ID: "e3", InvocationID: "inv1", Author: "agent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("It is sunny in PARIS.", genai.RoleModel)}},
+		}},
+		{userID: "bob", sessionID: "s2", events: []*session.Event{
+			{ID: "e4", InvocationID: "inv2", Author: "agent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("Paris is the capital of France.", genai.RoleModel)}},
+			{ID: "e5", InvocationID: "inv3", Author: "agent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("Lyon is in France too.", genai.RoleModel)}},
+		}},
+	} {
+		created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: sess.userID, SessionID: sess.sessionID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ev := range sess.events {
+			ev.Timestamp = start.Add(time.Duration(ev.ID[1]-'0') * time.Second)
+			if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	search := func(t *testing.T, req *session.SearchEventsRequest) []string {
+		t.Helper()
+		resp, err := s.SearchEvents(ctx, req)
+		if err != nil {
+			t.Fatalf("SearchEvents() failed: %v", err)
+		}
+		var got []string
+		for _, result := range resp.Results {
+			got = append(got, result.SessionID+"/"+result.Event.ID)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name string
+		req  *session.SearchEventsRequest
+		want []string
+	}{
+		{name: "all the words", req: &session.SearchEventsRequest{AppName: "app", Query: "paris sunny"}, want: []string{"s1/e3"}},
+		{name: "most recent first", req: &session.SearchEventsRequest{AppName: "app", Query: "Paris"}, want: []string{"s2/e4", "s1/e3", "s1/e2", "s1/e1"}},
+		{name: "function call name", req: &session.SearchEventsRequest{AppName: "app", Query: "get_weather"}, want: []string{"s1/e2"}},
+		{name: "function call args", req: &session.SearchEventsRequest{AppName: "app", Query: "city"}, want: []string{"s1/e2"}},
+		{name: "whole words", req: &session.SearchEventsRequest{AppName: "app", Query: "par"}},
+		{name: "operators are words", req: &session.SearchEventsRequest{AppName: "app", Query: "paris OR lyon"}},
+		{name: "user", req: &session.SearchEventsRequest{AppName: "app", UserID: "alice", Query: "paris"}, want: []string{"s1/e3", "s1/e2", "s1/e1"}},
+		{name: "author", req: &session.SearchEventsRequest{AppName: "app", Author: "user", Query: "paris"}, want: []string{"s1/e1"}},
+		{name: "time range", req: &session.SearchEventsRequest{AppName: "app", Query: "paris", After: start.Add(3 * time.Second), Before: start.Add(4 * time.Second)}, want: []string{"s1/e3"}},
+		{name: "limit", req: &session.SearchEventsRequest{AppName: "app", Query: "paris", Limit: 2}, want: []string{"s2/e4", "s1/e3"}},
+		{name: "other app", req: &session.SearchEventsRequest{AppName: "other", Query: "paris"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, search(t, tt.req)); diff != "" {
+				t.Errorf("SearchEvents() events mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("no words", func(t *testing.T) {
+		if _, err := s.SearchEvents(ctx, &session.SearchEventsRequest{AppName: "app", Query: " ?! "}); err == nil {
+			t.Error("SearchEvents() without words succeeded, want error")
+		}
+	})
+
+	t.Run("index follows the events", func(t *testing.T) {
+		if _, err := s.Fork(ctx, &session.ForkRequest{AppName: "app", UserID: "bob", SessionID: "s2", EventID: "e4", NewSessionID: "s3"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "bob", SessionID: "s2", InvocationID: "inv2"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Delete(ctx, &session.DeleteRequest{AppName: "app", UserID: "alice", SessionID: "s1"}); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"s3/e4"}, search(t, &session.SearchEventsRequest{AppName: "app", Query: "paris"})); diff != "" {
+			t.Errorf("SearchEvents() events mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("existing events are indexed", func(t *testing.T) {
+		for _, stmt := range []string{"DROP TRIGGER events_search_delete", "DROP TABLE " + searchIndexTable, "DROP TABLE event_search_keys"} {
+			if err := s.db.Exec(stmt).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := s.SearchEvents(ctx, &session.SearchEventsRequest{AppName: "app", Query: "paris"}); err == nil {
+			t.Error("SearchEvents() without index succeeded, want error")
+		}
+		if err := AutoMigrate(s); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"s3/e4"}, search(t, &session.SearchEventsRequest{AppName: "app", Query: "paris"})); diff != "" {
+			t.Errorf("SearchEvents() events mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
@@ -36,6 +36,14 @@ type localSession struct {
 	events    []*session.Event
 	state     map[string]any
 	updatedAt time.Time
+	// version is the version of the session in the storage when it was
+	// last read or written.
+	version int64
+	// forkOrigin is where the session was forked from, nil if it was not.
+	forkOrigin *session.ForkOrigin
+	// initialState is the session state the events apply to, nil if the
+	// session was got with only some of its events.
+	initialState map[string]any
 }
 
 func (s *localSession) ID() string {
@@ -61,6 +69,18 @@ func (s *localSession) Events() session.Events {
 	return events(s.events)
 }
 
+// ForkOrigin returns where the session was forked from, see
+// session.ForkOriginOf.
+func (s *localSession) ForkOrigin() *session.ForkOrigin {
+	return s.forkOrigin
+}
+
+// InitialState returns the state the events of the session apply to, see
+// session.InitialStateOf.
+func (s *localSession) InitialState() map[string]any {
+	return s.initialState
+}
+
 func (s *localSession) LastUpdateTime() time.Time {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -33,6 +33,17 @@ type storageSession struct {
 	State      stateMap
 	CreateTime time.Time `gorm:"precision:6"`
 	UpdateTime time.Time `gorm:"precision:6"`
+	// Version is incremented by every update of the session, to detect
+	// stale sessions.
+	Version int64 `gorm:"not null;default:0"`
+	// InitialState is the session state the session was created with, from
+	// which Rewind replays the state deltas.
+	InitialState stateMap
+	// InitialArtifacts maps the artifacts saved by the trimmed events to
+	// their last versions, see session.TrimEventsRequest.
+	InitialArtifacts versionMap
+	// ForkOrigin is where the session was forked from, nil if it was not.
+	ForkOrigin *session.ForkOrigin `gorm:"serializer:json"`
 
 	// Has-Many relationship: A session has many events.
 	Events []storageEvent `gorm:"foreignKey:AppName,UserID,SessionID;references:AppName,UserID,ID;constraint:OnDelete:CASCADE"`
@@ -58,11 +69,13 @@ func createStorageSession(s *localSession) (*storageSession, error) {
 // Helper to map from GORM struct to internal struct
 func createSessionFromStorageSession(storage *storageSession) (*localSession, error) {
 	return &localSession{
-		appName:   storage.AppName,
-		userID:    storage.UserID,
-		sessionID: storage.ID,
-		state:     storage.State,
-		updatedAt: storage.UpdateTime,
+		appName:    storage.AppName,
+		userID:     storage.UserID,
+		sessionID:  storage.ID,
+		state:      storage.State,
+		updatedAt:  storage.UpdateTime,
+		version:    storage.Version,
+		forkOrigin: storage.ForkOrigin,
 	}, nil
 }
 
@@ -119,7 +132,10 @@ func createStorageEvent(session session.Session, event *session.Event) (*storage
 	}
 
 	// --- Handle complex or nullable fields ---
-	// Serialize the entire Actions struct into a JSON byte slice.
+	// Serialize the entire Actions struct into a JSON byte slice, in the
+	// canonical encoding of the actions, see session.EventJSONSchema. The
+	// rows written before used the Go field names as keys, which still
+	// decode as JSON object keys match the field names case-insensitively.
 	actionsJSON, err := json.Marshal(event.Actions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal event actions: %w", err)
@@ -0,0 +1,191 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+)
+
+// EventSchemaVersion is the version of the canonical JSON encoding of Event
+// written by Event.MarshalJSON. The filesystem session service stores the
+// events in this encoding, and the database session service stores their
+// fields in separate columns with the same encodings, the actions included.
+// The REST API and the A2A metadata keep their own formats, those of the ADK
+// web server and of the A2A extension of ADK, shared with the other ADK
+// implementations.
+//
+// The version is incremented when the encoding changes in a way older
+// readers would misread, such as a renamed or restructured field; added
+// fields keep the version.
+const EventSchemaVersion = 1
+
+//go:embed event.schema.json
+var eventSchema []byte
+
+// EventJSONSchema returns the JSON Schema of the canonical JSON encoding of
+// Event, at EventSchemaVersion.
+func EventJSONSchema() []byte {
+	return slices.Clone(eventSchema)
+}
+
+// eventMigration upgrades the JSON object of an event from a schema version
+// to the next one.
+type eventMigration func(event map[string]json.RawMessage) error
+
+// eventMigrations are the migrations of the JSON objects of events, by the
+// schema version they upgrade from. Incrementing EventSchemaVersion requires
+// adding the migration from the previous version, so that the events
+// stored by older versions keep loading.
+//
+// Events without a schema version, version 0, were encoded with the default
+// encoding of the Event struct, whose field names match the ones of version
+// 1 case-insensitively, so they need no migration.
+var eventMigrations = map[int]eventMigration{}
+
+// eventJSON is the canonical JSON encoding of Event.
+type eventJSON struct {
+	SchemaVersion      int          `json:"schemaVersion"`
+	ID                 string       `json:"id"`
+	Timestamp          time.Time    `json:"timestamp"`
+	InvocationID       string       `json:"invocationId,omitempty"`
+	Branch             string       `json:"branch,omitempty"`
+	Author             string       `json:"author,omitempty"`
+	Actions            EventActions `json:"actions"`
+	LongRunningToolIDs []string     `json:"longRunningToolIds,omitempty"`
+
+	Content           *genai.Content                              `json:"content,omitempty"`
+	CitationMetadata  *genai.CitationMetadata                     `json:"citationMetadata,omitempty"`
+	GroundingMetadata *genai.GroundingMetadata                    `json:"groundingMetadata,omitempty"`
+	UsageMetadata     *genai.GenerateContentResponseUsageMetadata `json:"usageMetadata,omitempty"`
+	CustomMetadata    map[string]any                              `json:"customMetadata,omitempty"`
+	LogprobsResult    *genai.LogprobsResult                       `json:"logprobsResult,omitempty"`
+	Partial           bool                                        `json:"partial,omitempty"`
+	TurnComplete      bool                                        `json:"turnComplete,omitempty"`
+	Interrupted       bool                                        `json:"interrupted,omitempty"`
+	ErrorCode         string                                      `json:"errorCode,omitempty"`
+	ErrorMessage      string                                      `json:"errorMessage,omitempty"`
+	FinishReason      genai.FinishReason                          `json:"finishReason,omitempty"`
+	AvgLogprobs       float64                                     `json:"avgLogprobs,omitempty"`
+}
+
+// MarshalJSON encodes the event in its canonical JSON encoding, described by
+// EventJSONSchema, at EventSchemaVersion.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eventJSON{
+		SchemaVersion:      EventSchemaVersion,
+		ID:                 e.ID,
+		Timestamp:          e.Timestamp,
+		InvocationID:       e.InvocationID,
+		Branch:             e.Branch,
+		Author:             e.Author,
+		Actions:            e.Actions,
+		LongRunningToolIDs: e.LongRunningToolIDs,
+		Content:            e.Content,
+		CitationMetadata:   e.CitationMetadata,
+		GroundingMetadata:  e.GroundingMetadata,
+		UsageMetadata:      e.UsageMetadata,
+		CustomMetadata:     e.CustomMetadata,
+		LogprobsResult:     e.LogprobsResult,
+		Partial:            e.Partial,
+		TurnComplete:       e.TurnComplete,
+		Interrupted:        e.Interrupted,
+		ErrorCode:          e.ErrorCode,
+		ErrorMessage:       e.ErrorMessage,
+		FinishReason:       e.FinishReason,
+		AvgLogprobs:        e.AvgLogprobs,
+	})
+}
+
+// UnmarshalJSON decodes an event from its canonical JSON encoding. Events
+// encoded by older schema versions are migrated to EventSchemaVersion.
+// Events encoded by newer schema versions are decoded on a best-effort
+// basis: the fields unknown to this version are ignored.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var version struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	if version.SchemaVersion < EventSchemaVersion {
+		migrated, err := migrateEvent(data, version.SchemaVersion)
+		if err != nil {
+			return err
+		}
+		data = migrated
+	}
+
+	var ej eventJSON
+	if err := json.Unmarshal(data, &ej); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	*e = Event{
+		ID:                 ej.ID,
+		Timestamp:          ej.Timestamp,
+		InvocationID:       ej.InvocationID,
+		Branch:             ej.Branch,
+		Author:             ej.Author,
+		Actions:            ej.Actions,
+		LongRunningToolIDs: ej.LongRunningToolIDs,
+		LLMResponse: model.LLMResponse{
+			Content:           ej.Content,
+			CitationMetadata:  ej.CitationMetadata,
+			GroundingMetadata: ej.GroundingMetadata,
+			UsageMetadata:     ej.UsageMetadata,
+			CustomMetadata:    ej.CustomMetadata,
+			LogprobsResult:    ej.LogprobsResult,
+			Partial:           ej.Partial,
+			TurnComplete:      ej.TurnComplete,
+			Interrupted:       ej.Interrupted,
+			ErrorCode:         ej.ErrorCode,
+			ErrorMessage:      ej.ErrorMessage,
+			FinishReason:      ej.FinishReason,
+			AvgLogprobs:       ej.AvgLogprobs,
+		},
+	}
+	return nil
+}
+
+// migrateEvent applies the migrations of the JSON object of an event from a
+// schema version to EventSchemaVersion.
+func migrateEvent(data []byte, version int) ([]byte, error) {
+	var event map[string]json.RawMessage
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+	migrated := false
+	for v := version; v < EventSchemaVersion; v++ {
+		migrate, ok := eventMigrations[v]
+		if !ok {
+			continue
+		}
+		if err := migrate(event); err != nil {
+			return nil, fmt.Errorf("failed to migrate event from schema version %d: %w", v, err)
+		}
+		migrated = true
+	}
+	if !migrated {
+		return data, nil
+	}
+	return json.Marshal(event)
+}
@@ -0,0 +1,231 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/jsonschema-go/jsonschema"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/auth"
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/tool/toolconfirmation"
+)
+
+func testEvent() *Event {
+	return &Event{
+		ID:                 "e1",
+		Timestamp:          time.Date(2025, 6, 1, 12, 0, 0, 123456789, time.UTC),
+		InvocationID:       "inv1",
+		Branch:             "root.sub",
+		Author:             "sub",
+		LongRunningToolIDs: []string{"call1"},
+		LLMResponse: model.LLMResponse{
+			Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				genai.NewPartFromText("Let me check."),
+				{FunctionCall: &genai.FunctionCall{ID: "call1", Name: "get_weather", Args: map[string]any{"city": "Paris"}}},
+			}},
+			UsageMetadata:  &genai.GenerateContentResponseUsageMetadata{TotalTokenCount: 42},
+			CustomMetadata: map[string]any{"trace": "abc"},
+			TurnComplete:   true,
+			ErrorCode:      "code",
+			ErrorMessage:   "message",
+			FinishReason:   genai.FinishReasonStop,
+			AvgLogprobs:    -0.5,
+		},
+		Actions: EventActions{
+			StateDelta:                 map[string]any{"k": "v"},
+			ArtifactDelta:              map[string]int64{"a.txt": 2},
+			RequestedToolConfirmations: map[string]toolconfirmation.ToolConfirmation{"call1": {Hint: "sure?"}},
+			RequestedAuthConfigs:       map[string]*auth.AuthConfig{"call2": {CredentialKey: "key"}},
+			SkipSummarization:          true,
+			TransferToAgent:            "other",
+			Escalate:                   true,
+			Compaction: &EventCompaction{
+				StartEventID:     "e0",
+				EndEventID:       "e0",
+				CompactedContent: genai.NewContentFromText("summary", genai.RoleModel),
+			},
+			AgentState: map[string]any{"step": 1.0},
+			EndOfAgent: true,
+		},
+	}
+}
+
+func TestEvent_JSON(t *testing.T) {
+	want := testEvent()
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("json.Marshal() failed: %v", err)
+	}
+
+	var got Event
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if diff := cmp.Diff(want, &got); diff != "" {
+		t.Errorf("decoded event mismatch (-want +got):\n%s", diff)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if got := fields["schemaVersion"]; got != float64(EventSchemaVersion) {
+		t.Errorf("schemaVersion = %v, want %d", got, EventSchemaVersion)
+	}
+	for _, key := range []string{"id", "invocationId", "longRunningToolIds", "content", "finishReason"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("encoded event has no %q field: %s", key, data)
+		}
+	}
+
+	// Events in a slice or a map are encoded the same.
+	values, err := json.Marshal(map[string]Event{"e": *want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"e":` + string(data) + `}`; string(values) != want {
+		t.Errorf("json.Marshal() of an event value = %s, want %s", values, want)
+	}
+}
+
+func TestEventJSONSchema(t *testing.T) {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(EventJSONSchema(), &schema); err != nil {
+		t.Fatalf("failed to parse the event schema: %v", err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		t.Fatalf("failed to resolve the event schema: %v", err)
+	}
+
+	for name, ev := range map[string]*Event{
+		"full":  testEvent(),
+		"empty": {ID: "e1", Timestamp: time.Now()},
+	} {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var instance map[string]any
+		if err := json.Unmarshal(data, &instance); err != nil {
+			t.Fatal(err)
+		}
+		if err := resolved.Validate(instance); err != nil {
+			t.Errorf("%s event does not match the schema: %v\n%s", name, err, data)
+		}
+	}
+
+	invalid := map[string]any{"schemaVersion": 1.0, "id": 1.0, "timestamp": "now", "actions": map[string]any{}}
+	if err := resolved.Validate(invalid); err == nil {
+		t.Error("Validate() of an event with a numeric ID succeeded, want error")
+	}
+}
+
+func TestEvent_UnmarshalJSON_Versions(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *Event
+	}{
+		{
+			name: "default struct encoding before versioning",
+			data: `{
+				"Content": {"role": "model", "parts": [{"text": "hi"}]},
+				"Partial": false,
+				"TurnComplete": true,
+				"ID": "e1",
+				"Timestamp": "2025-06-01T12:00:00Z",
+				"InvocationID": "inv1",
+				"Branch": "",
+				"Author": "agent",
+				"Actions": {"StateDelta": {"k": "v"}, "ArtifactDelta": null, "SkipSummarization": false, "TransferToAgent": "other", "Compaction": null},
+				"LongRunningToolIDs": ["call1"]
+			}`,
+			want: &Event{
+				ID:                 "e1",
+				Timestamp:          time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+				InvocationID:       "inv1",
+				Author:             "agent",
+				LongRunningToolIDs: []string{"call1"},
+				LLMResponse: model.LLMResponse{
+					Content:      genai.NewContentFromText("hi", genai.RoleModel),
+					TurnComplete: true,
+				},
+				Actions: EventActions{StateDelta: map[string]any{"k": "v"}, TransferToAgent: "other"},
+			},
+		},
+		{
+			name: "newer version",
+			data: `{"schemaVersion": 99, "id": "e1", "timestamp": "2025-06-01T12:00:00Z", "author": "agent", "actions": {"newAction": true}, "newField": 1}`,
+			want: &Event{
+				ID:        "e1",
+				Timestamp: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+				Author:    "agent",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Event
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatalf("json.Unmarshal() failed: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, &got); diff != "" {
+				t.Errorf("decoded event mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEvent_UnmarshalJSON_Migrations(t *testing.T) {
+	// The migration from version 0 renames a field, as a future version
+	// would.
+	eventMigrations[0] = func(event map[string]json.RawMessage) error {
+		if v, ok := event["writer"]; ok {
+			event["author"] = v
+			delete(event, "writer")
+		}
+		return nil
+	}
+	t.Cleanup(func() { delete(eventMigrations, 0) })
+
+	var migrated Event
+	if err := json.Unmarshal([]byte(`{"id": "e1", "writer": "agent"}`), &migrated); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if migrated.Author != "agent" {
+		t.Errorf("migrated event author = %q, want %q", migrated.Author, "agent")
+	}
+
+	var current Event
+	if err := json.Unmarshal([]byte(`{"schemaVersion": 1, "id": "e1", "writer": "agent"}`), &current); err != nil {
+		t.Fatalf("json.Unmarshal() failed: %v", err)
+	}
+	if current.Author != "" {
+		t.Errorf("event of the current version was migrated, author = %q", current.Author)
+	}
+
+	eventMigrations[0] = func(map[string]json.RawMessage) error { return errors.New("failed") }
+	if err := json.Unmarshal([]byte(`{"id": "e1"}`), &migrated); err == nil {
+		t.Error("json.Unmarshal() with a failing migration succeeded, want error")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package export provides a stable, versioned JSON serialization of sessions,
+// to move them between session.Service backends and environments, attach them
+// to bug reports or seed eval cases, and renders them as Markdown or HTML
+// transcripts.
+//
+// The artifacts of a session are referenced by name and version, their
+// content stays in the artifact.Service.
+package export
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// FormatVersion is the version of the serialization written by this package.
+// It is incremented on incompatible changes; fields added in a compatible way
+// keep the version.
+const FormatVersion = 1
+
+// ErrUnsupportedVersion is returned when decoding a serialization with a
+// format version newer than FormatVersion.
+var ErrUnsupportedVersion = errors.New("unsupported session export format version")
+
+// Session is the serialization of a session.
+type Session struct {
+	// FormatVersion is the version of the serialization, see FormatVersion.
+	FormatVersion int       `json:"formatVersion"`
+	ExportTime    time.Time `json:"exportTime"`
+
+	ID             string    `json:"id"`
+	AppName        string    `json:"appName"`
+	UserID         string    `json:"userId"`
+	LastUpdateTime time.Time `json:"lastUpdateTime"`
+	// State is the state of the session, including the app and user state
+	// it sees, without the temporary state.
+	State map[string]any `json:"state"`
+	// Events are encoded with the canonical encoding of session.Event.
+	Events []*session.Event `json:"events"`
+	// Artifacts are the artifact versions saved by the events, in the order
+	// of the events.
+	Artifacts  []ArtifactRef       `json:"artifacts,omitempty"`
+	ForkOrigin *session.ForkOrigin `json:"forkOrigin,omitempty"`
+}
+
+// ArtifactRef references an artifact version saved by an event, see
+// session.EventActions.ArtifactDelta.
+type ArtifactRef struct {
+	Filename string `json:"filename"`
+	Version  int64  `json:"version"`
+	EventID  string `json:"eventId"`
+}
+
+// FromSession serializes a session.
+func FromSession(sess session.Session) *Session {
+	s := &Session{
+		FormatVersion:  FormatVersion,
+		ExportTime:     time.Now(),
+		ID:             sess.ID(),
+		AppName:        sess.AppName(),
+		UserID:         sess.UserID(),
+		LastUpdateTime: sess.LastUpdateTime(),
+		State:          make(map[string]any),
+		Events:         make([]*session.Event, 0, sess.Events().Len()),
+		ForkOrigin:     session.ForkOriginOf(sess),
+	}
+	for key, value := range sess.State().All() {
+		if !strings.HasPrefix(key, session.KeyPrefixTemp) {
+			s.State[key] = value
+		}
+	}
+	for ev := range sess.Events().All() {
+		s.Events = append(s.Events, ev)
+		for _, filename := range slices.Sorted(maps.Keys(ev.Actions.ArtifactDelta)) {
+			s.Artifacts = append(s.Artifacts, ArtifactRef{
+				Filename: filename,
+				Version:  ev.Actions.ArtifactDelta[filename],
+				EventID:  ev.ID,
+			})
+		}
+	}
+	return s
+}
+
+// Encode writes the indented JSON serialization of a session.
+func Encode(w io.Writer, s *Session) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	return nil
+}
+
+// Decode reads the JSON serialization of a session. It fails with an error
+// wrapping ErrUnsupportedVersion if the serialization is newer than the
+// package.
+func Decode(r io.Reader) (*Session, error) {
+	var s Session
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	if err := checkFormatVersion(s.FormatVersion); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return &s, nil
+}
+
+// checkFormatVersion fails if a serialization has no format version or a
+// newer one than FormatVersion.
+func checkFormatVersion(version int) error {
+	switch {
+	case version <= 0:
+		return fmt.Errorf("missing format version")
+	case version > FormatVersion:
+		return fmt.Errorf("%w: got %d, supported up to %d", ErrUnsupportedVersion, version, FormatVersion)
+	}
+	return nil
+}
+
+// Export gets a session from a session service and serializes it. The
+// request can select the events, see session.GetRequest.
+func Export(ctx context.Context, service session.Service, req *session.GetRequest) (*Session, error) {
+	resp, err := service.Get(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return FromSession(resp.Session), nil
+}
+
+// ImportOptions customize Import.
+type ImportOptions struct {
+	// AppName, UserID and SessionID identify the imported session.
+	// Optional: the ones of the serialized session are used if empty.
+	AppName   string
+	UserID    string
+	SessionID string
+}
+
+// Import creates a session in a session service from its serialization, and
+// appends its events, keeping their IDs and timestamps.
+//
+// The state the session is created with is the serialized state without the
+// keys set by the events, which the events then set again, so that rewinding
+// the imported session restores these keys as they were before the events.
+// The app and user state of the serialized session is written to the shared
+// app and user state of the service.
+func Import(ctx context.Context, service session.Service, s *Session, opts ImportOptions) (session.Session, error) {
+	if err := checkFormatVersion(s.FormatVersion); err != nil {
+		return nil, fmt.Errorf("failed to import session: %w", err)
+	}
+	state := maps.Clone(s.State)
+	for _, ev := range s.Events {
+		for key := range ev.Actions.StateDelta {
+			delete(state, key)
+		}
+	}
+	resp, err := service.Create(ctx, &session.CreateRequest{
+		AppName:   cmp.Or(opts.AppName, s.AppName),
+		UserID:    cmp.Or(opts.UserID, s.UserID),
+		SessionID: cmp.Or(opts.SessionID, s.ID),
+		State:     state,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	for _, ev := range s.Events {
+		// AppendEvent can modify the event, and the service can keep it:
+		// the event and its delta maps are copied, so that the serialized
+		// session and the imported one do not change each other.
+		ev := *ev
+		ev.Actions.StateDelta = maps.Clone(ev.Actions.StateDelta)
+		ev.Actions.ArtifactDelta = maps.Clone(ev.Actions.ArtifactDelta)
+		if err := service.AppendEvent(ctx, resp.Session, &ev); err != nil {
+			return nil, fmt.Errorf("failed to append event %q: %w", ev.ID, err)
+		}
+	}
+	return resp.Session, nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/filesystem"
+)
+
+var testTime = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func testEvents() []*session.Event {
+	return []*session.Event{
+		{
+			ID:           "e1",
+			InvocationID: "inv1",
+			Author:       "user",
+			Timestamp:    testTime,
+			LLMResponse:  model.LLMResponse{Content: genai.NewContentFromText("What is the weather in Paris?", genai.RoleUser)},
+			Actions:      session.EventActions{StateDelta: map[string]any{"city": "Paris"}},
+		},
+		{
+			ID:           "e2",
+			InvocationID: "inv1",
+			Author:       "weather_agent",
+			Timestamp:    testTime.Add(time.Second),
+			LLMResponse: model.LLMResponse{Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+				genai.NewPartFromFunctionCall("get_weather", map[string]any{"city": "Paris"}),
+			}}},
+		},
+		{
+			ID:           "e3",
+			InvocationID: "inv1",
+			Author:       "weather_agent",
+			Timestamp:    testTime.Add(2 * time.Second),
+			LLMResponse: model.LLMResponse{Content: &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{
+				genai.NewPartFromFunctionResponse("get_weather", map[string]any{"forecast": "sunny"}),
+			}}},
+			Actions: session.EventActions{ArtifactDelta: map[string]int64{"report.txt": 2, "chart.png": 1}},
+		},
+		{
+			ID:           "e4",
+			InvocationID: "inv1",
+			Author:       "weather_agent",
+			Timestamp:    testTime.Add(3 * time.Second),
+			LLMResponse: model.LLMResponse{
+				Content:       genai.NewContentFromText("It is sunny in Paris.", genai.RoleModel),
+				UsageMetadata: &genai.GenerateContentResponseUsageMetadata{TotalTokenCount: 42},
+			},
+			Actions: session.EventActions{StateDelta: map[string]any{"city": "Lyon", "user:visits": 2.0}},
+		},
+	}
+}
+
+func createTestSession(t *testing.T, service session.Service) session.Session {
+	t.Helper()
+	resp, err := service.Create(t.Context(), &session.CreateRequest{
+		AppName:   "app",
+		UserID:    "user",
+		SessionID: "session",
+		State:     map[string]any{"city": "unknown", "units": "metric", "app:version": "1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range testEvents() {
+		if err := service.AppendEvent(t.Context(), resp.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.Session
+}
+
+func TestFromSession(t *testing.T) {
+	sess := createTestSession(t, session.InMemoryService())
+	if err := sess.State().Set("temp:scratch", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := FromSession(sess)
+	want := &Session{
+		FormatVersion:  FormatVersion,
+		ID:             "session",
+		AppName:        "app",
+		UserID:         "user",
+		LastUpdateTime: testTime.Add(3 * time.Second),
+		State:          map[string]any{"city": "Lyon", "units": "metric", "app:version": "1", "user:visits": 2.0},
+		Artifacts: []ArtifactRef{
+			{Filename: "chart.png", Version: 1, EventID: "e3"},
+			{Filename: "report.txt", Version: 2, EventID: "e3"},
+		},
+	}
+	want.Events = testEvents()
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Session{}, "ExportTime")); diff != "" {
+		t.Errorf("FromSession() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	exported := FromSession(createTestSession(t, session.InMemoryService()))
+	var buf bytes.Buffer
+	if err := Encode(&buf, exported); err != nil {
+		t.Fatalf("Encode() failed: %v", err)
+	}
+	for _, want := range []string{`"formatVersion": 1`, `"invocationId": "inv1"`, `"functionCall"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Encode() output does not contain %s:\n%s", want, buf.String())
+		}
+	}
+
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if diff := cmp.Diff(exported, got); diff != "" {
+		t.Errorf("Decode() mismatch (-encoded +decoded):\n%s", diff)
+	}
+	if diff := cmp.Diff(testEvents(), got.Events); diff != "" {
+		t.Errorf("decoded events mismatch (-want +got):\n%s", diff)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{name: "no version", data: `{"id": "session"}`},
+		{name: "newer version", data: `{"formatVersion": 2}`, wantErr: ErrUnsupportedVersion},
+		{name: "invalid", data: `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(tt.data))
+			if err == nil {
+				t.Fatal("Decode() succeeded, want error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Decode() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := t.Context()
+	source := session.InMemoryService()
+	createTestSession(t, source)
+	exported, err := Export(ctx, source, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session"})
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+	if _, err := Export(ctx, source, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "unknown"}); err == nil {
+		t.Error("Export() of an unknown session succeeded, want error")
+	}
+
+	target, err := filesystem.NewSessionService(filesystem.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(ctx, target, exported, ImportOptions{UserID: "other"}); err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+	reexported, err := Export(ctx, target, &session.GetRequest{AppName: "app", UserID: "other", SessionID: "session"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(exported, reexported,
+		cmpopts.IgnoreFields(Session{}, "ExportTime", "UserID"),
+		cmpopts.EquateApproxTime(0),
+	); diff != "" {
+		t.Errorf("imported session mismatch (-exported +imported):\n%s", diff)
+	}
+
+	// The state before the events is restored by rewinding.
+	rewound, err := target.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "other", SessionID: "session", InvocationID: "inv1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantState := map[string]any{"units": "metric", "app:version": "1", "user:visits": 2.0}
+	if diff := cmp.Diff(wantState, maps.Collect(rewound.Session.State().All())); diff != "" {
+		t.Errorf("state of the rewound import mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := Import(ctx, target, exported, ImportOptions{UserID: "other"}); err == nil {
+		t.Error("Import() of an existing session succeeded, want error")
+	}
+	newer := *exported
+	newer.FormatVersion = FormatVersion + 1
+	if _, err := Import(ctx, target, &newer, ImportOptions{SessionID: "newer"}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Import() of a newer version error = %v, want ErrUnsupportedVersion", err)
+	}
+	unversioned := *exported
+	unversioned.FormatVersion = 0
+	if _, err := Import(ctx, target, &unversioned, ImportOptions{SessionID: "unversioned"}); err == nil {
+		t.Error("Import() without a format version succeeded, want error")
+	}
+}
+
+func TestImport_CopiesEvents(t *testing.T) {
+	ctx := t.Context()
+	s := &Session{FormatVersion: FormatVersion, AppName: "app", UserID: "user", ID: "session", Events: testEvents()}
+	target := session.InMemoryService()
+	imported, err := Import(ctx, target, s, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Import() failed: %v", err)
+	}
+
+	// Changing the serialized session does not change the imported one.
+	s.Events[0].Actions.StateDelta["city"] = "Nice"
+	s.Events[2].Actions.ArtifactDelta["report.txt"] = 3
+	resp, err := target.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: imported.ID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(testEvents(), slices.Collect(resp.Session.Events().All()), cmpopts.EquateApproxTime(0)); diff != "" {
+		t.Errorf("imported events mismatch (-want +got):\n%s", diff)
+	}
+}
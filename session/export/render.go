@@ -0,0 +1,227 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// entry is a rendered event of a transcript.
+type entry struct {
+	Author string
+	Time   string
+	Blocks []block
+}
+
+// block is a rendered part of an event. Blocks with a summary are
+// collapsible.
+type block struct {
+	// Summary is the title of a collapsible block, empty for text.
+	Summary string
+	Text    string
+	// Lang is the language of a code block, empty for text.
+	Lang string
+}
+
+// transcript is the view of a session shared by the renderers.
+type transcript struct {
+	Session *Session
+	Updated string
+	Entries []entry
+	State   string
+}
+
+func newTranscript(s *Session) *transcript {
+	t := &transcript{Session: s, Updated: formatTime(s.LastUpdateTime)}
+	if len(s.State) > 0 {
+		t.State = indentJSON(s.State)
+	}
+	for _, e := range s.Events {
+		ent := entry{Author: e.Author, Time: formatTime(e.Timestamp)}
+		if e.Content != nil {
+			for _, part := range e.Content.Parts {
+				if b, ok := partBlock(part); ok {
+					ent.Blocks = append(ent.Blocks, b)
+				}
+			}
+		}
+		if e.ErrorCode != "" || e.ErrorMessage != "" {
+			ent.Blocks = append(ent.Blocks, block{Text: fmt.Sprintf("Error %s: %s", e.ErrorCode, e.ErrorMessage)})
+		}
+		if e.Actions.TransferToAgent != "" {
+			ent.Blocks = append(ent.Blocks, block{Text: "Transferred to " + e.Actions.TransferToAgent})
+		}
+		if len(ent.Blocks) > 0 {
+			t.Entries = append(t.Entries, ent)
+		}
+	}
+	return t
+}
+
+func partBlock(part *genai.Part) (block, bool) {
+	switch {
+	case part.Thought && part.Text != "":
+		return block{Summary: "Thought", Text: part.Text}, true
+	case part.Text != "":
+		return block{Text: part.Text}, true
+	case part.FunctionCall != nil:
+		return block{
+			Summary: "Tool call: " + part.FunctionCall.Name,
+			Text:    indentJSON(part.FunctionCall.Args),
+			Lang:    "json",
+		}, true
+	case part.FunctionResponse != nil:
+		return block{
+			Summary: "Tool result: " + part.FunctionResponse.Name,
+			Text:    indentJSON(part.FunctionResponse.Response),
+			Lang:    "json",
+		}, true
+	case part.ExecutableCode != nil:
+		return block{
+			Summary: "Code",
+			Text:    part.ExecutableCode.Code,
+			Lang:    strings.ToLower(string(part.ExecutableCode.Language)),
+		}, true
+	case part.CodeExecutionResult != nil:
+		return block{
+			Summary: fmt.Sprintf("Code execution result: %s", part.CodeExecutionResult.Outcome),
+			Text:    part.CodeExecutionResult.Output,
+			Lang:    "text",
+		}, true
+	case part.InlineData != nil:
+		name := cmp.Or(part.InlineData.DisplayName, "inline data")
+		return block{Text: fmt.Sprintf("[Attachment: %s (%s)]", name, part.InlineData.MIMEType)}, true
+	case part.FileData != nil:
+		return block{Text: fmt.Sprintf("[File: %s (%s)]", part.FileData.FileURI, part.FileData.MIMEType)}, true
+	}
+	return block{}, false
+}
+
+// RenderMarkdown writes a Markdown transcript of a session. Tool calls and
+// results, thoughts and code executions are collapsible.
+func RenderMarkdown(w io.Writer, s *Session) error {
+	t := newTranscript(s)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", s.ID)
+	fmt.Fprintf(&b, "- App: %s\n- User: %s\n- Last update: %s\n", s.AppName, s.UserID, t.Updated)
+	for _, ent := range t.Entries {
+		fmt.Fprintf(&b, "\n### %s", cmp.Or(ent.Author, "unknown"))
+		if ent.Time != "" {
+			fmt.Fprintf(&b, " · %s", ent.Time)
+		}
+		b.WriteString("\n")
+		for _, blk := range ent.Blocks {
+			b.WriteString("\n")
+			if blk.Summary == "" {
+				b.WriteString(blk.Text)
+				b.WriteString("\n")
+				continue
+			}
+			fmt.Fprintf(&b, "<details>\n<summary>%s</summary>\n\n", template.HTMLEscapeString(blk.Summary))
+			writeCodeBlock(&b, blk.Lang, blk.Text)
+			b.WriteString("\n</details>\n")
+		}
+	}
+	if t.State != "" {
+		b.WriteString("\n## State\n\n")
+		writeCodeBlock(&b, "json", t.State)
+	}
+	if len(s.Artifacts) > 0 {
+		b.WriteString("\n## Artifacts\n\n")
+		for _, a := range s.Artifacts {
+			fmt.Fprintf(&b, "- %s (version %d, event %s)\n", a.Filename, a.Version, a.EventID)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeCodeBlock writes a fenced code block, with a fence longer than the
+// backtick runs of the code.
+func writeCodeBlock(b *strings.Builder, lang, code string) {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, lang, strings.TrimSuffix(code, "\n"), fence)
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Session {{.Session.ID}}</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; }
+.entry { border-left: 3px solid #ccc; margin: 1em 0; padding: 0 1em; }
+.author { font-weight: bold; }
+.time { color: #777; font-size: 0.9em; }
+.text { white-space: pre-wrap; }
+pre { background: #f5f5f5; padding: 0.5em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Session {{.Session.ID}}</h1>
+<ul>
+<li>App: {{.Session.AppName}}</li>
+<li>User: {{.Session.UserID}}</li>
+<li>Last update: {{.Updated}}</li>
+</ul>
+{{range .Entries}}<div class="entry">
+<p><span class="author">{{or .Author "unknown"}}</span>{{if .Time}} <span class="time">{{.Time}}</span>{{end}}</p>
+{{range .Blocks}}{{if .Summary}}<details>
+<summary>{{.Summary}}</summary>
+<pre><code{{if .Lang}} class="language-{{.Lang}}"{{end}}>{{.Text}}</code></pre>
+</details>
+{{else}}<div class="text">{{.Text}}</div>
+{{end}}{{end}}</div>
+{{end}}{{if .State}}<h2>State</h2>
+<pre><code class="language-json">{{.State}}</code></pre>
+{{end}}{{with .Session.Artifacts}}<h2>Artifacts</h2>
+<ul>
+{{range .}}<li>{{.Filename}} (version {{.Version}}, event {{.EventID}})</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// RenderHTML writes a standalone HTML transcript of a session. Tool calls
+// and results, thoughts and code executions are collapsible.
+func RenderHTML(w io.Writer, s *Session) error {
+	return htmlTemplate.Execute(w, newTranscript(s))
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func indentJSON(v any) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
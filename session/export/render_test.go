@@ -0,0 +1,149 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+func testSession() *Session {
+	s := &Session{
+		FormatVersion:  FormatVersion,
+		ID:             "session",
+		AppName:        "app",
+		UserID:         "user",
+		LastUpdateTime: testTime,
+		State:          map[string]any{"city": "Paris"},
+		Artifacts:      []ArtifactRef{{Filename: "report.txt", Version: 2, EventID: "e3"}},
+	}
+	s.Events = testEvents()
+	return s
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := RenderMarkdown(&b, testSession()); err != nil {
+		t.Fatalf("RenderMarkdown() failed: %v", err)
+	}
+	want := "# Session session\n" +
+		"\n" +
+		"- App: app\n" +
+		"- User: user\n" +
+		"- Last update: 2025-06-01T12:00:00Z\n" +
+		"\n" +
+		"### user · 2025-06-01T12:00:00Z\n" +
+		"\n" +
+		"What is the weather in Paris?\n" +
+		"\n" +
+		"### weather_agent · 2025-06-01T12:00:01Z\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary>Tool call: get_weather</summary>\n" +
+		"\n" +
+		"```json\n" +
+		"{\n" +
+		"  \"city\": \"Paris\"\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"</details>\n" +
+		"\n" +
+		"### weather_agent · 2025-06-01T12:00:02Z\n" +
+		"\n" +
+		"<details>\n" +
+		"<summary>Tool result: get_weather</summary>\n" +
+		"\n" +
+		"```json\n" +
+		"{\n" +
+		"  \"forecast\": \"sunny\"\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"</details>\n" +
+		"\n" +
+		"### weather_agent · 2025-06-01T12:00:03Z\n" +
+		"\n" +
+		"It is sunny in Paris.\n" +
+		"\n" +
+		"## State\n" +
+		"\n" +
+		"```json\n" +
+		"{\n" +
+		"  \"city\": \"Paris\"\n" +
+		"}\n" +
+		"```\n" +
+		"\n" +
+		"## Artifacts\n" +
+		"\n" +
+		"- report.txt (version 2, event e3)\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("RenderMarkdown() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderMarkdown_Fence(t *testing.T) {
+	s := &Session{ID: "session", Events: []*session.Event{{
+		Author: "agent",
+		LLMResponse: modelResponse(&genai.Part{ExecutableCode: &genai.ExecutableCode{
+			Code:     "print('```')",
+			Language: genai.LanguagePython,
+		}}),
+	}}}
+	var b strings.Builder
+	if err := RenderMarkdown(&b, s); err != nil {
+		t.Fatal(err)
+	}
+	if want := "````python\nprint('```')\n````\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("RenderMarkdown() = %q, want it to contain %q", b.String(), want)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	s := testSession()
+	s.Events = append(s.Events, &session.Event{
+		Author:      "agent",
+		LLMResponse: modelResponse(genai.NewPartFromText("<script>alert(1)</script>")),
+	})
+	var b strings.Builder
+	if err := RenderHTML(&b, s); err != nil {
+		t.Fatalf("RenderHTML() failed: %v", err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"<title>Session session</title>",
+		"<details>\n<summary>Tool call: get_weather</summary>",
+		`<pre><code class="language-json">{
+  &#34;city&#34;: &#34;Paris&#34;
+}</code></pre>`,
+		`<div class="text">It is sunny in Paris.</div>`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"<li>report.txt (version 2, event e3)</li>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RenderHTML() does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func modelResponse(parts ...*genai.Part) model.LLMResponse {
+	return model.LLMResponse{Content: &genai.Content{Role: genai.RoleModel, Parts: parts}}
+}
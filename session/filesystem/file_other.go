@@ -0,0 +1,28 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !unix
+
+package filesystem
+
+import "os"
+
+// lockFile is a no-op on this platform, only the goroutines of the process
+// are excluded, not the other processes.
+func lockFile(f *os.File, exclusive bool) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
+
+// syncDir is a no-op on this platform, renames are durable on their own.
+func syncDir(dir string) error { return nil }
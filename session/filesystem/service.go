@@ -0,0 +1,733 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filesystem provides a session.Service storing the sessions in a
+// directory, with no dependency besides the file system. It suits local
+// development and single-host deployments, such as the console launcher.
+//
+// Each session is stored as an append-only JSONL log of its events plus a
+// JSON snapshot of its metadata and state. Files are replaced atomically and
+// synced, and a torn write at the end of a log, left by a crash, is ignored,
+// so a crash never corrupts a session. Rewind and TrimEvents append markers
+// to the log, which is compacted once it has enough records that no longer
+// contribute events. The sessions of an app are guarded by a lock file,
+// which also excludes the other processes using the directory on Unix.
+package filesystem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// DefaultCompactionThreshold is the default number of records of the log
+// of a session which no longer contribute events, see
+// Config.CompactionThreshold.
+const DefaultCompactionThreshold = 100
+
+// Config is the configuration of the file-system session service.
+type Config struct {
+	// Dir is the directory storing the sessions. It is created if it does
+	// not exist.
+	Dir string
+	// CompactionThreshold is the number of records of the log of a session
+	// which no longer contribute events, because of Rewind or TrimEvents,
+	// from which the log is compacted.
+	// Optional: if zero, DefaultCompactionThreshold is used, if negative,
+	// logs are never compacted.
+	CompactionThreshold int
+}
+
+// fileService is a file-system implementation of session.Service.
+// Thread-safe.
+type fileService struct {
+	dir                 string
+	compactionThreshold int
+
+	// mu excludes the goroutines of the process, the lock files exclude
+	// the other processes.
+	mu sync.RWMutex
+}
+
+// NewSessionService creates a session.Service storing the sessions in
+// cfg.Dir.
+func NewSessionService(cfg Config) (session.Service, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("dir is required")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	threshold := cfg.CompactionThreshold
+	if threshold == 0 {
+		threshold = DefaultCompactionThreshold
+	}
+	return &fileService{dir: cfg.Dir, compactionThreshold: threshold}, nil
+}
+
+// lockApp locks the sessions of an app, exclusively to modify them, and
+// returns the function unlocking them.
+func (s *fileService) lockApp(appName string, exclusive bool) (func(), error) {
+	appDir, err := s.appDir(appName)
+	if err != nil {
+		return nil, err
+	}
+	if exclusive {
+		s.mu.Lock()
+	} else {
+		s.mu.RLock()
+	}
+	unlockMu := func() {
+		if exclusive {
+			s.mu.Unlock()
+		} else {
+			s.mu.RUnlock()
+		}
+	}
+
+	if err := os.MkdirAll(appDir, 0o755); err != nil {
+		unlockMu()
+		return nil, fmt.Errorf("failed to create app directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(appDir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		unlockMu()
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f, exclusive); err != nil {
+		f.Close()
+		unlockMu()
+		return nil, fmt.Errorf("failed to lock app %q: %w", appName, err)
+	}
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+		unlockMu()
+	}, nil
+}
+
+func (s *fileService) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+	if req.AppName == "" || req.UserID == "" {
+		return nil, fmt.Errorf("app_name and user_id are required, got app_name: %q, user_id: %q", req.AppName, req.UserID)
+	}
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = uuid.NewString()
+	}
+	dir, err := s.sessionDir(req.AppName, req.UserID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockApp(req.AppName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := prepareSessionDir(dir, sessionID); err != nil {
+		return nil, err
+	}
+	appDelta, userDelta, sessionState := sessionutils.ExtractStateDeltas(req.State)
+	appState, userState, err := s.updateAppAndUserStates(req.AppName, req.UserID, appDelta, userDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stored := &storedSession{
+		dir: dir,
+		snapshot: snapshot{
+			ID:               sessionID,
+			AppName:          req.AppName,
+			UserID:           req.UserID,
+			CreateTime:       now,
+			UpdateTime:       now,
+			State:            sessionState,
+			InitialState:     maps.Clone(sessionState),
+			InitialArtifacts: make(map[string]int64),
+		},
+	}
+	if err := writeJSON(filepath.Join(dir, snapshotFileName), stored.snapshot); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return &session.CreateResponse{
+		Session: newLocalSession(stored, appState, userState),
+	}, nil
+}
+
+func (s *fileService) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+
+	unlock, err := s.lockApp(appName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stored, err := s.loadSession(appName, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	appState, userState, err := s.readAppAndUserStates(appName, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(stored.events)
+	if !req.After.IsZero() {
+		stored.events = slices.DeleteFunc(stored.events, func(ev *session.Event) bool {
+			return ev.Timestamp.Before(req.After)
+		})
+	}
+	if req.NumRecentEvents > 0 && len(stored.events) > req.NumRecentEvents {
+		stored.events = stored.events[len(stored.events)-req.NumRecentEvents:]
+	}
+	sess := newLocalSession(stored, appState, userState)
+	if len(stored.events) != n {
+		sess.initialState = nil
+	}
+	return &session.GetResponse{Session: sess}, nil
+}
+
+func (s *fileService) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
+	appName, userID := req.AppName, req.UserID
+	if appName == "" {
+		return nil, fmt.Errorf("app_name is required, got app_name: %q", appName)
+	}
+
+	unlock, err := s.lockApp(appName, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	appDir, err := s.appDir(appName)
+	if err != nil {
+		return nil, err
+	}
+	appState, err := readState(filepath.Join(appDir, appStateFileName))
+	if err != nil {
+		return nil, err
+	}
+	var userDirs []string
+	if userID != "" {
+		userDir, err := s.userDir(appName, userID)
+		if err != nil {
+			return nil, err
+		}
+		userDirs = []string{userDir}
+	} else {
+		userDirs, err = subdirs(filepath.Join(appDir, usersDirName))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sessions []*localSession
+	for _, userDir := range userDirs {
+		userState, err := readState(filepath.Join(userDir, userStateFileName))
+		if err != nil {
+			return nil, err
+		}
+		sessionDirs, err := subdirs(filepath.Join(userDir, sessionsDirName))
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range sessionDirs {
+			snap, err := readSnapshot(dir)
+			if errors.Is(err, fs.ErrNotExist) {
+				// The session is being created or deleted.
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !sessionutils.InTimeRange(snap.CreateTime, req.CreatedAfter, req.CreatedBefore) {
+				continue
+			}
+			sess := newLocalSession(&storedSession{snapshot: *snap}, appState, userState)
+			if !sessionutils.StateMatches(sess.state, req.StateFilter) {
+				continue
+			}
+			if req.MetadataOnly {
+				sess.state = make(map[string]any)
+			}
+			sessions = append(sessions, sess)
+		}
+	}
+
+	switch req.OrderBy {
+	case session.ListOrderID:
+		slices.SortFunc(sessions, func(a, b *localSession) int {
+			return cmp.Or(cmp.Compare(a.userID, b.userID), cmp.Compare(a.sessionID, b.sessionID))
+		})
+	case session.ListOrderUpdateTimeDesc:
+		slices.SortStableFunc(sessions, func(a, b *localSession) int { return b.updatedAt.Compare(a.updatedAt) })
+	case session.ListOrderUpdateTimeAsc:
+		slices.SortStableFunc(sessions, func(a, b *localSession) int { return a.updatedAt.Compare(b.updatedAt) })
+	default:
+		return nil, fmt.Errorf("unknown list order %d", req.OrderBy)
+	}
+	page, nextPageToken, err := sessionutils.Paginate(sessions, req.PageToken, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &session.ListResponse{
+		Sessions:      make([]session.Session, 0, len(page)),
+		NextPageToken: nextPageToken,
+	}
+	for _, sess := range page {
+		// Listed sessions have no events.
+		sess.events, sess.initialState = nil, nil
+		resp.Sessions = append(resp.Sessions, sess)
+	}
+	return resp, nil
+}
+
+func (s *fileService) Delete(ctx context.Context, req *session.DeleteRequest) error {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+	dir, err := s.sessionDir(appName, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := s.lockApp(appName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Removing the snapshot first deletes the session at once, the rest of
+	// the directory is only leftovers.
+	if err := os.Remove(filepath.Join(dir, snapshotFileName)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *fileService) AppendEvent(ctx context.Context, curSession session.Session, event *session.Event) error {
+	if curSession == nil {
+		return fmt.Errorf("session is nil")
+	}
+	if event == nil {
+		return fmt.Errorf("event is nil")
+	}
+	if event.Partial {
+		return nil
+	}
+
+	sess, ok := curSession.(*localSession)
+	if !ok {
+		return fmt.Errorf("unexpected session type %T", curSession)
+	}
+	// append it to session
+	if err := sess.appendEvent(event); err != nil {
+		return err
+	}
+	// Trim temp state before persisting
+	event = trimTempDeltaState(event)
+
+	unlock, err := s.lockApp(sess.appName, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := s.loadSession(sess.appName, sess.userID, sess.sessionID)
+	if err != nil {
+		return fmt.Errorf("session not found, cannot apply event: %w", err)
+	}
+	if stored.snapshot.Version != sess.version {
+		return fmt.Errorf("%w: session %q has version %d in the storage, the request has version %d",
+			session.ErrStaleSession, sess.sessionID, stored.snapshot.Version, sess.version)
+	}
+
+	if err := appendLog(stored, record{Event: event}); err != nil {
+		return err
+	}
+	appDelta, userDelta, sessionDelta := sessionutils.ExtractStateDeltas(event.Actions.StateDelta)
+	if _, _, err := s.updateAppAndUserStates(sess.appName, sess.userID, appDelta, userDelta); err != nil {
+		return err
+	}
+	snap := &stored.snapshot
+	if snap.State == nil {
+		snap.State = make(map[string]any)
+	}
+	maps.Copy(snap.State, sessionDelta)
+	snap.UpdateTime = event.Timestamp
+	snap.Version++
+	if err := writeJSON(filepath.Join(stored.dir, snapshotFileName), snap); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	sess.version = snap.Version
+	sess.updatedAt = event.Timestamp
+	return nil
+}
+
+// Rewind removes the events of an invocation and the later ones, and
+// restores the session state, implements session.Service
+func (s *fileService) Rewind(ctx context.Context, req *session.RewindRequest) (*session.RewindResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" || req.InvocationID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id, invocation_id are required, got app_name: %q, user_id: %q, session_id: %q, invocation_id: %q", appName, userID, sessionID, req.InvocationID)
+	}
+
+	unlock, err := s.lockApp(appName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stored, err := s.loadSession(appName, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(stored.events, func(ev *session.Event) bool {
+		return ev.InvocationID == req.InvocationID
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("invocation %q not found in session %q", req.InvocationID, sessionID)
+	}
+	kept, removed := stored.events[:i], stored.events[i:]
+	artifactVersions := sessionutils.RevertedArtifactVersions(stored.snapshot.InitialArtifacts, artifactDeltas(kept), artifactDeltas(removed))
+
+	if err := appendLog(stored, record{RewindInvocation: req.InvocationID}); err != nil {
+		return nil, err
+	}
+	stored.events = kept
+	stored.deadRecords += len(removed) + 1
+	snap := &stored.snapshot
+	snap.State = sessionutils.ReplayStateDeltas(snap.InitialState, stateDeltas(kept))
+	snap.UpdateTime = time.Now()
+	snap.Version++
+	if err := s.saveSnapshot(stored); err != nil {
+		return nil, err
+	}
+
+	appState, userState, err := s.readAppAndUserStates(appName, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &session.RewindResponse{
+		Session:          newLocalSession(stored, appState, userState),
+		ArtifactVersions: artifactVersions,
+	}, nil
+}
+
+// Fork creates a new session from a prefix of the events of a session,
+// implements session.Service
+func (s *fileService) Fork(ctx context.Context, req *session.ForkRequest) (*session.ForkResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+	newSessionID := req.NewSessionID
+	if newSessionID == "" {
+		newSessionID = uuid.NewString()
+	}
+	dir, err := s.sessionDir(appName, userID, newSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockApp(appName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	parent, err := s.loadSession(appName, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	events := parent.events
+	if req.EventID != "" {
+		i := slices.IndexFunc(events, func(ev *session.Event) bool { return ev.ID == req.EventID })
+		if i < 0 {
+			return nil, fmt.Errorf("event %q not found in session %q", req.EventID, sessionID)
+		}
+		events = events[:i+1]
+	}
+	origin := &session.ForkOrigin{SessionID: sessionID}
+	records := make([]record, 0, len(events))
+	for _, ev := range events {
+		origin.EventID = ev.ID
+		records = append(records, record{Event: ev})
+	}
+	origin.Artifacts = sessionutils.LatestArtifactVersions(parent.snapshot.InitialArtifacts, artifactDeltas(events))
+
+	if err := prepareSessionDir(dir, newSessionID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	forked := &storedSession{
+		dir: dir,
+		snapshot: snapshot{
+			ID:               newSessionID,
+			AppName:          appName,
+			UserID:           userID,
+			CreateTime:       now,
+			UpdateTime:       now,
+			State:            sessionutils.ReplayStateDeltas(parent.snapshot.InitialState, stateDeltas(events)),
+			InitialState:     maps.Clone(parent.snapshot.InitialState),
+			InitialArtifacts: make(map[string]int64),
+			ForkOrigin:       origin,
+		},
+		events: events,
+	}
+	if len(records) > 0 {
+		if err := appendLog(forked, records...); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeJSON(filepath.Join(dir, snapshotFileName), forked.snapshot); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	appState, userState, err := s.readAppAndUserStates(appName, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &session.ForkResponse{Session: newLocalSession(forked, appState, userState)}, nil
+}
+
+// TrimEvents deletes the oldest events of a session, keeping at most
+// req.MaxEvents events. See session.TrimEventsRequest.
+func (s *fileService) TrimEvents(ctx context.Context, req *session.TrimEventsRequest) (*session.TrimEventsResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+	if req.MaxEvents < 0 {
+		return nil, fmt.Errorf("max_events must not be negative, got %d", req.MaxEvents)
+	}
+
+	unlock, err := s.lockApp(appName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	stored, err := s.loadSession(appName, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	n := len(stored.events) - req.MaxEvents
+	if n <= 0 {
+		return &session.TrimEventsResponse{}, nil
+	}
+
+	if err := appendLog(stored, record{Trim: n}); err != nil {
+		return nil, err
+	}
+	// The session state and version are unchanged: the trimmed events only
+	// move into the initial state.
+	snap := &stored.snapshot
+	snap.InitialState = sessionutils.ReplayStateDeltas(snap.InitialState, stateDeltas(stored.events[:n]))
+	snap.InitialArtifacts = sessionutils.LatestArtifactVersions(snap.InitialArtifacts, artifactDeltas(stored.events[:n]))
+	stored.events = stored.events[n:]
+	stored.deadRecords += n + 1
+	if err := s.saveSnapshot(stored); err != nil {
+		return nil, err
+	}
+	return &session.TrimEventsResponse{Deleted: n}, nil
+}
+
+// loadSession loads a session, see loadSession.
+func (s *fileService) loadSession(appName, userID, sessionID string) (*storedSession, error) {
+	dir, err := s.sessionDir(appName, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := loadSession(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("session %q not found: %w", sessionID, err)
+		}
+		return nil, fmt.Errorf("failed to load session %q: %w", sessionID, err)
+	}
+	return stored, nil
+}
+
+// saveSnapshot writes the snapshot of a session, after compacting its log
+// if it has enough dead records.
+func (s *fileService) saveSnapshot(stored *storedSession) error {
+	// The snapshot is written before compacting, which drops the records
+	// it reflects from the log.
+	if err := writeJSON(filepath.Join(stored.dir, snapshotFileName), stored.snapshot); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if s.compactionThreshold > 0 && stored.deadRecords >= s.compactionThreshold {
+		if err := compactLog(stored); err != nil {
+			return fmt.Errorf("failed to compact session: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *fileService) readAppAndUserStates(appName, userID string) (appState, userState map[string]any, err error) {
+	appDir, err := s.appDir(appName)
+	if err != nil {
+		return nil, nil, err
+	}
+	userDir, err := s.userDir(appName, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if appState, err = readState(filepath.Join(appDir, appStateFileName)); err != nil {
+		return nil, nil, err
+	}
+	if userState, err = readState(filepath.Join(userDir, userStateFileName)); err != nil {
+		return nil, nil, err
+	}
+	return appState, userState, nil
+}
+
+// updateAppAndUserStates applies the state deltas to the app and user states,
+// and returns the updated states.
+func (s *fileService) updateAppAndUserStates(appName, userID string, appDelta, userDelta map[string]any) (appState, userState map[string]any, err error) {
+	appState, userState, err = s.readAppAndUserStates(appName, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(appDelta) > 0 {
+		appDir, _ := s.appDir(appName)
+		maps.Copy(appState, appDelta)
+		if err := writeJSON(filepath.Join(appDir, appStateFileName), appState); err != nil {
+			return nil, nil, fmt.Errorf("failed to save app state: %w", err)
+		}
+	}
+	if len(userDelta) > 0 {
+		userDir, _ := s.userDir(appName, userID)
+		if err := os.MkdirAll(userDir, 0o755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create user directory: %w", err)
+		}
+		maps.Copy(userState, userDelta)
+		if err := writeJSON(filepath.Join(userDir, userStateFileName), userState); err != nil {
+			return nil, nil, fmt.Errorf("failed to save user state: %w", err)
+		}
+	}
+	return appState, userState, nil
+}
+
+// prepareSessionDir makes an empty directory for a new session, failing if
+// the session exists. A directory without a snapshot is a leftover of an
+// interrupted creation or deletion, and is removed.
+func prepareSessionDir(dir, sessionID string) error {
+	_, err := os.Stat(filepath.Join(dir, snapshotFileName))
+	if err == nil {
+		return fmt.Errorf("session %s already exists", sessionID)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove session leftovers: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return nil
+}
+
+// readSnapshot reads the snapshot of the session stored in dir, loading the
+// whole session only if the snapshot does not reflect the whole log.
+func readSnapshot(dir string) (*snapshot, error) {
+	var snap snapshot
+	if err := readJSON(filepath.Join(dir, snapshotFileName), &snap); err != nil {
+		return nil, err
+	}
+	var logSize int64
+	info, err := os.Stat(filepath.Join(dir, logFileName))
+	if err == nil {
+		logSize = info.Size()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to check event log: %w", err)
+	}
+	if logSize == snap.LogSize {
+		return &snap, nil
+	}
+	stored, err := loadSession(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &stored.snapshot, nil
+}
+
+// subdirs returns the subdirectories of dir, none if it does not exist.
+func subdirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+func newLocalSession(stored *storedSession, appState, userState map[string]any) *localSession {
+	snap := &stored.snapshot
+	initialState := maps.Clone(snap.InitialState)
+	if initialState == nil {
+		initialState = map[string]any{}
+	}
+	return &localSession{
+		appName:      snap.AppName,
+		userID:       snap.UserID,
+		sessionID:    snap.ID,
+		events:       stored.events,
+		state:        sessionutils.MergeStates(appState, userState, snap.State),
+		updatedAt:    snap.UpdateTime,
+		version:      snap.Version,
+		forkOrigin:   snap.ForkOrigin,
+		initialState: initialState,
+	}
+}
+
+var (
+	_ session.Service      = (*fileService)(nil)
+	_ session.EventTrimmer = (*fileService)(nil)
+)
@@ -0,0 +1,595 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/model"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+func newService(t *testing.T, dir string, threshold int) *fileService {
+	t.Helper()
+	s, err := NewSessionService(Config{Dir: dir, CompactionThreshold: threshold})
+	if err != nil {
+		t.Fatalf("NewSessionService() failed: %v", err)
+	}
+	return s.(*fileService)
+}
+
+// appendEvents appends events with increasing timestamps.
+func appendEvents(t *testing.T, s session.Service, sess session.Session, events ...*session.Event) {
+	t.Helper()
+	for i, ev := range events {
+		ev.Timestamp = time.Now().Add(time.Duration(i) * time.Second)
+		if err := s.AppendEvent(t.Context(), sess, ev); err != nil {
+			t.Fatalf("AppendEvent() failed: %v", err)
+		}
+	}
+}
+
+func getSession(t *testing.T, s session.Service, sessionID string) session.Session {
+	t.Helper()
+	resp, err := s.Get(t.Context(), &session.GetRequest{AppName: "app", UserID: "user", SessionID: sessionID})
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	return resp.Session
+}
+
+func eventIDs(sess session.Session) []string {
+	var ids []string
+	for ev := range sess.Events().All() {
+		ids = append(ids, ev.ID)
+	}
+	return ids
+}
+
+func TestNewSessionService(t *testing.T) {
+	if _, err := NewSessionService(Config{}); err == nil {
+		t.Error("NewSessionService() without a directory succeeded, want error")
+	}
+	dir := filepath.Join(t.TempDir(), "nested", "sessions")
+	if _, err := NewSessionService(Config{Dir: dir}); err != nil {
+		t.Fatalf("NewSessionService() failed: %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("sessions directory was not created: %v", err)
+	}
+}
+
+func Test_fileService_Create(t *testing.T) {
+	ctx := t.Context()
+	s := newService(t, t.TempDir(), 0)
+
+	for _, req := range []*session.CreateRequest{
+		{UserID: "user"},
+		{AppName: "app"},
+		{AppName: "app", UserID: "..", SessionID: "session"},
+	} {
+		if _, err := s.Create(ctx, req); err == nil {
+			t.Errorf("Create(%+v) succeeded, want error", req)
+		}
+	}
+
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session/1", State: map[string]any{"k": "v"}})
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if got := created.Session.ID(); got != "session/1" {
+		t.Errorf("Create() session ID = %q, want %q", got, "session/1")
+	}
+	if _, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session/1"}); err == nil {
+		t.Error("Create() of an existing session succeeded, want error")
+	}
+
+	generated, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user"})
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if generated.Session.ID() == "" {
+		t.Error("Create() did not generate a session ID")
+	}
+}
+
+func Test_fileService_Persistence(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := newService(t, dir, 0)
+	created, err := s.Create(ctx, &session.CreateRequest{
+		AppName:   "app",
+		UserID:    "user",
+		SessionID: "session",
+		State:     map[string]any{"k": "initial", "app:a": "app", "user:u": "user"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEvents(t, s, created.Session,
+		&session.Event{
+			ID:           "e1",
+			InvocationID: "inv1",
+			Author:       "user",
+			LLMResponse:  model.LLMResponse{Content: genai.NewContentFromText("hello", genai.RoleUser)},
+			Actions:      session.EventActions{StateDelta: map[string]any{"k": "v1", "temp:t": "temp", "user:u": "user2"}},
+		},
+		&session.Event{
+			ID:           "e2",
+			InvocationID: "inv1",
+			Author:       "agent",
+			LLMResponse:  model.LLMResponse{Content: genai.NewContentFromText("hi", genai.RoleModel)},
+			Actions:      session.EventActions{StateDelta: map[string]any{"n": 1.0}},
+		},
+	)
+	// Partial events are not stored.
+	if err := s.AppendEvent(ctx, created.Session, &session.Event{ID: "partial", LLMResponse: model.LLMResponse{Partial: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another service, such as one of another process, reads the session.
+	got := getSession(t, newService(t, dir, 0), "session")
+	if diff := cmp.Diff(created.Session, got,
+		cmp.AllowUnexported(localSession{}),
+		cmpopts.IgnoreFields(localSession{}, "mu"),
+		// The temp state is only in the session of the invocation.
+		cmpopts.IgnoreMapEntries(func(key string, _ any) bool { return key == "temp:t" }),
+	); diff != "" {
+		t.Errorf("Get() from a new service mismatch (-created +got):\n%s", diff)
+	}
+	wantState := map[string]any{"k": "v1", "n": 1.0, "app:a": "app", "user:u": "user2"}
+	if diff := cmp.Diff(wantState, maps.Collect(got.State().All())); diff != "" {
+		t.Errorf("state mismatch (-want +got):\n%s", diff)
+	}
+
+	// The app and user states are shared with the other sessions.
+	other, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]any{"app:a": "app", "user:u": "user2"}, maps.Collect(other.Session.State().All())); diff != "" {
+		t.Errorf("state of another session mismatch (-want +got):\n%s", diff)
+	}
+
+	// Get filters the events.
+	recent, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session", NumRecentEvents: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"e2"}, eventIDs(recent.Session)); diff != "" {
+		t.Errorf("Get() with NumRecentEvents events mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := s.Delete(ctx, &session.DeleteRequest{AppName: "app", UserID: "user", SessionID: "session"}); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session"}); err == nil {
+		t.Error("Get() of a deleted session succeeded, want error")
+	}
+	if err := s.Delete(ctx, &session.DeleteRequest{AppName: "app", UserID: "user", SessionID: "session"}); err != nil {
+		t.Errorf("Delete() of a deleted session failed: %v", err)
+	}
+}
+
+func Test_fileService_List(t *testing.T) {
+	ctx := t.Context()
+	s := newService(t, t.TempDir(), 0)
+	start := time.Now()
+	for _, req := range []*session.CreateRequest{
+		{AppName: "app", UserID: "user1", SessionID: "s1", State: map[string]any{"topic": "a"}},
+		{AppName: "app", UserID: "user1", SessionID: "s2", State: map[string]any{"topic": "b"}},
+		{AppName: "app", UserID: "user2", SessionID: "s3", State: map[string]any{"topic": "a"}},
+		{AppName: "other", UserID: "user1", SessionID: "s4"},
+	} {
+		if _, err := s.Create(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s2, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user1", SessionID: "s2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AppendEvent(ctx, s2.Session, &session.Event{ID: "e1", Timestamp: start.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		req  *session.ListRequest
+		want []string
+	}{
+		{
+			name: "app",
+			req:  &session.ListRequest{AppName: "app"},
+			want: []string{"s1", "s2", "s3"},
+		},
+		{
+			name: "user",
+			req:  &session.ListRequest{AppName: "app", UserID: "user1"},
+			want: []string{"s1", "s2"},
+		},
+		{
+			name: "unknown app",
+			req:  &session.ListRequest{AppName: "unknown"},
+		},
+		{
+			name: "state filter",
+			req:  &session.ListRequest{AppName: "app", StateFilter: map[string]any{"topic": "a"}},
+			want: []string{"s1", "s3"},
+		},
+		{
+			name: "update time desc",
+			req:  &session.ListRequest{AppName: "app", UserID: "user1", OrderBy: session.ListOrderUpdateTimeDesc},
+			want: []string{"s2", "s1"},
+		},
+		{
+			name: "page",
+			req:  &session.ListRequest{AppName: "app", PageSize: 2},
+			want: []string{"s1", "s2"},
+		},
+		{
+			name: "created before",
+			req:  &session.ListRequest{AppName: "app", CreatedBefore: start},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.List(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			var got []string
+			for _, sess := range resp.Sessions {
+				got = append(got, sess.ID())
+				if sess.Events().Len() != 0 {
+					t.Errorf("listed session %q has events", sess.ID())
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("List() sessions mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("next page", func(t *testing.T) {
+		first, err := s.List(ctx, &session.ListRequest{AppName: "app", PageSize: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		next, err := s.List(ctx, &session.ListRequest{AppName: "app", PageSize: 2, PageToken: first.NextPageToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(next.Sessions) != 1 || next.Sessions[0].ID() != "s3" || next.NextPageToken != "" {
+			t.Errorf("List() of the next page = %v, %q, want [s3] and no next page", next.Sessions, next.NextPageToken)
+		}
+	})
+
+	t.Run("metadata only", func(t *testing.T) {
+		resp, err := s.List(ctx, &session.ListRequest{AppName: "app", UserID: "user1", MetadataOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sess := range resp.Sessions {
+			if state := maps.Collect(sess.State().All()); len(state) != 0 {
+				t.Errorf("session %q listed with metadata only has state %v", sess.ID(), state)
+			}
+		}
+	})
+}
+
+func Test_fileService_StaleSession(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := newService(t, dir, 0)
+	if _, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two processes read the session, the first one appends an event.
+	first, second := getSession(t, s, "session"), getSession(t, newService(t, dir, 0), "session")
+	appendEvents(t, s, first, &session.Event{ID: "e1"}, &session.Event{ID: "e2"})
+
+	err := s.AppendEvent(ctx, second, &session.Event{ID: "e3", Timestamp: time.Now().Add(time.Hour)})
+	if !errors.Is(err, session.ErrStaleSession) {
+		t.Fatalf("AppendEvent() to a stale session error = %v, want ErrStaleSession", err)
+	}
+	appendEvents(t, s, getSession(t, s, "session"), &session.Event{ID: "e3"})
+	if diff := cmp.Diff([]string{"e1", "e2", "e3"}, eventIDs(getSession(t, s, "session"))); diff != "" {
+		t.Errorf("stored events mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_fileService_Rewind(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := newService(t, dir, 0)
+	created, err := s.Create(ctx, &session.CreateRequest{
+		AppName:   "app",
+		UserID:    "user",
+		SessionID: "session",
+		State:     map[string]any{"k": "initial", "app:shared": 1.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEvents(t, s, created.Session,
+		&session.Event{ID: "e1", InvocationID: "inv1", Actions: session.EventActions{
+			StateDelta:    map[string]any{"k": "v1"},
+			ArtifactDelta: map[string]int64{"a.txt": 1},
+		}},
+		&session.Event{ID: "e2", InvocationID: "inv2", Actions: session.EventActions{
+			StateDelta:    map[string]any{"k": "v2", "added": true, "app:shared": 2.0},
+			ArtifactDelta: map[string]int64{"a.txt": 2, "b.txt": 1},
+		}},
+		&session.Event{ID: "e3", InvocationID: "inv3", Actions: session.EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	)
+
+	if _, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "unknown"}); err == nil {
+		t.Error("Rewind() to an unknown invocation succeeded, want error")
+	}
+
+	resp, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "inv2"})
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]int64{"a.txt": 1, "b.txt": 0}, resp.ArtifactVersions); diff != "" {
+		t.Errorf("Rewind() artifact versions mismatch (-want +got):\n%s", diff)
+	}
+	wantState := map[string]any{"k": "v1", "app:shared": 2.0}
+	for name, sess := range map[string]session.Session{
+		"rewound":  resp.Session,
+		"reloaded": getSession(t, newService(t, dir, 0), "session"),
+	} {
+		if diff := cmp.Diff([]string{"e1"}, eventIDs(sess)); diff != "" {
+			t.Errorf("%s events mismatch (-want +got):\n%s", name, diff)
+		}
+		if diff := cmp.Diff(wantState, maps.Collect(sess.State().All())); diff != "" {
+			t.Errorf("%s state mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+
+	// The session read before rewinding is stale.
+	err = s.AppendEvent(ctx, created.Session, &session.Event{ID: "e4", Timestamp: time.Now()})
+	if !errors.Is(err, session.ErrStaleSession) {
+		t.Errorf("AppendEvent() to the session read before Rewind() error = %v, want ErrStaleSession", err)
+	}
+}
+
+func Test_fileService_Fork(t *testing.T) {
+	ctx := t.Context()
+	s := newService(t, t.TempDir(), 0)
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "parent", State: map[string]any{"k": "initial"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEvents(t, s, created.Session,
+		&session.Event{ID: "e1", Actions: session.EventActions{StateDelta: map[string]any{"k": "v1"}, ArtifactDelta: map[string]int64{"a.txt": 1}}},
+		&session.Event{ID: "e2", Actions: session.EventActions{StateDelta: map[string]any{"k": "v2"}, ArtifactDelta: map[string]int64{"a.txt": 2, "b.txt": 1}}},
+		&session.Event{ID: "e3", Actions: session.EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	)
+
+	for _, req := range []*session.ForkRequest{
+		{AppName: "app", UserID: "user", SessionID: "parent", EventID: "unknown"},
+		{AppName: "app", UserID: "user", SessionID: "parent", NewSessionID: "parent"},
+		{AppName: "app", UserID: "user", SessionID: "unknown"},
+	} {
+		if _, err := s.Fork(ctx, req); err == nil {
+			t.Errorf("Fork(%+v) succeeded, want error", req)
+		}
+	}
+
+	resp, err := s.Fork(ctx, &session.ForkRequest{AppName: "app", UserID: "user", SessionID: "parent", EventID: "e2", NewSessionID: "child"})
+	if err != nil {
+		t.Fatalf("Fork() failed: %v", err)
+	}
+	got := getSession(t, s, "child")
+	if diff := cmp.Diff(resp.Session, got, cmp.AllowUnexported(localSession{}), cmpopts.IgnoreFields(localSession{}, "mu")); diff != "" {
+		t.Errorf("Fork() and Get() sessions mismatch (-fork +get):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"e1", "e2"}, eventIDs(got)); diff != "" {
+		t.Errorf("forked events mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]any{"k": "v2"}, maps.Collect(got.State().All())); diff != "" {
+		t.Errorf("forked state mismatch (-want +got):\n%s", diff)
+	}
+	wantOrigin := &session.ForkOrigin{SessionID: "parent", EventID: "e2", Artifacts: map[string]int64{"a.txt": 2, "b.txt": 1}}
+	if diff := cmp.Diff(wantOrigin, session.ForkOriginOf(got)); diff != "" {
+		t.Errorf("ForkOriginOf() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The sessions diverge.
+	appendEvents(t, s, got, &session.Event{ID: "e4"})
+	if got := getSession(t, s, "parent").Events().Len(); got != 3 {
+		t.Errorf("parent session has %d events after appending to the fork, want 3", got)
+	}
+}
+
+func Test_fileService_TrimEvents(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := newService(t, dir, 0)
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session", State: map[string]any{"k": "initial"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEvents(t, s, created.Session,
+		&session.Event{ID: "e1", InvocationID: "inv1", Actions: session.EventActions{StateDelta: map[string]any{"k": "v1", "old": true}, ArtifactDelta: map[string]int64{"a.txt": 1, "b.txt": 1}}},
+		&session.Event{ID: "e2", InvocationID: "inv2", Actions: session.EventActions{StateDelta: map[string]any{"k": "v2"}, ArtifactDelta: map[string]int64{"a.txt": 2, "c.txt": 1}}},
+		&session.Event{ID: "e3", InvocationID: "inv3", Actions: session.EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	)
+
+	resp, err := s.TrimEvents(ctx, &session.TrimEventsRequest{AppName: "app", UserID: "user", SessionID: "session", MaxEvents: 2})
+	if err != nil {
+		t.Fatalf("TrimEvents() failed: %v", err)
+	}
+	if resp.Deleted != 1 {
+		t.Errorf("TrimEvents() deleted %d events, want 1", resp.Deleted)
+	}
+	if diff := cmp.Diff([]string{"e2", "e3"}, eventIDs(getSession(t, newService(t, dir, 0), "session"))); diff != "" {
+		t.Errorf("events after TrimEvents() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Trimming does not make the sessions read before stale.
+	if err := s.AppendEvent(ctx, created.Session, &session.Event{ID: "e4", InvocationID: "inv4", Timestamp: time.Now().Add(3 * time.Second)}); err != nil {
+		t.Errorf("AppendEvent() after TrimEvents() failed: %v", err)
+	}
+
+	rewound, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "inv2"})
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if got := rewound.Session.Events().Len(); got != 0 {
+		t.Errorf("events after Rewind() = %d, want 0", got)
+	}
+	wantState := map[string]any{"k": "v1", "old": true}
+	if diff := cmp.Diff(wantState, maps.Collect(rewound.Session.State().All())); diff != "" {
+		t.Errorf("state after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+	// The artifacts saved by the trimmed events are reverted to their
+	// versions before the rewound invocation, not deleted.
+	wantVersions := map[string]int64{"a.txt": 1, "c.txt": 0}
+	if diff := cmp.Diff(wantVersions, rewound.ArtifactVersions); diff != "" {
+		t.Errorf("Rewind() artifact versions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_fileService_Compaction(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := newService(t, dir, 3)
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEvents(t, s, created.Session,
+		&session.Event{ID: "e1", InvocationID: "inv1", Actions: session.EventActions{StateDelta: map[string]any{"k": "v1"}}},
+		&session.Event{ID: "e2", InvocationID: "inv2", Actions: session.EventActions{StateDelta: map[string]any{"k": "v2"}}},
+		&session.Event{ID: "e3", InvocationID: "inv3", Actions: session.EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	)
+	logPath := filepath.Join(dir, "app", "users", "user", "sessions", "session", "events.jsonl")
+	countLines := func() int {
+		t.Helper()
+		data, err := os.ReadFile(logPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.Count(data, []byte("\n"))
+	}
+
+	// The rewind of one event leaves 2 dead records, below the threshold.
+	if _, err := s.Rewind(ctx, &session.RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "inv3"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := countLines(); got != 4 {
+		t.Errorf("log has %d records after rewinding, want 4", got)
+	}
+	// Trimming an event adds 2 dead records, and compacts the log.
+	if _, err := s.TrimEvents(ctx, &session.TrimEventsRequest{AppName: "app", UserID: "user", SessionID: "session", MaxEvents: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := countLines(); got != 1 {
+		t.Errorf("log has %d records after compacting, want 1", got)
+	}
+
+	got := getSession(t, newService(t, dir, 3), "session")
+	if diff := cmp.Diff([]string{"e2"}, eventIDs(got)); diff != "" {
+		t.Errorf("events after compacting mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]any{"k": "v2"}, maps.Collect(got.State().All())); diff != "" {
+		t.Errorf("state after compacting mismatch (-want +got):\n%s", diff)
+	}
+	appendEvents(t, s, got, &session.Event{ID: "e4"})
+}
+
+func Test_fileService_CrashRecovery(t *testing.T) {
+	ctx := t.Context()
+	dir := t.TempDir()
+	s := newService(t, dir, 0)
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendEvents(t, s, created.Session, &session.Event{ID: "e1", Actions: session.EventActions{StateDelta: map[string]any{"k": "v1"}}})
+	sessionDir := filepath.Join(dir, "app", "users", "user", "sessions", "session")
+	appendToLog := func(data []byte) {
+		t.Helper()
+		f, err := os.OpenFile(filepath.Join(sessionDir, "events.jsonl"), os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A crash after appending an event to the log, before the snapshot is
+	// replaced.
+	line, err := json.Marshal(record{Event: &session.Event{ID: "e2", Timestamp: time.Now().Add(time.Hour), Actions: session.EventActions{StateDelta: map[string]any{"k": "v2"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendToLog(append(line, '\n'))
+	// A crash in the middle of appending an event.
+	appendToLog([]byte(`{"event":{"id":"e3"`))
+
+	s = newService(t, dir, 0)
+	got := getSession(t, s, "session")
+	if diff := cmp.Diff([]string{"e1", "e2"}, eventIDs(got)); diff != "" {
+		t.Errorf("recovered events mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]any{"k": "v2"}, maps.Collect(got.State().All())); diff != "" {
+		t.Errorf("recovered state mismatch (-want +got):\n%s", diff)
+	}
+	listed, err := s.List(ctx, &session.ListRequest{AppName: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Sessions) != 1 || listed.Sessions[0].State() == nil {
+		t.Fatalf("List() = %v, want the session", listed.Sessions)
+	}
+	if v, err := listed.Sessions[0].State().Get("k"); err != nil || v != "v2" {
+		t.Errorf("listed state k = %v, %v, want the recovered v2", v, err)
+	}
+
+	// The torn write is overwritten by the next event.
+	appendEvents(t, s, got, &session.Event{ID: "e3"})
+	if diff := cmp.Diff([]string{"e1", "e2", "e3"}, eventIDs(getSession(t, newService(t, dir, 0), "session"))); diff != "" {
+		t.Errorf("events after recovering mismatch (-want +got):\n%s", diff)
+	}
+
+	// A session directory without a snapshot is not a session.
+	if err := os.MkdirAll(filepath.Join(dir, "app", "users", "user", "sessions", "leftover"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "leftover"}); err == nil {
+		t.Error("Get() of a leftover directory succeeded, want error")
+	}
+	if _, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "leftover"}); err != nil {
+		t.Errorf("Create() over a leftover directory failed: %v", err)
+	}
+}
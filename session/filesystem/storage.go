@@ -0,0 +1,360 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filesystem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// The layout of the directory of the service is:
+//
+//	<app>/.lock                                     lock of the app
+//	<app>/app_state.json                            app state
+//	<app>/users/<user>/user_state.json              user state
+//	<app>/users/<user>/sessions/<session>/snapshot.json
+//	<app>/users/<user>/sessions/<session>/events.jsonl
+//
+// The path elements are escaped as URL path segments.
+const (
+	lockFileName      = ".lock"
+	appStateFileName  = "app_state.json"
+	userStateFileName = "user_state.json"
+	usersDirName      = "users"
+	sessionsDirName   = "sessions"
+	snapshotFileName  = "snapshot.json"
+	logFileName       = "events.jsonl"
+)
+
+// snapshot is the stored metadata and state of a session, as of the first
+// Records records of its log. It is replaced atomically after the log is
+// appended to, so a crash in between leaves records after the snapshot,
+// which are replayed when the session is loaded.
+type snapshot struct {
+	ID         string    `json:"id"`
+	AppName    string    `json:"appName"`
+	UserID     string    `json:"userId"`
+	CreateTime time.Time `json:"createTime"`
+	UpdateTime time.Time `json:"updateTime"`
+	// State is the session-scoped state.
+	State map[string]any `json:"state"`
+	// InitialState is the state the events apply to, see session.Service.Rewind.
+	InitialState map[string]any `json:"initialState"`
+	// InitialArtifacts maps the artifacts saved by the trimmed events to
+	// their last versions, see session.TrimEventsRequest.
+	InitialArtifacts map[string]int64    `json:"initialArtifacts"`
+	ForkOrigin       *session.ForkOrigin `json:"forkOrigin,omitempty"`
+	// Version is the number of events and rewinds applied to the session.
+	// Trimming and compacting do not change the events and the state seen
+	// by the readers of the session, so they do not change its version.
+	Version int64 `json:"version"`
+	// Records is the number of records of the log, and LogSize their size
+	// in bytes.
+	Records int   `json:"records"`
+	LogSize int64 `json:"logSize"`
+}
+
+// record is a line of the event log of a session. Exactly one field is set.
+type record struct {
+	// Event is an appended event.
+	Event *session.Event `json:"event,omitempty"`
+	// RewindInvocation removes the events from the first event of the
+	// invocation, see session.Service.Rewind.
+	RewindInvocation string `json:"rewindInvocation,omitempty"`
+	// Trim removes the given number of oldest events, see
+	// session.TrimEventsRequest.
+	Trim int `json:"trim,omitempty"`
+}
+
+// storedSession is a session loaded from its files.
+type storedSession struct {
+	dir      string
+	snapshot snapshot
+	// records is the number of valid records of the log, and logSize their
+	// size in bytes. A torn last line, left by a crash, is not counted.
+	records int
+	logSize int64
+	// deadRecords is the number of records which no longer contribute an
+	// event, reclaimed by compaction.
+	deadRecords int
+	events      []*session.Event
+}
+
+func (s *fileService) appDir(appName string) (string, error) {
+	app, err := sessionutils.PathSegment(appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, app), nil
+}
+
+func (s *fileService) userDir(appName, userID string) (string, error) {
+	appDir, err := s.appDir(appName)
+	if err != nil {
+		return "", err
+	}
+	user, err := sessionutils.PathSegment(userID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(appDir, usersDirName, user), nil
+}
+
+func (s *fileService) sessionDir(appName, userID, sessionID string) (string, error) {
+	userDir, err := s.userDir(appName, userID)
+	if err != nil {
+		return "", err
+	}
+	sess, err := sessionutils.PathSegment(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userDir, sessionsDirName, sess), nil
+}
+
+// loadSession loads the session stored in dir, replaying the log records
+// which are not reflected by the snapshot. It returns an error wrapping
+// fs.ErrNotExist if the session does not exist.
+func loadSession(dir string) (*storedSession, error) {
+	stored := &storedSession{dir: dir}
+	if err := readJSON(filepath.Join(dir, snapshotFileName), &stored.snapshot); err != nil {
+		return nil, err
+	}
+	records, logSize, err := readLog(filepath.Join(dir, logFileName))
+	if err != nil {
+		return nil, err
+	}
+	stored.records, stored.logSize = len(records), logSize
+
+	snap := &stored.snapshot
+	initialState := maps.Clone(snap.InitialState)
+	initialArtifacts := maps.Clone(snap.InitialArtifacts)
+	for i, rec := range records {
+		if i >= snap.Records && rec.Trim == 0 {
+			snap.Version++
+		}
+		switch {
+		case rec.Event != nil:
+			stored.events = append(stored.events, rec.Event)
+		case rec.RewindInvocation != "":
+			j := slices.IndexFunc(stored.events, func(ev *session.Event) bool { return ev.InvocationID == rec.RewindInvocation })
+			if j >= 0 {
+				stored.events = stored.events[:j]
+			}
+		case rec.Trim > 0:
+			n := min(rec.Trim, len(stored.events))
+			// The snapshot initial state and artifacts already reflect its
+			// records.
+			if i >= snap.Records {
+				initialState = sessionutils.ReplayStateDeltas(initialState, stateDeltas(stored.events[:n]))
+				initialArtifacts = sessionutils.LatestArtifactVersions(initialArtifacts, artifactDeltas(stored.events[:n]))
+			}
+			stored.events = stored.events[n:]
+		}
+	}
+	stored.deadRecords = stored.records - len(stored.events)
+
+	if stored.records > snap.Records {
+		// Recover the records appended after the last snapshot.
+		snap.InitialState = initialState
+		snap.InitialArtifacts = initialArtifacts
+		snap.State = sessionutils.ReplayStateDeltas(initialState, stateDeltas(stored.events))
+		if n := len(stored.events); n > 0 && stored.events[n-1].Timestamp.After(snap.UpdateTime) {
+			snap.UpdateTime = stored.events[n-1].Timestamp
+		}
+	}
+	snap.Records, snap.LogSize = stored.records, stored.logSize
+	return stored, nil
+}
+
+// readLog reads the records of a log file. A missing file has no records.
+// A last line which is not terminated, or which is not valid JSON, is the
+// torn write of a crash and is ignored.
+func readLog(path string) ([]record, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer f.Close()
+
+	var records []record
+	var size int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// An unterminated last line is a torn write.
+			return records, size, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read event log: %w", err)
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			if _, peekErr := r.Peek(1); errors.Is(peekErr, io.EOF) {
+				return records, size, nil
+			}
+			return nil, 0, fmt.Errorf("corrupted event log %s at offset %d: %w", path, size, err)
+		}
+		records = append(records, rec)
+		size += int64(len(line))
+	}
+}
+
+// appendLog appends records to the log of the session, after dropping a
+// torn last line if there is one, and syncs the log.
+func appendLog(stored *storedSession, records ...record) error {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("failed to marshal log record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(filepath.Join(stored.dir, logFileName), os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer f.Close()
+	if err := f.Truncate(stored.logSize); err != nil {
+		return fmt.Errorf("failed to truncate event log: %w", err)
+	}
+	if _, err := f.WriteAt(buf.Bytes(), stored.logSize); err != nil {
+		return fmt.Errorf("failed to append to event log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync event log: %w", err)
+	}
+	stored.records += len(records)
+	stored.logSize += int64(buf.Len())
+	stored.snapshot.Records, stored.snapshot.LogSize = stored.records, stored.logSize
+	return nil
+}
+
+// compactLog rewrites the log of the session with only its events. The
+// snapshot must reflect the whole log before: if the snapshot is not
+// replaced after the log, it reflects more records than the log has, and
+// the log is not replayed.
+func compactLog(stored *storedSession) error {
+	var buf bytes.Buffer
+	for _, ev := range stored.events {
+		line, err := json.Marshal(record{Event: ev})
+		if err != nil {
+			return fmt.Errorf("failed to marshal log record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := writeFileAtomic(filepath.Join(stored.dir, logFileName), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write compacted event log: %w", err)
+	}
+	stored.records, stored.logSize, stored.deadRecords = len(stored.events), int64(buf.Len()), 0
+	stored.snapshot.Records, stored.snapshot.LogSize = stored.records, stored.logSize
+	return writeJSON(filepath.Join(stored.dir, snapshotFileName), stored.snapshot)
+}
+
+// readJSON reads a JSON file into v. It returns an error wrapping
+// fs.ErrNotExist if the file does not exist.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// readState reads a state file, a missing file is an empty state.
+func readState(path string) (map[string]any, error) {
+	state := make(map[string]any)
+	if err := readJSON(path, &state); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	return state, nil
+}
+
+// writeJSON atomically replaces a file with the JSON encoding of v.
+func writeJSON(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces a file with data, so that a crash leaves either
+// the old or the new content: the data is written and synced to a temporary
+// file which is then renamed over the file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filepath.Base(path), err)
+	}
+	return syncDir(dir)
+}
+
+func stateDeltas(events []*session.Event) []map[string]any {
+	deltas := make([]map[string]any, 0, len(events))
+	for _, ev := range events {
+		deltas = append(deltas, ev.Actions.StateDelta)
+	}
+	return deltas
+}
+
+// artifactDeltas returns the artifact deltas of the events.
+func artifactDeltas(events []*session.Event) []map[string]int64 {
+	deltas := make([]map[string]int64, 0, len(events))
+	for _, ev := range events {
+		deltas = append(deltas, ev.Actions.ArtifactDelta)
+	}
+	return deltas
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+)
+
+// ErrEventNotFound is returned by StateAt when the session has no event
+// with the given ID.
+var ErrEventNotFound = errors.New("event not found")
+
+// StateChange is a change of the value of a state key by an event.
+type StateChange struct {
+	EventID      string    `json:"eventId"`
+	InvocationID string    `json:"invocationId,omitempty"`
+	Author       string    `json:"author,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+	// OldValue is the value of the key before the event, nil if the key was
+	// not set or if its value is unknown, see StateHistory.
+	OldValue any `json:"oldValue"`
+	NewValue any `json:"newValue"`
+}
+
+// InitialStateOf returns the session-scoped state the events of the session
+// apply to: the state the session was created with, updated by the events
+// deleted since, see TrimEventsRequest. It returns nil if it is unknown: if
+// the session service does not record it, or if the session was got with
+// only some of its events, see GetRequest.
+func InitialStateOf(s Session) map[string]any {
+	if initial, ok := s.(interface{ InitialState() map[string]any }); ok {
+		return initial.InitialState()
+	}
+	return nil
+}
+
+// StateHistory returns the changes of a state key by the events of a
+// session, in the order of the events.
+//
+// The old value of the first change is the value in the initial state of
+// the session, see InitialStateOf. It is nil if the initial state is unknown,
+// and for the app and user keys, which are shared with other sessions.
+func StateHistory(s Session, key string) []StateChange {
+	var changes []StateChange
+	value := InitialStateOf(s)[key]
+	for ev := range s.Events().All() {
+		newValue, ok := ev.Actions.StateDelta[key]
+		if !ok {
+			continue
+		}
+		changes = append(changes, StateChange{
+			EventID:      ev.ID,
+			InvocationID: ev.InvocationID,
+			Author:       ev.Author,
+			Timestamp:    ev.Timestamp,
+			OldValue:     value,
+			NewValue:     newValue,
+		})
+		value = newValue
+	}
+	return changes
+}
+
+// StateAt reconstructs the state of a session right after one of its
+// events, from the initial state of the session and the state deltas of the
+// events. The app and user keys, which are shared with other sessions, only
+// reflect the changes made by the events of the session.
+//
+// It fails with an error wrapping errors.ErrUnsupported if the initial state
+// of the session is unknown, see InitialStateOf, and with an error wrapping
+// ErrEventNotFound if the session has no such event.
+func StateAt(s Session, eventID string) (map[string]any, error) {
+	initial := InitialStateOf(s)
+	if initial == nil {
+		return nil, fmt.Errorf("%w: the initial state of session %q is unknown", errors.ErrUnsupported, s.ID())
+	}
+	state := maps.Clone(initial)
+	for ev := range s.Events().All() {
+		maps.Copy(state, ev.Actions.StateDelta)
+		if ev.ID == eventID {
+			return state, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q in session %q", ErrEventNotFound, eventID, s.ID())
+}
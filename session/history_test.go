@@ -0,0 +1,122 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func createHistorySession(t *testing.T, service Service) Session {
+	t.Helper()
+	resp, err := service.Create(t.Context(), &CreateRequest{
+		AppName:   "app",
+		UserID:    "user",
+		SessionID: "session",
+		State:     map[string]any{"color": "red", "size": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, ev := range []*Event{
+		{ID: "e1", InvocationID: "inv1", Author: "planner", Actions: EventActions{StateDelta: map[string]any{"color": "blue"}}},
+		{ID: "e2", InvocationID: "inv1", Author: "writer", Actions: EventActions{StateDelta: map[string]any{"size": 2, "user:lang": "fr"}}},
+		{ID: "e3", InvocationID: "inv2", Author: "reviewer", Actions: EventActions{StateDelta: map[string]any{"color": "green"}}},
+	} {
+		ev.Timestamp = start.Add(time.Duration(i) * time.Second)
+		if err := service.AppendEvent(t.Context(), resp.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.Session
+}
+
+func TestStateHistory(t *testing.T) {
+	sess := createHistorySession(t, InMemoryService())
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		key  string
+		want []StateChange
+	}{
+		{
+			key: "color",
+			want: []StateChange{
+				{EventID: "e1", InvocationID: "inv1", Author: "planner", Timestamp: start, OldValue: "red", NewValue: "blue"},
+				{EventID: "e3", InvocationID: "inv2", Author: "reviewer", Timestamp: start.Add(2 * time.Second), OldValue: "blue", NewValue: "green"},
+			},
+		},
+		{
+			key: "user:lang",
+			want: []StateChange{
+				{EventID: "e2", InvocationID: "inv1", Author: "writer", Timestamp: start.Add(time.Second), NewValue: "fr"},
+			},
+		},
+		{
+			key: "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, StateHistory(sess, tt.key)); diff != "" {
+				t.Errorf("StateHistory() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStateAt(t *testing.T) {
+	service := InMemoryService()
+	sess := createHistorySession(t, service)
+
+	tests := []struct {
+		eventID string
+		want    map[string]any
+		wantErr error
+	}{
+		{eventID: "e1", want: map[string]any{"color": "blue", "size": 1}},
+		{eventID: "e2", want: map[string]any{"color": "blue", "size": 2, "user:lang": "fr"}},
+		{eventID: "e3", want: map[string]any{"color": "green", "size": 2, "user:lang": "fr"}},
+		{eventID: "e4", wantErr: ErrEventNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.eventID, func(t *testing.T) {
+			got, err := StateAt(sess, tt.eventID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StateAt() error = %v, want %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("StateAt() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// The initial state of a session got with only its recent events is
+	// unknown.
+	resp, err := service.Get(t.Context(), &GetRequest{AppName: "app", UserID: "user", SessionID: "session", NumRecentEvents: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := InitialStateOf(resp.Session); got != nil {
+		t.Errorf("InitialStateOf() of a partial session = %v, want nil", got)
+	}
+	if _, err := StateAt(resp.Session, "e3"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("StateAt() of a partial session error = %v, want errors.ErrUnsupported", err)
+	}
+}
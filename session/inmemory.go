@@ -71,14 +71,17 @@ func (s *inMemoryService) Create(ctx context.Context, req *CreateRequest) (*Crea
 	if state == nil {
 		state = make(stateMap)
 	}
+	appDelta, userDelta, sessionDelta := sessionutils.ExtractStateDeltas(req.State)
 	val := &session{
-		id:        key,
-		state:     state,
-		updatedAt: time.Now(),
+		id:               key,
+		state:            state,
+		initialState:     sessionDelta,
+		initialArtifacts: make(map[string]int64),
+		createdAt:        time.Now(),
+		updatedAt:        time.Now(),
 	}
 
 	s.sessions.Set(encodedKey, val)
-	appDelta, userDelta, _ := sessionutils.ExtractStateDeltas(req.State)
 	appState := s.updateAppState(appDelta, req.AppName)
 	userState := s.updateUserState(userDelta, req.AppName, req.UserID)
 	val.state = sessionutils.MergeStates(appState, userState, state)
@@ -86,6 +89,7 @@ func (s *inMemoryService) Create(ctx context.Context, req *CreateRequest) (*Crea
 	copiedSession := copySessionWithoutStateAndEvents(val)
 	copiedSession.state = maps.Clone(val.state)
 	copiedSession.events = slices.Clone(val.events)
+	copiedSession.initialState = maps.Clone(val.initialState)
 
 	return &CreateResponse{
 		Session: copiedSession,
@@ -132,6 +136,9 @@ func (s *inMemoryService) Get(ctx context.Context, req *GetRequest) (*GetRespons
 
 	copiedSession.events = make([]*Event, 0, len(filteredEvents))
 	copiedSession.events = append(copiedSession.events, filteredEvents...)
+	if len(filteredEvents) == len(res.events) {
+		copiedSession.initialState = maps.Clone(res.initialState)
+	}
 
 	return &GetResponse{
 		Session: copiedSession,
@@ -156,7 +163,7 @@ func (s *inMemoryService) List(ctx context.Context, req *ListRequest) (*ListResp
 		hi = id{appName: appName, userID: userID + "\x00"}.Encode()
 	}
 
-	sessions := make([]Session, 0)
+	sessions := make([]*session, 0)
 	for k, storedSession := range s.sessions.Scan(lo, hi) {
 		var key id
 		if err := key.Decode(k); err != nil {
@@ -166,13 +173,43 @@ func (s *inMemoryService) List(ctx context.Context, req *ListRequest) (*ListResp
 		if key.appName != appName && key.userID != userID {
 			break
 		}
+		if !sessionutils.InTimeRange(storedSession.createdAt, req.CreatedAfter, req.CreatedBefore) {
+			continue
+		}
 		copiedSession := copySessionWithoutStateAndEvents(storedSession)
 		copiedSession.state = s.mergeStates(storedSession.state, appName, storedSession.UserID())
+		if !sessionutils.StateMatches(copiedSession.state, req.StateFilter) {
+			continue
+		}
+		if req.MetadataOnly {
+			copiedSession.state = make(stateMap)
+		}
 		sessions = append(sessions, copiedSession)
 	}
-	return &ListResponse{
-		Sessions: sessions,
-	}, nil
+
+	switch req.OrderBy {
+	case ListOrderID:
+		// The scan is ordered by user ID and session ID.
+	case ListOrderUpdateTimeDesc:
+		slices.SortStableFunc(sessions, func(a, b *session) int { return b.updatedAt.Compare(a.updatedAt) })
+	case ListOrderUpdateTimeAsc:
+		slices.SortStableFunc(sessions, func(a, b *session) int { return a.updatedAt.Compare(b.updatedAt) })
+	default:
+		return nil, fmt.Errorf("unknown list order %d", req.OrderBy)
+	}
+
+	page, nextPageToken, err := sessionutils.Paginate(sessions, req.PageToken, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	resp := &ListResponse{
+		Sessions:      make([]Session, 0, len(page)),
+		NextPageToken: nextPageToken,
+	}
+	for _, sess := range page {
+		resp.Sessions = append(resp.Sessions, sess)
+	}
+	return resp, nil
 }
 
 func (s *inMemoryService) Delete(ctx context.Context, req *DeleteRequest) error {
@@ -235,6 +272,212 @@ func (s *inMemoryService) AppendEvent(ctx context.Context, curSession Session, e
 	return nil
 }
 
+func (s *inMemoryService) Rewind(ctx context.Context, req *RewindRequest) (*RewindResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" || req.InvocationID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id, invocation_id are required, got app_name: %q, user_id: %q, session_id: %q, invocation_id: %q", appName, userID, sessionID, req.InvocationID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := id{
+		appName:   appName,
+		userID:    userID,
+		sessionID: sessionID,
+	}
+	storedSession, ok := s.sessions.Get(id.Encode())
+	if !ok {
+		return nil, fmt.Errorf("session %+v not found", sessionID)
+	}
+	i := slices.IndexFunc(storedSession.events, func(ev *Event) bool {
+		return ev.InvocationID == req.InvocationID
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("invocation %q not found in session %q", req.InvocationID, sessionID)
+	}
+
+	kept, removed := storedSession.events[:i], storedSession.events[i:]
+	var stateDeltas []map[string]any
+	var keptArtifacts, removedArtifacts []map[string]int64
+	for _, ev := range kept {
+		stateDeltas = append(stateDeltas, ev.Actions.StateDelta)
+		keptArtifacts = append(keptArtifacts, ev.Actions.ArtifactDelta)
+	}
+	for _, ev := range removed {
+		removedArtifacts = append(removedArtifacts, ev.Actions.ArtifactDelta)
+	}
+
+	storedSession.events = slices.Clone(kept)
+	storedSession.state = sessionutils.ReplayStateDeltas(storedSession.initialState, stateDeltas)
+	storedSession.updatedAt = time.Now()
+
+	copiedSession := copySessionWithoutStateAndEvents(storedSession)
+	copiedSession.state = s.mergeStates(storedSession.state, appName, userID)
+	copiedSession.events = slices.Clone(storedSession.events)
+	copiedSession.initialState = maps.Clone(storedSession.initialState)
+
+	return &RewindResponse{
+		Session:          copiedSession,
+		ArtifactVersions: sessionutils.RevertedArtifactVersions(storedSession.initialArtifacts, keptArtifacts, removedArtifacts),
+	}, nil
+}
+
+func (s *inMemoryService) Fork(ctx context.Context, req *ForkRequest) (*ForkResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+	newSessionID := req.NewSessionID
+	if newSessionID == "" {
+		newSessionID = uuid.NewString()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parent, ok := s.sessions.Get(id{appName: appName, userID: userID, sessionID: sessionID}.Encode())
+	if !ok {
+		return nil, fmt.Errorf("session %+v not found", sessionID)
+	}
+	key := id{appName: appName, userID: userID, sessionID: newSessionID}
+	if _, ok := s.sessions.Get(key.Encode()); ok {
+		return nil, fmt.Errorf("session %s already exists", newSessionID)
+	}
+
+	carried := parent.events
+	if req.EventID != "" {
+		i := slices.IndexFunc(parent.events, func(ev *Event) bool { return ev.ID == req.EventID })
+		if i < 0 {
+			return nil, fmt.Errorf("event %q not found in session %q", req.EventID, sessionID)
+		}
+		carried = parent.events[:i+1]
+	}
+	origin := &ForkOrigin{SessionID: sessionID}
+	events := make([]*Event, 0, len(carried))
+	var stateDeltas []map[string]any
+	var artifactDeltas []map[string]int64
+	for _, ev := range carried {
+		copied := *ev
+		events = append(events, &copied)
+		stateDeltas = append(stateDeltas, ev.Actions.StateDelta)
+		artifactDeltas = append(artifactDeltas, ev.Actions.ArtifactDelta)
+		origin.EventID = ev.ID
+	}
+	origin.Artifacts = sessionutils.LatestArtifactVersions(parent.initialArtifacts, artifactDeltas)
+
+	val := &session{
+		id:               key,
+		events:           events,
+		state:            sessionutils.ReplayStateDeltas(parent.initialState, stateDeltas),
+		initialState:     maps.Clone(parent.initialState),
+		initialArtifacts: make(map[string]int64),
+		createdAt:        time.Now(),
+		updatedAt:        time.Now(),
+		forkOrigin:       origin,
+	}
+	s.sessions.Set(key.Encode(), val)
+
+	copiedSession := copySessionWithoutStateAndEvents(val)
+	copiedSession.state = s.mergeStates(val.state, appName, userID)
+	copiedSession.events = slices.Clone(val.events)
+	copiedSession.initialState = maps.Clone(val.initialState)
+	return &ForkResponse{Session: copiedSession}, nil
+}
+
+// TrimEvents deletes the oldest events of a session, keeping at most
+// req.MaxEvents events. See TrimEventsRequest.
+func (s *inMemoryService) TrimEvents(ctx context.Context, req *TrimEventsRequest) (*TrimEventsResponse, error) {
+	appName, userID, sessionID := req.AppName, req.UserID, req.SessionID
+	if appName == "" || userID == "" || sessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", appName, userID, sessionID)
+	}
+	if req.MaxEvents < 0 {
+		return nil, fmt.Errorf("max_events must not be negative, got %d", req.MaxEvents)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := id{
+		appName:   appName,
+		userID:    userID,
+		sessionID: sessionID,
+	}
+	storedSession, ok := s.sessions.Get(id.Encode())
+	if !ok {
+		return nil, fmt.Errorf("session %+v not found", sessionID)
+	}
+	n := len(storedSession.events) - req.MaxEvents
+	if n <= 0 {
+		return &TrimEventsResponse{}, nil
+	}
+
+	var stateDeltas []map[string]any
+	var artifactDeltas []map[string]int64
+	for _, ev := range storedSession.events[:n] {
+		stateDeltas = append(stateDeltas, ev.Actions.StateDelta)
+		artifactDeltas = append(artifactDeltas, ev.Actions.ArtifactDelta)
+	}
+	storedSession.initialState = sessionutils.ReplayStateDeltas(storedSession.initialState, stateDeltas)
+	storedSession.initialArtifacts = sessionutils.LatestArtifactVersions(storedSession.initialArtifacts, artifactDeltas)
+	storedSession.events = slices.Clone(storedSession.events[n:])
+
+	return &TrimEventsResponse{Deleted: n}, nil
+}
+
+// SearchEvents scans the events of the sessions for the words of
+// req.Query. See SearchEventsRequest.
+func (s *inMemoryService) SearchEvents(ctx context.Context, req *SearchEventsRequest) (*SearchEventsResponse, error) {
+	appName, userID := req.AppName, req.UserID
+	if appName == "" {
+		return nil, fmt.Errorf("app_name is required, got app_name: %q", appName)
+	}
+	terms := sessionutils.SearchTerms(req.Query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("query has no words to search for, got query: %q", req.Query)
+	}
+	if req.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative, got %d", req.Limit)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lo := id{appName: appName, userID: userID}.Encode()
+	var hi string
+	if userID == "" {
+		hi = id{appName: appName + "\x00"}.Encode()
+	} else {
+		hi = id{appName: appName, userID: userID + "\x00"}.Encode()
+	}
+
+	var results []SearchResult
+	for _, storedSession := range s.sessions.Scan(lo, hi) {
+		for _, ev := range storedSession.events {
+			if req.Author != "" && ev.Author != req.Author {
+				continue
+			}
+			if !sessionutils.InTimeRange(ev.Timestamp, req.After, req.Before) {
+				continue
+			}
+			if !sessionutils.MatchesTerms(sessionutils.SearchText(ev.Content), terms) {
+				continue
+			}
+			results = append(results, SearchResult{
+				UserID:    storedSession.UserID(),
+				SessionID: storedSession.ID(),
+				Event:     ev,
+			})
+		}
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int { return b.Event.Timestamp.Compare(a.Event.Timestamp) })
+	if req.Limit > 0 && len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+	return &SearchEventsResponse{Results: results}, nil
+}
+
 func (s *inMemoryService) updateAppState(appDelta stateMap, appName string) stateMap {
 	innerMap, ok := s.appState[appName]
 	if !ok {
@@ -292,6 +535,17 @@ type session struct {
 	events    []*Event
 	state     map[string]any
 	updatedAt time.Time
+	createdAt time.Time
+	// initialState is the session state the session was created with, from
+	// which Rewind replays the state deltas. It is nil in the sessions got
+	// with only some of their events.
+	initialState map[string]any
+	// initialArtifacts maps the artifacts saved by the events deleted by
+	// TrimEvents to their last versions, from which Rewind reverts the
+	// artifacts. It is only set in the stored sessions.
+	initialArtifacts map[string]int64
+	// forkOrigin is where the session was forked from, nil if it was not.
+	forkOrigin *ForkOrigin
 }
 
 func (s *session) ID() string {
@@ -317,6 +571,17 @@ func (s *session) Events() Events {
 	return events(s.events)
 }
 
+// ForkOrigin returns where the session was forked from, see ForkOriginOf.
+func (s *session) ForkOrigin() *ForkOrigin {
+	return s.forkOrigin
+}
+
+// InitialState returns the state the events of the session apply to, see
+// InitialStateOf.
+func (s *session) InitialState() map[string]any {
+	return s.initialState
+}
+
 func (s *session) LastUpdateTime() time.Time {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -451,8 +716,14 @@ func copySessionWithoutStateAndEvents(sess *session) *session {
 			userID:    sess.id.userID,
 			sessionID: sess.id.sessionID,
 		},
-		updatedAt: sess.updatedAt,
+		createdAt:  sess.createdAt,
+		updatedAt:  sess.updatedAt,
+		forkOrigin: sess.forkOrigin,
 	}
 }
 
-var _ Service = (*inMemoryService)(nil)
+var (
+	_ Service       = (*inMemoryService)(nil)
+	_ EventTrimmer  = (*inMemoryService)(nil)
+	_ EventSearcher = (*inMemoryService)(nil)
+)
@@ -355,7 +355,7 @@ func Test_databaseService_Get(t *testing.T) {
 				if diff := cmp.Diff(tt.wantResponse, got,
 					cmp.AllowUnexported(session{}),
 					cmp.AllowUnexported(id{}),
-					cmpopts.IgnoreFields(session{}, "mu", "updatedAt")); diff != "" {
+					cmpopts.IgnoreFields(session{}, "mu", "createdAt", "updatedAt")); diff != "" {
 					t.Errorf("Get session mismatch: (-want +got):\n%s", diff)
 				}
 			}
@@ -493,7 +493,7 @@ func Test_databaseService_List(t *testing.T) {
 				opts := []cmp.Option{
 					cmp.AllowUnexported(session{}),
 					cmp.AllowUnexported(id{}),
-					cmpopts.IgnoreFields(session{}, "mu", "updatedAt"),
+					cmpopts.IgnoreFields(session{}, "mu", "createdAt", "updatedAt"),
 					cmpopts.SortSlices(func(a, b Session) bool {
 						return a.ID() < b.ID()
 					}),
@@ -816,7 +816,7 @@ func Test_databaseService_AppendEvent(t *testing.T) {
 			opts := []cmp.Option{
 				cmp.AllowUnexported(session{}),
 				cmp.AllowUnexported(id{}),
-				cmpopts.IgnoreFields(session{}, "mu", "updatedAt"),
+				cmpopts.IgnoreFields(session{}, "mu", "createdAt", "updatedAt"),
 				cmpopts.IgnoreFields(Event{}, "Timestamp"),
 				// Add sorters if event order is not guaranteed
 				cmpopts.SortSlices(func(a, b *Event) bool {
@@ -1058,3 +1058,378 @@ func Test_inMemoryService_CreateConcurrentAccess(t *testing.T) {
 		t.Errorf("expected %d 'already exists' errors, but got %d", expectedErrors, errorCount.Load())
 	}
 }
+
+func Test_inMemoryService_Rewind(t *testing.T) {
+	ctx := t.Context()
+	s := InMemoryService()
+	created, err := s.Create(ctx, &CreateRequest{
+		AppName:   "app",
+		UserID:    "user",
+		SessionID: "session",
+		State:     map[string]any{"k": "initial", "app:shared": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []*Event{
+		{ID: "e1", InvocationID: "inv1", Actions: EventActions{
+			StateDelta:    map[string]any{"k": "v1"},
+			ArtifactDelta: map[string]int64{"a.txt": 1},
+		}},
+		{ID: "e2", InvocationID: "inv2", Actions: EventActions{
+			StateDelta:    map[string]any{"k": "v2", "added": true, "app:shared": 2},
+			ArtifactDelta: map[string]int64{"a.txt": 2, "b.txt": 1},
+		}},
+		{ID: "e3", InvocationID: "inv3", Actions: EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	} {
+		ev.Timestamp = time.Now()
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.Rewind(ctx, &RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "unknown"}); err == nil {
+		t.Error("Rewind() to an unknown invocation succeeded, want error")
+	}
+
+	resp, err := s.Rewind(ctx, &RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "inv2"})
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if diff := cmp.Diff(map[string]int64{"a.txt": 1, "b.txt": 0}, resp.ArtifactVersions); diff != "" {
+		t.Errorf("Rewind() artifact versions mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err := s.Get(ctx, &GetRequest{AppName: "app", UserID: "user", SessionID: "session"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for ev := range got.Session.Events().All() {
+		ids = append(ids, ev.ID)
+	}
+	if diff := cmp.Diff([]string{"e1"}, ids); diff != "" {
+		t.Errorf("events after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+	// The app state is shared with other sessions, so it is not rewound.
+	wantState := map[string]any{"k": "v1", "app:shared": 2}
+	if diff := cmp.Diff(wantState, maps.Collect(got.Session.State().All())); diff != "" {
+		t.Errorf("state after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_inMemoryService_Fork(t *testing.T) {
+	ctx := t.Context()
+	s := InMemoryService()
+	created, err := s.Create(ctx, &CreateRequest{AppName: "app", UserID: "user", SessionID: "parent", State: map[string]any{"k": "initial"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []*Event{
+		{ID: "e1", Actions: EventActions{StateDelta: map[string]any{"k": "v1"}, ArtifactDelta: map[string]int64{"a.txt": 1}}},
+		{ID: "e2", Actions: EventActions{StateDelta: map[string]any{"k": "v2"}, ArtifactDelta: map[string]int64{"a.txt": 2, "b.txt": 1}}},
+		{ID: "e3", Actions: EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	} {
+		ev.Timestamp = time.Now()
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, req := range []*ForkRequest{
+		{AppName: "app", UserID: "user", SessionID: "parent", EventID: "unknown"},
+		{AppName: "app", UserID: "user", SessionID: "parent", NewSessionID: "parent"},
+		{AppName: "app", UserID: "user", SessionID: "unknown"},
+	} {
+		if _, err := s.Fork(ctx, req); err == nil {
+			t.Errorf("Fork(%+v) succeeded, want error", req)
+		}
+	}
+
+	resp, err := s.Fork(ctx, &ForkRequest{AppName: "app", UserID: "user", SessionID: "parent", EventID: "e2", NewSessionID: "child"})
+	if err != nil {
+		t.Fatalf("Fork() failed: %v", err)
+	}
+	forked := resp.Session
+	var ids []string
+	for ev := range forked.Events().All() {
+		ids = append(ids, ev.ID)
+	}
+	if diff := cmp.Diff([]string{"e1", "e2"}, ids); diff != "" {
+		t.Errorf("forked events mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]any{"k": "v2"}, maps.Collect(forked.State().All())); diff != "" {
+		t.Errorf("forked state mismatch (-want +got):\n%s", diff)
+	}
+	wantOrigin := &ForkOrigin{SessionID: "parent", EventID: "e2", Artifacts: map[string]int64{"a.txt": 2, "b.txt": 1}}
+	if diff := cmp.Diff(wantOrigin, ForkOriginOf(forked)); diff != "" {
+		t.Errorf("ForkOriginOf() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The sessions diverge.
+	if err := s.AppendEvent(ctx, forked, &Event{ID: "e4", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	parent, err := s.Get(ctx, &GetRequest{AppName: "app", UserID: "user", SessionID: "parent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parent.Session.Events().Len(); got != 3 {
+		t.Errorf("parent session has %d events after appending to the fork, want 3", got)
+	}
+	if ForkOriginOf(parent.Session) != nil {
+		t.Errorf("ForkOriginOf() of the parent session = %+v, want nil", ForkOriginOf(parent.Session))
+	}
+}
+
+func Test_inMemoryService_TrimEvents(t *testing.T) {
+	ctx := t.Context()
+	s := InMemoryService()
+	created, err := s.Create(ctx, &CreateRequest{AppName: "app", UserID: "user", SessionID: "session", State: map[string]any{"k": "initial"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range []*Event{
+		{ID: "e1", InvocationID: "inv1", Actions: EventActions{StateDelta: map[string]any{"k": "v1", "old": true}, ArtifactDelta: map[string]int64{"a.txt": 1, "b.txt": 1}}},
+		{ID: "e2", InvocationID: "inv2", Actions: EventActions{StateDelta: map[string]any{"k": "v2"}, ArtifactDelta: map[string]int64{"a.txt": 2, "c.txt": 1}}},
+		{ID: "e3", InvocationID: "inv3", Actions: EventActions{StateDelta: map[string]any{"k": "v3"}}},
+	} {
+		ev.Timestamp = time.Now()
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trimmer := s.(EventTrimmer)
+
+	resp, err := trimmer.TrimEvents(ctx, &TrimEventsRequest{AppName: "app", UserID: "user", SessionID: "session", MaxEvents: 2})
+	if err != nil {
+		t.Fatalf("TrimEvents() failed: %v", err)
+	}
+	if resp.Deleted != 1 {
+		t.Errorf("TrimEvents() deleted %d events, want 1", resp.Deleted)
+	}
+	resp, err = trimmer.TrimEvents(ctx, &TrimEventsRequest{AppName: "app", UserID: "user", SessionID: "session", MaxEvents: 2})
+	if err != nil {
+		t.Fatalf("TrimEvents() failed: %v", err)
+	}
+	if resp.Deleted != 0 {
+		t.Errorf("second TrimEvents() deleted %d events, want 0", resp.Deleted)
+	}
+
+	// Rewinding to the oldest kept invocation restores the state of the
+	// trimmed events.
+	rewound, err := s.Rewind(ctx, &RewindRequest{AppName: "app", UserID: "user", SessionID: "session", InvocationID: "inv2"})
+	if err != nil {
+		t.Fatalf("Rewind() failed: %v", err)
+	}
+	if got := rewound.Session.Events().Len(); got != 0 {
+		t.Errorf("events after Rewind() = %d, want 0", got)
+	}
+	wantState := map[string]any{"k": "v1", "old": true}
+	if diff := cmp.Diff(wantState, maps.Collect(rewound.Session.State().All())); diff != "" {
+		t.Errorf("state after Rewind() mismatch (-want +got):\n%s", diff)
+	}
+	// The artifacts saved by the trimmed events are reverted to their
+	// versions before the rewound invocation, not deleted.
+	wantVersions := map[string]int64{"a.txt": 1, "c.txt": 0}
+	if diff := cmp.Diff(wantVersions, rewound.ArtifactVersions); diff != "" {
+		t.Errorf("Rewind() artifact versions mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_inMemoryService_List_Options(t *testing.T) {
+	ctx := t.Context()
+	s := InMemoryService()
+	start := time.Now()
+	for i, sessionID := range []string{"s1", "s2", "s3", "s4"} {
+		created, err := s.Create(ctx, &CreateRequest{
+			AppName:   "app",
+			UserID:    "user",
+			SessionID: sessionID,
+			State:     map[string]any{"parity": i % 2, "app:shared": true},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// s4 is the least recently updated, s1 the most.
+		ev := NewEvent("inv")
+		ev.Timestamp = start.Add(time.Duration(-i) * time.Minute)
+		if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	middle := time.Now()
+	time.Sleep(time.Millisecond)
+	if _, err := s.Create(ctx, &CreateRequest{AppName: "app", UserID: "user", SessionID: "s5", State: map[string]any{"parity": 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// listAll lists all the pages of a request.
+	listAll := func(req *ListRequest) ([]string, []int) {
+		t.Helper()
+		var ids []string
+		var pageSizes []int
+		for {
+			resp, err := s.List(ctx, req)
+			if err != nil {
+				t.Fatalf("List() failed: %v", err)
+			}
+			for _, sess := range resp.Sessions {
+				ids = append(ids, sess.ID())
+			}
+			pageSizes = append(pageSizes, len(resp.Sessions))
+			if resp.NextPageToken == "" {
+				return ids, pageSizes
+			}
+			req.PageToken = resp.NextPageToken
+		}
+	}
+
+	tests := []struct {
+		name          string
+		req           *ListRequest
+		wantIDs       []string
+		wantPageSizes []int
+	}{
+		{
+			name:          "pages",
+			req:           &ListRequest{AppName: "app", PageSize: 2},
+			wantIDs:       []string{"s1", "s2", "s3", "s4", "s5"},
+			wantPageSizes: []int{2, 2, 1},
+		},
+		{
+			name:          "update time ascending",
+			req:           &ListRequest{AppName: "app", UserID: "user", OrderBy: ListOrderUpdateTimeAsc, PageSize: 3},
+			wantIDs:       []string{"s4", "s3", "s2", "s1", "s5"},
+			wantPageSizes: []int{3, 2},
+		},
+		{
+			name:          "update time descending",
+			req:           &ListRequest{AppName: "app", OrderBy: ListOrderUpdateTimeDesc},
+			wantIDs:       []string{"s5", "s1", "s2", "s3", "s4"},
+			wantPageSizes: []int{5},
+		},
+		{
+			name:          "state filter",
+			req:           &ListRequest{AppName: "app", StateFilter: map[string]any{"parity": 0.0, "app:shared": true}},
+			wantIDs:       []string{"s1", "s3", "s5"},
+			wantPageSizes: []int{3},
+		},
+		{
+			name:          "created before",
+			req:           &ListRequest{AppName: "app", CreatedBefore: middle},
+			wantIDs:       []string{"s1", "s2", "s3", "s4"},
+			wantPageSizes: []int{4},
+		},
+		{
+			name:          "created after",
+			req:           &ListRequest{AppName: "app", CreatedAfter: middle},
+			wantIDs:       []string{"s5"},
+			wantPageSizes: []int{1},
+		},
+		{
+			name:          "no match",
+			req:           &ListRequest{AppName: "app", StateFilter: map[string]any{"missing": 1}},
+			wantPageSizes: []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, pageSizes := listAll(tt.req)
+			if diff := cmp.Diff(tt.wantIDs, ids); diff != "" {
+				t.Errorf("List() session IDs mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantPageSizes, pageSizes); diff != "" {
+				t.Errorf("List() page sizes mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("metadata only", func(t *testing.T) {
+		resp, err := s.List(ctx, &ListRequest{AppName: "app", MetadataOnly: true, PageSize: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := maps.Collect(resp.Sessions[0].State().All()); len(got) != 0 {
+			t.Errorf("List() state in metadata only mode = %v, want empty", got)
+		}
+		if got := resp.Sessions[0].LastUpdateTime(); !got.Equal(start) {
+			t.Errorf("List() last update time = %v, want %v", got, start)
+		}
+	})
+
+	t.Run("invalid page token", func(t *testing.T) {
+		if _, err := s.List(ctx, &ListRequest{AppName: "app", PageToken: "invalid"}); err == nil {
+			t.Error("List() with an invalid page token succeeded, want error")
+		}
+	})
+}
+
+func Test_inMemoryService_SearchEvents(t *testing.T) {
+	ctx := t.Context()
+	s := InMemoryService()
+	start := time.Now()
+	for _, sess := range []struct {
+		userID, sessionID string
+		events            []*Event
+	}{
+		{userID: "alice", sessionID: "s1", events: []*Event{
+			{ID: "e1", Author: "user", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("What is the weather in Paris?", genai.RoleUser)}},
+			{ID: "e2", Author: "agent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromFunctionCall("get_weather", map[string]any{"city": "Paris"}, genai.RoleModel)}},
+			{ID: "e3", Author: "agent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("It is sunny in PARIS.", genai.RoleModel)}},
+		}},
+		{userID: "bob", sessionID: "s2", events: []*Event{
+			{ID: "e4", Author: "agent", LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("Paris is the capital of France.", genai.RoleModel)}},
+		}},
+	} {
+		created, err := s.Create(ctx, &CreateRequest{AppName: "app", UserID: sess.userID, SessionID: sess.sessionID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ev := range sess.events {
+			ev.Timestamp = start.Add(time.Duration(ev.ID[1]-'0') * time.Second)
+			if err := s.AppendEvent(ctx, created.Session, ev); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	searcher := s.(EventSearcher)
+
+	tests := []struct {
+		name    string
+		req     *SearchEventsRequest
+		want    []string
+		wantErr bool
+	}{
+		{name: "all the words", req: &SearchEventsRequest{AppName: "app", Query: "paris sunny"}, want: []string{"e3"}},
+		{name: "most recent first", req: &SearchEventsRequest{AppName: "app", Query: "Paris"}, want: []string{"e4", "e3", "e2", "e1"}},
+		{name: "function call name", req: &SearchEventsRequest{AppName: "app", Query: "get_weather"}, want: []string{"e2"}},
+		{name: "function call args", req: &SearchEventsRequest{AppName: "app", Query: "city"}, want: []string{"e2"}},
+		{name: "whole words", req: &SearchEventsRequest{AppName: "app", Query: "par"}},
+		{name: "user", req: &SearchEventsRequest{AppName: "app", UserID: "alice", Query: "paris"}, want: []string{"e3", "e2", "e1"}},
+		{name: "author", req: &SearchEventsRequest{AppName: "app", Author: "user", Query: "paris"}, want: []string{"e1"}},
+		{name: "time range", req: &SearchEventsRequest{AppName: "app", Query: "paris", After: start.Add(3 * time.Second), Before: start.Add(4 * time.Second)}, want: []string{"e3"}},
+		{name: "limit", req: &SearchEventsRequest{AppName: "app", Query: "paris", Limit: 2}, want: []string{"e4", "e3"}},
+		{name: "other app", req: &SearchEventsRequest{AppName: "other", Query: "paris"}},
+		{name: "no words", req: &SearchEventsRequest{AppName: "app", Query: " ?! "}, wantErr: true},
+		{name: "no app", req: &SearchEventsRequest{Query: "paris"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := searcher.SearchEvents(ctx, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SearchEvents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var got []string
+			for _, result := range resp.Results {
+				got = append(got, result.Event.ID)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("SearchEvents() event IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
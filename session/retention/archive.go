@@ -0,0 +1,72 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/export"
+)
+
+// DirArchiver returns an Archiver writing each session in dir, at
+// <app>/<user>/<session>.json, in the format of export.Encode, so that
+// archived sessions can be restored with export.Decode and export.Import.
+// The path elements are escaped as URL path segments, an existing archive
+// of the session is replaced.
+func DirArchiver(dir string) Archiver {
+	return dirArchiver{dir: dir}
+}
+
+type dirArchiver struct {
+	dir string
+}
+
+func (a dirArchiver) Archive(ctx context.Context, s session.Session) error {
+	var segments []string
+	for _, name := range []string{s.AppName(), s.UserID(), s.ID()} {
+		segment, err := sessionutils.PathSegment(name)
+		if err != nil {
+			return fmt.Errorf("invalid archive path: %w", err)
+		}
+		segments = append(segments, segment)
+	}
+	dir := filepath.Join(a.dir, segments[0], segments[1])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	// Write to a temporary file first, so that a failed write does not leave
+	// a truncated archive behind.
+	tmp, err := os.CreateTemp(dir, ".archive-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := export.Encode(tmp, export.FromSession(s)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, segments[2]+".json")); err != nil {
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	return nil
+}
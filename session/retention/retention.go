@@ -0,0 +1,326 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retention enforces per-app retention policies on sessions.
+//
+// A Sweeper deletes, or archives, the sessions which were not updated for
+// longer than the policy allows, together with their artifacts, and trims
+// the oldest events of the sessions with too many events. It can sweep on
+// demand, see Sweeper.Sweep, or periodically in the background, see
+// Sweeper.Run.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/sjzsdu/adk-go/artifact"
+	"github.com/sjzsdu/adk-go/internal/version"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// DefaultInterval is the default interval between the sweeps of Sweeper.Run.
+const DefaultInterval = time.Hour
+
+// Policy is the retention policy of the sessions of an app.
+type Policy struct {
+	// MaxAge is the maximum time since the last update of a session. Older
+	// sessions are deleted, or archived if Archive is set.
+	// Optional: if zero, sessions do not expire.
+	MaxAge time.Duration
+	// MaxEvents is the maximum number of events of a session. The oldest
+	// events of the sessions with more events are deleted, the session
+	// state is kept. It requires a session service implementing
+	// session.EventTrimmer.
+	// Optional: if zero, the number of events is not limited.
+	MaxEvents int
+	// Archive makes the expired sessions be archived with Config.Archiver
+	// before they are deleted. The artifacts of archived sessions are kept,
+	// as the archived events refer to them.
+	Archive bool
+}
+
+// Archiver archives sessions before they are deleted.
+type Archiver interface {
+	// Archive stores the session, including its events.
+	Archive(ctx context.Context, s session.Session) error
+}
+
+// Config is the configuration of a Sweeper.
+type Config struct {
+	// SessionService is the service whose sessions are swept.
+	SessionService session.Service
+	// ArtifactService is used to delete the artifacts of the deleted
+	// sessions. User-scoped artifacts are shared between sessions and are
+	// never deleted.
+	// Optional: if nil, artifacts are not deleted.
+	ArtifactService artifact.Service
+	// Policies maps app names to their retention policies. The sessions of
+	// the apps without a policy are not swept.
+	Policies map[string]Policy
+	// Archiver archives the expired sessions of the apps whose policy has
+	// Archive set. Required if any policy has Archive set.
+	Archiver Archiver
+	// Interval is the interval between the sweeps of Sweeper.Run.
+	// Optional: defaults to DefaultInterval.
+	Interval time.Duration
+	// MeterProvider provides the meter recording the sweep metrics.
+	// Optional: defaults to the global meter provider.
+	MeterProvider metric.MeterProvider
+}
+
+// Stats are the results of a sweep.
+type Stats struct {
+	// Sessions is the number of swept sessions.
+	Sessions int
+	// Deleted is the number of expired sessions which were deleted without
+	// being archived.
+	Deleted int
+	// Archived is the number of expired sessions which were archived and
+	// deleted.
+	Archived int
+	// TrimmedEvents is the number of events deleted from the sessions with
+	// too many events.
+	TrimmedEvents int
+	// DeletedArtifacts is the number of artifacts deleted with their
+	// sessions.
+	DeletedArtifacts int
+}
+
+// Sweeper applies retention policies to the sessions of a session service.
+type Sweeper struct {
+	sessionService  session.Service
+	artifactService artifact.Service
+	policies        map[string]Policy
+	archiver        Archiver
+	interval        time.Duration
+
+	sweeps           metric.Int64Counter
+	sweepDuration    metric.Float64Histogram
+	deletedSessions  metric.Int64Counter
+	archivedSessions metric.Int64Counter
+	trimmedEvents    metric.Int64Counter
+	deletedArtifacts metric.Int64Counter
+}
+
+// New creates a Sweeper. It checks that the session service and the
+// archiver support the policies.
+func New(cfg Config) (*Sweeper, error) {
+	if cfg.SessionService == nil {
+		return nil, fmt.Errorf("session service is required")
+	}
+	for appName, policy := range cfg.Policies {
+		if policy.MaxAge < 0 || policy.MaxEvents < 0 {
+			return nil, fmt.Errorf("policy of app %q: max age and max events must not be negative", appName)
+		}
+		if policy.Archive && cfg.Archiver == nil {
+			return nil, fmt.Errorf("policy of app %q: archiving requires an archiver", appName)
+		}
+		if _, ok := cfg.SessionService.(session.EventTrimmer); policy.MaxEvents > 0 && !ok {
+			return nil, fmt.Errorf("policy of app %q: the session service does not support trimming events: %w", appName, errors.ErrUnsupported)
+		}
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	meterProvider := cfg.MeterProvider
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+
+	s := &Sweeper{
+		sessionService:  cfg.SessionService,
+		artifactService: cfg.ArtifactService,
+		policies:        cfg.Policies,
+		archiver:        cfg.Archiver,
+		interval:        interval,
+	}
+	meter := meterProvider.Meter("github.com/sjzsdu/adk-go/session/retention", metric.WithInstrumentationVersion(version.Version))
+	var errs []error
+	var err error
+	s.sweeps, err = meter.Int64Counter("adk.session.retention.sweeps",
+		metric.WithDescription("Number of retention sweeps, by app."))
+	errs = append(errs, err)
+	s.sweepDuration, err = meter.Float64Histogram("adk.session.retention.sweep.duration",
+		metric.WithDescription("Duration of retention sweeps, by app."), metric.WithUnit("s"))
+	errs = append(errs, err)
+	s.deletedSessions, err = meter.Int64Counter("adk.session.retention.sessions.deleted",
+		metric.WithDescription("Number of expired sessions deleted without being archived."))
+	errs = append(errs, err)
+	s.archivedSessions, err = meter.Int64Counter("adk.session.retention.sessions.archived",
+		metric.WithDescription("Number of expired sessions archived and deleted."))
+	errs = append(errs, err)
+	s.trimmedEvents, err = meter.Int64Counter("adk.session.retention.events.trimmed",
+		metric.WithDescription("Number of events trimmed from sessions with too many events."))
+	errs = append(errs, err)
+	s.deletedArtifacts, err = meter.Int64Counter("adk.session.retention.artifacts.deleted",
+		metric.WithDescription("Number of artifacts deleted with their sessions."))
+	errs = append(errs, err)
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("failed to create the retention metrics: %w", err)
+	}
+	return s, nil
+}
+
+// Sweep applies the retention policies once. It goes on with the other
+// sessions when a session fails to be swept, and returns the joined errors
+// with the stats of what was done.
+func (s *Sweeper) Sweep(ctx context.Context) (Stats, error) {
+	var stats Stats
+	var errs []error
+	appNames := make([]string, 0, len(s.policies))
+	for appName := range s.policies {
+		appNames = append(appNames, appName)
+	}
+	slices.Sort(appNames)
+	for _, appName := range appNames {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		start := time.Now()
+		appStats, err := s.sweepApp(ctx, appName, s.policies[appName])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to sweep app %q: %w", appName, err))
+		}
+
+		attrs := metric.WithAttributes(attribute.String("app_name", appName))
+		s.sweeps.Add(ctx, 1, attrs)
+		s.sweepDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+		s.deletedSessions.Add(ctx, int64(appStats.Deleted), attrs)
+		s.archivedSessions.Add(ctx, int64(appStats.Archived), attrs)
+		s.trimmedEvents.Add(ctx, int64(appStats.TrimmedEvents), attrs)
+		s.deletedArtifacts.Add(ctx, int64(appStats.DeletedArtifacts), attrs)
+
+		stats.Sessions += appStats.Sessions
+		stats.Deleted += appStats.Deleted
+		stats.Archived += appStats.Archived
+		stats.TrimmedEvents += appStats.TrimmedEvents
+		stats.DeletedArtifacts += appStats.DeletedArtifacts
+	}
+	return stats, errors.Join(errs...)
+}
+
+// Run sweeps at every interval until the context is done. Sweep errors are
+// logged.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if stats, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("session retention sweep failed (%+v): %v", stats, err)
+			}
+		}
+	}
+}
+
+func (s *Sweeper) sweepApp(ctx context.Context, appName string, policy Policy) (Stats, error) {
+	var stats Stats
+	// The sessions are not listed by pages, as deleting sessions would shift
+	// the next pages.
+	resp, err := s.sessionService.List(ctx, &session.ListRequest{AppName: appName, MetadataOnly: true})
+	if err != nil {
+		return stats, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	var errs []error
+	for _, sess := range resp.Sessions {
+		stats.Sessions++
+		if policy.MaxAge > 0 && time.Since(sess.LastUpdateTime()) > policy.MaxAge {
+			if err := s.expire(ctx, sess, policy, &stats); err != nil {
+				errs = append(errs, fmt.Errorf("failed to expire session %q: %w", sess.ID(), err))
+			}
+			continue
+		}
+		if policy.MaxEvents > 0 {
+			trimmed, err := s.sessionService.(session.EventTrimmer).TrimEvents(ctx, &session.TrimEventsRequest{
+				AppName:   appName,
+				UserID:    sess.UserID(),
+				SessionID: sess.ID(),
+				MaxEvents: policy.MaxEvents,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to trim the events of session %q: %w", sess.ID(), err))
+				continue
+			}
+			stats.TrimmedEvents += trimmed.Deleted
+		}
+	}
+	return stats, errors.Join(errs...)
+}
+
+// expire archives the session if the policy says so, or deletes its
+// artifacts, and then deletes the session.
+func (s *Sweeper) expire(ctx context.Context, sess session.Session, policy Policy, stats *Stats) error {
+	if policy.Archive {
+		// Listed sessions have no events, the archive needs them.
+		resp, err := s.sessionService.Get(ctx, &session.GetRequest{AppName: sess.AppName(), UserID: sess.UserID(), SessionID: sess.ID()})
+		if err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+		if err := s.archiver.Archive(ctx, resp.Session); err != nil {
+			return fmt.Errorf("failed to archive session: %w", err)
+		}
+	} else if s.artifactService != nil {
+		n, err := s.deleteArtifacts(ctx, sess)
+		stats.DeletedArtifacts += n
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.sessionService.Delete(ctx, &session.DeleteRequest{AppName: sess.AppName(), UserID: sess.UserID(), SessionID: sess.ID()})
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if policy.Archive {
+		stats.Archived++
+	} else {
+		stats.Deleted++
+	}
+	return nil
+}
+
+// deleteArtifacts deletes all the versions of the session-scoped artifacts
+// of the session, and returns the number of deleted artifacts.
+func (s *Sweeper) deleteArtifacts(ctx context.Context, sess session.Session) (int, error) {
+	resp, err := s.artifactService.List(ctx, &artifact.ListRequest{AppName: sess.AppName(), UserID: sess.UserID(), SessionID: sess.ID()})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	deleted := 0
+	for _, fileName := range resp.FileNames {
+		if strings.HasPrefix(fileName, "user:") {
+			continue
+		}
+		err := s.artifactService.Delete(ctx, &artifact.DeleteRequest{AppName: sess.AppName(), UserID: sess.UserID(), SessionID: sess.ID(), FileName: fileName})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete artifact %q: %w", fileName, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
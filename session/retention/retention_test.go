@@ -0,0 +1,269 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retention_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/genai"
+
+	"github.com/sjzsdu/adk-go/artifact"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/export"
+	"github.com/sjzsdu/adk-go/session/retention"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     retention.Config
+		wantErr bool
+	}{
+		{
+			name:    "no session service",
+			cfg:     retention.Config{},
+			wantErr: true,
+		},
+		{
+			name: "archive without archiver",
+			cfg: retention.Config{
+				SessionService: session.InMemoryService(),
+				Policies:       map[string]retention.Policy{"app": {MaxAge: time.Hour, Archive: true}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max age",
+			cfg: retention.Config{
+				SessionService: session.InMemoryService(),
+				Policies:       map[string]retention.Policy{"app": {MaxAge: -time.Hour}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "max events without trimming support",
+			cfg: retention.Config{
+				SessionService: struct{ session.Service }{session.InMemoryService()},
+				Policies:       map[string]retention.Policy{"app": {MaxEvents: 10}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid",
+			cfg: retention.Config{
+				SessionService: session.InMemoryService(),
+				Archiver:       retention.DirArchiver(t.TempDir()),
+				Policies: map[string]retention.Policy{
+					"app":      {MaxAge: time.Hour, MaxEvents: 10},
+					"archived": {MaxAge: time.Hour, Archive: true},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := retention.New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	ctx := t.Context()
+	sessionService := session.InMemoryService()
+	artifactService := artifact.InMemoryService()
+	archiveDir := t.TempDir()
+	reader := sdkmetric.NewManualReader()
+
+	// createSession creates a session last updated at updated, with the
+	// given number of events and an artifact of each scope.
+	createSession := func(appName, sessionID string, updated time.Time, events int) {
+		t.Helper()
+		created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: appName, UserID: "user", SessionID: sessionID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range events {
+			ev := session.NewEvent("inv")
+			ev.Timestamp = updated.Add(time.Duration(i-events+1) * time.Second)
+			if err := sessionService.AppendEvent(ctx, created.Session, ev); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, fileName := range []string{"a.txt", "user:profile.txt"} {
+			_, err := artifactService.Save(ctx, &artifact.SaveRequest{
+				AppName: appName, UserID: "user", SessionID: sessionID, FileName: fileName,
+				Part: genai.NewPartFromText(fileName),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	createSession("app", "old", old, 1)
+	createSession("app", "long", time.Now(), 5)
+	createSession("archived", "old", old, 2)
+	createSession("other", "old", old, 1)
+
+	sweeper, err := retention.New(retention.Config{
+		SessionService:  sessionService,
+		ArtifactService: artifactService,
+		Archiver:        retention.DirArchiver(archiveDir),
+		Policies: map[string]retention.Policy{
+			"app":      {MaxAge: 24 * time.Hour, MaxEvents: 2},
+			"archived": {MaxAge: 24 * time.Hour, Archive: true},
+		},
+		MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := sweeper.Sweep(ctx)
+	if err != nil {
+		t.Fatalf("Sweep() failed: %v", err)
+	}
+	wantStats := retention.Stats{Sessions: 3, Deleted: 1, Archived: 1, TrimmedEvents: 3, DeletedArtifacts: 1}
+	if diff := cmp.Diff(wantStats, stats); diff != "" {
+		t.Errorf("Sweep() stats mismatch (-want +got):\n%s", diff)
+	}
+
+	sessionIDs := func(appName string) []string {
+		t.Helper()
+		resp, err := sessionService.List(ctx, &session.ListRequest{AppName: appName})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, s := range resp.Sessions {
+			ids = append(ids, s.ID())
+		}
+		return ids
+	}
+	for appName, want := range map[string][]string{
+		"app":      {"long"},
+		"archived": nil,
+		"other":    {"old"},
+	} {
+		if diff := cmp.Diff(want, sessionIDs(appName)); diff != "" {
+			t.Errorf("sessions of app %q after Sweep() mismatch (-want +got):\n%s", appName, diff)
+		}
+	}
+
+	long, err := sessionService.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "long"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := long.Session.Events().Len(); got != 2 {
+		t.Errorf("events of the long session after Sweep() = %d, want 2", got)
+	}
+
+	artifacts := func(appName, sessionID string) []string {
+		t.Helper()
+		resp, err := artifactService.List(ctx, &artifact.ListRequest{AppName: appName, UserID: "user", SessionID: sessionID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.FileNames
+	}
+	// User-scoped artifacts and the artifacts of archived sessions are kept.
+	if diff := cmp.Diff([]string{"user:profile.txt"}, artifacts("app", "old")); diff != "" {
+		t.Errorf("artifacts of the deleted session mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a.txt", "user:profile.txt"}, artifacts("archived", "old")); diff != "" {
+		t.Errorf("artifacts of the archived session mismatch (-want +got):\n%s", diff)
+	}
+
+	f, err := os.Open(filepath.Join(archiveDir, "archived", "user", "old.json"))
+	if err != nil {
+		t.Fatalf("failed to open the archive: %v", err)
+	}
+	defer f.Close()
+	archived, err := export.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archived.AppName != "archived" || archived.ID != "old" || len(archived.Events) != 2 {
+		t.Errorf("archived session = %+v, want session %q with 2 events", archived, "old")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, dp := range sum.DataPoints {
+					got[m.Name] += dp.Value
+				}
+			}
+		}
+	}
+	wantMetrics := map[string]int64{
+		"adk.session.retention.sweeps":            2,
+		"adk.session.retention.sessions.deleted":  1,
+		"adk.session.retention.sessions.archived": 1,
+		"adk.session.retention.events.trimmed":    3,
+		"adk.session.retention.artifacts.deleted": 1,
+	}
+	if diff := cmp.Diff(wantMetrics, got); diff != "" {
+		t.Errorf("metrics mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSweeper_Sweep_Errors(t *testing.T) {
+	ctx := t.Context()
+	sessionService := session.InMemoryService()
+	if _, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	archiveErr := errors.New("archive failed")
+	sweeper, err := retention.New(retention.Config{
+		SessionService: sessionService,
+		Archiver:       archiverFunc(func(session.Session) error { return archiveErr }),
+		Policies:       map[string]retention.Policy{"app": {MaxAge: time.Nanosecond, Archive: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, err := sweeper.Sweep(ctx); !errors.Is(err, archiveErr) {
+		t.Errorf("Sweep() error = %v, want %v", err, archiveErr)
+	}
+	// The session is not deleted if it was not archived.
+	if _, err := sessionService.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "old"}); err != nil {
+		t.Errorf("Get() after a failed Sweep() error = %v, want the session kept", err)
+	}
+}
+
+type archiverFunc func(session.Session) error
+
+func (f archiverFunc) Archive(_ context.Context, s session.Session) error {
+	return f(s)
+}
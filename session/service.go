@@ -29,6 +29,12 @@ type Service interface {
 	Delete(context.Context, *DeleteRequest) error
 	// AppendEvent is used to append an event to a session, and remove temporary state keys from the event.
 	AppendEvent(context.Context, Session, *Event) error
+	// Rewind rewinds a session to before an invocation, e.g. to edit and
+	// resend an earlier message. See RewindRequest.
+	Rewind(context.Context, *RewindRequest) (*RewindResponse, error)
+	// Fork creates a new session from a prefix of the events of a session,
+	// e.g. to explore another answer. See ForkRequest.
+	Fork(context.Context, *ForkRequest) (*ForkResponse, error)
 }
 
 // InMemoryService returns an in-memory implementation of the session service.
@@ -78,11 +84,52 @@ type GetResponse struct {
 type ListRequest struct {
 	AppName string
 	UserID  string
+
+	// Below are optional fields.
+
+	// PageSize is the maximum number of sessions to return. If zero, all
+	// the sessions are returned.
+	PageSize int
+	// PageToken is the NextPageToken of a previous response, to get the
+	// next page. The other fields must be the same as in the request of the
+	// previous page.
+	PageToken string
+	// OrderBy is the order of the sessions, ListOrderID by default.
+	OrderBy ListOrder
+	// StateFilter keeps only the sessions whose state has all its keys,
+	// with equal values. Values are compared by their JSON encodings, so
+	// that e.g. 1 and 1.0 are equal. The keys may be app and user keys.
+	StateFilter map[string]any
+	// CreatedAfter and CreatedBefore keep only the sessions created in the
+	// time range: at or after CreatedAfter, and before CreatedBefore. A zero
+	// time leaves the range open.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// MetadataOnly makes the sessions be returned without their state and
+	// events, only with their IDs and update times, which is cheaper.
+	MetadataOnly bool
 }
 
+// ListOrder is the order of the sessions returned by [Service.List].
+type ListOrder int
+
+const (
+	// ListOrderID orders the sessions by user ID, then by session ID.
+	ListOrderID ListOrder = iota
+	// ListOrderUpdateTimeDesc orders the sessions by update time, the most
+	// recently updated first.
+	ListOrderUpdateTimeDesc
+	// ListOrderUpdateTimeAsc orders the sessions by update time, the least
+	// recently updated first.
+	ListOrderUpdateTimeAsc
+)
+
 // ListResponse represents a response from [Service.List].
 type ListResponse struct {
 	Sessions []Session
+	// NextPageToken is the token of the next page, see
+	// ListRequest.PageToken, or empty if this is the last page.
+	NextPageToken string
 }
 
 // DeleteRequest represents a request to delete a session.
@@ -91,3 +138,141 @@ type DeleteRequest struct {
 	UserID    string
 	SessionID string
 }
+
+// RewindRequest represents a request to rewind a session to before an
+// invocation. The events of the invocation and all the later events are
+// removed, and the session state is restored by replaying the state deltas
+// of the remaining events over the state the session was created with. The
+// app and user states are shared with other sessions and are not restored.
+type RewindRequest struct {
+	AppName   string
+	UserID    string
+	SessionID string
+	// InvocationID is the ID of the invocation to rewind before.
+	InvocationID string
+}
+
+// RewindResponse represents a response from [Service.Rewind].
+type RewindResponse struct {
+	// Session is the rewound session.
+	Session Session
+	// ArtifactVersions maps the artifacts saved by the removed events, see
+	// EventActions.ArtifactDelta, to their versions before the invocation,
+	// or 0 for the artifacts that did not exist. The artifact service is
+	// not part of the session service, so reverting the artifacts is up to
+	// the caller, e.g. Runner.Rewind.
+	ArtifactVersions map[string]int64
+}
+
+// ForkRequest represents a request to fork a session at an event. The new
+// session has copies of the events up to the event, and the session state
+// reconstructed by replaying their state deltas over the state the parent
+// session was created with. Where the session was forked from is recorded,
+// see ForkOriginOf.
+type ForkRequest struct {
+	AppName   string
+	UserID    string
+	SessionID string
+	// EventID is the ID of the last event to carry over to the new session.
+	// Optional: if empty, all the events are carried over.
+	EventID string
+	// NewSessionID is the client-provided ID of the new session.
+	// Optional: if not set, it will be autogenerated.
+	NewSessionID string
+}
+
+// ForkResponse represents a response from [Service.Fork].
+type ForkResponse struct {
+	Session Session
+}
+
+// ForkOrigin describes where a session was forked from, see Service.Fork.
+type ForkOrigin struct {
+	// SessionID is the ID of the parent session.
+	SessionID string `json:"sessionId"`
+	// EventID is the ID of the last event carried over from the parent
+	// session, empty if the parent session had no events.
+	EventID string `json:"eventId,omitempty"`
+	// Artifacts maps the artifacts saved by the carried over events, see
+	// EventActions.ArtifactDelta, to their versions at the fork point. The
+	// artifacts are stored in the parent session.
+	Artifacts map[string]int64 `json:"artifacts,omitempty"`
+}
+
+// ForkOriginOf returns where the session was forked from, or nil if it was
+// not forked.
+func ForkOriginOf(s Session) *ForkOrigin {
+	if forked, ok := s.(interface{ ForkOrigin() *ForkOrigin }); ok {
+		return forked.ForkOrigin()
+	}
+	return nil
+}
+
+// EventTrimmer is implemented by the session services which can delete the
+// oldest events of a session, such as the in-memory, the filesystem and the
+// database services.
+type EventTrimmer interface {
+	TrimEvents(context.Context, *TrimEventsRequest) (*TrimEventsResponse, error)
+}
+
+// TrimEventsRequest represents a request to delete the oldest events of a
+// session, keeping at most MaxEvents events, see EventTrimmer. The session
+// state is not changed: the state and artifact deltas of the deleted events
+// are folded into the state the session was created with and the artifact
+// versions it started from, so that Rewind still restores the right state
+// and artifacts.
+type TrimEventsRequest struct {
+	AppName   string
+	UserID    string
+	SessionID string
+	// MaxEvents is the number of the most recent events to keep.
+	MaxEvents int
+}
+
+// TrimEventsResponse represents a response to a TrimEventsRequest.
+type TrimEventsResponse struct {
+	// Deleted is the number of deleted events.
+	Deleted int
+}
+
+// EventSearcher is implemented by the session services which can search the
+// events of the sessions, such as the in-memory and the database services.
+type EventSearcher interface {
+	SearchEvents(context.Context, *SearchEventsRequest) (*SearchEventsResponse, error)
+}
+
+// SearchEventsRequest represents a request to search the events of the
+// sessions of an app by their text: the text parts of their content, and
+// the names and arguments of their function calls, see EventSearcher.
+type SearchEventsRequest struct {
+	AppName string
+	// UserID keeps only the events of the sessions of the user.
+	// Optional: if empty, the sessions of all the users are searched.
+	UserID string
+	// Query holds the words to search for. An event matches if its text has
+	// all of them as words, case-insensitively.
+	Query string
+	// Author keeps only the events of the author, e.g. the name of an agent
+	// or "user". Optional.
+	Author string
+	// After and Before keep only the events in the time range: at or after
+	// After, and before Before. A zero time leaves the range open.
+	After  time.Time
+	Before time.Time
+	// Limit is the maximum number of events to return. If zero, all the
+	// matching events are returned.
+	Limit int
+}
+
+// SearchEventsResponse represents a response to a SearchEventsRequest.
+type SearchEventsResponse struct {
+	// Results are the matching events, the most recent first.
+	Results []SearchResult
+}
+
+// SearchResult is an event matching a SearchEventsRequest.
+type SearchResult struct {
+	UserID    string
+	SessionID string
+	Event     *Event
+}
@@ -20,7 +20,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"google.golang.org/genai"
 
+	"github.com/sjzsdu/adk-go/auth"
 	"github.com/sjzsdu/adk-go/model"
 	"github.com/sjzsdu/adk-go/tool/toolconfirmation"
 )
@@ -142,21 +144,50 @@ func NewEvent(invocationID string) *Event {
 // EventActions represent the actions attached to an event.
 type EventActions struct {
 	// Set by agent.Context implementation.
-	StateDelta map[string]any
+	StateDelta map[string]any `json:"stateDelta,omitempty"`
 
 	// Indicates that the event is updating an artifact. key is the filename,
 	// value is the version.
-	ArtifactDelta map[string]int64
+	ArtifactDelta map[string]int64 `json:"artifactDelta,omitempty"`
 
-	RequestedToolConfirmations map[string]toolconfirmation.ToolConfirmation
+	RequestedToolConfirmations map[string]toolconfirmation.ToolConfirmation `json:"requestedToolConfirmations,omitempty"`
+
+	// RequestedAuthConfigs maps function call IDs to the credentials requested
+	// by the tools via tool.Context.RequestCredential.
+	RequestedAuthConfigs map[string]*auth.AuthConfig `json:"requestedAuthConfigs,omitempty"`
 
 	// If true, it won't call model to summarize function response.
 	// Only valid for function response event.
-	SkipSummarization bool
+	SkipSummarization bool `json:"skipSummarization,omitempty"`
 	// If set, the event transfers to the specified agent.
-	TransferToAgent string
+	TransferToAgent string `json:"transferToAgent,omitempty"`
 	// The agent is escalating to a higher level agent.
-	Escalate bool
+	Escalate bool `json:"escalate,omitempty"`
+
+	// Compaction is set on events that summarize earlier events of the
+	// session. Model requests contain the summary instead of the events it
+	// covers.
+	Compaction *EventCompaction `json:"compaction,omitempty"`
+
+	// AgentState is the progress of the author agent within the invocation,
+	// e.g. the sub-agent a workflow agent is running. It is recorded by
+	// resumable runners, see runner.Config.Resumable, so that a paused
+	// invocation can continue where it stopped.
+	AgentState map[string]any `json:"agentState,omitempty"`
+	// EndOfAgent reports that the author agent finished within the
+	// invocation, so its AgentState no longer applies.
+	EndOfAgent bool `json:"endOfAgent,omitempty"`
+}
+
+// EventCompaction summarizes a range of earlier events of a session.
+type EventCompaction struct {
+	// StartEventID and EndEventID are the IDs of the first and the last event
+	// covered by the compaction. Events in between that belong to the branch
+	// of the compaction event are covered too.
+	StartEventID string `json:"startEventId"`
+	EndEventID   string `json:"endEventId"`
+	// CompactedContent is the summary of the covered events.
+	CompactedContent *genai.Content `json:"compactedContent,omitempty"`
 }
 
 // Prefixes for defining session's state scopes
@@ -178,6 +209,11 @@ const (
 // ErrStateKeyNotExist is the error thrown when key does not exist.
 var ErrStateKeyNotExist = errors.New("state key does not exist")
 
+// ErrStaleSession is returned by Service.AppendEvent when the session was
+// modified in the storage since it was read, e.g. by a concurrent run. Get
+// the session again to continue.
+var ErrStaleSession = errors.New("stale session")
+
 func hasFunctionCalls(resp *model.LLMResponse) bool {
 	if resp == nil || resp.Content == nil {
 		return false
@@ -0,0 +1,80 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// ErrInvalidState is returned when a state does not match its schema.
+var ErrInvalidState = errors.New("invalid state")
+
+// Schema validates the session states of an app with a JSON Schema
+// describing the state as an object, whose properties are the state keys,
+// including the app and user keys. The temporary keys, which are not
+// persisted, are not validated.
+//
+// The schema applies to the whole state, which is built up by the events of
+// a session, so it should not require keys which are only set later.
+type Schema struct {
+	resolved *jsonschema.Resolved
+}
+
+// NewSchema returns a Schema validating the states with schema.
+func NewSchema(schema *jsonschema.Schema) (*Schema, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("schema is nil")
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve state schema: %w", err)
+	}
+	return &Schema{resolved: resolved}, nil
+}
+
+// Validate returns an error wrapping ErrInvalidState if the state does not
+// match the schema. The values of the state are validated in their JSON
+// form.
+func (s *Schema) Validate(state map[string]any) error {
+	persisted := make(map[string]any, len(state))
+	for key, value := range state {
+		if !strings.HasPrefix(key, session.KeyPrefixTemp) {
+			persisted[key] = value
+		}
+	}
+	instance, err := toJSONValue(persisted)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := s.resolved.Validate(instance); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+	return nil
+}
+
+// ValidateDelta returns an error wrapping ErrInvalidState if applying the
+// state delta of an event to the state would make it not match the schema.
+func (s *Schema) ValidateDelta(state session.ReadonlyState, delta map[string]any) error {
+	updated := maps.Collect(state.All())
+	maps.Copy(updated, delta)
+	return s.Validate(updated)
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/sjzsdu/adk-go/session"
+)
+
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	schema, err := NewSchema(&jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"count": {Type: "integer", Minimum: jsonschema.Ptr(0.0)},
+			"user:preferences": {
+				Type:       "object",
+				Properties: map[string]*jsonschema.Schema{"theme": {Enum: []any{"light", "dark"}}},
+			},
+		},
+		AdditionalProperties: &jsonschema.Schema{Type: "string"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema := testSchema(t)
+	tests := []struct {
+		name    string
+		state   map[string]any
+		wantErr bool
+	}{
+		{name: "empty", state: map[string]any{}},
+		{name: "valid", state: map[string]any{"count": 2, "user:preferences": preferences{Theme: "dark"}, "note": "x"}},
+		{name: "temporary keys are not validated", state: map[string]any{"temp:scratch": 1}},
+		{name: "wrong type", state: map[string]any{"count": "two"}, wantErr: true},
+		{name: "out of range", state: map[string]any{"count": -1}, wantErr: true},
+		{name: "invalid struct", state: map[string]any{"user:preferences": preferences{Theme: "blue"}}, wantErr: true},
+		{name: "invalid additional key", state: map[string]any{"note": 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(tt.state)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("Validate() error = %v, want error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidState) {
+				t.Errorf("Validate() error = %v, want ErrInvalidState", err)
+			}
+		})
+	}
+}
+
+func TestSchema_ValidateDelta(t *testing.T) {
+	schema := testSchema(t)
+	created, err := session.InMemoryService().Create(t.Context(), &session.CreateRequest{
+		AppName: "app",
+		UserID:  "user",
+		State:   map[string]any{"count": 1, "note": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The invalid value of the state is fixed by the delta.
+	if err := schema.ValidateDelta(created.Session.State(), map[string]any{"note": "x"}); err != nil {
+		t.Errorf("ValidateDelta() failed: %v", err)
+	}
+	if err := schema.ValidateDelta(created.Session.State(), map[string]any{"note": "x", "count": 1.5}); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("ValidateDelta() error = %v, want ErrInvalidState", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package state provides typed accessors of the session state and the
+// validation of the session state with a JSON Schema.
+//
+// The session services persisting the state, such as session/database,
+// store its values as JSON, so a value does not come back with the type it
+// was set with: integers come back as float64 and structs as maps. A Key
+// stores values in their JSON form and decodes them into its type, so
+// that they read the same with every session service:
+//
+//	var visits = state.NewKey[int]("user:visits")
+//
+//	func beforeAgent(ctx agent.CallbackContext) (*genai.Content, error) {
+//		n, err := visits.Get(ctx)
+//		if err != nil && !errors.Is(err, session.ErrStateKeyNotExist) {
+//			return nil, err
+//		}
+//		return nil, visits.Set(ctx, n+1)
+//	}
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/session"
+)
+
+// Key is a session state key holding values of type T, which must be
+// encodable as JSON.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns the key of the state with the given name. The name may
+// have a scope prefix, such as session.KeyPrefixUser.
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+// Name returns the name of the key.
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// Get returns the value of the key in the state of the context, such as an
+// agent.CallbackContext or a tool.Context, see GetFrom.
+func (k Key[T]) Get(ctx agent.ReadonlyContext) (T, error) {
+	if cbCtx, ok := ctx.(agent.CallbackContext); ok {
+		// The state of a callback context includes the changes made by
+		// the callback.
+		return k.GetFrom(cbCtx.State())
+	}
+	return k.GetFrom(ctx.ReadonlyState())
+}
+
+// Set sets the value of the key in the state of the context, such as an
+// agent.CallbackContext or a tool.Context, see SetIn.
+func (k Key[T]) Set(ctx agent.CallbackContext, value T) error {
+	return k.SetIn(ctx.State(), value)
+}
+
+// GetFrom returns the value of the key in the state, decoded from its JSON
+// form if it does not have type T. It returns an error wrapping
+// session.ErrStateKeyNotExist if the key is not set.
+func (k Key[T]) GetFrom(s session.ReadonlyState) (T, error) {
+	var value T
+	v, err := s.Get(k.name)
+	if err != nil {
+		return value, fmt.Errorf("failed to get state key %q: %w", k.name, err)
+	}
+	if value, ok := v.(T); ok {
+		return value, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return value, fmt.Errorf("failed to encode state key %q: %w", k.name, err)
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode state key %q as %T: %w", k.name, value, err)
+	}
+	return value, nil
+}
+
+// SetIn sets the value of the key in the state. The value is stored in its
+// JSON form, as a persisting session service would return it, so that the
+// state is the same with every session service.
+func (k Key[T]) SetIn(s session.State, value T) error {
+	v, err := toJSONValue(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode state key %q: %w", k.name, err)
+	}
+	return s.Set(k.name, v)
+}
+
+// toJSONValue returns the value decoded from its JSON encoding, i.e. a
+// nil, bool, float64, string, []any or map[string]any value.
+func toJSONValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/go-cmp/cmp"
+
+	icontext "github.com/sjzsdu/adk-go/internal/context"
+	"github.com/sjzsdu/adk-go/internal/toolinternal"
+	"github.com/sjzsdu/adk-go/session"
+	"github.com/sjzsdu/adk-go/session/database"
+	"github.com/sjzsdu/adk-go/session/filesystem"
+)
+
+type preferences struct {
+	Theme    string   `json:"theme"`
+	FontSize int      `json:"fontSize"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+var (
+	countKey       = NewKey[int]("count")
+	preferencesKey = NewKey[preferences]("user:preferences")
+)
+
+func TestKey_Backends(t *testing.T) {
+	databaseService, err := database.NewSessionService(sqlite.Open("file:state_test?mode=memory&cache=shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(databaseService); err != nil {
+		t.Fatal(err)
+	}
+	filesystemService, err := filesystem.NewSessionService(filesystem.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPreferences := preferences{Theme: "dark", FontSize: 14, Tags: []string{"a", "b"}}
+	for name, service := range map[string]session.Service{
+		"inmemory":   session.InMemoryService(),
+		"database":   databaseService,
+		"filesystem": filesystemService,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			created, err := service.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "session"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The values are set by a tool and stored by its event.
+			invCtx := icontext.NewInvocationContext(ctx, icontext.InvocationContextParams{Session: created.Session})
+			actions := &session.EventActions{}
+			toolCtx := toolinternal.NewToolContext(invCtx, "call", actions, nil)
+			if err := countKey.Set(toolCtx, 3); err != nil {
+				t.Fatalf("Set() failed: %v", err)
+			}
+			if err := preferencesKey.Set(toolCtx, wantPreferences); err != nil {
+				t.Fatalf("Set() failed: %v", err)
+			}
+			if got, err := countKey.Get(toolCtx); err != nil || got != 3 {
+				t.Errorf("Get() in the tool context = %v, %v, want 3", got, err)
+			}
+			ev := session.NewEvent("inv")
+			ev.Actions = *actions
+			if err := service.AppendEvent(ctx, created.Session, ev); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := service.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "session"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, err := countKey.GetFrom(got.Session.State())
+			if err != nil {
+				t.Fatalf("GetFrom() failed: %v", err)
+			}
+			if count != 3 {
+				t.Errorf("GetFrom() = %v, want 3", count)
+			}
+			prefs, err := preferencesKey.GetFrom(got.Session.State())
+			if err != nil {
+				t.Fatalf("GetFrom() failed: %v", err)
+			}
+			if diff := cmp.Diff(wantPreferences, prefs); diff != "" {
+				t.Errorf("GetFrom() mismatch (-want +got):\n%s", diff)
+			}
+
+			// The untyped value is the same with every service.
+			raw, err := got.Session.State().Get(preferencesKey.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantRaw := map[string]any{"theme": "dark", "fontSize": 14.0, "tags": []any{"a", "b"}}
+			if diff := cmp.Diff(wantRaw, raw); diff != "" {
+				t.Errorf("stored value mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKey_GetErrors(t *testing.T) {
+	ctx := t.Context()
+	created, err := session.InMemoryService().Create(ctx, &session.CreateRequest{
+		AppName: "app",
+		UserID:  "user",
+		State:   map[string]any{"count": "three"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cbCtx := icontext.NewCallbackContext(icontext.NewInvocationContext(ctx, icontext.InvocationContextParams{Session: created.Session}))
+
+	if _, err := countKey.Get(cbCtx); err == nil {
+		t.Error("Get() of a string as an int succeeded, want error")
+	}
+	if _, err := NewKey[int]("unknown").Get(cbCtx); !errors.Is(err, session.ErrStateKeyNotExist) {
+		t.Errorf("Get() of an unknown key error = %v, want session.ErrStateKeyNotExist", err)
+	}
+	if err := NewKey[func()]("func").Set(cbCtx, func() {}); err == nil {
+		t.Error("Set() of a function succeeded, want error")
+	}
+}
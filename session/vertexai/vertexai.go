@@ -15,12 +15,16 @@
 package vertexai
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/option"
 
+	"github.com/sjzsdu/adk-go/internal/sessionutils"
 	"github.com/sjzsdu/adk-go/session"
 )
 
@@ -108,11 +112,46 @@ func (s *vertexAiService) List(ctx context.Context, req *session.ListRequest) (*
 	if req.AppName == "" {
 		return nil, fmt.Errorf("app_name is required, got app_name: %q", req.AppName)
 	}
-	sessions, err := s.client.listSessions(ctx, req)
+	listed, err := s.client.listSessions(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to request sessions list: %w", err)
 	}
-	return &session.ListResponse{Sessions: sessions}, nil
+
+	// The service can neither filter on the state and the creation time
+	// nor order by ID, so the sessions are filtered, ordered and paginated
+	// here.
+	listed = slices.DeleteFunc(listed, func(l listedSession) bool {
+		return !sessionutils.InTimeRange(l.createdAt, req.CreatedAfter, req.CreatedBefore) ||
+			!sessionutils.StateMatches(l.state, req.StateFilter)
+	})
+	switch req.OrderBy {
+	case session.ListOrderID:
+		slices.SortStableFunc(listed, func(a, b listedSession) int {
+			return cmp.Or(cmp.Compare(a.userID, b.userID), cmp.Compare(a.sessionID, b.sessionID))
+		})
+	case session.ListOrderUpdateTimeDesc:
+		slices.SortStableFunc(listed, func(a, b listedSession) int { return b.updatedAt.Compare(a.updatedAt) })
+	case session.ListOrderUpdateTimeAsc:
+		slices.SortStableFunc(listed, func(a, b listedSession) int { return a.updatedAt.Compare(b.updatedAt) })
+	default:
+		return nil, fmt.Errorf("unknown list order %d", req.OrderBy)
+	}
+	page, nextPageToken, err := sessionutils.Paginate(listed, req.PageToken, req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &session.ListResponse{
+		Sessions:      make([]session.Session, 0, len(page)),
+		NextPageToken: nextPageToken,
+	}
+	for _, l := range page {
+		if req.MetadataOnly {
+			l.state = make(map[string]any)
+		}
+		resp.Sessions = append(resp.Sessions, l.localSession)
+	}
+	return resp, nil
 }
 
 func (s *vertexAiService) Delete(ctx context.Context, req *session.DeleteRequest) error {
@@ -126,6 +165,18 @@ func (s *vertexAiService) Delete(ctx context.Context, req *session.DeleteRequest
 	return nil
 }
 
+// Rewind is not supported: the Vertex AI session API cannot delete the events
+// of a session.
+func (s *vertexAiService) Rewind(ctx context.Context, req *session.RewindRequest) (*session.RewindResponse, error) {
+	return nil, fmt.Errorf("rewind of Vertex AI sessions: %w", errors.ErrUnsupported)
+}
+
+// Fork is not supported: the Vertex AI session API cannot create a session
+// with events.
+func (s *vertexAiService) Fork(ctx context.Context, req *session.ForkRequest) (*session.ForkResponse, error) {
+	return nil, fmt.Errorf("fork of Vertex AI sessions: %w", errors.ErrUnsupported)
+}
+
 func (s *vertexAiService) AppendEvent(ctx context.Context, sess session.Session, event *session.Event) error {
 	if sess.ID() == "" || event == nil {
 		return fmt.Errorf("session_id and event are required, got session_id: %q, event_id: %t", sess.ID(), event == nil)
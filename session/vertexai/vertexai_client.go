@@ -164,8 +164,15 @@ func (c *vertexAiClient) getSession(ctx context.Context, req *session.GetRequest
 	}, nil
 }
 
-func (c *vertexAiClient) listSessions(ctx context.Context, req *session.ListRequest) ([]session.Session, error) {
-	sessions := make([]session.Session, 0)
+// listedSession is a session returned by listSessions, with its creation
+// time to filter the sessions on.
+type listedSession struct {
+	*localSession
+	createdAt time.Time
+}
+
+func (c *vertexAiClient) listSessions(ctx context.Context, req *session.ListRequest) ([]listedSession, error) {
+	sessions := make([]listedSession, 0)
 
 	reasoningEngine, err := c.getReasoningEngineID(req.AppName)
 	if err != nil {
@@ -197,7 +204,7 @@ func (c *vertexAiClient) listSessions(ctx context.Context, req *session.ListRequ
 			state:     filterNilValues(rpcResp.SessionState.AsMap()),
 			updatedAt: rpcResp.UpdateTime.AsTime(),
 		}
-		sessions = append(sessions, session)
+		sessions = append(sessions, listedSession{localSession: session, createdAt: rpcResp.CreateTime.AsTime()})
 	}
 	return sessions, nil
 }
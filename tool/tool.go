@@ -21,6 +21,7 @@ import (
 	"context"
 
 	"github.com/sjzsdu/adk-go/agent"
+	"github.com/sjzsdu/adk-go/auth"
 	"github.com/sjzsdu/adk-go/memory"
 	"github.com/sjzsdu/adk-go/session"
 	"github.com/sjzsdu/adk-go/tool/toolconfirmation"
@@ -87,6 +88,34 @@ type Context interface {
 	//   - error: If there was a failure in initiating the confirmation process itself (e.g., invalid
 	//     arguments, issue with the event system). The request to ask the user has not been sent.
 	RequestConfirmation(hint string, payload any) error
+
+	// GetCredential returns the credential described by the config, or nil if
+	// it is not available yet.
+	//
+	// The credential is looked up in the credential service configured in the
+	// runner and among the credentials received from the client within the
+	// current invocation. API key and HTTP credentials set in
+	// cfg.RawAuthCredential are returned as is.
+	//
+	// Example Usage:
+	// cred, err := ctx.GetCredential(cfg)
+	// if err != nil {
+	//     return nil, err
+	// }
+	// if cred == nil {
+	//     return nil, ctx.RequestCredential(cfg)
+	// }
+	GetCredential(cfg *auth.AuthConfig) (*auth.AuthCredential, error)
+
+	// RequestCredential asks the client for the credential described by the
+	// config. ADK emits an "adk_request_credential" function call (see
+	// auth.FunctionCallName) and ends the invocation. Once the client responds,
+	// the credential is stored and the tool is called again, so that
+	// GetCredential returns it.
+	//
+	// For OAuth2 and OpenID Connect, cfg.RawAuthCredential must contain the
+	// client ID and secret. The URI the user is redirected to is generated.
+	RequestCredential(cfg *auth.AuthConfig) error
 }
 
 // Toolset is an interface for a collection of tools. It allows grouping